@@ -33,6 +33,31 @@ func Run() {
 	appConfig.AppName = Getenv("APP_NAME", "gokso")
 	appConfig.AppEnv = Getenv("APP_ENV", "development")
 	appConfig.AppPort = Getenv("APP_PORT", "9001")
+	appConfig.AppURL = Getenv("APP_URL", "http://localhost:"+appConfig.AppPort)
+	appConfig.PDFSignCert = Getenv("PDF_SIGN_CERT", "")
+	appConfig.PDFSignKey = Getenv("PDF_SIGN_KEY", "")
+	appConfig.PDFSignLocation = Getenv("PDF_SIGN_LOCATION", "")
+	appConfig.SessionSecret = Getenv("SESSION_SECRET", "")
+	appConfig.SessionIdleTimeout = Getenv("SESSION_IDLE_TIMEOUT", "2h")
+	appConfig.LoginMaxFailures = Getenv("LOGIN_MAX_FAILURES", "5")
+	appConfig.LoginLockout = Getenv("LOGIN_LOCKOUT", "15m")
+	appConfig.LDAPURL = Getenv("LDAP_URL", "")
+	appConfig.LDAPStartTLS = Getenv("LDAP_START_TLS", "false") == "true"
+	appConfig.LDAPBindDN = Getenv("LDAP_BIND_DN", "")
+	appConfig.LDAPBindPassword = Getenv("LDAP_BIND_PASSWORD", "")
+	appConfig.LDAPBaseDN = Getenv("LDAP_BASE_DN", "")
+	appConfig.LDAPUserFilter = Getenv("LDAP_USER_FILTER", "(&(objectClass=person)(uid=%s))")
+	appConfig.LDAPGroupFilter = Getenv("LDAP_GROUP_FILTER", "")
+	appConfig.LDAPNIKAttr = Getenv("LDAP_NIK_ATTR", "employeeNumber")
+	appConfig.LDAPGroupRoles = Getenv("LDAP_GROUP_ROLES", "")
+	appConfig.OIDCIssuer = Getenv("OIDC_ISSUER", "")
+	appConfig.OIDCClientID = Getenv("OIDC_CLIENT_ID", "")
+	appConfig.OIDCClientSecret = Getenv("OIDC_CLIENT_SECRET", "")
+	appConfig.OIDCRedirectURL = Getenv("OIDC_REDIRECT_URL", "")
+	appConfig.OIDCScopes = Getenv("OIDC_SCOPES", "openid profile email")
+	appConfig.OIDCRoleClaim = Getenv("OIDC_ROLE_CLAIM", "groups")
+	appConfig.OIDCRoleMapping = Getenv("OIDC_ROLE_MAPPING", "")
+	appConfig.OIDCAutoCreate = Getenv("OIDC_AUTO_CREATE", "false") == "true"
 
 	dbConfig.DBHost = Getenv("DB_HOST", "localhost")
 	dbConfig.DBUser = Getenv("DB_USER", "postgres")
@@ -4,6 +4,36 @@ type AppConfig struct {
 	AppName string
 	AppEnv  string
 	AppPort string
+	AppURL  string // URL publik aplikasi, dipakai pada tautan verifikasi dokumen
+
+	PDFSignCert     string // path sertifikat X.509 (PEM) untuk tanda tangan digital PDF
+	PDFSignKey      string // path kunci privat (PEM) pasangan sertifikat di atas
+	PDFSignLocation string // lokasi penandatanganan yang dicantumkan pada tanda tangan digital, mis. kota kantor pusat
+
+	SessionSecret      string // secret penandatangan cookie session login
+	SessionIdleTimeout string // durasi menganggur sebelum session berakhir, mis. "2h"; "0" menonaktifkan
+
+	LoginMaxFailures string // jumlah login gagal berturut-turut sebelum akun dikunci sementara
+	LoginLockout     string // lama penguncian akun, mis. "15m"
+
+	LDAPURL          string // mis. ldap://localhost:389 atau ldaps://ad.example.co.id; kosong berarti login LDAP nonaktif
+	LDAPStartTLS     bool   // naikkan koneksi ldap:// ke TLS sebelum bind
+	LDAPBindDN       string // akun layanan untuk mencari entri pengguna
+	LDAPBindPassword string
+	LDAPBaseDN       string // basis pencarian pengguna dan grup
+	LDAPUserFilter   string // filter pencarian pengguna, %s diganti username, mis. (uid=%s) atau (sAMAccountName=%s)
+	LDAPGroupFilter  string // opsional, filter grup bila direktori tidak punya memberOf, %s diganti DN pengguna
+	LDAPNIKAttr      string // atribut yang berisi NIK karyawan
+	LDAPGroupRoles   string // pemetaan grup ke role: role=DN grup, dipisah ";" (urutan = prioritas)
+
+	OIDCIssuer       string // URL issuer OpenID Connect; kosong berarti login SSO nonaktif
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string // default APP_URL + /login/sso/callback
+	OIDCScopes       string // dipisah spasi, mis. "openid profile email groups"
+	OIDCRoleClaim    string // nama claim berisi grup/role, mis. groups atau roles
+	OIDCRoleMapping  string // pemetaan nilai claim ke role: role=nilai, dipisah ";" (urutan = prioritas)
+	OIDCAutoCreate   bool   // buat admin baru saat login SSO pertama bila belum ada yang cocok
 }
 
 type DBConfig struct {
@@ -36,6 +36,8 @@ func Migrate(db *gorm.DB) {
 	db.Exec("UPDATE dms_files SET folder_id = NULL WHERE folder_id = ''")
 	db.Exec("ALTER TABLE dms_folders ALTER COLUMN parent_id DROP NOT NULL")
 	db.Exec("UPDATE dms_folders SET parent_id = NULL WHERE parent_id = ''")
+	// Kolom teks organisasi lama pada users hanya dipakai sebagai sumber migrasi relasi master data
+	db.Exec("ALTER TABLE users ALTER COLUMN position DROP NOT NULL")
 
 	for _, model := range models.RegisterModels() {
 		err := db.Debug().AutoMigrate(model.Model)
@@ -44,5 +46,11 @@ func Migrate(db *gorm.DB) {
 		}
 	}
 
+	issues, err := MigrateOrgReferences(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	PrintOrgMigrationReport(issues)
+
 	fmt.Println("Database Migrated Successfully")
 }
@@ -11,21 +11,31 @@ import (
 )
 
 // UserFaker menghasilkan data karyawan buatan (dummy) untuk keperluan testing atau seeding
+// Unit organisasi dan jabatan diambil acak dari master data yang sudah ada (jalankan seeder master data terlebih dahulu).
 func UserFaker(db *gorm.DB) *models.User {
-
-	return &models.User{
+	user := &models.User{
 		ID:             uuid.New().String(),
 		NIK:            faker.Phonenumber(), // Use phonenumber or similar for NIK faker
 		Name:           faker.Name(),
 		Email:          faker.Email(),
-		Branch:         faker.Word(),
-		Department:     faker.Word(),
-		SubDepartment:  faker.Word(),
-		Position:       faker.Word(),
 		StatusKaryawan: "Tetap",                                // or faker word
 		Password:       "dfasfsadgfreagfeawfasdfasfsadfsdafas", //password
 		CreatedAt:      time.Time{},
 		UpdatedAt:      time.Time{},
 		DeletedAt:      gorm.DeletedAt{},
 	}
+
+	var sub models.MasterSubDepartment
+	db.Preload("MasterDepartment").Order("RANDOM()").Limit(1).Find(&sub)
+	if sub.ID != 0 {
+		user.BranchID = &sub.MasterDepartment.MasterBranchID
+		user.DepartmentID = &sub.MasterDepartmentID
+		user.SubDepartmentID = &sub.ID
+	}
+	var position models.MasterPosition
+	db.Order("RANDOM()").Limit(1).Find(&position)
+	if position.ID != 0 {
+		user.PositionID = &position.ID
+	}
+	return user
 }
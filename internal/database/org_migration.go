@@ -0,0 +1,242 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// OrgMigrationIssue adalah nilai teks lama yang tidak ditemukan di master data saat migrasi relasi organisasi
+type OrgMigrationIssue struct {
+	Table string
+	Field string
+	Value string
+	Count int
+}
+
+// orgLookup memetakan nama master data organisasi (tanpa membedakan huruf besar/kecil) ke ID-nya
+type orgLookup struct {
+	branches  map[string]uint
+	depts     map[uint]map[string]uint // per cabang
+	subs      map[uint]map[string]uint // per bagian
+	positions map[string]uint
+}
+
+func orgKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func loadOrgLookup(db *gorm.DB) orgLookup {
+	lookup := orgLookup{
+		branches:  make(map[string]uint),
+		depts:     make(map[uint]map[string]uint),
+		subs:      make(map[uint]map[string]uint),
+		positions: make(map[string]uint),
+	}
+
+	var branches []models.MasterBranch
+	db.Find(&branches)
+	for _, b := range branches {
+		lookup.branches[orgKey(b.Name)] = b.ID
+	}
+	var depts []models.MasterDepartment
+	db.Find(&depts)
+	for _, d := range depts {
+		if lookup.depts[d.MasterBranchID] == nil {
+			lookup.depts[d.MasterBranchID] = make(map[string]uint)
+		}
+		lookup.depts[d.MasterBranchID][orgKey(d.Name)] = d.ID
+	}
+	var subs []models.MasterSubDepartment
+	db.Find(&subs)
+	for _, s := range subs {
+		if lookup.subs[s.MasterDepartmentID] == nil {
+			lookup.subs[s.MasterDepartmentID] = make(map[string]uint)
+		}
+		lookup.subs[s.MasterDepartmentID][orgKey(s.Name)] = s.ID
+	}
+	var positions []models.MasterPosition
+	db.Find(&positions)
+	for _, p := range positions {
+		lookup.positions[orgKey(p.Name)] = p.ID
+	}
+	return lookup
+}
+
+// orgIDs adalah relasi organisasi satu baris; nil berarti kosong atau belum terpetakan
+type orgIDs struct {
+	BranchID        *uint
+	DepartmentID    *uint
+	SubDepartmentID *uint
+	PositionID      *uint
+}
+
+// orgNames adalah nilai teks lama satu baris
+type orgNames struct {
+	Branch        string
+	Department    string
+	SubDepartment string
+	Position      string
+}
+
+// fill melengkapi ID yang masih kosong dari namanya. Bagian dicari di dalam cabangnya dan sub bagian di dalam
+// bagiannya, sehingga nama yang sama di cabang lain tidak tertukar. Nilai yang tidak ditemukan dicatat melalui miss.
+func (l orgLookup) fill(ids *orgIDs, names orgNames, miss func(field, value string)) {
+	find := func(current **uint, m map[string]uint, field, value string) {
+		if *current != nil || strings.TrimSpace(value) == "" {
+			return
+		}
+		if id, ok := m[orgKey(value)]; ok {
+			*current = &id
+			return
+		}
+		miss(field, strings.TrimSpace(value))
+	}
+
+	find(&ids.BranchID, l.branches, "cabang", names.Branch)
+	var depts, subs map[string]uint
+	if ids.BranchID != nil {
+		depts = l.depts[*ids.BranchID]
+	}
+	find(&ids.DepartmentID, depts, "bagian", names.Department)
+	if ids.DepartmentID != nil {
+		subs = l.subs[*ids.DepartmentID]
+	}
+	find(&ids.SubDepartmentID, subs, "sub bagian", names.SubDepartment)
+	find(&ids.PositionID, l.positions, "jabatan", names.Position)
+}
+
+// MigrateOrgReferences mengisi relasi organisasi (ID master data) dari kolom teks lama pada karyawan serta
+// laporan dan dokumen pemeliharaan. Hanya ID yang masih kosong yang diisi sehingga aman dijalankan berulang kali
+// setelah master data atau data karyawan dibetulkan. Teks lama karyawan dikosongkan begitu relasinya terisi,
+// sedangkan nama pada laporan dan dokumen pemeliharaan tetap disimpan sebagai snapshot. Nilai yang tidak
+// ditemukan di master data dikembalikan sebagai laporan.
+func MigrateOrgReferences(db *gorm.DB) ([]OrgMigrationIssue, error) {
+	lookup := loadOrgLookup(db)
+	counts := make(map[OrgMigrationIssue]int)
+	missFor := func(table string) func(field, value string) {
+		return func(field, value string) {
+			counts[OrgMigrationIssue{Table: table, Field: field, Value: value}]++
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Kolom teks lama di tabel users tidak lagi dipetakan ke struct sehingga dibaca langsung bila masih ada
+		if tx.Migrator().HasColumn("users", "branch") {
+			var rows []struct {
+				ID              string
+				BranchID        *uint
+				DepartmentID    *uint
+				SubDepartmentID *uint
+				PositionID      *uint
+				Branch          string
+				Department      string
+				SubDepartment   string
+				Position        string
+			}
+			if err := tx.Table("users").
+				Select("id, branch_id, department_id, sub_department_id, position_id, " +
+					"COALESCE(branch, '') AS branch, COALESCE(department, '') AS department, " +
+					"COALESCE(sub_department, '') AS sub_department, COALESCE(position, '') AS position").
+				Where("COALESCE(branch, '') <> '' OR COALESCE(department, '') <> '' OR COALESCE(sub_department, '') <> '' OR COALESCE(position, '') <> ''").
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				ids := orgIDs{row.BranchID, row.DepartmentID, row.SubDepartmentID, row.PositionID}
+				lookup.fill(&ids, orgNames{row.Branch, row.Department, row.SubDepartment, row.Position}, missFor("users"))
+				values := map[string]interface{}{
+					"branch_id":         ids.BranchID,
+					"department_id":     ids.DepartmentID,
+					"sub_department_id": ids.SubDepartmentID,
+					"position_id":       ids.PositionID,
+				}
+				// Teks yang sudah terwakili relasinya dikosongkan; yang belum terpetakan dibiarkan untuk laporan berikutnya
+				mapped := map[string]*uint{"branch": ids.BranchID, "department": ids.DepartmentID, "sub_department": ids.SubDepartmentID, "position": ids.PositionID}
+				for column, id := range mapped {
+					if id != nil {
+						values[column] = ""
+					}
+				}
+				if err := tx.Table("users").Where("id = ?", row.ID).Updates(values).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var reports []models.MaintenanceReport
+		if err := tx.Unscoped().
+			Where("(user_branch_id IS NULL AND user_branch <> '') OR (user_department_id IS NULL AND user_department <> '') OR " +
+				"(user_sub_department_id IS NULL AND user_sub_department <> '') OR (user_position_id IS NULL AND user_position <> '')").
+			Find(&reports).Error; err != nil {
+			return err
+		}
+		for _, report := range reports {
+			ids := orgIDs{report.UserBranchID, report.UserDepartmentID, report.UserSubDepartmentID, report.UserPositionID}
+			lookup.fill(&ids, orgNames{report.UserBranch, report.UserDepartment, report.UserSubDepartment, report.UserPosition}, missFor("maintenance_reports"))
+			if err := tx.Unscoped().Model(&models.MaintenanceReport{}).Where("id = ?", report.ID).Updates(map[string]interface{}{
+				"user_branch_id":         ids.BranchID,
+				"user_department_id":     ids.DepartmentID,
+				"user_sub_department_id": ids.SubDepartmentID,
+				"user_position_id":       ids.PositionID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		var documents []models.MaintenanceDocument
+		if err := tx.Unscoped().
+			Where("(branch_id IS NULL AND branch <> '') OR (department_id IS NULL AND department <> '') OR (sub_department_id IS NULL AND sub_department <> '')").
+			Find(&documents).Error; err != nil {
+			return err
+		}
+		for _, doc := range documents {
+			ids := orgIDs{BranchID: doc.BranchID, DepartmentID: doc.DepartmentID, SubDepartmentID: doc.SubDepartmentID}
+			lookup.fill(&ids, orgNames{Branch: doc.Branch, Department: doc.Department, SubDepartment: doc.SubDepartment}, missFor("maintenance_documents"))
+			if err := tx.Unscoped().Model(&models.MaintenanceDocument{}).Where("id = ?", doc.ID).Updates(map[string]interface{}{
+				"branch_id":         ids.BranchID,
+				"department_id":     ids.DepartmentID,
+				"sub_department_id": ids.SubDepartmentID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []OrgMigrationIssue
+	for issue, count := range counts {
+		issue.Count = count
+		issues = append(issues, issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Value < b.Value
+	})
+	return issues, nil
+}
+
+// PrintOrgMigrationReport menampilkan nilai teks lama yang belum terpetakan agar dapat dibetulkan
+// di master data atau di data karyawan, lalu migrasi dijalankan ulang
+func PrintOrgMigrationReport(issues []OrgMigrationIssue) {
+	if len(issues) == 0 {
+		fmt.Println("Relasi organisasi: semua nilai terpetakan ke master data")
+		return
+	}
+	fmt.Printf("Relasi organisasi: %d nilai tidak ditemukan di master data\n", len(issues))
+	for _, issue := range issues {
+		fmt.Printf("  %-22s %-11s %-30q %d baris\n", issue.Table, issue.Field, issue.Value, issue.Count)
+	}
+}
@@ -0,0 +1,104 @@
+package seeders
+
+import (
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SeedGoForms mengisi katalog GoForm bawaan; form yang slug-nya sudah ada tidak ditimpa
+func SeedGoForms(db *gorm.DB) error {
+	forms := []models.GoForm{
+		{
+			Slug:        "form-maintenance",
+			Name:        "Laporan Pemeliharaan Bulanan",
+			Description: "Formulir standar untuk pencatatan rutin kondisi aset laptop & komputer.",
+			Icon:        "bi-tools",
+			Color:       "#3b82f6",
+			Category:    "IT Support",
+			PDFTitle:    "LAPORAN PEMELIHARAAN BULANAN",
+			PDFTemplate: "Telah dilakukan pemeliharaan rutin terhadap aset {aset} pada tanggal {tanggal_pemeriksaan} dengan hasil sebagai berikut:",
+			SortOrder:   1,
+			Fields: []models.GoFormField{
+				{Name: "aset", Label: "Aset", Type: "select", OptionSource: "asset", Required: true, SortOrder: 1},
+				{Name: "tanggal_pemeriksaan", Label: "Tanggal Pemeriksaan", Type: "date", Required: true, SortOrder: 2},
+				{Name: "update_antivirus", Label: "Update Antivirus", Type: "checkbox", SortOrder: 3},
+				{Name: "clear_temp", Label: "Clear Temporary Files", Type: "checkbox", SortOrder: 4},
+				{Name: "kondisi", Label: "Kondisi Aset", Type: "select", Options: "Baik\nPerlu Perbaikan\nRusak", Required: true, SortOrder: 5},
+				{Name: "catatan", Label: "Catatan", Type: "textarea", SortOrder: 6},
+			},
+		},
+		{
+			Slug:        "form-peminjaman",
+			Name:        "Permohonan Pinjam Aset",
+			Description: "Digunakan untuk mengajukan peminjaman aset kantor bagi karyawan.",
+			Icon:        "bi-person-badge",
+			Color:       "#10b981",
+			Category:    "Logistik",
+			Handler:     "loan",
+			SortOrder:   2,
+		},
+		{
+			Slug:        "form-surat-jalan",
+			Name:        "Surat Jalan Barang",
+			Description: "Dokumen resmi pengiriman atau perpindahan aset antar cabang.",
+			Icon:        "bi-truck",
+			Color:       "#f59e0b",
+			Category:    "Logistik",
+			Handler:     "transfer",
+			SortOrder:   3,
+		},
+		{
+			Slug:        "form-bast",
+			Name:        "BA Serah Terima Aset",
+			Description: "Berita acara bukti penyerahan aset kepada pengguna/karyawan.",
+			Icon:        "bi-file-earmark-check",
+			Color:       "#8b5cf6",
+			Category:    "Asset Control",
+			Handler:     "bast",
+			SortOrder:   4,
+		},
+		{
+			Slug:        "form-bast-laptop",
+			Name:        "BA Serah Terima Laptop/Komputer",
+			Description: "Berita acara bukti penyerahan khusus aset IT (Laptop/Komputer).",
+			Icon:        "bi-laptop",
+			Color:       "#ec4899",
+			Category:    "Asset Control",
+			Handler:     "bast-laptop",
+			SortOrder:   5,
+		},
+		{
+			Slug:        "form-bast-kembali",
+			Name:        "BA Serah Terima Kembali Aset",
+			Description: "Berita acara pengembalian aset dari karyawan, mis. saat resign atau tukar perangkat.",
+			Icon:        "bi-arrow-return-left",
+			Color:       "#0ea5e9",
+			Category:    "Asset Control",
+			Handler:     "bast-return",
+			SortOrder:   6,
+		},
+	}
+
+	for _, form := range forms {
+		var existing models.GoForm
+		db.Unscoped().Where("slug = ?", form.Slug).Limit(1).Find(&existing)
+		if existing.ID != "" {
+			// Form yang sudah ada tetap dipertahankan, hanya alur bawaannya yang diselaraskan
+			if form.Handler != "" && existing.Handler != form.Handler {
+				if err := db.Model(&existing).Update("handler", form.Handler).Error; err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		form.ID = uuid.New().String()
+		form.IsActive = true
+		if err := db.Create(&form).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
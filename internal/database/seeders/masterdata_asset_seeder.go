@@ -5,7 +5,7 @@ import (
 	"gorm.io/gorm"
 )
 
-// SeedMasterDataAsset mengisi data master aset (kategori, RAM, penyimpanan) ke database
+// SeedMasterDataAsset mengisi data master aset (kategori, RAM, penyimpanan, prosesor, sistem operasi) ke database
 func SeedMasterDataAsset(db *gorm.DB) error {
 	// 1. Asset Categories
 	categories := []string{"Laptop", "Komputer", "Printer", "Scanner", "Proyektor", "Lain-lain"}
@@ -25,5 +25,17 @@ func SeedMasterDataAsset(db *gorm.DB) error {
 		db.Where(models.MasterStorageType{Name: name}).FirstOrCreate(&models.MasterStorageType{Name: name})
 	}
 
+	// 4. Processor Families
+	processorFamilies := []string{"Intel Core i3", "Intel Core i5", "Intel Core i7", "Intel Core i9", "Intel Core Ultra 5", "Intel Core Ultra 7", "AMD Ryzen 3", "AMD Ryzen 5", "AMD Ryzen 7", "Apple M1", "Apple M2", "Apple M3"}
+	for _, name := range processorFamilies {
+		db.Where(models.MasterProcessorFamily{Name: name}).FirstOrCreate(&models.MasterProcessorFamily{Name: name})
+	}
+
+	// 5. Operating Systems
+	operatingSystems := []string{"Windows 10 Pro", "Windows 11 Pro", "Windows 11 Home", "macOS", "Ubuntu", "Linux"}
+	for _, name := range operatingSystems {
+		db.Where(models.MasterOperatingSystem{Name: name}).FirstOrCreate(&models.MasterOperatingSystem{Name: name})
+	}
+
 	return nil
 }
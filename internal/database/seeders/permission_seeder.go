@@ -1,57 +1,123 @@
 package seeders
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/AbsoluteZero24/gokso/internal/models"
 	"gorm.io/gorm"
 )
 
-// SeedPermissions mengatur data izin akses (permission) default untuk setiap peran (role)
+// defaultRoles adalah role bawaan yang dibuat saat seeding
+var defaultRoles = []models.Role{
+	{Name: models.RoleSuperAdmin, Label: "Super Admin", Description: "Akses penuh ke seluruh sistem", IsSystem: true},
+	{Name: "asset_manager", Label: "Asset Manager", Description: "Mengelola inventori, penempatan, peminjaman dan maintenance aset"},
+	{Name: "staf_it", Label: "Staf IT", Description: "Penempatan aset dan pengisian laporan maintenance, tanpa persetujuan"},
+	{Name: "support", Label: "Support", Description: "Dashboard, dokumen dan formulir"},
+}
+
+// defaultRolePermissions adalah aksi yang diizinkan untuk role bawaan selain super_admin
+var defaultRolePermissions = map[string][]string{
+	"asset_manager": {
+		"dashboard.view",
+		"asset.view", "asset.create", "asset.update", "asset.delete", "asset_category.manage",
+		"assignment.view", "assignment.manage", "loan.approve", "loan.handover", "transfer.create", "transfer.receive",
+		"maintenance.view", "maintenance.create", "maintenance.approve",
+		"dms.view", "dms.upload", "dms.delete", "dms.delete_permanent",
+		"goform.view", "signature.manage",
+	},
+	"staf_it": {
+		"dashboard.view",
+		"assignment.view", "assignment.manage", "loan.handover", "transfer.create", "transfer.receive",
+		"maintenance.view", "maintenance.create",
+		"dms.view", "dms.upload", "dms.delete",
+		"goform.view", "signature.manage",
+	},
+	"support": {
+		"dashboard.view",
+		"dms.view", "dms.upload", "dms.delete",
+		"goform.view", "signature.manage",
+	},
+}
+
+// legacyModules adalah izin per modul sebelum izin per aksi; modul setting dulu tidak dipakai sehingga diabaikan
+var legacyModules = []string{"dashboard", "inventori", "asset_management", "maintenance", "administration"}
+
+// SeedPermissions membuat role bawaan dan izin per aksi untuk setiap role.
+// Izin yang sudah ada tidak diubah. Izin lama per modul dikonversi: modul yang dulu diizinkan memberi aksi bawaan
+// role tersebut pada modul itu (atau seluruh aksi modul bila role tidak punya bawaan di modul itu), lalu baris lama dihapus.
 func SeedPermissions(db *gorm.DB) error {
-	resources := []string{
-		"dashboard",
-		"inventori",
-		"asset_management",
-		"maintenance",
-		"administration",
-		"setting",
+	for _, role := range defaultRoles {
+		var count int64
+		db.Model(&models.Role{}).Where("name = ?", role.Name).Count(&count)
+		if count == 0 {
+			if err := db.Create(&role).Error; err != nil {
+				return err
+			}
+		}
 	}
 
-	roles := []string{"super_admin", "asset_manager", "staf_it", "support"}
+	// Role yang hanya dikenal dari data admin (misalnya dibuat sebelum tabel roles ada) ikut didaftarkan
+	var adminRoles []string
+	db.Model(&models.Admin{}).Distinct("role").Where("role <> ''").Pluck("role", &adminRoles)
+	for _, name := range adminRoles {
+		var count int64
+		db.Model(&models.Role{}).Where("name = ?", name).Count(&count)
+		if count == 0 {
+			if err := db.Create(&models.Role{Name: name, Label: name}).Error; err != nil {
+				return err
+			}
+		}
+	}
 
+	var roles []models.Role
+	db.Find(&roles)
 	for _, role := range roles {
-		for _, res := range resources {
-			var count int64
-			db.Model(&models.RolePermission{}).Where("role = ? AND resource = ?", role, res).Count(&count)
-			if count == 0 {
-				canAccess := false
-				if role == "super_admin" {
-					canAccess = true
-				} else if role == "asset_manager" {
-					// Default for asset_manager
-					if res == "dashboard" || res == "inventori" || res == "asset_management" || res == "maintenance" {
-						canAccess = true
-					}
-				} else if role == "staf_it" {
-					if res == "dashboard" || res == "asset_management" || res == "maintenance" {
-						canAccess = true
-					}
-				} else if role == "support" {
-					if res == "dashboard" {
-						canAccess = true
-					}
-				}
-
-				perm := models.RolePermission{
-					Role:      role,
-					Resource:  res,
-					CanAccess: canAccess,
-				}
-				if err := db.Create(&perm).Error; err != nil {
-					return err
-				}
-			}
+		if err := seedRolePermissions(db, role.Name); err != nil {
+			return err
 		}
 	}
 
+	// Baris izin lama per modul sudah dikonversi
+	return db.Where("resource NOT LIKE ?", "%.%").Delete(&models.RolePermission{}).Error
+}
+
+// seedRolePermissions melengkapi baris izin satu role untuk setiap aksi di katalog
+func seedRolePermissions(db *gorm.DB, role string) error {
+	var existing []models.RolePermission
+	db.Where("role = ?", role).Find(&existing)
+	have := make(map[string]bool)
+	legacy := make(map[string]bool)
+	for _, p := range existing {
+		if strings.Contains(p.Resource, ".") {
+			have[p.Resource] = true
+		} else if slices.Contains(legacyModules, p.Resource) {
+			legacy[p.Resource] = p.CanAccess
+		}
+	}
+
+	defaults := defaultRolePermissions[role]
+	for _, group := range models.PermissionCatalog {
+		granted, hasLegacy := legacy[group.Module]
+		groupHasDefault := false
+		for _, p := range group.Permissions {
+			if slices.Contains(defaults, p.Key) {
+				groupHasDefault = true
+			}
+		}
+
+		for _, p := range group.Permissions {
+			if have[p.Key] {
+				continue
+			}
+			canAccess := role == models.RoleSuperAdmin || slices.Contains(defaults, p.Key)
+			if hasLegacy && role != models.RoleSuperAdmin {
+				canAccess = granted && (!groupHasDefault || slices.Contains(defaults, p.Key))
+			}
+			if err := db.Create(&models.RolePermission{Role: role, Resource: p.Key, CanAccess: canAccess}).Error; err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errLoanConflict membatalkan transaksi persetujuan atau penyerahan bila aset tidak lagi tersedia
+	errLoanConflict = errors.New("aset tidak tersedia untuk dipinjam")
+	// errLoanStatusChanged membatalkan transaksi bila status peminjaman sudah diubah oleh request lain
+	errLoanStatusChanged = errors.New("status peminjaman sudah berubah")
+)
+
+// loanConditions adalah pilihan kondisi aset saat diserahkan atau dikembalikan
+var loanConditions = []string{"Baik", "Lecet", "Rusak", "Hilang"}
+
+// ListAssetLoans menampilkan daftar peminjaman aset beserta filter status
+func (server *Server) ListAssetLoans(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	scope := server.dataScope(r)
+	query := scope.applyLoans(server.DB.Preload("Borrower").Preload("Items.Asset")).Order("created_at desc")
+	switch status {
+	case "":
+	case "Terlambat":
+		query = query.Where("status = ? AND end_date < ?", models.LoanStatusOnLoan, today())
+	default:
+		query = query.Where("status = ?", status)
+	}
+
+	var loans []models.AssetLoan
+	query.Find(&loans)
+
+	var overdueCount int64
+	scope.applyLoans(server.DB.Model(&models.AssetLoan{})).Where("status = ? AND end_date < ?", models.LoanStatusOnLoan, today()).Count(&overdueCount)
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/loans", map[string]interface{}{
+		"title":        "Peminjaman Aset",
+		"loans":        loans,
+		"status":       status,
+		"statuses":     []string{models.LoanStatusPending, models.LoanStatusApproved, models.LoanStatusOnLoan, models.LoanStatusReturned, models.LoanStatusRejected},
+		"overdueCount": overdueCount,
+		"msg":          r.URL.Query().Get("msg"),
+		"error":        r.URL.Query().Get("error"),
+	})
+}
+
+// ShowAssetLoan menampilkan detail peminjaman beserta aksi sesuai status
+func (server *Server) ShowAssetLoan(w http.ResponseWriter, r *http.Request) {
+	loan, ok := server.findAssetLoan(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/peminjaman?error=Peminjaman tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(loan.Borrower) {
+		forbidScope(w)
+		return
+	}
+
+	var letter models.DMSFile
+	if loan.FileID != nil {
+		server.DB.Where("id = ?", *loan.FileID).Limit(1).Find(&letter)
+	}
+
+	_, _, role, _ := GetCurrentAdmin(r)
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/loan_detail", map[string]interface{}{
+		"title":      "Detail Peminjaman Aset",
+		"loan":       loan,
+		"letter":     letter,
+		"canApprove": server.hasPermission(role, "loan.approve"),
+		"conditions": loanConditions,
+		"msg":        r.URL.Query().Get("msg"),
+		"error":      r.URL.Query().Get("error"),
+	})
+}
+
+// ApproveAssetLoan menyetujui permohonan, memeriksa ulang ketersediaan aset dan membuat surat peminjaman
+func (server *Server) ApproveAssetLoan(w http.ResponseWriter, r *http.Request) {
+	loan, ok := server.findAssetLoan(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/peminjaman?error=Peminjaman tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(loan.Borrower) {
+		forbidScope(w)
+		return
+	}
+	detailURL := "/goform/peminjaman/" + loan.ID
+	if loan.Status != models.LoanStatusPending {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Hanya permohonan berstatus "+models.LoanStatusPending+" yang bisa disetujui"), http.StatusSeeOther)
+		return
+	}
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	now := time.Now()
+	loan.Status = models.LoanStatusApproved
+	loan.ApprovedByID = adminID
+	loan.ApprovedAt = &now
+
+	// Nomor surat, PDF dan persetujuan disimpan dalam satu transaksi agar nomor surat tidak loncat
+	approver := server.adminUser(adminID)
+	root := server.ensureFolder("Laporan Digital", nil, "#3b82f6")
+	folder := server.ensureFolder("Peminjaman Aset", &root.ID, "#10b981")
+	fileID := uuid.New().String()
+	uploadDir := filepath.Join("public", "uploads", "edoc")
+	os.MkdirAll(uploadDir, 0755)
+	physicalPath := filepath.Join(uploadDir, fileID+".pdf")
+
+	var conflicts []string
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		// Permohonan dan baris aset dikunci sehingga dua persetujuan untuk aset yang sama tidak dapat
+		// lolos pemeriksaan ketersediaan secara bersamaan
+		var current models.AssetLoan
+		tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", loan.ID).Limit(1).Find(&current)
+		if current.Status != models.LoanStatusPending {
+			conflicts = []string{"Hanya permohonan berstatus " + models.LoanStatusPending + " yang bisa disetujui"}
+			return errLoanConflict
+		}
+		var locked []models.AssetKSO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", loanAssetIDs(loan)).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		if conflicts = server.loanConflicts(tx, loanAssetIDs(loan), loan.StartDate, loan.EndDate, loan.ID); len(conflicts) > 0 {
+			return errLoanConflict
+		}
+
+		number, err := nextDocNumber(tx, "loan", loan.Borrower.Branch.Name, now)
+		if err != nil {
+			return err
+		}
+		loan.Number = number
+		stamp := server.newPDFStamp(number, "SURAT PEMINJAMAN ASET", loan.Borrower.Name, now)
+		if err := server.GenerateLoanPDF(loan, approver, stamp, physicalPath); err != nil {
+			return err
+		}
+		fileName := fmt.Sprintf("Surat_Peminjaman_%s_%s.pdf", loan.Borrower.Name, now.Format("20060102_150405"))
+		file, err := server.storeGeneratedPDF(tx, fileID, folder, fileName, "Peminjaman Aset", stamp)
+		if err != nil {
+			return err
+		}
+		loan.FileID = &file.ID
+		return tx.Omit("Borrower", "Items").Save(&loan).Error
+	})
+	if err == errLoanConflict {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape(strings.Join(conflicts, "; ")), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		os.Remove(physicalPath)
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Gagal membuat surat peminjaman: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, detailURL+"?msg="+url.QueryEscape("Peminjaman disetujui dan surat peminjaman disimpan ke eDoc"), http.StatusSeeOther)
+}
+
+// RejectAssetLoan menolak permohonan peminjaman dengan alasan
+func (server *Server) RejectAssetLoan(w http.ResponseWriter, r *http.Request) {
+	loan, ok := server.findAssetLoan(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/peminjaman?error=Peminjaman tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(loan.Borrower) {
+		forbidScope(w)
+		return
+	}
+	detailURL := "/goform/peminjaman/" + loan.ID
+	if loan.Status != models.LoanStatusPending {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Hanya permohonan berstatus "+models.LoanStatusPending+" yang bisa ditolak"), http.StatusSeeOther)
+		return
+	}
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	now := time.Now()
+	loan.Status = models.LoanStatusRejected
+	loan.ApprovedByID = adminID
+	loan.ApprovedAt = &now
+	loan.RejectReason = strings.TrimSpace(r.FormValue("reason"))
+
+	// Update bersyarat agar permohonan yang sudah disetujui di request lain tidak ikut ditolak
+	result := server.DB.Model(&models.AssetLoan{}).
+		Where("id = ? AND status = ?", loan.ID, models.LoanStatusPending).
+		Updates(map[string]interface{}{
+			"status":         loan.Status,
+			"approved_by_id": loan.ApprovedByID,
+			"approved_at":    loan.ApprovedAt,
+			"reject_reason":  loan.RejectReason,
+		})
+	if result.Error != nil {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Gagal menolak permohonan: "+result.Error.Error()), http.StatusSeeOther)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Hanya permohonan berstatus "+models.LoanStatusPending+" yang bisa ditolak"), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, detailURL+"?msg=Permohonan peminjaman ditolak", http.StatusSeeOther)
+}
+
+// CheckOutAssetLoan mencatat penyerahan aset ke peminjam dan mengubah status aset menjadi Dipinjam
+func (server *Server) CheckOutAssetLoan(w http.ResponseWriter, r *http.Request) {
+	loan, ok := server.findAssetLoan(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/peminjaman?error=Peminjaman tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(loan.Borrower) {
+		forbidScope(w)
+		return
+	}
+	detailURL := "/goform/peminjaman/" + loan.ID
+	if loan.Status != models.LoanStatusApproved {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Aset hanya bisa diserahkan untuk peminjaman yang sudah disetujui"), http.StatusSeeOther)
+		return
+	}
+
+	var notReady []string
+	for _, item := range loan.Items {
+		if item.Asset.Status != "Ready" {
+			notReady = append(notReady, fmt.Sprintf("%s masih berstatus %s", item.Asset.InventoryNumber, item.Asset.Status))
+		}
+	}
+	if len(notReady) > 0 {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape(strings.Join(notReady, "; ")), http.StatusSeeOther)
+		return
+	}
+
+	conditions, err := loanItemConditions(r, loan, "condition_out_%d")
+	if err != nil {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	now := time.Now()
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		// Hanya satu penyerahan yang berhasil: peminjaman diklaim selama statusnya masih Disetujui
+		result := tx.Model(&models.AssetLoan{}).
+			Where("id = ? AND status = ?", loan.ID, models.LoanStatusApproved).
+			Updates(map[string]interface{}{
+				"status":            models.LoanStatusOnLoan,
+				"checked_out_by_id": adminID,
+				"checked_out_at":    &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLoanStatusChanged
+		}
+
+		for _, item := range loan.Items {
+			if err := tx.Model(&models.AssetLoanItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"condition_out":  conditions[item.ID],
+				"checked_out_at": &now,
+			}).Error; err != nil {
+				return err
+			}
+			// Aset yang sudah tidak Ready (mis. dikirim lewat surat jalan) membatalkan penyerahan
+			result := tx.Model(&models.AssetKSO{}).Where("id = ? AND status = ?", item.AssetID, "Ready").Update("status", models.AssetStatusOnLoan)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errLoanConflict
+			}
+		}
+		return nil
+	})
+	if err == errLoanStatusChanged {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Aset hanya bisa diserahkan untuk peminjaman yang sudah disetujui"), http.StatusSeeOther)
+		return
+	}
+	if err == errLoanConflict {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Sebagian aset tidak lagi berstatus Ready"), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Gagal mencatat penyerahan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, detailURL+"?msg=Aset telah diserahkan kepada peminjam", http.StatusSeeOther)
+}
+
+// CheckInAssetLoan mencatat pengembalian aset dan mengembalikan status aset sesuai kondisinya
+func (server *Server) CheckInAssetLoan(w http.ResponseWriter, r *http.Request) {
+	loan, ok := server.findAssetLoan(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/peminjaman?error=Peminjaman tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(loan.Borrower) {
+		forbidScope(w)
+		return
+	}
+	detailURL := "/goform/peminjaman/" + loan.ID
+	if loan.Status != models.LoanStatusOnLoan {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Aset pada peminjaman ini belum diserahkan"), http.StatusSeeOther)
+		return
+	}
+
+	conditions, err := loanItemConditions(r, loan, "condition_in_%d")
+	if err != nil {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	now := time.Now()
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		// Hanya satu pengembalian yang berhasil: peminjaman diklaim selama statusnya masih Dipinjam
+		result := tx.Model(&models.AssetLoan{}).
+			Where("id = ? AND status = ?", loan.ID, models.LoanStatusOnLoan).
+			Updates(map[string]interface{}{
+				"status":         models.LoanStatusReturned,
+				"returned_by_id": adminID,
+				"returned_at":    &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLoanStatusChanged
+		}
+
+		for _, item := range loan.Items {
+			condition := conditions[item.ID]
+			assetStatus := "Ready"
+			if condition == "Rusak" || condition == "Hilang" {
+				assetStatus = condition
+			}
+			if err := tx.Model(&models.AssetLoanItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"condition_in": condition,
+				"notes":        strings.TrimSpace(r.FormValue(fmt.Sprintf("notes_%d", item.ID))),
+				"returned_at":  &now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.AssetKSO{}).Where("id = ?", item.AssetID).Update("status", assetStatus).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == errLoanStatusChanged {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Aset pada peminjaman ini belum diserahkan atau sudah dikembalikan"), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Gagal mencatat pengembalian: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, detailURL+"?msg=Pengembalian aset berhasil dicatat", http.StatusSeeOther)
+}
+
+// submitAssetLoan membuat permohonan peminjaman dari GoForm "Permohonan Pinjam Aset"
+func (server *Server) submitAssetLoan(w http.ResponseWriter, r *http.Request, form models.GoForm) {
+	fillURL := "/goform/fill/" + form.Slug
+
+	borrowerID := r.FormValue("borrower_id")
+	purpose := strings.TrimSpace(r.FormValue("purpose"))
+	assetIDs := r.Form["asset_ids[]"]
+	startDate, errStart := time.Parse("2006-01-02", r.FormValue("start_date"))
+	endDate, errEnd := time.Parse("2006-01-02", r.FormValue("end_date"))
+
+	var borrower models.User
+	server.DB.Where("id = ?", borrowerID).Limit(1).Find(&borrower)
+
+	// Peminjam dan setiap aset harus berada di dalam cakupan data admin
+	scope := server.dataScope(r)
+	if borrower.ID != "" && !scope.allowsUser(borrower) {
+		forbidScope(w)
+		return
+	}
+	if scope.restricted {
+		var requested []models.AssetKSO
+		server.DB.Preload("User").Where("id IN ?", assetIDs).Find(&requested)
+		for _, asset := range requested {
+			if !scope.allowsAsset(asset) {
+				forbidScope(w)
+				return
+			}
+		}
+	}
+
+	var errs []string
+	if borrower.ID == "" {
+		errs = append(errs, "Karyawan peminjam wajib dipilih")
+	}
+	if len(assetIDs) == 0 {
+		errs = append(errs, "Pilih minimal satu aset")
+	}
+	if errStart != nil || errEnd != nil {
+		errs = append(errs, "Tanggal mulai dan selesai wajib diisi")
+	} else if endDate.Before(startDate) {
+		errs = append(errs, "Tanggal selesai tidak boleh sebelum tanggal mulai")
+	}
+	if purpose == "" {
+		errs = append(errs, "Keperluan wajib diisi")
+	}
+	if len(errs) == 0 {
+		errs = server.loanConflicts(server.DB, assetIDs, startDate, endDate, "")
+	}
+	if len(errs) > 0 {
+		http.Redirect(w, r, fillURL+"?error="+url.QueryEscape(strings.Join(errs, "; ")), http.StatusSeeOther)
+		return
+	}
+
+	var assets []models.AssetKSO
+	server.DB.Where("id IN ?", assetIDs).Order("inventory_number asc").Find(&assets)
+	var assetNames []string
+	for _, a := range assets {
+		assetNames = append(assetNames, a.InventoryNumber+" - "+a.AssetName)
+	}
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	answers := []models.GoFormAnswer{
+		{Field: "borrower_id", Label: "Karyawan Peminjam", Value: borrower.ID, Display: borrower.Name},
+		{Field: "asset_ids", Label: "Aset", Value: strings.Join(assetIDs, ","), Display: strings.Join(assetNames, ", ")},
+		{Field: "start_date", Label: "Tanggal Mulai", Value: startDate.Format("2006-01-02"), Display: translateMonth(startDate.Format("02 January 2006"))},
+		{Field: "end_date", Label: "Tanggal Selesai", Value: endDate.Format("2006-01-02"), Display: translateMonth(endDate.Format("02 January 2006"))},
+		{Field: "purpose", Label: "Keperluan", Value: purpose, Display: purpose},
+	}
+	answersJSON, _ := json.Marshal(answers)
+
+	submission := models.GoFormSubmission{
+		ID:            uuid.New().String(),
+		FormID:        form.ID,
+		Data:          string(answersJSON),
+		SubmittedByID: adminID,
+	}
+	loan := models.AssetLoan{
+		ID:            uuid.New().String(),
+		BorrowerID:    borrower.ID,
+		Purpose:       purpose,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Status:        models.LoanStatusPending,
+		RequestedByID: adminID,
+		SubmissionID:  &submission.ID,
+	}
+	for _, a := range assets {
+		loan.Items = append(loan.Items, models.AssetLoanItem{AssetID: a.ID})
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&submission).Error; err != nil {
+			return err
+		}
+		return tx.Create(&loan).Error
+	})
+	if err != nil {
+		http.Redirect(w, r, fillURL+"?error="+url.QueryEscape("Gagal menyimpan permohonan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/goform/peminjaman/"+loan.ID+"?msg="+url.QueryEscape("Permohonan peminjaman berhasil diajukan dan menunggu persetujuan"), http.StatusSeeOther)
+}
+
+// loanConflicts memeriksa apakah aset bisa dipinjam pada rentang tanggal tertentu.
+// Aset harus berstatus Ready atau Dipinjam (akan kembali), dan tidak beririsan dengan
+// peminjaman lain yang sudah disetujui atau sedang berjalan. Peminjaman yang terlambat
+// dianggap menempati aset sampai hari ini.
+func (server *Server) loanConflicts(db *gorm.DB, assetIDs []string, start, end time.Time, excludeLoanID string) []string {
+	var conflicts []string
+
+	var assets []models.AssetKSO
+	db.Where("id IN ?", assetIDs).Find(&assets)
+	if len(assets) != len(assetIDs) {
+		conflicts = append(conflicts, "Sebagian aset tidak ditemukan")
+	}
+	names := map[string]string{}
+	for _, a := range assets {
+		names[a.ID] = a.InventoryNumber
+		if a.Status != "Ready" && a.Status != models.AssetStatusOnLoan {
+			conflicts = append(conflicts, fmt.Sprintf("%s berstatus %s", a.InventoryNumber, a.Status))
+		}
+	}
+
+	var items []models.AssetLoanItem
+	db.Joins("JOIN asset_loans ON asset_loans.id = asset_loan_items.loan_id AND asset_loans.deleted_at IS NULL").
+		Where("asset_loan_items.asset_id IN ? AND asset_loans.status IN ? AND asset_loans.id <> ?",
+			assetIDs, []string{models.LoanStatusApproved, models.LoanStatusOnLoan}, excludeLoanID).
+		Find(&items)
+	if len(items) == 0 {
+		return conflicts
+	}
+
+	loanIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		loanIDs = append(loanIDs, item.LoanID)
+	}
+	var loans []models.AssetLoan
+	db.Where("id IN ?", loanIDs).Find(&loans)
+	loansByID := map[string]models.AssetLoan{}
+	for _, l := range loans {
+		loansByID[l.ID] = l
+	}
+
+	for _, item := range items {
+		other := loansByID[item.LoanID]
+		otherEnd := other.EndDate
+		if other.Status == models.LoanStatusOnLoan && otherEnd.Before(today()) {
+			otherEnd = today()
+		}
+		if !other.StartDate.After(end) && !otherEnd.Before(start) {
+			conflicts = append(conflicts, fmt.Sprintf("%s sudah dipinjam %s s/d %s",
+				names[item.AssetID], other.StartDate.Format("02/01/2006"), other.EndDate.Format("02/01/2006")))
+		}
+	}
+
+	return conflicts
+}
+
+// loanItemConditions membaca kondisi setiap aset dari field form (kosong berarti Baik) dan menolak
+// nilai di luar loanConditions
+func loanItemConditions(r *http.Request, loan models.AssetLoan, field string) (map[uint]string, error) {
+	conditions := make(map[uint]string, len(loan.Items))
+	for _, item := range loan.Items {
+		condition := r.FormValue(fmt.Sprintf(field, item.ID))
+		if condition == "" {
+			condition = "Baik"
+		}
+		if !slices.Contains(loanConditions, condition) {
+			return nil, fmt.Errorf("Kondisi aset tidak valid: %s", condition)
+		}
+		conditions[item.ID] = condition
+	}
+	return conditions, nil
+}
+
+// findAssetLoan mengambil peminjaman beserta peminjam dan asetnya
+func (server *Server) findAssetLoan(id string) (models.AssetLoan, bool) {
+	var loan models.AssetLoan
+	preloadUserOrg(server.DB, "Borrower").Preload("Items.Asset").Where("id = ?", id).Limit(1).Find(&loan)
+	return loan, loan.ID != ""
+}
+
+// adminUser mengambil data karyawan yang tertaut ke akun admin, dengan username sebagai cadangan nama
+func (server *Server) adminUser(adminID string) models.User {
+	var admin models.Admin
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&admin)
+
+	var user models.User
+	if admin.UserID != "" {
+		preloadUserOrg(server.DB, "").Where("id = ?", admin.UserID).Limit(1).Find(&user)
+	}
+	if user.Name == "" {
+		user.Name = admin.Username
+	}
+	return user
+}
+
+// loanAssetIDs mengembalikan ID aset dalam sebuah peminjaman
+func loanAssetIDs(loan models.AssetLoan) []string {
+	ids := make([]string, 0, len(loan.Items))
+	for _, item := range loan.Items {
+		ids = append(ids, item.AssetID)
+	}
+	return ids
+}
+
+// today mengembalikan awal hari ini (UTC), sejalan dengan tanggal yang diparse dari input form
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
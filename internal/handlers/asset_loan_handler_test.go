@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// loanFixture membuat peminjaman aset as-1 oleh karyawan u-1 dengan status tertentu
+func loanFixture(tb testing.TB, server *Server, status string) models.AssetLoan {
+	tb.Helper()
+	scopeFixture(tb, server)
+	start := today()
+	loan := models.AssetLoan{ID: "l1", BorrowerID: "u-1", Purpose: "Presentasi", StartDate: start, EndDate: start.AddDate(0, 0, 3), Status: status,
+		Items: []models.AssetLoanItem{{AssetID: "as-1"}}}
+	if err := server.DB.Create(&loan).Error; err != nil {
+		tb.Fatal(err)
+	}
+	if status == models.LoanStatusOnLoan {
+		server.DB.Model(&models.AssetKSO{}).Where("id = ?", "as-1").Update("status", models.AssetStatusOnLoan)
+	}
+	return loan
+}
+
+// raceLoanStatus mengubah status peminjaman tepat setelah handler memuatnya,
+// seperti request lain yang selesai lebih dulu
+func raceLoanStatus(tb testing.TB, server *Server, loanID, status string) {
+	tb.Helper()
+	var once sync.Once
+	err := server.DB.Callback().Query().After("gorm:query").Register("test:race_loan", func(db *gorm.DB) {
+		if db.Statement.Table != "asset_loans" {
+			return
+		}
+		once.Do(func() {
+			db.Session(&gorm.Session{NewDB: true}).Model(&models.AssetLoan{}).Where("id = ?", loanID).Update("status", status)
+		})
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// loanStatus mengembalikan status peminjaman dan status aset as-1
+func loanStatus(server *Server) (string, string) {
+	var loan models.AssetLoan
+	var asset models.AssetKSO
+	server.DB.First(&loan, "id = ?", "l1")
+	server.DB.First(&asset, "id = ?", "as-1")
+	return loan.Status, asset.Status
+}
+
+func TestAssetLoanHandoverRejectsUnknownCondition(t *testing.T) {
+	for _, tc := range []struct {
+		action, status, field, assetStatus string
+	}{
+		{"checkout", models.LoanStatusApproved, "condition_out_%d", "Ready"},
+		{"checkin", models.LoanStatusOnLoan, "condition_in_%d", models.AssetStatusOnLoan},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			server := newTestServer(t)
+			loan := loanFixture(t, server, tc.status)
+			client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+			form := url.Values{}
+			form.Set(fmt.Sprintf(tc.field, loan.Items[0].ID), "Dicuri")
+			w := client.do(http.MethodPost, "/goform/peminjaman/"+tc.action+"/l1", form)
+			if msg := redirectError(t, w.Header().Get("Location")); !strings.Contains(msg, "Kondisi aset tidak valid") {
+				t.Fatalf("error = %q, want invalid condition", msg)
+			}
+			if status, assetStatus := loanStatus(server); status != tc.status || assetStatus != tc.assetStatus {
+				t.Errorf("loan %q asset %q, want %q and %q", status, assetStatus, tc.status, tc.assetStatus)
+			}
+		})
+	}
+}
+
+func TestAssetLoanTransitionsRequireCurrentStatus(t *testing.T) {
+	for _, tc := range []struct {
+		action, status, raced string
+	}{
+		{"reject", models.LoanStatusPending, models.LoanStatusApproved},
+		{"checkout", models.LoanStatusApproved, models.LoanStatusRejected},
+		{"checkin", models.LoanStatusOnLoan, models.LoanStatusReturned},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			server := newTestServer(t)
+			loanFixture(t, server, tc.status)
+			client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+			_, assetBefore := loanStatus(server)
+			raceLoanStatus(t, server, "l1", tc.raced)
+
+			w := client.do(http.MethodPost, "/goform/peminjaman/"+tc.action+"/l1", url.Values{"reason": {"Tidak tersedia"}})
+			if msg := redirectError(t, w.Header().Get("Location")); msg == "" {
+				t.Fatalf("%s succeeded after the status changed to %s", tc.action, tc.raced)
+			}
+			status, assetStatus := loanStatus(server)
+			if status != tc.raced || assetStatus != assetBefore {
+				t.Errorf("loan %q asset %q, want %q and %q", status, assetStatus, tc.raced, assetBefore)
+			}
+			var items []models.AssetLoanItem
+			server.DB.Where("loan_id = ?", "l1").Find(&items)
+			if items[0].CheckedOutAt != nil || items[0].ReturnedAt != nil {
+				t.Error("loan items updated by a stale transition")
+			}
+		})
+	}
+}
+
+func TestCheckOutAssetLoanRejectsAssetNoLongerReady(t *testing.T) {
+	server := newTestServer(t)
+	loanFixture(t, server, models.LoanStatusApproved)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	// Aset dikirim lewat surat jalan tepat setelah handler memuat peminjaman beserta asetnya
+	var once sync.Once
+	server.DB.Callback().Query().After("gorm:query").Register("test:race_asset", func(db *gorm.DB) {
+		if db.Statement.Schema != nil && db.Statement.Schema.Name == "AssetKSO" {
+			once.Do(func() {
+				db.Session(&gorm.Session{NewDB: true}).Model(&models.AssetKSO{}).Where("id = ?", "as-1").Update("status", "Dalam Perjalanan")
+			})
+		}
+	})
+
+	w := client.do(http.MethodPost, "/goform/peminjaman/checkout/l1", url.Values{})
+	if msg := redirectError(t, w.Header().Get("Location")); !strings.Contains(msg, "Ready") {
+		t.Fatalf("error = %q, want asset not ready", msg)
+	}
+	if status, assetStatus := loanStatus(server); status != models.LoanStatusApproved || assetStatus != "Dalam Perjalanan" {
+		t.Errorf("loan %q asset %q", status, assetStatus)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// assetSpecPart adalah bagian spesifikasi laptop/komputer yang disusun getAssetSpecFromForm dengan format
+// "<prosesor>, RAM <ukuran> <satuan> <tipe RAM>, <ukuran> <satuan> <tipe penyimpanan>, <sistem operasi>"
+type assetSpecPart int
+
+const (
+	specProcessor assetSpecPart = iota
+	specRamType
+	specStorageType
+	specOS
+)
+
+// specSizePattern memisahkan "16 GB DDR4" menjadi ukuran, satuan dan tipe, sama seperti form aset
+var specSizePattern = regexp.MustCompile(`(?i)^(\d+)\s+(GB|TB)\s+(.*)$`)
+
+// specRamPrefix adalah awalan bagian RAM pada spesifikasi
+var specRamPrefix = regexp.MustCompile(`(?i)^RAM\s+`)
+
+// assetSpecValue mengambil isi satu bagian spesifikasi; kosong bila spesifikasi tidak mengikuti format form
+func assetSpecValue(spec string, part assetSpecPart) string {
+	parts := strings.Split(spec, ",")
+	if int(part) >= len(parts) {
+		return ""
+	}
+	value := strings.TrimSpace(parts[part])
+	switch part {
+	case specRamType, specStorageType:
+		if part == specRamType {
+			value = specRamPrefix.ReplaceAllString(value, "")
+		}
+		m := specSizePattern.FindStringSubmatch(value)
+		if m == nil {
+			return ""
+		}
+		return strings.TrimSpace(m[3])
+	}
+	return value
+}
+
+// replaceAssetSpecValue mengganti isi satu bagian spesifikasi dan mempertahankan bagian lainnya
+func replaceAssetSpecValue(spec string, part assetSpecPart, value string) string {
+	parts := strings.Split(spec, ",")
+	if int(part) >= len(parts) {
+		return spec
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	switch part {
+	case specRamType, specStorageType:
+		current := parts[part]
+		if part == specRamType {
+			current = specRamPrefix.ReplaceAllString(current, "")
+		}
+		m := specSizePattern.FindStringSubmatch(current)
+		if m == nil {
+			return spec
+		}
+		parts[part] = m[1] + " " + m[2] + " " + value
+		if part == specRamType {
+			parts[part] = "RAM " + parts[part]
+		}
+	default:
+		parts[part] = value
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hasSpecPrefix memeriksa apakah value diawali name sebagai kata utuh, misalnya keluarga "Intel Core i5"
+// pada prosesor "Intel Core i5-1335U" tetapi tidak pada "Intel Core i50"
+func hasSpecPrefix(value, name string) bool {
+	if name == "" || len(value) < len(name) || !strings.EqualFold(value[:len(name)], name) {
+		return false
+	}
+	rest := value[len(name):]
+	if rest == "" {
+		return true
+	}
+	next := []rune(rest)[0]
+	return !unicode.IsLetter(next) && !unicode.IsDigit(next)
+}
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Kesalahan yang membatalkan transaksi surat jalan karena data berubah oleh proses lain
+var (
+	errTransferClosed   = errors.New("surat jalan sudah dikonfirmasi")
+	errTransferConflict = errors.New("sebagian aset sedang dipinjam, hilang atau dalam perjalanan")
+)
+
+// ListAssetTransfers menampilkan daftar surat jalan beserta filter status
+func (server *Server) ListAssetTransfers(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	query := server.dataScope(r).applyTransfers(server.DB.Preload("Items.Asset")).Order("created_at desc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var transfers []models.AssetTransfer
+	query.Find(&transfers)
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/transfers", map[string]interface{}{
+		"title":     "Surat Jalan Barang",
+		"transfers": transfers,
+		"status":    status,
+		"statuses":  []string{models.TransferStatusInTransit, models.TransferStatusReceived, models.TransferStatusDiscrepancy},
+		"msg":       r.URL.Query().Get("msg"),
+		"error":     r.URL.Query().Get("error"),
+	})
+}
+
+// ShowAssetTransfer menampilkan detail surat jalan dan form konfirmasi penerimaan
+func (server *Server) ShowAssetTransfer(w http.ResponseWriter, r *http.Request) {
+	transfer, ok := server.findAssetTransfer(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/surat-jalan?error=Surat jalan tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsTransfer(transfer) {
+		forbidScope(w)
+		return
+	}
+
+	var letter, receipt models.DMSFile
+	if transfer.FileID != nil {
+		server.DB.Where("id = ?", *transfer.FileID).Limit(1).Find(&letter)
+	}
+	if transfer.ReceiptFileID != nil {
+		server.DB.Where("id = ?", *transfer.ReceiptFileID).Limit(1).Find(&receipt)
+	}
+
+	// Penerima dipilih dari karyawan cabang tujuan
+	var employees []models.User
+	scope := server.dataScope(r)
+	scope.applyUsers(server.DB.Where("branch_id IN (SELECT id FROM master_branches WHERE name = ?)", transfer.DestinationBranch)).Order("name asc").Find(&employees)
+	if len(employees) == 0 {
+		scope.applyUsers(server.DB).Order("name asc").Find(&employees)
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/transfer_detail", map[string]interface{}{
+		"title":      "Detail Surat Jalan",
+		"transfer":   transfer,
+		"letter":     letter,
+		"receipt":    receipt,
+		"employees":  employees,
+		"conditions": loanConditions,
+		"msg":        r.URL.Query().Get("msg"),
+		"error":      r.URL.Query().Get("error"),
+	})
+}
+
+// ReceiveAssetTransfer mengonfirmasi penerimaan barang, memindahkan lokasi & pemegang aset dan mencatat selisih
+func (server *Server) ReceiveAssetTransfer(w http.ResponseWriter, r *http.Request) {
+	transfer, ok := server.findAssetTransfer(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/surat-jalan?error=Surat jalan tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsTransfer(transfer) {
+		forbidScope(w)
+		return
+	}
+	detailURL := "/goform/surat-jalan/" + transfer.ID
+	if transfer.Status != models.TransferStatusInTransit {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Surat jalan ini sudah dikonfirmasi"), http.StatusSeeOther)
+		return
+	}
+
+	var receiver models.User
+	server.DB.Where("id = ?", r.FormValue("receiver_id")).Limit(1).Find(&receiver)
+	if receiver.ID == "" {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Penerima wajib dipilih"), http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(receiver) {
+		forbidScope(w)
+		return
+	}
+	signature := r.FormValue("receiver_signature")
+	if signature == "" {
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Tanda tangan penerima wajib diisi"), http.StatusSeeOther)
+		return
+	}
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	now := time.Now()
+
+	// Hasil pemeriksaan setiap aset menentukan status akhir surat jalan
+	status := models.TransferStatusReceived
+	for i, item := range transfer.Items {
+		condition := r.FormValue(fmt.Sprintf("condition_%d", item.ID))
+		if condition == "" {
+			condition = "Baik"
+		}
+		if !slices.Contains(loanConditions, condition) {
+			http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Kondisi aset tidak valid: "+condition), http.StatusSeeOther)
+			return
+		}
+		if condition == "Rusak" || condition == "Hilang" {
+			status = models.TransferStatusDiscrepancy
+		}
+		item.Received = condition != "Hilang"
+		item.Condition = condition
+		item.Notes = strings.TrimSpace(r.FormValue(fmt.Sprintf("notes_%d", item.ID)))
+		transfer.Items[i] = item
+	}
+	transfer.Status = status
+	transfer.ReceiverID = &receiver.ID
+	transfer.Receiver = receiver
+	transfer.ReceiverSignature = signature
+	transfer.ReceivedAt = &now
+	transfer.ReceivedByID = adminID
+
+	// Surat jalan bertanda tangan penerima diterbitkan sebagai dokumen baru dengan ID verifikasi sendiri,
+	// sehingga surat jalan saat pengiriman beserta hash-nya tetap dapat diverifikasi seperti saat diterbitkan
+	root := server.ensureFolder("Laporan Digital", nil, "#3b82f6")
+	folder := server.ensureFolder("Surat Jalan", &root.ID, "#f59e0b")
+	fileID := uuid.New().String()
+	uploadDir := filepath.Join("public", "uploads", "edoc")
+	os.MkdirAll(uploadDir, 0755)
+	physicalPath := filepath.Join(uploadDir, fileID+".pdf")
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		// Hanya satu konfirmasi yang berhasil: surat jalan diklaim selama statusnya masih Dalam Perjalanan
+		result := tx.Model(&models.AssetTransfer{}).
+			Where("id = ? AND status = ?", transfer.ID, models.TransferStatusInTransit).
+			Updates(map[string]interface{}{
+				"status":             transfer.Status,
+				"receiver_id":        receiver.ID,
+				"receiver_signature": signature,
+				"received_at":        &now,
+				"received_by_id":     adminID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTransferClosed
+		}
+
+		for _, item := range transfer.Items {
+			if err := tx.Model(&models.AssetTransferItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"received":  item.Received,
+				"condition": item.Condition,
+				"notes":     item.Notes,
+			}).Error; err != nil {
+				return err
+			}
+
+			// Aset yang tiba berpindah lokasi dan pemegang lalu kembali ke status sebelum dikirim;
+			// aset yang rusak atau hilang ditandai sesuai kondisinya
+			assetUpdates := map[string]interface{}{}
+			if item.Received {
+				assetUpdates["location"] = transfer.DestinationBranch
+				assetUpdates["user_id"] = receiver.ID
+			}
+			switch {
+			case item.Condition == "Rusak" || item.Condition == "Hilang":
+				assetUpdates["status"] = item.Condition
+			case item.OriginStatus != "":
+				assetUpdates["status"] = item.OriginStatus
+			}
+			if len(assetUpdates) > 0 {
+				if err := tx.Model(&models.AssetKSO{}).Where("id = ?", item.AssetID).Updates(assetUpdates).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		stamp := server.newPDFStamp(transfer.Number, "SURAT JALAN (DITERIMA)", receiver.Name, now)
+		if err := server.GenerateTransferPDF(transfer, stamp, physicalPath); err != nil {
+			return err
+		}
+		fileName := fmt.Sprintf("Surat_Jalan_%s_Diterima.pdf", strings.ReplaceAll(transfer.Number, "/", "-"))
+		file, err := server.storeGeneratedPDF(tx, fileID, folder, fileName, "Surat Jalan", stamp)
+		if err != nil {
+			return err
+		}
+		transfer.ReceiptFileID = &file.ID
+		return tx.Model(&models.AssetTransfer{}).Where("id = ?", transfer.ID).Update("receipt_file_id", file.ID).Error
+	})
+	if err == errTransferClosed {
+		os.Remove(physicalPath)
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Surat jalan ini sudah dikonfirmasi"), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		os.Remove(physicalPath)
+		http.Redirect(w, r, detailURL+"?error="+url.QueryEscape("Gagal mengonfirmasi penerimaan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	msg := "Penerimaan barang berhasil dikonfirmasi"
+	if status == models.TransferStatusDiscrepancy {
+		msg = "Penerimaan dicatat dengan selisih (barang rusak/hilang)"
+	}
+	http.Redirect(w, r, detailURL+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// submitAssetTransfer membuat surat jalan dari GoForm "Surat Jalan Barang"
+func (server *Server) submitAssetTransfer(w http.ResponseWriter, r *http.Request, form models.GoForm) {
+	fillURL := "/goform/fill/" + form.Slug
+
+	// Surat jalan memindahkan lokasi dan pemegang aset, sehingga butuh izin tersendiri
+	_, _, role, _ := GetCurrentAdmin(r)
+	if !server.hasPermission(role, "transfer.create") {
+		http.Error(w, "Access Denied: You don't have permission to access this resource", http.StatusForbidden)
+		return
+	}
+
+	origin := r.FormValue("origin_branch")
+	destination := r.FormValue("destination_branch")
+	courier := strings.TrimSpace(r.FormValue("courier"))
+	notes := strings.TrimSpace(r.FormValue("notes"))
+	signature := r.FormValue("sender_signature")
+	assetIDs := r.Form["asset_ids[]"]
+	shipDate, errDate := time.Parse("2006-01-02", r.FormValue("ship_date"))
+
+	var sender models.User
+	server.DB.Where("id = ?", r.FormValue("sender_id")).Limit(1).Find(&sender)
+
+	// Cabang asal, pengirim dan setiap aset harus berada di dalam cakupan data admin
+	scope := server.dataScope(r)
+	if scope.restricted {
+		allowed := slices.Contains(scope.nodeBranches(), origin) && (sender.ID == "" || scope.allowsUser(sender))
+		var requested []models.AssetKSO
+		server.DB.Preload("User").Where("id IN ?", assetIDs).Find(&requested)
+		for _, asset := range requested {
+			allowed = allowed && scope.allowsAsset(asset)
+		}
+		if !allowed {
+			forbidScope(w)
+			return
+		}
+	}
+
+	var errs []string
+	var branchCount int64
+	server.DB.Model(&models.MasterBranch{}).Where("name IN ?", []string{origin, destination}).Count(&branchCount)
+	if origin == "" || destination == "" || branchCount != 2 {
+		errs = append(errs, "Cabang asal dan tujuan wajib dipilih dan harus berbeda")
+	}
+	if courier == "" {
+		errs = append(errs, "Kurir wajib diisi")
+	}
+	if errDate != nil {
+		errs = append(errs, "Tanggal kirim wajib diisi")
+	}
+	if sender.ID == "" {
+		errs = append(errs, "Pengirim wajib dipilih")
+	}
+	if signature == "" {
+		errs = append(errs, "Tanda tangan pengirim wajib diisi")
+	}
+	if len(assetIDs) == 0 {
+		errs = append(errs, "Pilih minimal satu aset")
+	} else {
+		errs = append(errs, server.transferConflicts(assetIDs, origin)...)
+	}
+	if len(errs) > 0 {
+		http.Redirect(w, r, fillURL+"?error="+url.QueryEscape(strings.Join(errs, "; ")), http.StatusSeeOther)
+		return
+	}
+
+	var assets []models.AssetKSO
+	server.DB.Where("id IN ?", assetIDs).Order("inventory_number asc").Find(&assets)
+
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	transfer := models.AssetTransfer{
+		ID:                uuid.New().String(),
+		OriginBranch:      origin,
+		DestinationBranch: destination,
+		Courier:           courier,
+		ShipDate:          shipDate,
+		Status:            models.TransferStatusInTransit,
+		Notes:             notes,
+		SenderID:          sender.ID,
+		Sender:            sender,
+		SenderSignature:   signature,
+		CreatedByID:       adminID,
+	}
+	var assetNames []string
+	for _, a := range assets {
+		transfer.Items = append(transfer.Items, models.AssetTransferItem{AssetID: a.ID, Asset: a, OriginLocation: a.Location, OriginStatus: a.Status})
+		assetNames = append(assetNames, a.InventoryNumber+" - "+a.AssetName)
+	}
+
+	answers := []models.GoFormAnswer{
+		{Field: "origin_branch", Label: "Cabang Asal", Value: origin, Display: origin},
+		{Field: "destination_branch", Label: "Cabang Tujuan", Value: destination, Display: destination},
+		{Field: "asset_ids", Label: "Aset", Value: strings.Join(assetIDs, ","), Display: strings.Join(assetNames, ", ")},
+		{Field: "courier", Label: "Kurir / Ekspedisi", Value: courier, Display: courier},
+		{Field: "ship_date", Label: "Tanggal Kirim", Value: shipDate.Format("2006-01-02"), Display: translateMonth(shipDate.Format("02 January 2006"))},
+		{Field: "sender_id", Label: "Pengirim", Value: sender.ID, Display: sender.Name},
+		{Field: "notes", Label: "Catatan", Value: notes, Display: notes},
+	}
+	answersJSON, _ := json.Marshal(answers)
+	submission := models.GoFormSubmission{
+		ID:            uuid.New().String(),
+		FormID:        form.ID,
+		Data:          string(answersJSON),
+		SubmittedByID: adminID,
+	}
+	transfer.SubmissionID = &submission.ID
+
+	// Nomor surat jalan, PDF dan datanya disimpan dalam satu transaksi agar nomor tidak loncat
+	root := server.ensureFolder("Laporan Digital", nil, "#3b82f6")
+	folder := server.ensureFolder("Surat Jalan", &root.ID, "#f59e0b")
+	fileID := uuid.New().String()
+	uploadDir := filepath.Join("public", "uploads", "edoc")
+	os.MkdirAll(uploadDir, 0755)
+	physicalPath := filepath.Join(uploadDir, fileID+".pdf")
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		number, err := nextDocNumber(tx, "transfer", origin, shipDate)
+		if err != nil {
+			return err
+		}
+		transfer.Number = number
+		stamp := server.newPDFStamp(number, "SURAT JALAN", destination, shipDate)
+		if err := server.GenerateTransferPDF(transfer, stamp, physicalPath); err != nil {
+			return err
+		}
+		fileName := fmt.Sprintf("Surat_Jalan_%s.pdf", strings.ReplaceAll(number, "/", "-"))
+		file, err := server.storeGeneratedPDF(tx, fileID, folder, fileName, "Surat Jalan", stamp)
+		if err != nil {
+			return err
+		}
+		transfer.FileID = &file.ID
+		submission.FileID = &file.ID
+		if err := tx.Create(&submission).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Sender", "Receiver", "Items.Asset").Create(&transfer).Error; err != nil {
+			return err
+		}
+
+		// Aset berstatus Dalam Perjalanan sampai diterima; update bersyarat mencegah aset yang baru saja
+		// dikirim atau dipinjam lewat proses lain ikut terkirim
+		result := tx.Model(&models.AssetKSO{}).
+			Where("id IN ? AND status NOT IN ?", assetIDs, []string{"Hilang", models.AssetStatusOnLoan, models.AssetStatusInTransit}).
+			Update("status", models.AssetStatusInTransit)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(assets)) {
+			return errTransferConflict
+		}
+		return nil
+	})
+	if err != nil {
+		os.Remove(physicalPath)
+		http.Redirect(w, r, fillURL+"?error="+url.QueryEscape("Gagal menyimpan surat jalan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/goform/surat-jalan/"+transfer.ID+"?msg="+url.QueryEscape("Surat jalan "+transfer.Number+" berhasil dibuat"), http.StatusSeeOther)
+}
+
+// transferConflicts memastikan aset bisa dikirim: berada di cabang asal, tidak hilang, tidak sedang dipinjam
+// dan tidak sedang dalam perjalanan
+func (server *Server) transferConflicts(assetIDs []string, origin string) []string {
+	var conflicts []string
+
+	var assets []models.AssetKSO
+	server.DB.Where("id IN ?", assetIDs).Find(&assets)
+	if len(assets) != len(assetIDs) {
+		conflicts = append(conflicts, "Sebagian aset tidak ditemukan")
+	}
+	for _, a := range assets {
+		if a.Status == "Hilang" || a.Status == models.AssetStatusOnLoan {
+			conflicts = append(conflicts, fmt.Sprintf("%s berstatus %s", a.InventoryNumber, a.Status))
+		}
+		if a.Location != origin {
+			conflicts = append(conflicts, fmt.Sprintf("%s berada di %s, bukan di cabang asal", a.InventoryNumber, a.Location))
+		}
+	}
+
+	var inTransit []string
+	server.DB.Model(&models.AssetTransferItem{}).
+		Joins("JOIN asset_transfers ON asset_transfers.id = asset_transfer_items.transfer_id AND asset_transfers.deleted_at IS NULL").
+		Joins("JOIN asset_kso ON asset_kso.id = asset_transfer_items.asset_id").
+		Where("asset_transfer_items.asset_id IN ? AND asset_transfers.status = ?", assetIDs, models.TransferStatusInTransit).
+		Pluck("asset_kso.inventory_number", &inTransit)
+	for _, inv := range inTransit {
+		conflicts = append(conflicts, inv+" sedang dalam perjalanan pada surat jalan lain")
+	}
+
+	return conflicts
+}
+
+// findAssetTransfer mengambil surat jalan beserta aset, pengirim dan penerimanya
+func (server *Server) findAssetTransfer(id string) (models.AssetTransfer, bool) {
+	var transfer models.AssetTransfer
+	server.DB.Preload("Items.Asset").Preload("Sender").Preload("Receiver").Where("id = ?", id).Limit(1).Find(&transfer)
+	return transfer, transfer.ID != ""
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// transferForm menyiapkan GoForm surat jalan dan isian pengiriman dari Jakarta ke Bandung
+func transferForm(server *Server, assetIDs ...string) url.Values {
+	server.DB.Create(&models.GoForm{ID: "f-transfer", Slug: "form-surat-jalan", Name: "Surat Jalan", Handler: "transfer", IsActive: true})
+	return url.Values{
+		"origin_branch":      {"Jakarta"},
+		"destination_branch": {"Bandung"},
+		"courier":            {"JNE"},
+		"ship_date":          {"2026-01-05"},
+		"sender_id":          {"u-1"},
+		"sender_signature":   {"data:image/png;base64,AA=="},
+		"asset_ids[]":        assetIDs,
+	}
+}
+
+func TestSubmitAssetTransferRequiresPermission(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	client := loginAs(t, server, scopedAdmin(t, server, "s1", "support"))
+
+	w := client.do(http.MethodPost, "/goform/submit/form-surat-jalan", transferForm(server, "as-1"))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("submit without transfer.create = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := client.do(http.MethodGet, "/goform/fill/form-surat-jalan", nil); w.Code != http.StatusForbidden {
+		t.Errorf("fill without transfer.create = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestSubmitAssetTransferOutsideScope(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	client := loginAs(t, server, scopedAdmin(t, server, "m1", "asset_manager", 1))
+
+	form := transferForm(server, "as-2")
+	if w := client.do(http.MethodPost, "/goform/submit/form-surat-jalan", form); w.Code != http.StatusForbidden {
+		t.Errorf("asset outside scope = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	form.Set("origin_branch", "Bandung")
+	form.Set("destination_branch", "Jakarta")
+	form["asset_ids[]"] = []string{"as-1"}
+	if w := client.do(http.MethodPost, "/goform/submit/form-surat-jalan", form); w.Code != http.StatusForbidden {
+		t.Errorf("origin outside scope = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	var count int64
+	server.DB.Model(&models.AssetTransfer{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d transfers created outside the data scope", count)
+	}
+}
+
+func TestSubmitAssetTransferRejectsAssetAtOtherBranch(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	w := client.do(http.MethodPost, "/goform/submit/form-surat-jalan", transferForm(server, "as-2"))
+	if msg := redirectError(t, w.Header().Get("Location")); !strings.Contains(msg, "bukan di cabang asal") {
+		t.Fatalf("error = %q, want asset location conflict", msg)
+	}
+	var asset models.AssetKSO
+	server.DB.First(&asset, "id = ?", "as-2")
+	if asset.Status != "Ready" {
+		t.Errorf("asset status = %q, want Ready", asset.Status)
+	}
+}
+
+func TestReceiveAssetTransferRejectsUnknownCondition(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	transfer := models.AssetTransfer{ID: "t1", Number: "SJ/1", OriginBranch: "Jakarta", DestinationBranch: "Bandung", Status: models.TransferStatusInTransit,
+		Items: []models.AssetTransferItem{{AssetID: "as-1", OriginStatus: "Ready"}}}
+	server.DB.Create(&transfer)
+
+	form := url.Values{"receiver_id": {"u-2"}, "receiver_signature": {"data:image/png;base64,AA=="}}
+	form.Set(fmt.Sprintf("condition_%d", transfer.Items[0].ID), "Dicuri")
+	w := client.do(http.MethodPost, "/goform/surat-jalan/receive/t1", form)
+	if msg := redirectError(t, w.Header().Get("Location")); !strings.Contains(msg, "Kondisi aset tidak valid") {
+		t.Fatalf("error = %q, want invalid condition", msg)
+	}
+	server.DB.First(&transfer, "id = ?", "t1")
+	if transfer.Status != models.TransferStatusInTransit {
+		t.Errorf("status = %q, want %q", transfer.Status, models.TransferStatusInTransit)
+	}
+}
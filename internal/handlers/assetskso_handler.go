@@ -13,27 +13,25 @@ import (
 	"gorm.io/gorm"
 )
 
-// fetchAssetMasterData mengambil data master (kategori, RAM, penyimpanan) untuk form aset
+// fetchAssetMasterData mengambil data master (kategori dan master spesifikasi seperti RAM, penyimpanan,
+// prosesor dan sistem operasi) untuk form aset
 func (server *Server) fetchAssetMasterData() (map[string]interface{}, error) {
 	var categories []models.MasterAssetCategory
-	var ramTypes []models.MasterRamType
-	var storageTypes []models.MasterStorageType
-
 	if err := server.DB.Order("name asc").Find(&categories).Error; err != nil {
 		return nil, err
 	}
-	if err := server.DB.Order("name asc").Find(&ramTypes).Error; err != nil {
-		return nil, err
+
+	data := map[string]interface{}{
+		"categories": categories,
 	}
-	if err := server.DB.Order("name asc").Find(&storageTypes).Error; err != nil {
-		return nil, err
+	for _, m := range lookupMasters {
+		items, err := server.lookupItems(m)
+		if err != nil {
+			return nil, err
+		}
+		data[m.TemplateKey] = items
 	}
-
-	return map[string]interface{}{
-		"categories":   categories,
-		"ramTypes":     ramTypes,
-		"storageTypes": storageTypes,
-	}, nil
+	return data, nil
 }
 
 // getAssetSpecFromForm mengekstrak dan memformat spesifikasi aset dari form request
@@ -67,7 +65,7 @@ func (server *Server) ListAssetKSO(w http.ResponseWriter, r *http.Request) {
 
 	var assets []models.AssetKSO
 	// Start a fresh query for AssetKSO
-	db := server.DB.Model(&models.AssetKSO{}).Preload("User")
+	db := server.dataScope(r).applyAssets(server.DB.Model(&models.AssetKSO{}).Preload("User"))
 
 	if year != "" {
 		fmt.Printf("[ListAssetKSO] Applied filter year: %s\n", year)
@@ -139,6 +137,11 @@ func (server *Server) StoreAssetKSO(w http.ResponseWriter, r *http.Request) {
 	asset.PurchaseDate = purchaseDate
 	asset.Status = r.FormValue("status")
 
+	if !server.assetAllowed(server.dataScope(r), asset) {
+		forbidScope(w)
+		return
+	}
+
 	if err := server.DB.Create(&asset).Error; err != nil {
 		http.Error(w, "Gagal membuat aset: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -180,6 +183,12 @@ func (server *Server) StoreAssetKSOBulk(w http.ResponseWriter, r *http.Request)
 	invStart := r.FormValue("inventory_number_start")
 	purchaseDate, _ := time.Parse("2006-01-02", r.FormValue("purchase_date"))
 
+	// Aset masal belum dipegang karyawan sehingga lokasinya harus berada di dalam cakupan
+	if !server.assetAllowed(server.dataScope(r), models.AssetKSO{Location: r.FormValue("location")}) {
+		forbidScope(w)
+		return
+	}
+
 	// Helper to increment inventory number
 	// It looks for digits at the end of the string
 	re := regexp.MustCompile(`(\d+)$`)
@@ -253,6 +262,10 @@ func (server *Server) EditAssetKSOForm(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/inventori/aset-laptop", http.StatusSeeOther)
 		return
 	}
+	if !server.dataScope(r).allowsAsset(asset) {
+		forbidScope(w)
+		return
+	}
 
 	masterData, _ := server.fetchAssetMasterData()
 	masterData["title"] = "Edit Aset"
@@ -277,6 +290,11 @@ func (server *Server) UpdateAssetKSO(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/inventori/aset-laptop", http.StatusSeeOther)
 		return
 	}
+	scope := server.dataScope(r)
+	if !server.assetAllowed(scope, asset) {
+		forbidScope(w)
+		return
+	}
 
 	purchaseDate, _ := time.Parse("2006-01-02", r.FormValue("purchase_date"))
 	userID := r.FormValue("user_id")
@@ -300,6 +318,10 @@ func (server *Server) UpdateAssetKSO(w http.ResponseWriter, r *http.Request) {
 	asset.UserID = userIDPtr
 	asset.PurchaseDate = purchaseDate
 	asset.Status = r.FormValue("status")
+	if !server.assetAllowed(scope, asset) {
+		forbidScope(w)
+		return
+	}
 	if err := server.DB.Save(&asset).Error; err != nil {
 		http.Error(w, "Gagal memperbarui aset: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -323,6 +345,11 @@ func (server *Server) DeleteAssetKSO(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	if !server.assetIDAllowed(r, id) {
+		forbidScope(w)
+		return
+	}
+
 	server.DB.Where("id = ?", id).Delete(&models.AssetKSO{})
 	http.Redirect(w, r, "/inventori/aset-laptop", http.StatusSeeOther)
 }
@@ -336,7 +363,7 @@ func (server *Server) BulkDeleteAssetKSO(w http.ResponseWriter, r *http.Request)
 
 	ids := r.Form["ids"]
 	if len(ids) > 0 {
-		server.DB.Where("id IN ?", ids).Delete(&models.AssetKSO{})
+		server.dataScope(r).applyAssets(server.DB.Where("id IN ?", ids)).Delete(&models.AssetKSO{})
 	}
 
 	http.Redirect(w, r, "/inventori/aset-laptop", http.StatusSeeOther)
@@ -345,10 +372,11 @@ func (server *Server) BulkDeleteAssetKSO(w http.ResponseWriter, r *http.Request)
 // ListAssetLaptop menampilkan halaman manajemen aset khusus untuk kategori Laptop
 func (server *Server) ListAssetLaptop(w http.ResponseWriter, r *http.Request) {
 	var assets []models.AssetKSO
-	server.DB.Preload("User").Where("category = ?", "Laptop").Order("inventory_number asc").Find(&assets)
+	scope := server.dataScope(r)
+	scope.applyAssets(preloadUserOrg(server.DB, "User").Where("category = ?", "Laptop")).Order("inventory_number asc").Find(&assets)
 
 	var users []models.User
-	server.DB.Find(&users)
+	scope.applyUsers(preloadUserOrg(server.DB, "")).Find(&users)
 
 	server.RenderHTML(w, r, http.StatusOK, "assets_kso/laptop_management", map[string]interface{}{
 		"title":  "Asset Management - Laptop",
@@ -360,7 +388,7 @@ func (server *Server) ListAssetLaptop(w http.ResponseWriter, r *http.Request) {
 // CreateAssetLaptopForm menampilkan form untuk menambah aset Laptop baru melalui menu Asset Management
 func (server *Server) CreateAssetLaptopForm(w http.ResponseWriter, r *http.Request) {
 	var users []models.User
-	server.DB.Find(&users)
+	server.dataScope(r).applyUsers(server.DB).Find(&users)
 
 	masterData, _ := server.fetchAssetMasterData()
 	masterData["title"] = "Tambah Laptop"
@@ -380,9 +408,14 @@ func (server *Server) EditAssetLaptopForm(w http.ResponseWriter, r *http.Request
 		http.Redirect(w, r, "/asset-management/laptop", http.StatusSeeOther)
 		return
 	}
+	scope := server.dataScope(r)
+	if !scope.allowsAsset(asset) {
+		forbidScope(w)
+		return
+	}
 
 	var users []models.User
-	server.DB.Find(&users)
+	scope.applyUsers(server.DB).Find(&users)
 
 	masterData, _ := server.fetchAssetMasterData()
 	masterData["title"] = "Edit Laptop"
@@ -397,6 +430,11 @@ func (server *Server) DeleteAssetLaptop(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	if !server.assetIDAllowed(r, id) {
+		forbidScope(w)
+		return
+	}
+
 	server.DB.Unscoped().Where("id = ?", id).Delete(&models.AssetKSO{})
 	http.Redirect(w, r, "/asset-management/laptop", http.StatusSeeOther)
 }
@@ -414,6 +452,11 @@ func (server *Server) AssignAssetLaptop(w http.ResponseWriter, r *http.Request)
 
 	var asset models.AssetKSO
 	if err := server.DB.Where("id = ?", assetID).First(&asset).Error; err == nil {
+		scope := server.dataScope(r)
+		if !server.assetAllowed(scope, asset) || (userID != "" && !server.userAllowed(scope, userID)) {
+			forbidScope(w)
+			return
+		}
 		if userID == "" {
 			asset.UserID = nil
 		} else {
@@ -428,10 +471,11 @@ func (server *Server) AssignAssetLaptop(w http.ResponseWriter, r *http.Request)
 // ListAssetKomputer menampilkan halaman manajemen aset khusus untuk kategori Komputer
 func (server *Server) ListAssetKomputer(w http.ResponseWriter, r *http.Request) {
 	var assets []models.AssetKSO
-	server.DB.Preload("User").Where("category = ?", "Komputer").Order("inventory_number asc").Find(&assets)
+	scope := server.dataScope(r)
+	scope.applyAssets(preloadUserOrg(server.DB, "User").Where("category = ?", "Komputer")).Order("inventory_number asc").Find(&assets)
 
 	var users []models.User
-	server.DB.Find(&users)
+	scope.applyUsers(preloadUserOrg(server.DB, "")).Find(&users)
 
 	server.RenderHTML(w, r, http.StatusOK, "assets_kso/komputer_management", map[string]interface{}{
 		"title":  "Asset Management - Komputer",
@@ -443,7 +487,7 @@ func (server *Server) ListAssetKomputer(w http.ResponseWriter, r *http.Request)
 // CreateAssetKomputerForm menampilkan form untuk menambah aset Komputer baru
 func (server *Server) CreateAssetKomputerForm(w http.ResponseWriter, r *http.Request) {
 	var users []models.User
-	server.DB.Find(&users)
+	server.dataScope(r).applyUsers(server.DB).Find(&users)
 
 	masterData, _ := server.fetchAssetMasterData()
 	masterData["title"] = "Tambah Komputer"
@@ -462,9 +506,14 @@ func (server *Server) EditAssetKomputerForm(w http.ResponseWriter, r *http.Reque
 		http.Redirect(w, r, "/asset-management/komputer", http.StatusSeeOther)
 		return
 	}
+	scope := server.dataScope(r)
+	if !scope.allowsAsset(asset) {
+		forbidScope(w)
+		return
+	}
 
 	var users []models.User
-	server.DB.Find(&users)
+	scope.applyUsers(server.DB).Find(&users)
 
 	masterData, _ := server.fetchAssetMasterData()
 	masterData["title"] = "Edit Komputer"
@@ -478,6 +527,11 @@ func (server *Server) DeleteAssetKomputer(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	if !server.assetIDAllowed(r, id) {
+		forbidScope(w)
+		return
+	}
+
 	server.DB.Unscoped().Where("id = ?", id).Delete(&models.AssetKSO{})
 	http.Redirect(w, r, "/asset-management/komputer", http.StatusSeeOther)
 }
@@ -494,6 +548,11 @@ func (server *Server) AssignAssetKomputer(w http.ResponseWriter, r *http.Request
 
 	var asset models.AssetKSO
 	if err := server.DB.Where("id = ?", assetID).First(&asset).Error; err == nil {
+		scope := server.dataScope(r)
+		if !server.assetAllowed(scope, asset) || (userID != "" && !server.userAllowed(scope, userID)) {
+			forbidScope(w)
+			return
+		}
 		if userID == "" {
 			asset.UserID = nil
 		} else {
@@ -526,6 +585,10 @@ func (server *Server) UpdateAssetLabel(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	if !server.assetAllowed(server.dataScope(r), asset) {
+		forbidScope(w)
+		return
+	}
 
 	asset.DeviceName = newLabel
 	server.DB.Save(&asset)
@@ -552,7 +615,7 @@ func (server *Server) BulkUpdateAssetLabel(w http.ResponseWriter, r *http.Reques
 
 	// 1. Fetch assets in range
 	var assets []models.AssetKSO
-	query := server.DB.Where("inventory_number >= ? AND inventory_number <= ?", invStart, invEnd)
+	query := server.dataScope(r).applyAssets(server.DB.Where("inventory_number >= ? AND inventory_number <= ?", invStart, invEnd))
 	if category != "" {
 		query = query.Where("category = ?", category)
 	}
@@ -612,6 +675,8 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 	dept := r.URL.Query().Get("department")
 	subDeptParam := r.URL.Query().Get("sub_department")
 	_, _, adminRole, _ := GetCurrentAdmin(r)
+	scope := server.dataScope(r)
+	filter := server.findOrgNode(branchParam, dept, subDeptParam)
 
 	now := time.Now()
 	if year == "" {
@@ -637,6 +702,7 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 	// Fetch all Master Data for dropdowns (Hierarchical)
 	var branches []models.MasterBranch
 	server.DB.Preload("Departments.SubDepartments").Find(&branches)
+	branches = scope.scopedBranches(branches)
 
 	// Fetch all simple lists for non-hierarchical use if needed, but we'll use branches mostly
 	var departments []models.MasterDepartment
@@ -649,7 +715,7 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 	// Filtering via SQL JOIN with 'users' only filters by CURRENT assignment,
 	// which breaks historical views when employees move or resign.
 	var assets []models.AssetKSO
-	server.DB.Preload("User").Where("category = ?", "Laptop").Find(&assets)
+	preloadUserOrg(server.DB, "User").Where("category = ?", "Laptop").Find(&assets)
 
 	// Fetch maintenance reports for this period that are NOT yet archived (document_id is null)
 	var reports []models.MaintenanceReport
@@ -676,29 +742,25 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 		// Determine effective location for this asset in this period
 		// Snapshot rule: Only use report snapshot if it has been submitted/approved.
 		// If it's still a draft, use the latest data from the User record.
-		effBranch := asset.User.Branch
-		effDept := asset.User.Department
-		effSubDept := asset.User.SubDepartment
+		effBranch := uintValue(asset.User.BranchID)
+		effDept := uintValue(asset.User.DepartmentID)
+		effSubDept := uintValue(asset.User.SubDepartmentID)
+		effSubDeptName := asset.User.SubDepartment.Name
 
 		if exists && report.IsSubmitted && report.UserBranch != "" {
-			effBranch = report.UserBranch
-			effDept = report.UserDepartment
-			effSubDept = report.UserSubDepartment
+			effBranch = uintValue(report.UserBranchID)
+			effDept = uintValue(report.UserDepartmentID)
+			effSubDept = uintValue(report.UserSubDepartmentID)
+			effSubDeptName = report.UserSubDepartment
 		}
 
 		// Apply Filters (Branch, Dept, SubDept)
-		if branchParam != "" && effBranch != branchParam {
-			continue
-		}
-		if dept != "" && effDept != dept {
-			continue
-		}
-		if subDeptParam != "" && effSubDept != subDeptParam {
+		if !scope.allows(effBranch, effDept, effSubDept) || !filter.matches(effBranch, effDept, effSubDept) {
 			continue
 		}
 
 		// Grouping key
-		groupKey := effSubDept
+		groupKey := effSubDeptName
 		if groupKey == "" {
 			groupKey = "Lainnya"
 		}
@@ -720,13 +782,8 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 	// We'll take the first report that matches the current filters and is submitted/approved
 	for _, r := range reports {
 		// Filter match check for signature display
-		if branchParam != "" && r.UserBranch != branchParam {
-			continue
-		}
-		if dept != "" && r.UserDepartment != dept {
-			continue
-		}
-		if subDeptParam != "" && r.UserSubDepartment != subDeptParam {
+		reportBranch, reportDept, reportSubDept := uintValue(r.UserBranchID), uintValue(r.UserDepartmentID), uintValue(r.UserSubDepartmentID)
+		if !scope.allows(reportBranch, reportDept, reportSubDept) || !filter.matches(reportBranch, reportDept, reportSubDept) {
 			continue
 		}
 
@@ -735,9 +792,9 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 			// Fetch employee info for submitter
 			if submitter.UserID != "" {
 				var u models.User
-				server.DB.First(&u, "id = ?", submitter.UserID)
-				submitter.Username = u.Name // Use actual name
-				submitter.Role = u.Position // Temporarily reuse Role field or just pass separately
+				preloadUserOrg(server.DB, "").First(&u, "id = ?", submitter.UserID)
+				submitter.Username = u.Name      // Use actual name
+				submitter.Role = u.Position.Name // Temporarily reuse Role field or just pass separately
 			}
 		}
 		if r.IsApproved && approver.ID == "" {
@@ -745,9 +802,9 @@ func (server *Server) MaintenanceLaptop(w http.ResponseWriter, r *http.Request)
 			// Fetch employee info for approver
 			if approver.UserID != "" {
 				var u models.User
-				server.DB.First(&u, "id = ?", approver.UserID)
+				preloadUserOrg(server.DB, "").First(&u, "id = ?", approver.UserID)
 				approver.Username = u.Name
-				approver.Role = u.Position
+				approver.Role = u.Position.Name
 			}
 			approvedDate = r.ApprovedAt
 		}
@@ -779,6 +836,8 @@ func (server *Server) MaintenanceKomputer(w http.ResponseWriter, r *http.Request
 	dept := r.URL.Query().Get("department")
 	subDeptParam := r.URL.Query().Get("sub_department")
 	_, _, adminRole, _ := GetCurrentAdmin(r)
+	scope := server.dataScope(r)
+	filter := server.findOrgNode(branchParam, dept, subDeptParam)
 
 	now := time.Now()
 	if year == "" {
@@ -804,10 +863,11 @@ func (server *Server) MaintenanceKomputer(w http.ResponseWriter, r *http.Request
 	// Fetch hierarchy for dropdowns
 	var branches []models.MasterBranch
 	server.DB.Preload("Departments.SubDepartments").Find(&branches)
+	branches = scope.scopedBranches(branches)
 
 	// Fetch all assets of category "Komputer"
 	var assets []models.AssetKSO
-	server.DB.Preload("User").Where("category = ?", "Komputer").Find(&assets)
+	preloadUserOrg(server.DB, "User").Where("category = ?", "Komputer").Find(&assets)
 
 	// Fetch maintenance reports for this period that are NOT yet archived (document_id is null)
 	var reports []models.MaintenanceReport
@@ -832,29 +892,25 @@ func (server *Server) MaintenanceKomputer(w http.ResponseWriter, r *http.Request
 		}
 
 		// Snapshot rule: Only use report snapshot if it has been submitted/approved.
-		effBranch := asset.User.Branch
-		effDept := asset.User.Department
-		effSubDept := asset.User.SubDepartment
+		effBranch := uintValue(asset.User.BranchID)
+		effDept := uintValue(asset.User.DepartmentID)
+		effSubDept := uintValue(asset.User.SubDepartmentID)
+		effSubDeptName := asset.User.SubDepartment.Name
 
 		if exists && report.IsSubmitted && report.UserBranch != "" {
-			effBranch = report.UserBranch
-			effDept = report.UserDepartment
-			effSubDept = report.UserSubDepartment
+			effBranch = uintValue(report.UserBranchID)
+			effDept = uintValue(report.UserDepartmentID)
+			effSubDept = uintValue(report.UserSubDepartmentID)
+			effSubDeptName = report.UserSubDepartment
 		}
 
 		// Apply Filters (Branch, Dept, SubDept)
-		if branchParam != "" && effBranch != branchParam {
-			continue
-		}
-		if dept != "" && effDept != dept {
-			continue
-		}
-		if subDeptParam != "" && effSubDept != subDeptParam {
+		if !scope.allows(effBranch, effDept, effSubDept) || !filter.matches(effBranch, effDept, effSubDept) {
 			continue
 		}
 
 		// Grouping key
-		groupKey := effSubDept
+		groupKey := effSubDeptName
 		if groupKey == "" {
 			groupKey = "Lainnya"
 		}
@@ -875,13 +931,8 @@ func (server *Server) MaintenanceKomputer(w http.ResponseWriter, r *http.Request
 
 	for _, r := range reports {
 		// Filter match check for signature display
-		if branchParam != "" && r.UserBranch != branchParam {
-			continue
-		}
-		if dept != "" && r.UserDepartment != dept {
-			continue
-		}
-		if subDeptParam != "" && r.UserSubDepartment != subDeptParam {
+		reportBranch, reportDept, reportSubDept := uintValue(r.UserBranchID), uintValue(r.UserDepartmentID), uintValue(r.UserSubDepartmentID)
+		if !scope.allows(reportBranch, reportDept, reportSubDept) || !filter.matches(reportBranch, reportDept, reportSubDept) {
 			continue
 		}
 
@@ -889,18 +940,18 @@ func (server *Server) MaintenanceKomputer(w http.ResponseWriter, r *http.Request
 			server.DB.First(&submitter, "id = ?", r.SubmittedByID)
 			if submitter.UserID != "" {
 				var u models.User
-				server.DB.First(&u, "id = ?", submitter.UserID)
+				preloadUserOrg(server.DB, "").First(&u, "id = ?", submitter.UserID)
 				submitter.Username = u.Name
-				submitter.Role = u.Position
+				submitter.Role = u.Position.Name
 			}
 		}
 		if r.IsApproved && approver.ID == "" {
 			server.DB.First(&approver, "id = ?", r.ApprovedByID)
 			if approver.UserID != "" {
 				var u models.User
-				server.DB.First(&u, "id = ?", approver.UserID)
+				preloadUserOrg(server.DB, "").First(&u, "id = ?", approver.UserID)
 				approver.Username = u.Name
-				approver.Role = u.Position
+				approver.Role = u.Position.Name
 			}
 			approvedDate = r.ApprovedAt
 		}
@@ -949,15 +1000,19 @@ func (server *Server) StoreMaintenanceLaptop(w http.ResponseWriter, r *http.Requ
 
 	// Fetch current asset and user for snapshot
 	var asset models.AssetKSO
-	server.DB.Preload("User").Where("id = ?", assetID).First(&asset)
+	preloadUserOrg(server.DB, "User").Where("id = ?", assetID).First(&asset)
+	if !server.dataScope(r).allowsAsset(asset) {
+		forbidScope(w)
+		return
+	}
 
 	var userName, userPos, userBranch, userDept, userSub string
 	if asset.User.ID != "" {
 		userName = asset.User.Name
-		userPos = asset.User.Position
-		userBranch = asset.User.Branch
-		userDept = asset.User.Department
-		userSub = asset.User.SubDepartment
+		userPos = asset.User.Position.Name
+		userBranch = asset.User.Branch.Name
+		userDept = asset.User.Department.Name
+		userSub = asset.User.SubDepartment.Name
 	}
 
 	// Check if a report already exists for this asset and period
@@ -980,26 +1035,34 @@ func (server *Server) StoreMaintenanceLaptop(w http.ResponseWriter, r *http.Requ
 			existingReport.UserBranch = userBranch
 			existingReport.UserDepartment = userDept
 			existingReport.UserSubDepartment = userSub
+			existingReport.UserBranchID = asset.User.BranchID
+			existingReport.UserDepartmentID = asset.User.DepartmentID
+			existingReport.UserSubDepartmentID = asset.User.SubDepartmentID
+			existingReport.UserPositionID = asset.User.PositionID
 		}
 
 		server.DB.Save(&existingReport)
 	} else {
 		// Create new
 		newReport := models.MaintenanceReport{
-			ID:                uuid.New().String(),
-			AssetID:           assetID,
-			Period:            period,
-			AntivirusUpdated:  antivirus,
-			ClearTemporary:    clearTemp,
-			OverallCondition:  condition,
-			InspectionDate:    inspectionDate,
-			Remarks:           remarks,
-			CheckerID:         adminID,
-			UserName:          userName,
-			UserPosition:      userPos,
-			UserBranch:        userBranch,
-			UserDepartment:    userDept,
-			UserSubDepartment: userSub,
+			ID:                  uuid.New().String(),
+			AssetID:             assetID,
+			Period:              period,
+			AntivirusUpdated:    antivirus,
+			ClearTemporary:      clearTemp,
+			OverallCondition:    condition,
+			InspectionDate:      inspectionDate,
+			Remarks:             remarks,
+			CheckerID:           adminID,
+			UserName:            userName,
+			UserPosition:        userPos,
+			UserBranch:          userBranch,
+			UserDepartment:      userDept,
+			UserSubDepartment:   userSub,
+			UserBranchID:        asset.User.BranchID,
+			UserDepartmentID:    asset.User.DepartmentID,
+			UserSubDepartmentID: asset.User.SubDepartmentID,
+			UserPositionID:      asset.User.PositionID,
 		}
 		server.DB.Create(&newReport)
 	}
@@ -1030,20 +1093,30 @@ func (server *Server) SubmitMaintenance(w http.ResponseWriter, r *http.Request)
 	subDept := r.FormValue("sub_department")
 	category := r.FormValue("category")
 
+	// Admin dengan cakupan data hanya dapat memproses area yang seluruhnya berada di dalam cakupannya
+	node := server.findOrgNode(branch, dept, subDept)
+	if !server.dataScope(r).allows(node.BranchID, node.DepartmentID, node.SubDepartmentID) {
+		forbidScope(w)
+		return
+	}
+
 	adminID, _, _, _ := GetCurrentAdmin(r)
 	now := time.Now()
 
 	// 1. Create a MaintenanceDocument
 	doc := models.MaintenanceDocument{
-		ID:            uuid.New().String(),
-		Category:      category,
-		Branch:        branch,
-		Department:    dept,
-		SubDepartment: subDept,
-		Period:        period,
-		Status:        "Submitted",
-		SubmittedByID: adminID,
-		SubmittedAt:   &now,
+		ID:              uuid.New().String(),
+		Category:        category,
+		BranchID:        optionalID(node.BranchID),
+		DepartmentID:    optionalID(node.DepartmentID),
+		SubDepartmentID: optionalID(node.SubDepartmentID),
+		Branch:          branch,
+		Department:      dept,
+		SubDepartment:   subDept,
+		Period:          period,
+		Status:          "Submitted",
+		SubmittedByID:   adminID,
+		SubmittedAt:     &now,
 	}
 
 	if err := server.DB.Create(&doc).Error; err != nil {
@@ -1061,13 +1134,13 @@ func (server *Server) SubmitMaintenance(w http.ResponseWriter, r *http.Request)
 		Where("asset_kso.category = ?", category)
 
 	if branch != "" {
-		subQuery = subQuery.Where("maintenance_reports.user_branch = ?", branch)
+		subQuery = subQuery.Where("maintenance_reports.user_branch_id = ?", node.BranchID)
 	}
 	if dept != "" {
-		subQuery = subQuery.Where("maintenance_reports.user_department = ?", dept)
+		subQuery = subQuery.Where("maintenance_reports.user_department_id = ?", node.DepartmentID)
 	}
 	if subDept != "" {
-		subQuery = subQuery.Where("maintenance_reports.user_sub_department = ?", subDept)
+		subQuery = subQuery.Where("maintenance_reports.user_sub_department_id = ?", node.SubDepartmentID)
 	}
 
 	subQuery.Scan(&reportIDs)
@@ -1111,13 +1184,28 @@ func (server *Server) ApproveMaintenance(w http.ResponseWriter, r *http.Request)
 	subDept := r.FormValue("sub_department")
 	category := r.FormValue("category")
 
+	// Admin dengan cakupan data hanya dapat memproses area yang seluruhnya berada di dalam cakupannya
+	node := server.findOrgNode(branch, dept, subDept)
+	if !server.dataScope(r).allows(node.BranchID, node.DepartmentID, node.SubDepartmentID) {
+		forbidScope(w)
+		return
+	}
+
 	adminID, _, _, _ := GetCurrentAdmin(r)
 	now := time.Now()
 
 	// 1. Find the current Submitted document for this filter
+	// Dokumen dicari dengan ID unit sehingga tetap ditemukan walaupun nama unit diganti setelah diajukan
 	var doc models.MaintenanceDocument
-	err = server.DB.Where("category = ? AND branch = ? AND department = ? AND sub_department = ? AND period = ? AND status = ?",
-		category, branch, dept, subDept, period, "Submitted").First(&doc).Error
+	query := server.DB.Where("category = ? AND period = ? AND status = ?", category, period, "Submitted")
+	for column, id := range map[string]uint{"branch_id": node.BranchID, "department_id": node.DepartmentID, "sub_department_id": node.SubDepartmentID} {
+		if id == 0 {
+			query = query.Where(column + " IS NULL")
+		} else {
+			query = query.Where(column+" = ?", id)
+		}
+	}
+	err = query.First(&doc).Error
 
 	if err != nil {
 		http.Error(w, "Dokumen pengajuan tidak ditemukan", http.StatusNotFound)
@@ -1149,7 +1237,7 @@ func (server *Server) ApproveMaintenance(w http.ResponseWriter, r *http.Request)
 
 func (server *Server) MaintenanceHistory(w http.ResponseWriter, r *http.Request) {
 	var documents []models.MaintenanceDocument
-	server.DB.Order("created_at desc").Find(&documents)
+	server.dataScope(r).apply(server.DB, "branch_id", "department_id", "sub_department_id").Order("created_at desc").Find(&documents)
 
 	server.RenderHTML(w, r, http.StatusOK, "maintenance/history", map[string]interface{}{
 		"title":     "Riwayat Pemeliharaan",
@@ -1166,6 +1254,10 @@ func (server *Server) MaintenanceHistoryDetail(w http.ResponseWriter, r *http.Re
 		http.Redirect(w, r, "/maintenance/history", http.StatusSeeOther)
 		return
 	}
+	if !server.dataScope(r).allows(uintValue(doc.BranchID), uintValue(doc.DepartmentID), uintValue(doc.SubDepartmentID)) {
+		forbidScope(w)
+		return
+	}
 
 	var reports []models.MaintenanceReport
 	server.DB.Preload("Asset", func(db *gorm.DB) *gorm.DB {
@@ -1202,9 +1294,9 @@ func (server *Server) MaintenanceHistoryDetail(w http.ResponseWriter, r *http.Re
 	enhanceAdmin := func(a *models.Admin) {
 		if a.ID != "" && a.UserID != "" {
 			var u models.User
-			server.DB.First(&u, "id = ?", a.UserID)
+			preloadUserOrg(server.DB, "").First(&u, "id = ?", a.UserID)
 			a.Username = u.Name
-			a.Role = u.Position
+			a.Role = u.Position.Name
 		}
 	}
 	enhanceAdmin(&submitter)
@@ -1,35 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
 	"github.com/google/uuid"
-	"github.com/gorilla/sessions"
+	"github.com/gorilla/mux"
 	"github.com/unrolled/render"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var store = sessions.NewCookieStore([]byte("gokso-secret-key-change-in-production"))
-
-func init() {
-	store.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   86400 * 7, // 7 days
-		HttpOnly: true,
-	}
-}
+// store menyimpan session login admin di database; disiapkan oleh initSessionStore
+var store *dbSessionStore
 
 // LoginForm menampilkan halaman login admin
 func (server *Server) LoginForm(w http.ResponseWriter, r *http.Request) {
 	// Check if already logged in
-	session, _ := store.Get(r, "gokso-session")
+	session, _ := store.Get(r, sessionCookieName)
 	if session.Values["admin_id"] != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -37,41 +34,117 @@ func (server *Server) LoginForm(w http.ResponseWriter, r *http.Request) {
 
 	_ = server.Renderer.HTML(w, http.StatusOK, "auth/login", map[string]interface{}{
 		"Error": r.URL.Query().Get("error"),
+		"SSO":   server.SSO != nil,
 	}, render.HTMLOptions{Layout: ""})
 }
 
 // Login menangani proses verifikasi kredensial admin dan pembuatan session
 func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
-	username := r.FormValue("username")
+	username := strings.TrimSpace(r.FormValue("username"))
 	password := r.FormValue("password")
 
-	fmt.Printf("Login attempt: username=%s\n", username)
-
 	var admin models.Admin
-	if err := server.DB.Where("username = ?", username).First(&admin).Error; err != nil {
-		fmt.Printf("Login failed: username %s not found in admins table\n", username)
-		http.Redirect(w, r, "/login?error=Username atau password salah", http.StatusSeeOther)
+	server.DB.Where("username = ?", username).Limit(1).Find(&admin)
+	if admin.ID == "" && server.Directory != nil {
+		// Username direktori tidak peka huruf besar/kecil dan akun hasil provisioning disimpan huruf kecil.
+		// Disamakan sebelum pencarian agar varian huruf tidak membuat akun baru di samping akun yang sudah ada
+		// dan jeda login per username tetap berlaku.
+		username = strings.ToLower(username)
+		server.DB.Where("username = ?", username).Limit(1).Find(&admin)
+	}
+
+	// Jeda eksponensial per username dan per IP diperiksa sebelum password agar tebakan beruntun tidak efektif
+	if wait := server.loginRetryAfter(username, clientIP(r), &admin); wait > 0 {
+		server.recordLoginAttempt(r, username, admin.ID, false, loginReasonRateLimited)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(retryMessage(wait)), http.StatusSeeOther)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)); err != nil {
-		http.Redirect(w, r, "/login?error=Username atau password salah", http.StatusSeeOther)
+	if admin.ID == "" && server.Directory == nil {
+		// Hash tetap dihitung agar waktu respons tidak membedakan username terdaftar
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		server.recordLoginAttempt(r, username, "", false, loginReasonUnknownUser)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Username atau password salah"), http.StatusSeeOther)
 		return
 	}
 
-	// Create session
-	session, _ := store.Get(r, "gokso-session")
-	session.Values["admin_id"] = admin.ID
-	session.Values["admin_username"] = admin.Username
-	session.Values["admin_role"] = admin.Role
-	session.Save(r, w)
+	if left := server.accountLockedFor(&admin); left > 0 {
+		server.recordLoginAttempt(r, username, admin.ID, false, loginReasonLocked)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(lockedMessage(left)), http.StatusSeeOther)
+		return
+	}
+
+	if server.usesDirectory(admin) {
+		err := server.directoryLogin(username, password, &admin)
+		switch {
+		case err == nil:
+		case errors.Is(err, errDirectoryUnavailable):
+			log.Printf("LDAP: %v", err)
+			server.recordLoginAttempt(r, username, admin.ID, false, loginReasonDirectoryDown)
+			http.Redirect(w, r, "/login?error="+url.QueryEscape("Server direktori tidak dapat dihubungi, coba lagi nanti"), http.StatusSeeOther)
+			return
+		case errors.Is(err, errDirectoryNoAccess):
+			server.recordLoginAttempt(r, username, admin.ID, false, loginReasonNoAccess)
+			http.Redirect(w, r, "/login?error="+url.QueryEscape("Akun Anda tidak memiliki akses ke aplikasi ini. Hubungi administrator"), http.StatusSeeOther)
+			return
+		default:
+			server.rejectPassword(w, r, username, &admin)
+			return
+		}
+	} else if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)); err != nil {
+		server.rejectPassword(w, r, username, &admin)
+		return
+	}
+
+	if admin.Disabled {
+		server.recordLoginAttempt(r, username, admin.ID, false, loginReasonNoAccess)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Akun Anda dinonaktifkan. Hubungi administrator"), http.StatusSeeOther)
+		return
+	}
+
+	// Admin dengan 2FA (atau yang role-nya mewajibkan 2FA) harus lolos langkah kedua sebelum session dibuat
+	if admin.TOTPEnabled || server.requires2FA(admin.Role) {
+		if err := server.startLoginChallenge(w, admin.ID); err != nil {
+			http.Redirect(w, r, "/login?error="+url.QueryEscape("Gagal memulai verifikasi dua langkah"), http.StatusSeeOther)
+			return
+		}
+		next := "/login/2fa"
+		if !admin.TOTPEnabled {
+			next = "/login/2fa/setup"
+		}
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	if err := server.createLoginSession(w, r, admin); err != nil {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Gagal membuat session login"), http.StatusSeeOther)
+		return
+	}
+	server.resetLoginFailures(&admin)
+	server.recordLoginAttempt(r, username, admin.ID, true, loginReasonSuccess)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// rejectPassword mencatat login dengan password salah, menambah penghitung gagal dan mengunci akun bila perlu
+func (server *Server) rejectPassword(w http.ResponseWriter, r *http.Request, username string, admin *models.Admin) {
+	reason := loginReasonUnknownUser
+	if admin.ID != "" {
+		reason = loginReasonPassword
+		server.registerLoginFailure(admin)
+	}
+	server.recordLoginAttempt(r, username, admin.ID, false, reason)
+
+	msg := "Username atau password salah"
+	if admin.LockedUntil != nil {
+		msg = lockedMessage(server.Lockout.Duration)
+	}
+	http.Redirect(w, r, "/login?error="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
 // Logout menghapus data session admin dan mengarahkan ke halaman login
 func (server *Server) Logout(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "gokso-session")
+	session, _ := store.Get(r, sessionCookieName)
 	session.Values["admin_id"] = nil
 	session.Values["admin_username"] = nil
 	session.Values["admin_role"] = nil
@@ -84,7 +157,7 @@ func (server *Server) Logout(w http.ResponseWriter, r *http.Request) {
 // GetCurrentAdmin returns the current logged in admin info from session
 // GetCurrentAdmin mengambil informasi admin yang sedang login dari session
 func GetCurrentAdmin(r *http.Request) (adminID string, username string, role string, isLoggedIn bool) {
-	session, err := store.Get(r, "gokso-session")
+	session, err := store.Get(r, sessionCookieName)
 	if err != nil {
 		return "", "", "", false
 	}
@@ -103,81 +176,122 @@ func GetCurrentAdmin(r *http.Request) (adminID string, username string, role str
 // GetAdminData returns a map with current admin info to be used in templates
 // GetAdminData menyediakan data admin dan notifikasi untuk dikirim ke template/UI
 func (server *Server) GetAdminData(r *http.Request) map[string]interface{} {
-	adminID, username, role, isLoggedIn := GetCurrentAdmin(r)
-	perms := server.GetPermissions(role)
-
-	var avatar string
-	var signature string
-	var adminName string = username // Fallback to username
+	// Admin, karyawan dan izin dimuat sekali per request lewat principal
+	p := server.currentPrincipal(r)
+	isLoggedIn := p != nil
+	perms := map[string]bool{}
+	var username, role, avatar, signature, adminName, linkedUserID, impersonator string
 	if isLoggedIn {
-		var admin models.Admin
-		if err := server.DB.First(&admin, "id = ?", adminID).Error; err == nil {
-			avatar = admin.Avatar
-			signature = admin.Signature
-			if admin.UserID != "" {
-				var user models.User
-				if err := server.DB.Select("name").Where("id = ?", admin.UserID).First(&user).Error; err == nil {
-					adminName = user.Name
-				}
-			}
-		}
+		username = p.Admin.Username
+		role = p.Admin.Role
+		avatar = p.Admin.Avatar
+		signature = p.Admin.Signature
+		adminName = p.Name()
+		linkedUserID = p.User.ID
+		perms = p.Permissions
+		impersonator = p.Impersonator
 	}
 
+	// Laporan yang menunggu persetujuan sudah dikelompokkan per dokumen pengajuan, sehingga cukup satu query
 	var pendingCount int64
 	var pendingReports []map[string]interface{}
 	var approvalLink string = "/maintenance/laptop"
-	if isLoggedIn && role == "super_admin" {
-		// Group by location and period to avoid duplicate notifications for the same section
-		server.DB.Table("(?) as grouped",
-			server.DB.Model(&models.MaintenanceReport{}).
-				Select("user_branch, user_department, user_sub_department, period").
-				Where("is_submitted = ? AND is_approved = ?", true, false).
-				Group("user_branch, user_department, user_sub_department, period"),
-		).Count(&pendingCount)
-
-		if pendingCount > 0 {
-			type GroupedReport struct {
-				UserBranch        string
-				UserDepartment    string
-				UserSubDepartment string
-				Period            string
-				MaxUpdate         time.Time
+	if isLoggedIn && perms["maintenance.approve"] {
+		var docs []models.MaintenanceDocument
+		p.Scope().apply(server.DB, "branch_id", "department_id", "sub_department_id").
+			Select("id", "category", "branch", "department", "sub_department", "period", "updated_at").
+			Where("status = ?", "Submitted").
+			Order("updated_at desc").
+			Find(&docs)
+		pendingCount = int64(len(docs))
+
+		for i, doc := range docs {
+			if i == 5 {
+				break
 			}
-			var groups []GroupedReport
-			server.DB.Model(&models.MaintenanceReport{}).
-				Select("user_branch, user_department, user_sub_department, period, MAX(updated_at) as max_update").
-				Where("is_submitted = ? AND is_approved = ?", true, false).
-				Group("user_branch, user_department, user_sub_department, period").
-				Order("max_update desc").
-				Limit(5).
-				Scan(&groups)
-
-			for _, g := range groups {
-				var semester, year string
-				parts := strings.Split(g.Period, "-")
-				if len(parts) == 2 {
-					semester = parts[0]
-					year = parts[1]
-				}
-
-				link := fmt.Sprintf("/maintenance/laptop?branch=%s&department=%s&sub_department=%s&year=%s&semester=%s",
-					g.UserBranch, g.UserDepartment, g.UserSubDepartment, year, semester)
-
-				pendingReports = append(pendingReports, map[string]interface{}{
-					"Branch":     g.UserBranch,
-					"Department": g.UserDepartment,
-					"Period":     g.Period,
-					"Link":       link,
-					"Time":       g.MaxUpdate.Format("02 Jan 15:04"),
-				})
+			var semester, year string
+			parts := strings.Split(doc.Period, "-")
+			if len(parts) == 2 {
+				semester = parts[0]
+				year = parts[1]
 			}
-			if len(pendingReports) > 0 {
-				approvalLink = pendingReports[0]["Link"].(string)
+
+			page := "/maintenance/laptop"
+			if doc.Category == "Komputer" {
+				page = "/maintenance/komputer"
 			}
+			link := fmt.Sprintf("%s?branch=%s&department=%s&sub_department=%s&year=%s&semester=%s",
+				page, url.QueryEscape(doc.Branch), url.QueryEscape(doc.Department), url.QueryEscape(doc.SubDepartment), year, semester)
+
+			pendingReports = append(pendingReports, map[string]interface{}{
+				"Branch":     doc.Branch,
+				"Department": doc.Department,
+				"Period":     doc.Period,
+				"Link":       link,
+				"Time":       doc.UpdatedAt.Format("02 Jan 15:04"),
+			})
+		}
+		if len(pendingReports) > 0 {
+			approvalLink = pendingReports[0]["Link"].(string)
+		}
+	}
+
+	// Peminjaman aset yang menunggu persetujuan atau terlambat dikembalikan
+	var loans []models.AssetLoan
+	if isLoggedIn && perms["loan.approve"] {
+		p.Scope().applyLoans(server.DB.Preload("Borrower")).
+			Where("status = ? OR (status = ? AND end_date < ?)", models.LoanStatusPending, models.LoanStatusOnLoan, today()).
+			Order("end_date asc").Limit(5).Find(&loans)
+	}
+	// Pengingat bagi peminjam: peminjaman karyawan yang terhubung dengan akun ini yang lewat tanggal kembali.
+	// Ini satu-satunya pengingat keterlambatan; aplikasi tidak mengirim email atau pesan ke peminjam.
+	if isLoggedIn && linkedUserID != "" {
+		var own []models.AssetLoan
+		server.DB.Preload("Borrower").
+			Where("borrower_id = ? AND status = ? AND end_date < ?", linkedUserID, models.LoanStatusOnLoan, today()).
+			Order("end_date asc").Limit(5).Find(&own)
+		for _, l := range own {
+			if !slices.ContainsFunc(loans, func(x models.AssetLoan) bool { return x.ID == l.ID }) {
+				loans = append(loans, l)
+			}
+		}
+	}
+	var loanAlerts []map[string]interface{}
+	for _, l := range loans {
+		label := "Menunggu persetujuan"
+		if l.IsOverdue() {
+			label = "Terlambat sejak " + l.EndDate.Format("02 Jan 2006")
+		}
+		loanAlerts = append(loanAlerts, map[string]interface{}{
+			"Borrower": l.Borrower.Name,
+			"Label":    label,
+			"Overdue":  l.IsOverdue(),
+			"Link":     "/goform/peminjaman/" + l.ID,
+		})
+	}
+
+	// Dokumen yang menunggu tanda tangan karyawan yang terhubung dengan akun admin ini
+	var signatureAlerts []map[string]interface{}
+	if isLoggedIn && linkedUserID != "" {
+		var signers []models.SignatureRequestSigner
+		server.DB.Joins("JOIN signature_requests ON signature_requests.id = signature_request_signers.request_id").
+			Where("signature_request_signers.user_id = ? AND signature_request_signers.signed_at IS NULL AND signature_request_signers.expires_at > ? AND signature_requests.status = ?", linkedUserID, time.Now(), models.SignatureStatusPending).
+			Order("signature_request_signers.created_at desc").Limit(5).Find(&signers)
+		for _, s := range signers {
+			var request models.SignatureRequest
+			server.DB.Select("title").Where("id = ?", s.RequestID).Limit(1).Find(&request)
+			signatureAlerts = append(signatureAlerts, map[string]interface{}{
+				"Title": request.Title,
+				"Label": s.Label,
+				"Link":  "/sign/" + s.Token,
+			})
 		}
 	}
 
 	return map[string]interface{}{
+		"NotificationsCount":    pendingCount + int64(len(loanAlerts)) + int64(len(signatureAlerts)),
+		"LoanAlerts":            loanAlerts,
+		"SignatureAlerts":       signatureAlerts,
 		"IsLoggedIn":            isLoggedIn,
 		"AdminUsername":         username,
 		"AdminName":             adminName,
@@ -188,25 +302,47 @@ func (server *Server) GetAdminData(r *http.Request) map[string]interface{} {
 		"PendingApprovalsCount": pendingCount,
 		"PendingReports":        pendingReports,
 		"ApprovalLink":          approvalLink,
+		"Impersonator":          impersonator,
+		"CSRFToken":             csrfToken(r),
 	}
 }
 
 // GetPermissions returns a map of resources allowed for the role
+// GetPermissions mengembalikan aksi yang diizinkan untuk role; super_admin selalu mendapat semua aksi.
+// Hasilnya diambil dari permissionCache dan dipakai bersama, sehingga tidak boleh diubah.
 func (server *Server) GetPermissions(role string) map[string]bool {
-	res := make(map[string]bool)
 	if role == "" {
-		return res
+		return map[string]bool{}
+	}
+	if perms, ok := server.permCache.get(role); ok {
+		return perms
 	}
 
-	var perms []models.RolePermission
-	server.DB.Where("role = ?", role).Find(&perms)
-
-	for _, p := range perms {
-		res[p.Resource] = p.CanAccess
+	res := make(map[string]bool)
+	if role == models.RoleSuperAdmin {
+		for _, key := range models.PermissionKeys() {
+			res[key] = true
+		}
+	} else {
+		var perms []models.RolePermission
+		server.DB.Where("role = ?", role).Find(&perms)
+		for _, p := range perms {
+			res[p.Resource] = p.CanAccess
+		}
 	}
+
+	server.permCache.set(role, res)
 	return res
 }
 
+// hasPermission memeriksa satu aksi untuk role
+func (server *Server) hasPermission(role, action string) bool {
+	if role == models.RoleSuperAdmin {
+		return true
+	}
+	return server.GetPermissions(role)[action]
+}
+
 // Profile menampilkan halaman profil admin yang sedang login
 func (server *Server) Profile(w http.ResponseWriter, r *http.Request) {
 	adminID, _, _, _ := GetCurrentAdmin(r)
@@ -215,13 +351,63 @@ func (server *Server) Profile(w http.ResponseWriter, r *http.Request) {
 	server.DB.First(&admin, "id = ?", adminID)
 
 	server.RenderHTML(w, r, http.StatusOK, "auth/profile", map[string]interface{}{
-		"title": "My Profile",
-		"admin": admin,
-		"error": r.URL.Query().Get("error"),
-		"msg":   r.URL.Query().Get("msg"),
+		"title":    "My Profile",
+		"admin":    admin,
+		"sessions": server.adminSessions(r, adminID),
+		"SSO":      server.SSO != nil,
+		"error":    r.URL.Query().Get("error"),
+		"msg":      r.URL.Query().Get("msg"),
 	})
 }
 
+// adminSessions menyiapkan daftar session login aktif milik admin untuk halaman profil
+func (server *Server) adminSessions(r *http.Request, adminID string) []map[string]interface{} {
+	var rows []models.AdminSession
+	server.DB.Where("admin_id = ? AND expires_at > ?", adminID, time.Now()).Order("last_seen_at desc").Find(&rows)
+
+	current := currentSessionKey(r)
+	var list []map[string]interface{}
+	for _, row := range rows {
+		if store.IdleTimeout > 0 && time.Since(row.LastSeenAt) > store.IdleTimeout {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"ID":        row.ID,
+			"Device":    describeUserAgent(row.UserAgent),
+			"UserAgent": row.UserAgent,
+			"IP":        row.IP,
+			"LastSeen":  row.LastSeenAt.Format("02 Jan 2006 15:04"),
+			"LoginAt":   row.CreatedAt.Format("02 Jan 2006 15:04"),
+			"Current":   row.ID == current,
+		})
+	}
+	return list
+}
+
+// RevokeSession mengakhiri satu session login milik admin yang sedang login (mis. perangkat yang hilang)
+func (server *Server) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	id := mux.Vars(r)["id"]
+	if id == currentSessionKey(r) {
+		server.Logout(w, r)
+		return
+	}
+
+	result := server.DB.Where("id = ? AND admin_id = ?", id, adminID).Delete(&models.AdminSession{})
+	if result.RowsAffected == 0 {
+		http.Redirect(w, r, "/profile?error="+url.QueryEscape("Session tidak ditemukan"), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/profile?msg="+url.QueryEscape("Perangkat berhasil dikeluarkan"), http.StatusSeeOther)
+}
+
+// LogoutEverywhere mengakhiri semua session login admin di seluruh perangkat, termasuk perangkat ini
+func (server *Server) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	revokeAdminSessions(server.DB, adminID)
+	server.Logout(w, r)
+}
+
 // UpdatePassword menangani proses perubahan password admin
 func (server *Server) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	adminID, _, _, _ := GetCurrentAdmin(r)
@@ -238,6 +424,15 @@ func (server *Server) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	var admin models.Admin
 	server.DB.First(&admin, "id = ?", adminID)
 
+	if admin.AuthSource == authSourceLDAP {
+		http.Redirect(w, r, "/profile?error="+url.QueryEscape("Password akun ini dikelola oleh direktori perusahaan (LDAP)"), http.StatusSeeOther)
+		return
+	}
+	if admin.AuthSource == authSourceOIDC {
+		http.Redirect(w, r, "/profile?error="+url.QueryEscape("Akun ini masuk melalui SSO dan tidak memakai password gokso"), http.StatusSeeOther)
+		return
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(oldPassword)); err != nil {
 		http.Redirect(w, r, "/profile?error=Password lama salah", http.StatusSeeOther)
 		return
@@ -247,6 +442,12 @@ func (server *Server) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	admin.Password = string(hashedPassword)
 	server.DB.Save(&admin)
 
+	// Semua session lama dicabut; perangkat ini mendapat session baru agar tetap login
+	revokeAdminSessions(server.DB, adminID)
+	session, _ := store.Get(r, sessionCookieName)
+	store.Rotate(session)
+	session.Save(r, w)
+
 	http.Redirect(w, r, "/profile?msg=Password berhasil diperbarui", http.StatusSeeOther)
 }
 
@@ -7,25 +7,39 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/AbsoluteZero24/gokso/internal/config"
 	"github.com/AbsoluteZero24/gokso/internal/database"
 	"github.com/AbsoluteZero24/gokso/internal/database/seeders"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/securecookie"
 	"github.com/unrolled/render"
 	"github.com/urfave/cli"
 	"gorm.io/gorm"
 )
 
 type Server struct {
-	DB       *gorm.DB
-	Router   *mux.Router
-	Renderer *render.Render
+	DB        *gorm.DB
+	Router    *mux.Router
+	Renderer  *render.Render
+	AppURL    string
+	AppName   string     // dipakai sebagai issuer aplikasi authenticator 2FA
+	PDFSigner *pdfSigner // nil jika sertifikat tanda tangan digital PDF tidak dikonfigurasi
+	Lockout   loginLockout
+	Directory directory   // nil jika login LDAP tidak dikonfigurasi
+	SSO       *oidcClient // nil jika login SSO (OIDC) tidak dikonfigurasi
+
+	permCache permissionCache // izin per role, dikosongkan saat pengaturan role diubah
 }
 
 // Initialize mengatur koneksi database, sistem render template, dan inisialisasi rute
 func (server *Server) Initialize(appConfig config.AppConfig, dbConfig config.DBConfig) {
 	fmt.Println("Welcome to " + appConfig.AppName)
+	server.AppURL = strings.TrimRight(appConfig.AppURL, "/")
+	server.AppName = appConfig.AppName
+	server.initPDFSigner(appConfig)
 
 	var err error
 	server.DB, err = database.Initialize(dbConfig)
@@ -33,8 +47,20 @@ func (server *Server) Initialize(appConfig config.AppConfig, dbConfig config.DBC
 		log.Fatal(err)
 	}
 
-	server.Renderer = render.New(render.Options{
-		Layout: "layout",
+	server.Renderer = newRenderer("templates")
+
+	server.initSessionStore(appConfig)
+	server.initLoginLockout(appConfig)
+	server.initDirectory(appConfig)
+	server.initSSO(appConfig)
+	server.initializeRoutes()
+}
+
+// newRenderer menyiapkan render template HTML dari folder directory
+func newRenderer(directory string) *render.Render {
+	return render.New(render.Options{
+		Directory: directory,
+		Layout:    "layout",
 		Funcs: []template.FuncMap{
 			{
 				"add": func(a, b int) int {
@@ -43,8 +69,68 @@ func (server *Server) Initialize(appConfig config.AppConfig, dbConfig config.DBC
 			},
 		},
 	})
+}
 
-	server.initializeRoutes()
+// initSessionStore menyiapkan penyimpanan session login di database.
+// Di production SESSION_SECRET wajib diisi; di lingkungan lain dipakai secret acak sehingga session hilang saat restart.
+func (server *Server) initSessionStore(appConfig config.AppConfig) {
+	secret := []byte(appConfig.SessionSecret)
+	if len(secret) < 32 {
+		if appConfig.AppEnv == "production" {
+			log.Fatal("SESSION_SECRET wajib diisi minimal 32 karakter di production")
+		}
+		log.Printf("Warning: SESSION_SECRET kosong atau terlalu pendek, memakai secret sementara")
+		secret = securecookie.GenerateRandomKey(32)
+	}
+
+	idle, err := time.ParseDuration(appConfig.SessionIdleTimeout)
+	if err != nil {
+		log.Printf("Warning: SESSION_IDLE_TIMEOUT %q tidak valid, memakai 2h", appConfig.SessionIdleTimeout)
+		idle = 2 * time.Hour
+	}
+
+	store = newDBSessionStore(server.DB, secret, idle, strings.HasPrefix(server.AppURL, "https://"))
+}
+
+// initLoginLockout membaca batas login gagal dan lama penguncian akun dari konfigurasi
+func (server *Server) initLoginLockout(appConfig config.AppConfig) {
+	server.Lockout = defaultLoginLockout
+
+	if n, err := strconv.Atoi(appConfig.LoginMaxFailures); err == nil && n > 0 {
+		server.Lockout.MaxFailures = n
+	} else {
+		log.Printf("Warning: LOGIN_MAX_FAILURES %q tidak valid, memakai %d", appConfig.LoginMaxFailures, defaultLoginLockout.MaxFailures)
+	}
+	if d, err := time.ParseDuration(appConfig.LoginLockout); err == nil && d > 0 {
+		server.Lockout.Duration = d
+	} else {
+		log.Printf("Warning: LOGIN_LOCKOUT %q tidak valid, memakai %s", appConfig.LoginLockout, defaultLoginLockout.Duration)
+	}
+}
+
+// initPDFSigner memuat sertifikat organisasi untuk tanda tangan digital PDF.
+// Di production sertifikat wajib valid; di lingkungan lain PDF tetap dibuat tanpa tanda tangan digital.
+func (server *Server) initPDFSigner(appConfig config.AppConfig) {
+	server.PDFSigner = nil
+	if appConfig.PDFSignCert == "" || appConfig.PDFSignKey == "" {
+		if appConfig.AppEnv == "production" {
+			log.Fatal("PDF_SIGN_CERT dan PDF_SIGN_KEY wajib diisi di production")
+		}
+		log.Printf("Warning: PDF_SIGN_CERT/PDF_SIGN_KEY kosong, PDF tidak ditandatangani secara digital")
+		return
+	}
+
+	signer, err := loadPDFSigner(appConfig.PDFSignCert, appConfig.PDFSignKey)
+	if err != nil {
+		if appConfig.AppEnv == "production" {
+			log.Fatal(err)
+		}
+		log.Printf("Warning: sertifikat tanda tangan PDF tidak dapat dimuat (%v); jalankan perintah pdf:dev-cert untuk membuat sertifikat pengembangan", err)
+		return
+	}
+	signer.Reason = "Dokumen resmi " + appConfig.AppName
+	signer.Location = appConfig.PDFSignLocation
+	server.PDFSigner = signer
 }
 
 // Run menjalankan server HTTP pada alamat (address) yang ditentukan
@@ -55,6 +141,10 @@ func (server *Server) Run(addr string) {
 
 // InitCommands mendefinisikan dan menjalankan perintah CLI seperti migrasi dan seeding database
 func (server *Server) InitCommands(appConfig config.AppConfig, dbConfig config.DBConfig) {
+	server.AppURL = strings.TrimRight(appConfig.AppURL, "/")
+	server.AppName = appConfig.AppName
+	server.initPDFSigner(appConfig)
+
 	var err error
 	server.DB, err = database.Initialize(dbConfig)
 	if err != nil {
@@ -67,6 +157,10 @@ func (server *Server) InitCommands(appConfig config.AppConfig, dbConfig config.D
 			Name: "db:migrate",
 			Action: func(c *cli.Context) error {
 				database.Migrate(server.DB)
+				// Role bawaan dan izin per aksi harus ada agar admin selain super_admin tetap bisa mengakses modulnya
+				if err := seeders.SeedPermissions(server.DB); err != nil {
+					log.Fatal(err)
+				}
 				return nil
 			},
 		},
@@ -130,6 +224,43 @@ func (server *Server) InitCommands(appConfig config.AppConfig, dbConfig config.D
 				return nil
 			},
 		},
+		{
+			Name: "db:seed_goform",
+			Action: func(c *cli.Context) error {
+				err := seeders.SeedGoForms(server.DB)
+				if err != nil {
+					log.Fatal(err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "ldap:sync",
+			Usage: "Nonaktifkan admin LDAP yang sudah dihapus, dinonaktifkan atau dikeluarkan dari grup di direktori",
+			Action: func(c *cli.Context) error {
+				server.initDirectory(appConfig)
+				checked, disabled, err := server.SyncDirectoryAdmins()
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Printf("%d admin LDAP diperiksa, %d nonaktif\n", checked, disabled)
+				return nil
+			},
+		},
+		{
+			Name: "pdf:dev-cert",
+			Action: func(c *cli.Context) error {
+				certPath, keyPath := appConfig.PDFSignCert, appConfig.PDFSignKey
+				if certPath == "" || keyPath == "" {
+					certPath, keyPath = "certs/dev-signing.crt", "certs/dev-signing.key"
+				}
+				if err := GenerateDevSigningCert(certPath, keyPath, appConfig.AppName); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Printf("Sertifikat pengembangan dibuat: %s dan %s\n", certPath, keyPath)
+				return nil
+			},
+		},
 	}
 
 	err = cmdApp.Run(os.Args)
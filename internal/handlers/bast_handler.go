@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// bindBAST membaca isian BAST serah terima (bast, bast-laptop) beserta pihak dan aset yang diserahkan
+func (server *Server) bindBAST(values url.Values) (BASTData, []string) {
+	data := BASTData{
+		Notes:     values.Get("notes"),
+		SigP1Data: values.Get("sig_p1_data"),
+		SigP2Data: values.Get("sig_p2_data"),
+	}
+	var errs []string
+
+	date, err := time.Parse("2006-01-02", values.Get("handover_date"))
+	if err != nil {
+		date = time.Now()
+	}
+	data.HandoverDate = date
+
+	preloadUserOrg(server.DB, "").Where("id = ?", values.Get("p1_employee_id")).Limit(1).Find(&data.P1)
+	preloadUserOrg(server.DB, "").Where("id = ?", values.Get("p2_employee_id")).Limit(1).Find(&data.P2)
+	if data.P1.ID == "" {
+		errs = append(errs, "Pihak pertama wajib dipilih")
+	}
+	if data.P2.ID == "" {
+		errs = append(errs, "Pihak kedua wajib dipilih")
+	}
+
+	assetIDs := values["selected_asset_ids[]"]
+	if len(assetIDs) == 0 {
+		errs = append(errs, "Pilih minimal satu aset yang diserahkan")
+	} else {
+		server.DB.Where("id IN ?", assetIDs).Find(&data.Items)
+	}
+
+	return data, errs
+}
+
+// bastAllowed memeriksa bahwa kedua pihak dan setiap aset pada isian BAST berada di dalam cakupan data admin
+func (server *Server) bastAllowed(scope dataScope, values url.Values) bool {
+	if !scope.restricted {
+		return true
+	}
+	for _, id := range []string{values.Get("p1_employee_id"), values.Get("p2_employee_id")} {
+		if id != "" && !server.userAllowed(scope, id) {
+			return false
+		}
+	}
+	var assets []models.AssetKSO
+	server.DB.Preload("User").Where("id IN ?", values["selected_asset_ids[]"]).Find(&assets)
+	for _, asset := range assets {
+		if !scope.allowsAsset(asset) {
+			return false
+		}
+	}
+	return true
+}
+
+// bastTemplate mengambil template aktif BAST; judul yang diatur pada GoForm tetap diutamakan
+func (server *Server) bastTemplate(form models.GoForm) models.DocumentTemplate {
+	tmpl := documentTemplateFor(server.DB, form.Handler)
+	if form.PDFTitle != "" {
+		tmpl.Title = form.PDFTitle
+	}
+	return tmpl
+}
+
+// prepareBASTDocument menyiapkan dokumen BAST (serah terima, laptop/komputer, pengembalian) dari isian formulir.
+// Isian yang sama dipakai ulang saat permintaan tanda tangan jarak jauh selesai ditandatangani.
+func (server *Server) prepareBASTDocument(form models.GoForm, values url.Values) (goFormDocument, []string) {
+	// Dapatkan atau Buat Folder "Digital Reports" di eDoc
+	folder := server.ensureFolder("Laporan Digital", nil, "#3b82f6") // Blue
+
+	if form.Handler == "bast-return" {
+		data, errs := server.bindBASTReturn(values)
+		if len(errs) > 0 {
+			return goFormDocument{}, errs
+		}
+
+		tmpl := server.bastTemplate(form)
+		pdfTitle := tmpl.Title
+
+		var itemNames []string
+		for _, item := range data.Items {
+			condition := data.Conditions[item.ID]
+			if note := data.ItemNotes[item.ID]; note != "" {
+				condition += ": " + note
+			}
+			itemNames = append(itemNames, fmt.Sprintf("%s - %s (%s)", item.InventoryNumber, item.AssetName, condition))
+		}
+
+		// Nomor BAST pengembalian mengikuti cabang karyawan yang mengembalikan
+		return goFormDocument{
+			DocType:   form.Handler,
+			DocBranch: data.P1.Branch.Name,
+			DocDate:   data.HandoverDate,
+			Title:     pdfTitle,
+			IssuedTo:  data.P1.Name,
+			Folder:    server.ensureFolder("BAST Pengembalian", &folder.ID, "#0ea5e9"),
+			FileName:  fmt.Sprintf("BAST_Kembali_%s_%s.pdf", data.P1.Name, time.Now().Format("20060102_150405")),
+			Message:   "BAST pengembalian berhasil dibuat dan aset telah dilepas dari karyawan.",
+			Answers: []models.GoFormAnswer{
+				{Field: "p1_employee_id", Label: "Pihak Pertama", Value: data.P1.ID, Display: data.P1.Name},
+				{Field: "p2_employee_id", Label: "Pihak Kedua", Value: data.P2.ID, Display: data.P2.Name},
+				{Field: "handover_date", Label: "Tanggal Pengembalian", Value: data.HandoverDate.Format("2006-01-02"), Display: translateMonth(data.HandoverDate.Format("02 January 2006"))},
+				{Field: "selected_asset_ids", Label: "Aset", Value: strings.Join(values["selected_asset_ids[]"], ","), Display: strings.Join(itemNames, ", ")},
+				{Field: "notes", Label: "Catatan", Value: data.Notes, Display: data.Notes},
+			},
+			Generate: func(stamp pdfStamp, outputPath string) error {
+				data.DocNumber = stamp.DocNumber
+				return server.GenerateBASTPDF(data, tmpl, stamp, outputPath)
+			},
+			// Aset dilepas dari karyawan; aset yang kembali dalam kondisi rusak ditandai Rusak
+			Apply: func(tx *gorm.DB) error {
+				for _, item := range data.Items {
+					updates := map[string]interface{}{"user_id": nil}
+					if data.Conditions[item.ID] == "Rusak" {
+						updates["status"] = "Rusak"
+					}
+					if err := tx.Model(&models.AssetKSO{}).Where("id = ? AND user_id = ?", item.ID, data.P1.ID).Updates(updates).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}, nil
+	}
+
+	data, errs := server.bindBAST(values)
+	if len(errs) > 0 {
+		return goFormDocument{}, errs
+	}
+	assetIDs := values["selected_asset_ids[]"]
+	p2ID := data.P2.ID
+
+	tmpl := server.bastTemplate(form)
+	pdfTitle := tmpl.Title
+	if form.Handler == "bast-laptop" {
+		// Create/Find Subfolder "BAST Laptop/Komputer"; the file is saved here
+		folder = server.ensureFolder("BAST Laptop/Komputer", &folder.ID, "#ec4899") // Pink matching the icon
+	}
+
+	var itemNames []string
+	for _, item := range data.Items {
+		itemNames = append(itemNames, item.InventoryNumber+" - "+item.AssetName)
+	}
+
+	prefix := "BAST"
+	if form.Handler == "bast-laptop" {
+		prefix = "BAST_IT"
+	}
+
+	// Nomor BAST mengikuti cabang pihak pertama (penyerah aset)
+	docBranch := data.P1.Branch.Name
+	if docBranch == "" {
+		docBranch = data.P2.Branch.Name
+	}
+
+	return goFormDocument{
+		DocType:   form.Handler,
+		DocBranch: docBranch,
+		DocDate:   data.HandoverDate,
+		Title:     pdfTitle,
+		IssuedTo:  data.P2.Name,
+		Folder:    folder,
+		FileName:  fmt.Sprintf("%s_%s_%s.pdf", prefix, data.P2.Name, time.Now().Format("20060102_150405")),
+		Message:   "Berita Acara Serah Terima (BAST) berhasil dibuat dan disimpan ke eDoc.",
+		Answers: []models.GoFormAnswer{
+			{Field: "p1_employee_id", Label: "Pihak Pertama", Value: data.P1.ID, Display: data.P1.Name},
+			{Field: "p2_employee_id", Label: "Pihak Kedua", Value: p2ID, Display: data.P2.Name},
+			{Field: "handover_date", Label: "Tanggal Serah Terima", Value: data.HandoverDate.Format("2006-01-02"), Display: translateMonth(data.HandoverDate.Format("02 January 2006"))},
+			{Field: "selected_asset_ids", Label: "Aset", Value: strings.Join(assetIDs, ","), Display: strings.Join(itemNames, ", ")},
+			{Field: "notes", Label: "Catatan", Value: data.Notes, Display: data.Notes},
+		},
+		Generate: func(stamp pdfStamp, outputPath string) error {
+			data.DocNumber = stamp.DocNumber
+			return server.GenerateBASTPDF(data, tmpl, stamp, outputPath)
+		},
+		// Auto-assign assets to recipient (Requirement: Update asset holder on BAST submit)
+		Apply: func(tx *gorm.DB) error {
+			return tx.Model(&models.AssetKSO{}).Where("id IN ?", assetIDs).Update("user_id", p2ID).Error
+		},
+	}, nil
+}
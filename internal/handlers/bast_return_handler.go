@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// returnConditions adalah pilihan kondisi aset saat diserahkan kembali oleh karyawan
+var returnConditions = []string{"Baik", "Lecet", "Rusak"}
+
+// bindBASTReturn membaca isian BAST pengembalian dan memastikan aset memang masih dipegang pihak pertama
+func (server *Server) bindBASTReturn(values url.Values) (BASTData, []string) {
+	data := BASTData{
+		Return:     true,
+		Conditions: map[string]string{},
+		ItemNotes:  map[string]string{},
+		Notes:      strings.TrimSpace(values.Get("notes")),
+		SigP1Data:  values.Get("sig_p1_data"),
+		SigP2Data:  values.Get("sig_p2_data"),
+	}
+	var errs []string
+
+	preloadUserOrg(server.DB, "").Where("id = ?", values.Get("p1_employee_id")).Limit(1).Find(&data.P1)
+	preloadUserOrg(server.DB, "").Where("id = ?", values.Get("p2_employee_id")).Limit(1).Find(&data.P2)
+	if data.P1.ID == "" {
+		errs = append(errs, "Karyawan yang mengembalikan wajib dipilih")
+	}
+	if data.P2.ID == "" {
+		errs = append(errs, "Penerima wajib dipilih")
+	}
+	if data.P1.ID != "" && data.P1.ID == data.P2.ID {
+		errs = append(errs, "Penerima tidak boleh sama dengan karyawan yang mengembalikan")
+	}
+
+	date, err := time.Parse("2006-01-02", values.Get("handover_date"))
+	if err != nil {
+		date = time.Now()
+	}
+	data.HandoverDate = date
+
+	assetIDs := values["selected_asset_ids[]"]
+	if len(assetIDs) == 0 {
+		errs = append(errs, "Pilih minimal satu aset yang dikembalikan")
+	} else if data.P1.ID != "" {
+		server.DB.Where("id IN ? AND user_id = ?", assetIDs, data.P1.ID).Order("inventory_number asc").Find(&data.Items)
+		if len(data.Items) != len(assetIDs) {
+			errs = append(errs, "Sebagian aset tidak sedang dipegang oleh karyawan tersebut")
+		}
+	}
+	for _, item := range data.Items {
+		condition := values.Get("condition_" + item.ID)
+		valid := false
+		for _, c := range returnConditions {
+			if c == condition {
+				valid = true
+			}
+		}
+		if !valid {
+			errs = append(errs, fmt.Sprintf("Kondisi %s wajib dipilih", item.InventoryNumber))
+		}
+		data.Conditions[item.ID] = condition
+		data.ItemNotes[item.ID] = strings.TrimSpace(values.Get("notes_" + item.ID))
+	}
+
+	return data, errs
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// csrfFieldName adalah nama field form (atau header X-CSRF-Token) yang membawa token CSRF
+const csrfFieldName = "csrf_token"
+
+// csrfToken mengembalikan token CSRF milik session login. Token diturunkan dari token session dengan
+// secret aplikasi sehingga tidak perlu disimpan, dan otomatis tidak berlaku lagi begitu session berakhir.
+// Kosong bila belum ada session.
+func csrfToken(r *http.Request) string {
+	if store == nil {
+		return ""
+	}
+	session, err := store.Get(r, sessionCookieName)
+	if err != nil || session.ID == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, store.secret)
+	mac.Write([]byte("csrf:" + session.ID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CSRFProtect adalah middleware untuk tindakan yang mengubah data (hapus, gabung) yang memastikan request
+// berasal dari form aplikasi ini: token pada field csrf_token atau header X-CSRF-Token harus sama dengan token session
+func (server *Server) CSRFProtect(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := csrfToken(r)
+		given := r.FormValue(csrfFieldName)
+		if given == "" {
+			given = r.Header.Get("X-CSRF-Token")
+		}
+		if expected == "" || !hmac.Equal([]byte(given), []byte(expected)) {
+			http.Error(w, "Forbidden: invalid or missing CSRF token, please reload the page and try again", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// orgNode adalah satu node organisasi dalam cakupan admin. DepartmentID/SubDepartmentID 0 berarti seluruh isi node induk.
+// Nama disimpan untuk label dan untuk data yang masih mencatat cabang sebagai teks (lokasi aset, surat jalan).
+type orgNode struct {
+	Level           string
+	ID              uint
+	BranchID        uint
+	DepartmentID    uint
+	SubDepartmentID uint
+	Branch          string
+	Department      string
+	SubDepartment   string
+}
+
+// Label mengembalikan nama lengkap node, misalnya "Jakarta / Keuangan"
+func (n orgNode) Label() string {
+	parts := []string{n.Branch}
+	if n.Department != "" {
+		parts = append(parts, n.Department)
+	}
+	if n.SubDepartment != "" {
+		parts = append(parts, n.SubDepartment)
+	}
+	return strings.Join(parts, " / ")
+}
+
+// Value mengembalikan nilai node untuk form pengaturan, misalnya "department:3"
+func (n orgNode) Value() string {
+	return fmt.Sprintf("%s:%d", n.Level, n.ID)
+}
+
+// dataScope adalah cakupan data organisasi yang boleh diakses admin.
+// Data karyawan, aset, maintenance dan peminjaman dicocokkan dengan ID cabang, bagian dan sub bagian di master data.
+type dataScope struct {
+	restricted bool
+	nodes      []orgNode
+}
+
+// dataScope mengembalikan cakupan data admin yang sedang login
+func (server *Server) dataScope(r *http.Request) dataScope {
+	p := server.currentPrincipal(r)
+	if p == nil {
+		return dataScope{restricted: true}
+	}
+	return p.Scope()
+}
+
+// adminDataScope memuat cakupan data seorang admin. Node yang sudah dihapus dari master data tidak lagi memberi akses.
+func (server *Server) adminDataScope(adminID, role string) dataScope {
+	if role == models.RoleSuperAdmin {
+		return dataScope{}
+	}
+
+	var scopes []models.AdminScope
+	server.DB.Where("admin_id = ?", adminID).Order("id asc").Find(&scopes)
+	if len(scopes) == 0 {
+		return dataScope{}
+	}
+
+	scope := dataScope{restricted: true}
+	for _, s := range scopes {
+		if node, ok := server.resolveOrgNode(s.Level, s.NodeID); ok {
+			scope.nodes = append(scope.nodes, node)
+		}
+	}
+	return scope
+}
+
+// resolveOrgNode melengkapi node master data dengan ID dan nama cabang, bagian dan sub bagian induknya
+func (server *Server) resolveOrgNode(level string, id uint) (orgNode, bool) {
+	node := orgNode{Level: level, ID: id}
+	switch level {
+	case models.ScopeLevelBranch:
+		var branch models.MasterBranch
+		server.DB.Where("id = ?", id).Limit(1).Find(&branch)
+		if branch.ID == 0 {
+			return node, false
+		}
+		node.BranchID, node.Branch = branch.ID, branch.Name
+	case models.ScopeLevelDepartment:
+		var dept models.MasterDepartment
+		server.DB.Preload("MasterBranch").Where("id = ?", id).Limit(1).Find(&dept)
+		if dept.ID == 0 || dept.MasterBranch.ID == 0 {
+			return node, false
+		}
+		node.BranchID, node.Branch = dept.MasterBranch.ID, dept.MasterBranch.Name
+		node.DepartmentID, node.Department = dept.ID, dept.Name
+	case models.ScopeLevelSubDepartment:
+		var sub models.MasterSubDepartment
+		server.DB.Preload("MasterDepartment.MasterBranch").Where("id = ?", id).Limit(1).Find(&sub)
+		if sub.ID == 0 || sub.MasterDepartment.ID == 0 || sub.MasterDepartment.MasterBranch.ID == 0 {
+			return node, false
+		}
+		node.BranchID, node.Branch = sub.MasterDepartment.MasterBranch.ID, sub.MasterDepartment.MasterBranch.Name
+		node.DepartmentID, node.Department = sub.MasterDepartment.ID, sub.MasterDepartment.Name
+		node.SubDepartmentID, node.SubDepartment = sub.ID, sub.Name
+	default:
+		return node, false
+	}
+	return node, true
+}
+
+// parseOrgNodeValue membaca nilai form "level:id" menjadi AdminScope
+func parseOrgNodeValue(value string) (models.AdminScope, bool) {
+	level, rawID, found := strings.Cut(value, ":")
+	if !found {
+		return models.AdminScope{}, false
+	}
+	if level != models.ScopeLevelBranch && level != models.ScopeLevelDepartment && level != models.ScopeLevelSubDepartment {
+		return models.AdminScope{}, false
+	}
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil || id == 0 {
+		return models.AdminScope{}, false
+	}
+	return models.AdminScope{Level: level, NodeID: uint(id)}, true
+}
+
+// orgNodes mengembalikan seluruh node organisasi (cabang, bagian, sub bagian) untuk pilihan cakupan admin
+func (server *Server) orgNodes() []orgNode {
+	var branches []models.MasterBranch
+	server.DB.Preload("Departments.SubDepartments").Order("name asc").Find(&branches)
+
+	var nodes []orgNode
+	for _, b := range branches {
+		nodes = append(nodes, orgNode{Level: models.ScopeLevelBranch, ID: b.ID, BranchID: b.ID, Branch: b.Name})
+		for _, d := range b.Departments {
+			nodes = append(nodes, orgNode{Level: models.ScopeLevelDepartment, ID: d.ID, BranchID: b.ID, DepartmentID: d.ID, Branch: b.Name, Department: d.Name})
+			for _, s := range d.SubDepartments {
+				nodes = append(nodes, orgNode{Level: models.ScopeLevelSubDepartment, ID: s.ID, BranchID: b.ID, DepartmentID: d.ID, SubDepartmentID: s.ID,
+					Branch: b.Name, Department: d.Name, SubDepartment: s.Name})
+			}
+		}
+	}
+	return nodes
+}
+
+// Restricted bernilai true bila admin hanya boleh mengakses sebagian data
+func (s dataScope) Restricted() bool {
+	return s.restricted
+}
+
+// Nodes mengembalikan node organisasi dalam cakupan
+func (s dataScope) Nodes() []orgNode {
+	return s.nodes
+}
+
+// allows memeriksa apakah area (ID cabang, bagian, sub bagian) seluruhnya berada di dalam cakupan.
+// Nilai 0 berarti seluruh isi tingkat di atasnya, sehingga (cabang Jakarta, 0, 0) hanya diizinkan untuk cakupan cabang Jakarta.
+func (s dataScope) allows(branchID, deptID, subDeptID uint) bool {
+	if !s.restricted {
+		return true
+	}
+	for _, n := range s.nodes {
+		if n.BranchID != branchID {
+			continue
+		}
+		if n.DepartmentID != 0 && n.DepartmentID != deptID {
+			continue
+		}
+		if n.SubDepartmentID != 0 && n.SubDepartmentID != subDeptID {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// covers memeriksa apakah cakupan other seluruhnya berada di dalam cakupan s. Cakupan tanpa batasan hanya
+// tercakup oleh cakupan tanpa batasan.
+func (s dataScope) covers(other dataScope) bool {
+	if !s.restricted {
+		return true
+	}
+	if !other.restricted {
+		return false
+	}
+	for _, n := range other.nodes {
+		if !s.allows(n.BranchID, n.DepartmentID, n.SubDepartmentID) {
+			return false
+		}
+	}
+	return true
+}
+
+// scopedOrgNodes membatasi pilihan node cakupan admin pada node di dalam cakupan
+func (s dataScope) scopedOrgNodes(nodes []orgNode) []orgNode {
+	if !s.restricted {
+		return nodes
+	}
+	var result []orgNode
+	for _, n := range nodes {
+		if s.allows(n.BranchID, n.DepartmentID, n.SubDepartmentID) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// allowsUser memeriksa apakah karyawan berada di dalam cakupan
+func (s dataScope) allowsUser(user models.User) bool {
+	return s.allows(uintValue(user.BranchID), uintValue(user.DepartmentID), uintValue(user.SubDepartmentID))
+}
+
+// allowsAsset memeriksa apakah aset berada di dalam cakupan: aset yang dipegang karyawan mengikuti karyawannya,
+// aset yang belum diserahkan mengikuti lokasi (nama cabang) dan hanya terlihat oleh cakupan tingkat cabang.
+// Relasi User harus sudah dimuat.
+func (s dataScope) allowsAsset(asset models.AssetKSO) bool {
+	if !s.restricted {
+		return true
+	}
+	if asset.UserID != nil && *asset.UserID != "" {
+		return asset.User.ID != "" && s.allowsUser(asset.User)
+	}
+	return slices.Contains(s.branchNames(), asset.Location)
+}
+
+// branchNames mengembalikan nama cabang yang seluruhnya berada di dalam cakupan
+func (s dataScope) branchNames() []string {
+	var names []string
+	for _, n := range s.nodes {
+		if n.Department == "" && !slices.Contains(names, n.Branch) {
+			names = append(names, n.Branch)
+		}
+	}
+	return names
+}
+
+// nodeBranches mengembalikan nama cabang dari setiap node dalam cakupan
+func (s dataScope) nodeBranches() []string {
+	var names []string
+	for _, n := range s.nodes {
+		if !slices.Contains(names, n.Branch) {
+			names = append(names, n.Branch)
+		}
+	}
+	return names
+}
+
+// condition menyusun klausa SQL yang mencocokkan kolom ID cabang, bagian dan sub bagian dengan node dalam cakupan
+func (s dataScope) condition(branchCol, deptCol, subCol string) (string, []interface{}) {
+	if len(s.nodes) == 0 {
+		return "1 = 0", nil
+	}
+
+	var parts []string
+	var args []interface{}
+	for _, n := range s.nodes {
+		switch {
+		case n.SubDepartmentID != 0:
+			parts = append(parts, fmt.Sprintf("(%s = ? AND %s = ? AND %s = ?)", branchCol, deptCol, subCol))
+			args = append(args, n.BranchID, n.DepartmentID, n.SubDepartmentID)
+		case n.DepartmentID != 0:
+			parts = append(parts, fmt.Sprintf("(%s = ? AND %s = ?)", branchCol, deptCol))
+			args = append(args, n.BranchID, n.DepartmentID)
+		default:
+			parts = append(parts, fmt.Sprintf("%s = ?", branchCol))
+			args = append(args, n.BranchID)
+		}
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args
+}
+
+// apply membatasi query dengan kolom ID cabang, bagian dan sub bagian milik tabelnya sendiri
+func (s dataScope) apply(db *gorm.DB, branchCol, deptCol, subCol string) *gorm.DB {
+	if !s.restricted {
+		return db
+	}
+	cond, args := s.condition(branchCol, deptCol, subCol)
+	return db.Where(cond, args...)
+}
+
+// applyUsers membatasi query karyawan (tabel users)
+func (s dataScope) applyUsers(db *gorm.DB) *gorm.DB {
+	return s.apply(db, "users.branch_id", "users.department_id", "users.sub_department_id")
+}
+
+// userSubquery menyusun subquery ID karyawan dalam cakupan untuk kolom relasi ke users
+func (s dataScope) userSubquery(column string) (string, []interface{}) {
+	cond, args := s.condition("users.branch_id", "users.department_id", "users.sub_department_id")
+	return column + " IN (SELECT users.id FROM users WHERE " + cond + ")", args
+}
+
+// applyAssets membatasi query aset (tabel asset_kso) sesuai aturan allowsAsset
+func (s dataScope) applyAssets(db *gorm.DB) *gorm.DB {
+	if !s.restricted {
+		return db
+	}
+	query, args := s.userSubquery("asset_kso.user_id")
+	if branches := s.branchNames(); len(branches) > 0 {
+		query += " OR ((asset_kso.user_id IS NULL OR asset_kso.user_id = '') AND asset_kso.location IN ?)"
+		args = append(args, branches)
+	}
+	return db.Where("("+query+")", args...)
+}
+
+// applyLoans membatasi query peminjaman (tabel asset_loans) menurut karyawan peminjam
+func (s dataScope) applyLoans(db *gorm.DB) *gorm.DB {
+	if !s.restricted {
+		return db
+	}
+	query, args := s.userSubquery("asset_loans.borrower_id")
+	return db.Where(query, args...)
+}
+
+// applyTransfers membatasi query surat jalan (tabel asset_transfers) menurut cabang asal atau tujuan
+func (s dataScope) applyTransfers(db *gorm.DB) *gorm.DB {
+	if !s.restricted {
+		return db
+	}
+	branches := s.nodeBranches()
+	if len(branches) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where("(asset_transfers.origin_branch IN ? OR asset_transfers.destination_branch IN ?)", branches, branches)
+}
+
+// allowsTransfer memeriksa apakah cabang asal atau tujuan surat jalan berada di dalam cakupan
+func (s dataScope) allowsTransfer(transfer models.AssetTransfer) bool {
+	if !s.restricted {
+		return true
+	}
+	branches := s.nodeBranches()
+	return slices.Contains(branches, transfer.OriginBranch) || slices.Contains(branches, transfer.DestinationBranch)
+}
+
+// scopedBranches membatasi pilihan cabang/bagian/sub bagian pada filter halaman agar sesuai cakupan
+func (s dataScope) scopedBranches(branches []models.MasterBranch) []models.MasterBranch {
+	if !s.restricted {
+		return branches
+	}
+	var result []models.MasterBranch
+	for _, b := range branches {
+		var depts []models.MasterDepartment
+		for _, d := range b.Departments {
+			var subs []models.MasterSubDepartment
+			for _, sd := range d.SubDepartments {
+				if s.allows(b.ID, d.ID, sd.ID) {
+					subs = append(subs, sd)
+				}
+			}
+			if len(subs) > 0 || s.allows(b.ID, d.ID, 0) {
+				d.SubDepartments = subs
+				depts = append(depts, d)
+			}
+		}
+		if len(depts) > 0 || s.allows(b.ID, 0, 0) {
+			b.Departments = depts
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// forbidScope menolak akses langsung ke data di luar cakupan admin
+func forbidScope(w http.ResponseWriter) {
+	http.Error(w, "Access Denied: This record is outside your data scope", http.StatusForbidden)
+}
+
+// assetAllowed memeriksa cakupan aset dan memuat karyawan pemegangnya bila belum dimuat
+func (server *Server) assetAllowed(scope dataScope, asset models.AssetKSO) bool {
+	if !scope.restricted {
+		return true
+	}
+	if asset.UserID != nil && *asset.UserID != "" && asset.User.ID != *asset.UserID {
+		asset.User = models.User{}
+		server.DB.Where("id = ?", *asset.UserID).Limit(1).Find(&asset.User)
+	}
+	return scope.allowsAsset(asset)
+}
+
+// userAllowed memeriksa apakah karyawan dengan ID tersebut ada dan berada di dalam cakupan
+func (server *Server) userAllowed(scope dataScope, userID string) bool {
+	if !scope.restricted {
+		return true
+	}
+	var user models.User
+	server.DB.Where("id = ?", userID).Limit(1).Find(&user)
+	return user.ID != "" && scope.allowsUser(user)
+}
+
+// assetIDAllowed memeriksa cakupan aset berdasarkan ID; aset yang tidak ditemukan dianggap diizinkan agar penanganannya tetap seperti semula
+func (server *Server) assetIDAllowed(r *http.Request, id string) bool {
+	var asset models.AssetKSO
+	server.DB.Unscoped().Where("id = ?", id).Limit(1).Find(&asset)
+	return asset.ID == "" || server.assetAllowed(server.dataScope(r), asset)
+}
+
+// formScopes membaca node cakupan yang dipilih pada form; node yang tidak valid atau sudah dihapus diabaikan
+func (server *Server) formScopes(values []string) ([]models.AdminScope, dataScope) {
+	var scopes []models.AdminScope
+	var nodes []orgNode
+	for _, value := range values {
+		scope, ok := parseOrgNodeValue(value)
+		if !ok {
+			continue
+		}
+		node, exists := server.resolveOrgNode(scope.Level, scope.NodeID)
+		if !exists {
+			continue
+		}
+		scopes = append(scopes, scope)
+		nodes = append(nodes, node)
+	}
+	return scopes, dataScope{restricted: len(nodes) > 0, nodes: nodes}
+}
+
+// grantableScope memeriksa bahwa cakupan yang dipilih pada form tidak lebih luas dari cakupan admin yang mengatur;
+// pilihan kosong berarti tanpa batasan sehingga hanya boleh diberikan oleh admin tanpa batasan
+func (server *Server) grantableScope(r *http.Request, values []string) bool {
+	_, requested := server.formScopes(values)
+	return server.dataScope(r).covers(requested)
+}
+
+// saveAdminScopes mengganti cakupan data admin dengan node yang dipilih pada form; pilihan kosong berarti tanpa batasan
+func (server *Server) saveAdminScopes(adminID string, values []string) error {
+	scopes, _ := server.formScopes(values)
+	for i := range scopes {
+		scopes[i].AdminID = adminID
+	}
+
+	return server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("admin_id = ?", adminID).Delete(&models.AdminScope{}).Error; err != nil {
+			return err
+		}
+		if len(scopes) == 0 {
+			return nil
+		}
+		return tx.Create(&scopes).Error
+	})
+}
+
+// adminScopeValues mengembalikan nilai node cakupan admin untuk menandai pilihan pada form
+func (server *Server) adminScopeValues(adminID string) map[string]bool {
+	var scopes []models.AdminScope
+	server.DB.Where("admin_id = ?", adminID).Find(&scopes)
+	values := make(map[string]bool)
+	for _, s := range scopes {
+		values[orgNode{Level: s.Level, ID: s.NodeID}.Value()] = true
+	}
+	return values
+}
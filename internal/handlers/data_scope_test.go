@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// scopeFixture menyiapkan cabang Jakarta (ID 1) dan Bandung (ID 2), satu karyawan dan satu aset
+// yang dipegang karyawan di masing-masing cabang
+func scopeFixture(tb testing.TB, server *Server) {
+	tb.Helper()
+	for i, name := range []string{"Jakarta", "Bandung"} {
+		branchID := uint(i + 1)
+		server.DB.Create(&models.MasterBranch{ID: branchID, Name: name})
+		userID := fmt.Sprintf("u-%d", branchID)
+		server.DB.Create(&models.User{ID: userID, NIK: fmt.Sprintf("200%d", branchID), Name: "Karyawan " + name, Email: userID + "@example.com", Password: "x", BranchID: &branchID})
+		server.DB.Create(&models.AssetKSO{ID: fmt.Sprintf("as-%d", branchID), InventoryNumber: fmt.Sprintf("INV-%d", branchID), AssetName: "Laptop", Category: "Laptop", Location: name, UserID: &userID, Status: "Ready"})
+	}
+}
+
+// scopedAdmin membuat admin dengan role tertentu yang dibatasi pada cabang branchIDs
+func scopedAdmin(tb testing.TB, server *Server, id, role string, branchIDs ...uint) models.Admin {
+	tb.Helper()
+	admin := models.Admin{ID: id, Username: id, Password: "x", Role: role}
+	server.DB.Create(&admin)
+	for _, branchID := range branchIDs {
+		server.DB.Create(&models.AdminScope{AdminID: id, Level: models.ScopeLevelBranch, NodeID: branchID})
+	}
+	return admin
+}
+
+// adminScopeNodes mengembalikan nilai node cakupan admin yang tersimpan
+func adminScopeNodes(server *Server, adminID string) []string {
+	var values []string
+	for value := range server.adminScopeValues(adminID) {
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestStoreSettingUserRejectsWiderScope(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	createRole(t, server, "user_admin", "dashboard.view", "setting.user")
+	client := loginAs(t, server, scopedAdmin(t, server, "m1", "user_admin", 1))
+
+	for name, scope := range map[string][]string{"unrestricted": nil, "other branch": {"branch:2"}} {
+		form := url.Values{"username": {"new-" + name}, "password": {"secret"}, "role": {"user_admin"}, "scope": scope}
+		w := client.do(http.MethodPost, "/setting/user/store", form)
+		if msg := redirectError(t, w.Header().Get("Location")); msg != errScopeBeyondActor {
+			t.Errorf("%s: error = %q, want %q", name, msg, errScopeBeyondActor)
+		}
+	}
+	var count int64
+	server.DB.Model(&models.Admin{}).Where("username LIKE ?", "new-%").Count(&count)
+	if count != 0 {
+		t.Errorf("%d admins created with a wider scope", count)
+	}
+
+	w := client.do(http.MethodPost, "/setting/user/store", url.Values{"username": {"jkt"}, "password": {"secret"}, "role": {"user_admin"}, "scope": {"branch:1"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != "" {
+		t.Fatalf("scope within own branch rejected: %q", msg)
+	}
+}
+
+func TestUpdateSettingUserRejectsWiderScope(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	createRole(t, server, "user_admin", "dashboard.view", "setting.user")
+	client := loginAs(t, server, scopedAdmin(t, server, "m1", "user_admin", 1))
+	scopedAdmin(t, server, "jkt", "user_admin", 1)
+	scopedAdmin(t, server, "all", "user_admin")
+
+	// Cakupan baru tidak boleh melebihi cakupan pengatur
+	w := client.do(http.MethodPost, "/setting/user/update/jkt", url.Values{"username": {"jkt"}, "role": {"user_admin"}, "scope": {"branch:1", "branch:2"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != errScopeBeyondActor {
+		t.Errorf("error = %q, want %q", msg, errScopeBeyondActor)
+	}
+	if values := adminScopeNodes(server, "jkt"); len(values) != 1 || values[0] != "branch:1" {
+		t.Errorf("scope = %v, want [branch:1]", values)
+	}
+
+	// Admin tanpa batasan tidak dapat diubah (misalnya reset password) oleh admin yang dibatasi
+	w = client.do(http.MethodPost, "/setting/user/update/all", url.Values{"username": {"all"}, "role": {"user_admin"}, "password": {"taken-over"}, "scope": {"branch:1"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != errScopeBeyondActor {
+		t.Errorf("error = %q, want %q", msg, errScopeBeyondActor)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "all")
+	if admin.Password != "x" {
+		t.Error("password of an unrestricted admin was reset")
+	}
+}
+
+func TestSignatureRequestsOutsideScope(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	client := loginAs(t, server, scopedAdmin(t, server, "m1", "asset_manager", 1))
+	for i, branchID := range []int{1, 2} {
+		values := url.Values{"p1_employee_id": {fmt.Sprintf("u-%d", branchID)}, "p2_employee_id": {fmt.Sprintf("u-%d", branchID)}, "selected_asset_ids[]": {fmt.Sprintf("as-%d", branchID)}}
+		server.DB.Create(&models.SignatureRequest{ID: fmt.Sprintf("sr-%d", branchID), Title: fmt.Sprintf("BAST %d", i), FormData: values.Encode(), Status: models.SignatureStatusPending})
+	}
+
+	w := client.do(http.MethodGet, "/goform/signature", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list = %d, want %d", w.Code, http.StatusOK)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "/goform/signature/sr-1") || strings.Contains(body, "/goform/signature/sr-2") {
+		t.Error("list does not match the data scope")
+	}
+
+	if w := client.do(http.MethodGet, "/goform/signature/sr-2", nil); w.Code != http.StatusForbidden {
+		t.Errorf("show = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	for _, action := range []string{"remind", "retry", "cancel"} {
+		if w := client.do(http.MethodPost, "/goform/signature/"+action+"/sr-2", url.Values{}); w.Code != http.StatusForbidden {
+			t.Errorf("%s = %d, want %d", action, w.Code, http.StatusForbidden)
+		}
+	}
+	var request models.SignatureRequest
+	server.DB.First(&request, "id = ?", "sr-2")
+	if request.Status != models.SignatureStatusPending {
+		t.Errorf("status = %q, want %q", request.Status, models.SignatureStatusPending)
+	}
+}
+
+func TestSubmitAssetLoanOutsideScope(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	server.DB.Create(&models.GoForm{ID: "f-loan", Slug: "form-peminjaman", Name: "Peminjaman", Handler: "loan", IsActive: true})
+	client := loginAs(t, server, scopedAdmin(t, server, "m1", "asset_manager", 1))
+
+	for name, form := range map[string]url.Values{
+		"borrower": {"borrower_id": {"u-2"}, "asset_ids[]": {"as-1"}},
+		"asset":    {"borrower_id": {"u-1"}, "asset_ids[]": {"as-2"}},
+	} {
+		form.Set("start_date", "2026-01-05")
+		form.Set("end_date", "2026-01-06")
+		form.Set("purpose", "Presentasi")
+		if w := client.do(http.MethodPost, "/goform/submit/form-peminjaman", form); w.Code != http.StatusForbidden {
+			t.Errorf("%s outside scope = %d, want %d", name, w.Code, http.StatusForbidden)
+		}
+	}
+	var count int64
+	server.DB.Model(&models.AssetLoan{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d loans created outside the data scope", count)
+	}
+}
+
+func TestDataScopeAllowsAndCovers(t *testing.T) {
+	jakarta := orgNode{Level: models.ScopeLevelBranch, ID: 1, BranchID: 1, Branch: "Jakarta"}
+	keuangan := orgNode{Level: models.ScopeLevelDepartment, ID: 10, BranchID: 2, DepartmentID: 10, Branch: "Bandung", Department: "Keuangan"}
+	pajak := orgNode{Level: models.ScopeLevelSubDepartment, ID: 20, BranchID: 2, DepartmentID: 10, SubDepartmentID: 20, Branch: "Bandung", Department: "Keuangan", SubDepartment: "Pajak"}
+	scope := dataScope{restricted: true, nodes: []orgNode{jakarta, keuangan}}
+
+	for _, tc := range []struct {
+		branch, dept, sub uint
+		want              bool
+	}{
+		{1, 0, 0, true},
+		{1, 5, 7, true},
+		{2, 0, 0, false},
+		{2, 10, 0, true},
+		{2, 10, 20, true},
+		{2, 11, 0, false},
+		{0, 0, 0, false},
+	} {
+		if got := scope.allows(tc.branch, tc.dept, tc.sub); got != tc.want {
+			t.Errorf("allows(%d, %d, %d) = %v, want %v", tc.branch, tc.dept, tc.sub, got, tc.want)
+		}
+	}
+	if !(dataScope{}).allows(0, 0, 0) {
+		t.Error("unrestricted scope denies data without a branch")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		outer dataScope
+		inner dataScope
+		want  bool
+	}{
+		{"unrestricted covers all", dataScope{}, scope, true},
+		{"restricted never covers unrestricted", scope, dataScope{}, false},
+		{"department covers its sub department", scope, dataScope{restricted: true, nodes: []orgNode{pajak}}, true},
+		{"sub department does not cover its department", dataScope{restricted: true, nodes: []orgNode{pajak}}, dataScope{restricted: true, nodes: []orgNode{keuangan}}, false},
+		{"one node outside", dataScope{restricted: true, nodes: []orgNode{keuangan}}, scope, false},
+		{"empty restricted scope", scope, dataScope{restricted: true}, true},
+	} {
+		if got := tc.outer.covers(tc.inner); got != tc.want {
+			t.Errorf("%s: covers = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if names := scope.branchNames(); len(names) != 1 || names[0] != "Jakarta" {
+		t.Errorf("branchNames = %v, want only branches covered entirely", names)
+	}
+}
+
+func TestDataScopeQueriesMatchChecks(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	server.DB.Create(&models.MasterDepartment{ID: 10, MasterBranchID: 2, Name: "Keuangan"})
+	server.DB.Create(&models.MasterDepartment{ID: 11, MasterBranchID: 2, Name: "IT"})
+	server.DB.Create(&models.MasterSubDepartment{ID: 20, MasterDepartmentID: 10, Name: "Pajak"})
+	server.DB.Create(&models.MasterSubDepartment{ID: 21, MasterDepartmentID: 10, Name: "Gaji"})
+	for id, org := range map[string][3]uint{"u-keu": {2, 10, 0}, "u-pajak": {2, 10, 20}, "u-gaji": {2, 10, 21}, "u-it": {2, 11, 0}} {
+		branch, dept, sub := org[0], org[1], org[2]
+		user := models.User{ID: id, NIK: id, Name: id, Email: id + "@example.com", Password: "x", BranchID: &branch, DepartmentID: &dept}
+		if sub != 0 {
+			user.SubDepartmentID = &sub
+		}
+		server.DB.Create(&user)
+		userID := id
+		server.DB.Create(&models.AssetKSO{ID: "as-" + id, InventoryNumber: "INV-" + id, AssetName: "Laptop", Category: "Laptop", Location: "Bandung", UserID: &userID, Status: "Ready"})
+	}
+	// Aset yang belum diserahkan mengikuti lokasi cabangnya
+	server.DB.Create(&models.AssetKSO{ID: "as-gudang-jkt", InventoryNumber: "INV-G1", AssetName: "Laptop", Category: "Laptop", Location: "Jakarta", Status: "Ready"})
+	server.DB.Create(&models.AssetKSO{ID: "as-gudang-bdg", InventoryNumber: "INV-G2", AssetName: "Laptop", Category: "Laptop", Location: "Bandung", Status: "Ready"})
+
+	var users []models.User
+	var assets []models.AssetKSO
+	server.DB.Find(&users)
+	server.DB.Preload("User").Find(&assets)
+
+	for name, nodes := range map[string][]string{
+		"branch":                  {"branch:1"},
+		"department":              {"department:10"},
+		"sub department":          {"sub_department:20"},
+		"branch and sub":          {"branch:1", "sub_department:21"},
+		"other branch department": {"branch:2", "department:11"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			adminID := strings.ReplaceAll(name, " ", "-")
+			scopedAdmin(t, server, adminID, "asset_manager")
+			if err := server.saveAdminScopes(adminID, nodes); err != nil {
+				t.Fatal(err)
+			}
+			scope := server.adminDataScope(adminID, "asset_manager")
+			if len(scope.Nodes()) != len(nodes) {
+				t.Fatalf("scope nodes = %v, want %v", scope.Nodes(), nodes)
+			}
+
+			var want, got []string
+			for _, u := range users {
+				if scope.allowsUser(u) {
+					want = append(want, u.ID)
+				}
+			}
+			scope.applyUsers(server.DB.Model(&models.User{})).Order("id").Pluck("id", &got)
+			if strings.Join(got, ",") != strings.Join(sortedCopy(want), ",") {
+				t.Errorf("applyUsers = %v, allowsUser = %v", got, want)
+			}
+
+			want, got = nil, nil
+			for _, a := range assets {
+				if scope.allowsAsset(a) {
+					want = append(want, a.ID)
+				}
+			}
+			scope.applyAssets(server.DB.Model(&models.AssetKSO{})).Order("id").Pluck("id", &got)
+			if strings.Join(got, ",") != strings.Join(sortedCopy(want), ",") {
+				t.Errorf("applyAssets = %v, allowsAsset = %v", got, want)
+			}
+		})
+	}
+}
+
+// sortedCopy mengembalikan salinan slice yang sudah diurutkan
+func sortedCopy(values []string) []string {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted
+}
+
+func TestAdminDataScopeDeletedNodeGrantsNothing(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	scopedAdmin(t, server, "m1", "asset_manager", 2)
+	server.DB.Delete(&models.MasterBranch{}, 2)
+
+	// Node yang dihapus tidak membuat admin menjadi tanpa batasan
+	scope := server.adminDataScope("m1", "asset_manager")
+	if !scope.Restricted() || len(scope.Nodes()) != 0 {
+		t.Fatalf("scope = %+v, want restricted without nodes", scope)
+	}
+	var count int64
+	scope.applyUsers(server.DB.Model(&models.User{})).Count(&count)
+	if count != 0 {
+		t.Errorf("%d employees visible through a deleted branch", count)
+	}
+
+	// super_admin dan admin tanpa baris cakupan tidak dibatasi
+	scopedAdmin(t, server, "m2", "asset_manager")
+	for _, scope := range []dataScope{server.adminDataScope("m2", "asset_manager"), server.adminDataScope("m1", models.RoleSuperAdmin)} {
+		if scope.Restricted() {
+			t.Error("scope restricted without scope rows or for super_admin")
+		}
+	}
+}
+
+func TestListsFollowDataScope(t *testing.T) {
+	server := newTestServer(t)
+	scopeFixture(t, server)
+	createRole(t, server, "hr_jkt", "dashboard.view", "employee.view", "asset.view", "asset.update")
+	client := loginAs(t, server, scopedAdmin(t, server, "m1", "hr_jkt", 1))
+
+	body := client.do(http.MethodGet, "/administration/employee", nil).Body.String()
+	if !strings.Contains(body, "Karyawan Jakarta") || strings.Contains(body, "Karyawan Bandung") {
+		t.Error("employee list does not match the data scope")
+	}
+	body = client.do(http.MethodGet, "/inventori/aset-laptop?year=", nil).Body.String()
+	if !strings.Contains(body, "INV-1") || strings.Contains(body, "INV-2") {
+		t.Error("asset list does not match the data scope")
+	}
+	if w := client.do(http.MethodGet, "/inventori/aset-laptop/edit/as-2", nil); w.Code != http.StatusForbidden {
+		t.Errorf("edit asset outside scope = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := client.do(http.MethodGet, "/inventori/aset-laptop/edit/as-1", nil); w.Code != http.StatusOK {
+		t.Errorf("edit asset inside scope = %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// docNumberType adalah jenis dokumen yang memiliki penomoran surat resmi
+type docNumberType struct {
+	Code    string
+	Label   string
+	Pattern string // pola bawaan bila belum diatur di Setting
+}
+
+// docNumberTypes adalah daftar jenis dokumen beserta pola bawaannya
+var docNumberTypes = []docNumberType{
+	{Code: "bast", Label: "BA Serah Terima Aset", Pattern: "{seq}/BAST/{branch}/{roman_month}/{year}"},
+	{Code: "bast-laptop", Label: "BA Serah Terima Laptop/Komputer", Pattern: "{seq}/BAST-IT/{branch}/{roman_month}/{year}"},
+	{Code: "bast-return", Label: "BA Serah Terima Kembali Aset", Pattern: "{seq}/BAST-RET/{branch}/{roman_month}/{year}"},
+	{Code: "loan", Label: "Surat Peminjaman Aset", Pattern: "{seq}/PINJAM/{branch}/{roman_month}/{year}"},
+	{Code: "transfer", Label: "Surat Jalan Barang", Pattern: "{seq}/SJ/{branch}/{roman_month}/{year}"},
+	{Code: "clearance", Label: "Surat Keterangan Bebas Aset", Pattern: "{seq}/SKBA/{branch}/{roman_month}/{year}"},
+	{Code: "form", Label: "Formulir GoForm Lainnya", Pattern: "{seq}/FORM/{roman_month}/{year}"},
+}
+
+// docNumberTokens adalah placeholder yang boleh dipakai pada pola penomoran
+var docNumberTokens = []string{"{seq}", "{branch}", "{month}", "{roman_month}", "{year}", "{yy}"}
+
+var docNumberTokenPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+var romanMonths = []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X", "XI", "XII"}
+
+// docNumberFormat mengambil pola penomoran jenis dokumen, memakai pola bawaan bila belum diatur
+func docNumberFormat(db *gorm.DB, docType string) models.DocumentNumberFormat {
+	var format models.DocumentNumberFormat
+	db.Where("doc_type = ?", docType).Limit(1).Find(&format)
+	if format.DocType != "" {
+		return format
+	}
+
+	format = models.DocumentNumberFormat{DocType: docType, Pattern: "{seq}/" + strings.ToUpper(docType) + "/{roman_month}/{year}", Padding: 3}
+	for _, t := range docNumberTypes {
+		if t.Code == docType {
+			format.Pattern = t.Pattern
+		}
+	}
+	return format
+}
+
+// validateDocNumberPattern memastikan pola memuat nomor urut dan tahun, serta hanya memakai placeholder yang dikenal
+func validateDocNumberPattern(pattern string) error {
+	if !strings.Contains(pattern, "{seq}") {
+		return fmt.Errorf("pola wajib memuat {seq}")
+	}
+	// Nomor urut direset setiap tahun, jadi tahun wajib ada agar nomor tidak berulang
+	if !strings.Contains(pattern, "{year}") && !strings.Contains(pattern, "{yy}") {
+		return fmt.Errorf("pola wajib memuat {year} atau {yy}")
+	}
+	for _, token := range docNumberTokenPattern.FindAllString(pattern, -1) {
+		known := false
+		for _, t := range docNumberTokens {
+			if token == t {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("placeholder %s tidak dikenal", token)
+		}
+	}
+	return nil
+}
+
+// formatDocNumber menyusun nomor surat dari pola, nomor urut, cabang dan tanggal dokumen
+func formatDocNumber(format models.DocumentNumberFormat, seq int, branch string, date time.Time) string {
+	branch = strings.ToUpper(strings.Join(strings.Fields(branch), "-"))
+	if branch == "" {
+		branch = "PUSAT"
+	}
+	padding := format.Padding
+	if padding < 1 {
+		padding = 3
+	}
+
+	return strings.NewReplacer(
+		"{seq}", fmt.Sprintf("%0*d", padding, seq),
+		"{branch}", branch,
+		"{month}", fmt.Sprintf("%02d", int(date.Month())),
+		"{roman_month}", romanMonths[date.Month()-1],
+		"{year}", fmt.Sprintf("%d", date.Year()),
+		"{yy}", fmt.Sprintf("%02d", date.Year()%100),
+	).Replace(format.Pattern)
+}
+
+// nextDocNumber mengalokasikan nomor surat berikutnya untuk jenis dokumen pada tahun tanggal dokumen.
+// Harus dipanggil di dalam transaksi yang juga menyimpan dokumennya: baris urutan terkunci sampai commit
+// sehingga permintaan bersamaan antre, dan rollback ikut membatalkan nomor sehingga tidak ada nomor yang loncat.
+func nextDocNumber(tx *gorm.DB, docType, branch string, date time.Time) (string, error) {
+	var seq int
+	err := tx.Raw(`INSERT INTO document_sequences (doc_type, year, last_number, updated_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (doc_type, year) DO UPDATE SET last_number = document_sequences.last_number + 1, updated_at = EXCLUDED.updated_at
+		RETURNING last_number`, docType, date.Year(), time.Now()).Scan(&seq).Error
+	if err != nil {
+		return "", err
+	}
+	if seq == 0 {
+		return "", fmt.Errorf("gagal mengalokasikan nomor %s", docType)
+	}
+
+	return formatDocNumber(docNumberFormat(tx, docType), seq, branch, date), nil
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// allocate mengalokasikan satu nomor surat di transaksi tersendiri
+func allocate(tb testing.TB, db *gorm.DB, docType, branch string, date time.Time) string {
+	tb.Helper()
+	var number string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		number, err = nextDocNumber(tx, docType, branch, date)
+		return err
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return number
+}
+
+func TestNextDocNumberSequencePerTypeAndYear(t *testing.T) {
+	server := newTestServer(t)
+	march := time.Date(2026, time.March, 10, 0, 0, 0, 0, time.Local)
+
+	for _, tc := range []struct {
+		docType, branch string
+		date            time.Time
+		want            string
+	}{
+		{"loan", "Jakarta", march, "001/PINJAM/JAKARTA/III/2026"},
+		{"loan", "Bandung Barat", march, "002/PINJAM/BANDUNG-BARAT/III/2026"},
+		{"transfer", "", march, "001/SJ/PUSAT/III/2026"},
+		{"loan", "Jakarta", march.AddDate(0, 9, 0), "003/PINJAM/JAKARTA/XII/2026"},
+		{"loan", "Jakarta", march.AddDate(1, 0, 0), "001/PINJAM/JAKARTA/III/2027"},
+	} {
+		if got := allocate(t, server.DB, tc.docType, tc.branch, tc.date); got != tc.want {
+			t.Errorf("nextDocNumber(%s, %s, %s) = %q, want %q", tc.docType, tc.branch, tc.date.Format("2006-01"), got, tc.want)
+		}
+	}
+
+	// Pola yang diatur di Setting berlaku untuk alokasi berikutnya tanpa mereset nomor urut
+	server.DB.Create(&models.DocumentNumberFormat{DocType: "loan", Pattern: "PJ-{yy}{month}-{seq}", Padding: 5})
+	if got := allocate(t, server.DB, "loan", "Jakarta", march); got != "PJ-2603-00004" {
+		t.Errorf("custom pattern = %q, want PJ-2603-00004", got)
+	}
+}
+
+func TestNextDocNumberRollbackReleasesNumber(t *testing.T) {
+	server := newTestServer(t)
+	date := time.Date(2026, time.May, 2, 0, 0, 0, 0, time.Local)
+	allocate(t, server.DB, "clearance", "Jakarta", date)
+
+	// Dokumen yang gagal disimpan membatalkan nomornya sehingga tidak ada nomor yang loncat
+	errSave := errors.New("gagal menyimpan dokumen")
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := nextDocNumber(tx, "clearance", "Jakarta", date); err != nil {
+			return err
+		}
+		return errSave
+	})
+	if !errors.Is(err, errSave) {
+		t.Fatalf("transaction error = %v", err)
+	}
+	if got := allocate(t, server.DB, "clearance", "Jakarta", date); got != "002/SKBA/JAKARTA/V/2026" {
+		t.Errorf("number after rollback = %q, want 002/SKBA/JAKARTA/V/2026", got)
+	}
+}
+
+func TestNextDocNumberConcurrentAllocationsAreUnique(t *testing.T) {
+	// Koneksi terpisah menunggu kunci tulis alih-alih langsung gagal, seperti baris terkunci di PostgreSQL
+	db, err := gorm.Open(sqlite.Open(t.TempDir()+"/seq.db?_pragma=busy_timeout(10000)"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.DocumentSequence{}, &models.DocumentNumberFormat{}); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.Local)
+
+	const workers = 20
+	numbers := make([]string, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				numbers[i], err = nextDocNumber(tx, "bast", "Jakarta", date)
+				return err
+			})
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(numbers)
+	if got := slices.Compact(slices.Clone(numbers)); len(got) != workers {
+		t.Fatalf("allocated %d unique numbers out of %d: %v", len(got), workers, numbers)
+	}
+	if numbers[0] != "001/BAST/JAKARTA/VII/2026" || numbers[workers-1] != "020/BAST/JAKARTA/VII/2026" {
+		t.Errorf("numbers range from %q to %q, want 001 to 020 without gaps", numbers[0], numbers[workers-1])
+	}
+}
+
+func TestValidateDocNumberPattern(t *testing.T) {
+	for pattern, valid := range map[string]bool{
+		"{seq}/BAST/{branch}/{roman_month}/{year}": true,
+		"{seq}-{yy}":        true,
+		"BAST/{year}":       false,
+		"{seq}/BAST":        false,
+		"{seq}/{tahun}":     false,
+		"{seq}/{dept}/{yy}": false,
+	} {
+		if err := validateDocNumberPattern(pattern); (err == nil) != valid {
+			t.Errorf("validateDocNumberPattern(%q) = %v, want valid %v", pattern, err, valid)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// templateLogo adalah satu logo kop surat beserta posisinya (mm)
+type templateLogo struct {
+	Path  string
+	X     float64
+	Y     float64
+	Width float64
+}
+
+// templateColumn adalah satu kolom tabel daftar barang
+type templateColumn struct {
+	Header string
+	Width  float64
+	Field  string
+	Align  string
+}
+
+// documentTemplateTypes adalah jenis dokumen yang tata letaknya diatur lewat template
+var documentTemplateTypes = []string{"bast", "bast-laptop", "bast-return"}
+
+// documentTemplateTokens adalah placeholder yang boleh dipakai pada teks template
+var documentTemplateTokens = []string{
+	"{nomor}", "{hari}", "{tanggal}", "{kota}", "{cabang}",
+	"{pihak_pertama}", "{pihak_pertama_bagian}", "{pihak_pertama_jabatan}",
+	"{pihak_kedua}", "{pihak_kedua_bagian}", "{pihak_kedua_jabatan}",
+	"{jumlah_barang}", "{catatan}",
+}
+
+// documentTemplateFields adalah isian yang boleh dipakai pada kolom tabel daftar barang
+var documentTemplateFields = []string{
+	"no", "nama_barang", "nama_aset", "no_inventaris", "serial_number", "kategori", "label", "jumlah", "keterangan", "kondisi", "catatan_item",
+}
+
+// defaultHeaderLogos adalah kop surat bawaan (Danantara, IDSurvey, KSOSCISI)
+var defaultHeaderLogos = []templateLogo{
+	{Path: "public/assets/img/logo-danantara.png", X: 20, Y: 15, Width: 38},
+	{Path: "public/assets/img/logo-idsurvey.png", X: 85, Y: 15, Width: 40},
+	{Path: "public/assets/img/logo-ksoscisi.png", X: 145, Y: 15, Width: 45},
+}
+
+const defaultTemplateColumns = "NO|10|no|C\nNAMA BARANG|90|nama_barang|L\nJumlah|20|jumlah|C\nKETERANGAN|50|keterangan|L"
+
+// defaultDocumentTemplates adalah template bawaan yang dipakai selama belum ada versi di database
+var defaultDocumentTemplates = map[string]models.DocumentTemplate{
+	"bast": {
+		Title:          "BERITA ACARA SERAH TERIMA",
+		Body:           "PIHAK PERTAMA telah menyerahkan barang kepada PIHAK KEDUA, dan PIHAK KEDUA menyatakan telah menerima barang dari PIHAK PERTAMA berupa daftar terlampir.",
+		Closing:        "Demikian berita acara serah terima barang ini kami buat oleh kedua belah pihak, adapun barang-barang tersebut dalam keadaan baik dan cukup. Maka barang tersebut menjadi tanggung jawab PIHAK KEDUA, memelihara/ merawat dengan baik serta dipergunakan sebagaimana mestinya.",
+		SignatureLeft:  "Yang Menerima\nPIHAK KEDUA,",
+		SignatureRight: "Yang Menyerahkan\nPIHAK PERTAMA,",
+	},
+	"bast-laptop": {
+		Title:          "BERITA ACARA SERAH TERIMA\nLAPTOP/KOMPUTER",
+		Body:           "PIHAK PERTAMA telah menyerahkan barang kepada PIHAK KEDUA, dan PIHAK KEDUA menyatakan telah menerima barang dari PIHAK PERTAMA berupa daftar terlampir.",
+		Closing:        "Demikian berita acara serah terima barang ini kami buat oleh kedua belah pihak, adapun barang-barang tersebut dalam keadaan baik dan cukup. Maka barang tersebut menjadi tanggung jawab PIHAK KEDUA, memelihara/ merawat dengan baik serta dipergunakan sebagaimana mestinya.",
+		SignatureLeft:  "Yang Menerima\nPIHAK KEDUA,",
+		SignatureRight: "Yang Menyerahkan\nPIHAK PERTAMA,",
+	},
+	"bast-return": {
+		Title:          "BERITA ACARA SERAH TERIMA KEMBALI",
+		Body:           "PIHAK PERTAMA telah mengembalikan barang yang sebelumnya dipegang kepada PIHAK KEDUA, dan PIHAK KEDUA menyatakan telah menerima kembali barang tersebut dengan kondisi sebagaimana daftar terlampir.",
+		Closing:        "Demikian berita acara serah terima kembali barang ini kami buat oleh kedua belah pihak. Dengan diterimanya barang-barang tersebut, tanggung jawab PIHAK PERTAMA atas barang tersebut dinyatakan selesai dan selanjutnya berada pada PIHAK KEDUA.",
+		SignatureLeft:  "Yang Menerima\nPIHAK KEDUA,",
+		SignatureRight: "Yang Menyerahkan\nPIHAK PERTAMA,",
+	},
+}
+
+// defaultDocumentTemplate melengkapi template bawaan suatu jenis dokumen dengan bagian yang sama untuk semua BAST
+func defaultDocumentTemplate(docType string) models.DocumentTemplate {
+	tmpl := defaultDocumentTemplates[docType]
+	tmpl.DocType = docType
+	tmpl.City = "Jakarta"
+	tmpl.Logos = formatTemplateLogos(defaultHeaderLogos)
+	tmpl.Opening = "Pada hari ini {hari}, tanggal {tanggal}, Kami yang bertanda tangan dibawah ini:"
+	tmpl.FirstParty = "Selanjutnya disebut sebagai \"PIHAK PERTAMA\""
+	tmpl.SecondParty = "Selanjutnya disebut sebagai \"PIHAK KEDUA\""
+	tmpl.Columns = defaultTemplateColumns
+	tmpl.SignaturePlace = "{kota}, {tanggal}"
+	tmpl.LeftParty = "p2"
+	tmpl.RightParty = "p1"
+	return tmpl
+}
+
+// documentTemplateFor mengambil versi template yang aktif, atau template bawaan bila belum pernah diubah
+func documentTemplateFor(db *gorm.DB, docType string) models.DocumentTemplate {
+	var tmpl models.DocumentTemplate
+	db.Where("doc_type = ? AND is_active = ?", docType, true).Order("version desc").Limit(1).Find(&tmpl)
+	if tmpl.ID == 0 {
+		return defaultDocumentTemplate(docType)
+	}
+	return tmpl
+}
+
+// parseTemplateLogos membaca daftar logo "path|x|y|lebar", satu per baris
+func parseTemplateLogos(text string) ([]templateLogo, error) {
+	var logos []templateLogo
+	for i, line := range templateLines(text) {
+		parts := strings.Split(line, "|")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("logo baris %d harus berformat path|x|y|lebar", i+1)
+		}
+		logo := templateLogo{Path: strings.TrimSpace(parts[0])}
+		var nums [3]float64
+		for j, p := range parts[1:] {
+			n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("logo baris %d: posisi dan lebar harus angka positif", i+1)
+			}
+			nums[j] = n
+		}
+		logo.X, logo.Y, logo.Width = nums[0], nums[1], nums[2]
+
+		// Logo hanya boleh diambil dari folder public agar template tidak bisa membaca file lain di server
+		clean := filepath.ToSlash(filepath.Clean(logo.Path))
+		if !strings.HasPrefix(clean, "public/") || strings.Contains(clean, "..") {
+			return nil, fmt.Errorf("logo baris %d harus berada di folder public/", i+1)
+		}
+		logo.Path = clean
+		logos = append(logos, logo)
+	}
+	return logos, nil
+}
+
+// formatTemplateLogos menuliskan daftar logo kembali ke format teks template
+func formatTemplateLogos(logos []templateLogo) string {
+	var lines []string
+	for _, logo := range logos {
+		lines = append(lines, fmt.Sprintf("%s|%g|%g|%g", logo.Path, logo.X, logo.Y, logo.Width))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTemplateColumns membaca definisi tabel "judul|lebar|isian|perataan", satu kolom per baris
+func parseTemplateColumns(text string) ([]templateColumn, error) {
+	var columns []templateColumn
+	var total float64
+	for i, line := range templateLines(text) {
+		parts := strings.Split(line, "|")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("kolom baris %d harus berformat judul|lebar|isian|perataan", i+1)
+		}
+		width, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("kolom baris %d: lebar harus angka positif", i+1)
+		}
+		col := templateColumn{Header: strings.TrimSpace(parts[0]), Width: width, Field: strings.TrimSpace(parts[2]), Align: "L"}
+		if !slices.Contains(documentTemplateFields, col.Field) {
+			return nil, fmt.Errorf("kolom baris %d: isian %q tidak dikenal", i+1, col.Field)
+		}
+		if len(parts) == 4 {
+			col.Align = strings.ToUpper(strings.TrimSpace(parts[3]))
+			if col.Align != "L" && col.Align != "C" && col.Align != "R" {
+				return nil, fmt.Errorf("kolom baris %d: perataan harus L, C atau R", i+1)
+			}
+		}
+		total += width
+		columns = append(columns, col)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("tabel minimal memiliki satu kolom")
+	}
+	// Lebar area isi A4 dengan margin 20 mm kiri-kanan
+	if total > 170 {
+		return nil, fmt.Errorf("total lebar kolom %g mm melebihi 170 mm", total)
+	}
+	return columns, nil
+}
+
+// validateDocumentTemplate memastikan template dapat dirender sebelum disimpan sebagai versi baru
+func validateDocumentTemplate(tmpl models.DocumentTemplate) error {
+	if !slices.Contains(documentTemplateTypes, tmpl.DocType) {
+		return fmt.Errorf("jenis dokumen tidak dikenal")
+	}
+	if strings.TrimSpace(tmpl.Title) == "" {
+		return fmt.Errorf("judul dokumen wajib diisi")
+	}
+	logos, err := parseTemplateLogos(tmpl.Logos)
+	if err != nil {
+		return err
+	}
+	for _, logo := range logos {
+		if _, err := os.Stat(logo.Path); err != nil {
+			return fmt.Errorf("file logo %s tidak ditemukan", logo.Path)
+		}
+	}
+	if _, err := parseTemplateColumns(tmpl.Columns); err != nil {
+		return err
+	}
+
+	parties := []string{tmpl.LeftParty, tmpl.RightParty}
+	if !slices.Contains(parties, "p1") || !slices.Contains(parties, "p2") {
+		return fmt.Errorf("blok tanda tangan kiri dan kanan harus diisi PIHAK PERTAMA dan PIHAK KEDUA")
+	}
+
+	if docNumberTokenPattern.MatchString(tmpl.Title) {
+		return fmt.Errorf("judul dokumen tidak boleh memuat placeholder")
+	}
+	for _, text := range []string{tmpl.City, tmpl.Opening, tmpl.FirstParty, tmpl.SecondParty, tmpl.Body, tmpl.Closing, tmpl.SignaturePlace, tmpl.SignatureLeft, tmpl.SignatureRight} {
+		for _, token := range docNumberTokenPattern.FindAllString(text, -1) {
+			if !slices.Contains(documentTemplateTokens, token) {
+				return fmt.Errorf("placeholder %s tidak dikenal", token)
+			}
+		}
+	}
+	return nil
+}
+
+// bastTemplateVars menyiapkan nilai placeholder template untuk satu dokumen BAST
+func bastTemplateVars(data BASTData, city string) map[string]string {
+	branch := data.P1.Branch.Name
+	if branch == "" {
+		branch = data.P2.Branch.Name
+	}
+	return map[string]string{
+		"{nomor}":                 data.DocNumber,
+		"{hari}":                  getIndonesianDay(data.HandoverDate),
+		"{tanggal}":               translateMonth(data.HandoverDate.Format("02 January 2006")),
+		"{kota}":                  city,
+		"{cabang}":                branch,
+		"{pihak_pertama}":         data.P1.Name,
+		"{pihak_pertama_bagian}":  data.P1.Department.Name,
+		"{pihak_pertama_jabatan}": data.P1.Position.Name,
+		"{pihak_kedua}":           data.P2.Name,
+		"{pihak_kedua_bagian}":    data.P2.Department.Name,
+		"{pihak_kedua_jabatan}":   data.P2.Position.Name,
+		"{jumlah_barang}":         strconv.Itoa(len(data.Items)),
+		"{catatan}":               data.Notes,
+	}
+}
+
+// fillTemplate mengganti placeholder pada teks template dengan nilai dokumen
+func fillTemplate(text string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for token, value := range vars {
+		pairs = append(pairs, token, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// bastColumnValue mengisi satu sel tabel daftar barang BAST
+func bastColumnValue(data BASTData, index int, item models.AssetKSO, field string) string {
+	switch field {
+	case "no":
+		return strconv.Itoa(index + 1)
+	case "nama_barang":
+		return fmt.Sprintf("%s - %s", item.AssetName, item.SerialNumber)
+	case "nama_aset":
+		return item.AssetName
+	case "no_inventaris":
+		return item.InventoryNumber
+	case "serial_number":
+		return item.SerialNumber
+	case "kategori":
+		return item.Category
+	case "label":
+		return item.DeviceName
+	case "jumlah":
+		return "1 Unit"
+	case "kondisi":
+		return data.Conditions[item.ID]
+	case "catatan_item":
+		return data.ItemNotes[item.ID]
+	case "keterangan":
+		// Pada BAST pengembalian, keterangan berisi kondisi barang saat diterima kembali
+		if data.Return {
+			keterangan := "Kondisi: " + data.Conditions[item.ID]
+			if note := data.ItemNotes[item.ID]; note != "" {
+				keterangan += " - " + note
+			}
+			return keterangan
+		}
+		// Use Label (DeviceName) if available, otherwise Category
+		if item.DeviceName != "" {
+			return item.DeviceName
+		}
+		return item.Category
+	}
+	return ""
+}
+
+// templateParagraphs memecah teks template menjadi paragraf yang dipisah baris kosong
+func templateParagraphs(text string) []string {
+	var paragraphs []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// templateLines memecah teks template per baris dan membuang baris kosong
+func templateLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// sampleBASTData menyiapkan data contoh untuk pratinjau template
+func sampleBASTData(docType string) BASTData {
+	data := BASTData{
+		HandoverDate: time.Now(),
+		P1: models.User{Name: "Andi Pratama", Department: models.MasterDepartment{Name: "IT Support"},
+			Position: models.MasterPosition{Name: "Staff IT"}, Branch: models.MasterBranch{Name: "Jakarta"}},
+		P2: models.User{Name: "Siti Rahmawati", Department: models.MasterDepartment{Name: "Keuangan"},
+			Position: models.MasterPosition{Name: "Supervisor"}, Branch: models.MasterBranch{Name: "Jakarta"}},
+		Items: []models.AssetKSO{
+			{ID: "sample-1", InventoryNumber: "INV-0001", AssetName: "Laptop Lenovo ThinkPad E14", SerialNumber: "PF3XK2L9", Category: "Laptop", DeviceName: "KSO-LT-001"},
+			{ID: "sample-2", InventoryNumber: "INV-0002", AssetName: "Mouse Logitech M185", SerialNumber: "2115LZ0A", Category: "Aksesoris"},
+		},
+		Notes: "Contoh catatan serah terima",
+	}
+	if docType == "bast-return" {
+		data.Return = true
+		data.Conditions = map[string]string{"sample-1": "Baik", "sample-2": "Rusak"}
+		data.ItemNotes = map[string]string{"sample-2": "Tombol kiri tidak berfungsi"}
+	}
+	return data
+}
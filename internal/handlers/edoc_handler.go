@@ -44,17 +44,13 @@ func (server *Server) StoreFolder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	folder := models.DMSFolder{
-		ID:    uuid.New().String(),
-		Name:  name,
-		Color: "#fbbf24", // Default yellow
-	}
-
-	if parentID := r.FormValue("parent_id"); parentID != "" {
-		folder.ParentID = &parentID
+	var parentID *string
+	if pid := r.FormValue("parent_id"); pid != "" {
+		parentID = &pid
 	}
 
-	if err := server.DB.Create(&folder).Error; err != nil {
+	folder, err := server.createFolder(name, parentID)
+	if err != nil {
 		fmt.Printf("Error creating folder: %v\n", err)
 	}
 
@@ -65,6 +61,40 @@ func (server *Server) StoreFolder(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, redirect, http.StatusSeeOther)
 }
 
+// createFolder membuat record folder DMS baru di bawah parentID (nil untuk root)
+func (server *Server) createFolder(name string, parentID *string) (models.DMSFolder, error) {
+	folder := models.DMSFolder{
+		ID:       uuid.New().String(),
+		Name:     name,
+		Color:    "#fbbf24", // Default yellow
+		ParentID: parentID,
+	}
+	err := server.DB.Create(&folder).Error
+	return folder, err
+}
+
+// ensureFolder mencari folder berdasarkan nama di bawah parent tertentu, atau membuatnya jika belum ada
+func (server *Server) ensureFolder(name string, parentID *string, color string) models.DMSFolder {
+	var folder models.DMSFolder
+	query := server.DB.Where("name = ? AND trashed_at IS NULL", name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	query.Limit(1).Find(&folder)
+	if folder.ID == "" {
+		folder = models.DMSFolder{
+			ID:       uuid.New().String(),
+			Name:     name,
+			ParentID: parentID,
+			Color:    color,
+		}
+		server.DB.Create(&folder)
+	}
+	return folder
+}
+
 // ListFolderContent menampilkan isi dari sebuah folder (subfolder dan file)
 func (server *Server) ListFolderContent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -0,0 +1,601 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// errEmployeeImportApplied membatalkan transaksi bila impor sudah diterapkan oleh request lain
+var errEmployeeImportApplied = errors.New("impor ini sudah diterapkan")
+
+// employeeImportHeaders adalah kolom file master karyawan; dipakai juga untuk file template
+var employeeImportHeaders = []string{"NIK", "Nama", "Email", "Cabang", "Bagian", "Sub Bagian", "Jabatan", "Status"}
+
+// employeeImportColumns memetakan judul kolom (huruf kecil, tanpa garis bawah) ke field baris impor
+var employeeImportColumns = map[string]string{
+	"nik":             "nik",
+	"nama":            "name",
+	"nama karyawan":   "name",
+	"name":            "name",
+	"email":           "email",
+	"e-mail":          "email",
+	"cabang":          "branch",
+	"branch":          "branch",
+	"bagian":          "department",
+	"departemen":      "department",
+	"department":      "department",
+	"sub bagian":      "sub_department",
+	"sub department":  "sub_department",
+	"jabatan":         "position",
+	"position":        "position",
+	"status":          "status",
+	"status karyawan": "status",
+}
+
+// employeeImportRequired adalah kolom yang wajib ada di file beserta judul kolomnya di template
+var employeeImportRequired = []struct{ Field, Header string }{
+	{"nik", "NIK"}, {"name", "Nama"}, {"email", "Email"}, {"branch", "Cabang"}, {"department", "Bagian"}, {"position", "Jabatan"},
+}
+
+// employeeStatuses adalah status karyawan yang dikenali
+var employeeStatuses = []string{"Tetap", "Kontrak", "Magang", models.EmployeeStatusResign}
+
+// employeeImportRow adalah satu baris data dari file HR
+type employeeImportRow struct {
+	Line          int
+	NIK           string
+	Name          string
+	Email         string
+	Branch        string
+	Department    string
+	SubDepartment string
+	Position      string
+	Status        string
+
+	placement orgPlacement // hasil pencocokan dengan master data, diisi saat perbandingan
+}
+
+// employeeImportChange adalah perubahan satu field karyawan
+type employeeImportChange struct {
+	Field  string
+	Column string
+	Old    string
+	New    string
+	Value  interface{} // nilai yang disimpan ke kolom
+}
+
+// employeeImportItem adalah hasil perbandingan satu baris file atau satu karyawan dengan database
+type employeeImportItem struct {
+	Row     employeeImportRow
+	User    models.User // karyawan yang sudah ada; kosong untuk karyawan baru
+	Restore bool        // karyawan pernah dihapus dan akan dipulihkan
+	Changes []employeeImportChange
+	Errors  []string
+	Assets  int64  // aset yang masih dipegang karyawan yang perlu offboarding
+	Reason  string // alasan karyawan perlu offboarding
+}
+
+// employeeImportDiff adalah pratinjau perubahan sebelum impor diterapkan
+type employeeImportDiff struct {
+	Created   []employeeImportItem
+	Updated   []employeeImportItem
+	Unchanged []employeeImportItem
+	Resigned  []employeeImportItem // karyawan yang keluar; statusnya diubah lewat offboarding, bukan oleh impor
+	Invalid   []employeeImportItem
+}
+
+// parseEmployeeImport membaca baris file menjadi baris impor. Baris pertama yang berisi adalah judul kolom.
+func parseEmployeeImport(rows [][]string) ([]employeeImportRow, error) {
+	header := -1
+	columns := make(map[string]int)
+	var result []employeeImportRow
+	for i, cells := range rows {
+		if isBlankRow(cells) {
+			continue
+		}
+		if header < 0 {
+			header = i
+			for j, cell := range cells {
+				key := strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(cell, "_", " "))), " ")
+				if field, ok := employeeImportColumns[key]; ok {
+					if _, dup := columns[field]; !dup {
+						columns[field] = j
+					}
+				}
+			}
+			var missing []string
+			for _, col := range employeeImportRequired {
+				if _, ok := columns[col.Field]; !ok {
+					missing = append(missing, col.Header)
+				}
+			}
+			if len(missing) > 0 {
+				return nil, &importHeaderError{Missing: missing}
+			}
+			continue
+		}
+
+		value := func(field string) string {
+			j, ok := columns[field]
+			if !ok || j >= len(cells) {
+				return ""
+			}
+			return strings.Join(strings.Fields(cells[j]), " ")
+		}
+		result = append(result, employeeImportRow{
+			Line:          i + 1,
+			NIK:           value("nik"),
+			Name:          value("name"),
+			Email:         strings.ToLower(value("email")),
+			Branch:        value("branch"),
+			Department:    value("department"),
+			SubDepartment: value("sub_department"),
+			Position:      value("position"),
+			Status:        value("status"),
+		})
+	}
+	if header < 0 {
+		return nil, &importHeaderError{}
+	}
+	return result, nil
+}
+
+// importHeaderError menandai file tanpa judul kolom yang dikenali
+type importHeaderError struct {
+	Missing []string
+}
+
+func (e *importHeaderError) Error() string {
+	if len(e.Missing) == 0 {
+		return "File kosong"
+	}
+	return "Kolom wajib tidak ditemukan: " + strings.Join(e.Missing, ", ") + ". Gunakan template impor."
+}
+
+// isBlankRow memeriksa apakah semua sel baris kosong
+func isBlankRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// employeeImportDiff membandingkan baris file dengan data karyawan. Nama cabang, bagian, sub bagian dan
+// jabatan dicocokkan dengan master data tanpa membedakan huruf besar kecil lalu disimpan dengan ejaan master data.
+// Karyawan di dalam cakupan admin yang NIK-nya tidak ada di file ditandai resign.
+func (server *Server) employeeImportDiff(scope dataScope, rows []employeeImportRow) employeeImportDiff {
+	type deptNode struct {
+		models.MasterDepartment
+		subs map[string]models.MasterSubDepartment
+	}
+	type branchNode struct {
+		models.MasterBranch
+		depts map[string]deptNode
+	}
+	var branches []models.MasterBranch
+	server.DB.Preload("Departments.SubDepartments").Find(&branches)
+	orgChart := make(map[string]branchNode)
+	for _, b := range branches {
+		node := branchNode{MasterBranch: b, depts: make(map[string]deptNode)}
+		for _, d := range b.Departments {
+			dept := deptNode{MasterDepartment: d, subs: make(map[string]models.MasterSubDepartment)}
+			for _, s := range d.SubDepartments {
+				dept.subs[strings.ToLower(s.Name)] = s
+			}
+			node.depts[strings.ToLower(d.Name)] = dept
+		}
+		orgChart[strings.ToLower(b.Name)] = node
+	}
+	var positionList []models.MasterPosition
+	server.DB.Find(&positionList)
+	positions := make(map[string]models.MasterPosition)
+	for _, p := range positionList {
+		positions[strings.ToLower(p.Name)] = p
+	}
+
+	// Karyawan yang pernah dihapus ikut dimuat karena NIK dan email tetap unik di database
+	var users []models.User
+	preloadUserOrg(server.DB.Unscoped(), "").Find(&users)
+	byNIK := make(map[string]models.User)
+	byEmail := make(map[string]models.User)
+	for _, u := range users {
+		if u.NIK != "" {
+			byNIK[u.NIK] = u
+		}
+		byEmail[strings.ToLower(u.Email)] = u
+	}
+
+	var diff employeeImportDiff
+	seenNIK := make(map[string]bool)
+	seenEmail := make(map[string]bool)
+	for _, row := range rows {
+		item := employeeImportItem{Row: row}
+		addError := func(msg string) { item.Errors = append(item.Errors, msg) }
+
+		switch {
+		case row.NIK == "":
+			addError("NIK kosong")
+		case seenNIK[row.NIK]:
+			addError("NIK " + row.NIK + " muncul lebih dari sekali di file")
+		}
+		if row.Name == "" {
+			addError("Nama kosong")
+		}
+		switch {
+		case row.Email == "" || !strings.Contains(row.Email, "@"):
+			addError("Email tidak valid")
+		case seenEmail[row.Email]:
+			addError("Email " + row.Email + " muncul lebih dari sekali di file")
+		default:
+			if other, ok := byEmail[row.Email]; ok && other.NIK != row.NIK {
+				addError("Email " + row.Email + " sudah dipakai karyawan lain (NIK " + other.NIK + ")")
+			}
+		}
+		seenNIK[row.NIK] = true
+		seenEmail[row.Email] = true
+
+		if branch, ok := orgChart[strings.ToLower(row.Branch)]; !ok {
+			addError("Cabang \"" + row.Branch + "\" tidak ada di master data")
+		} else {
+			row.Branch, row.placement.Branch = branch.Name, branch.MasterBranch
+			if dept, ok := branch.depts[strings.ToLower(row.Department)]; !ok {
+				addError("Bagian \"" + row.Department + "\" tidak ada di cabang " + branch.Name)
+			} else {
+				row.Department, row.placement.Department = dept.Name, dept.MasterDepartment
+				if row.SubDepartment != "" {
+					if sub, ok := dept.subs[strings.ToLower(row.SubDepartment)]; !ok {
+						addError("Sub bagian \"" + row.SubDepartment + "\" tidak ada di bagian " + dept.Name)
+					} else {
+						row.SubDepartment, row.placement.SubDepartment = sub.Name, sub
+					}
+				}
+			}
+		}
+		if position, ok := positions[strings.ToLower(row.Position)]; !ok {
+			addError("Jabatan \"" + row.Position + "\" tidak ada di master data")
+		} else {
+			row.Position, row.placement.Position = position.Name, position
+		}
+		if row.Status != "" {
+			status := ""
+			for _, s := range employeeStatuses {
+				if strings.EqualFold(s, row.Status) {
+					status = s
+				}
+			}
+			if status == "" {
+				addError("Status \"" + row.Status + "\" tidak dikenal (" + strings.Join(employeeStatuses, ", ") + ")")
+			}
+			row.Status = status
+		}
+		item.Row = row
+
+		existing, found := byNIK[row.NIK]
+		if len(item.Errors) == 0 {
+			if !scope.allows(row.placement.Branch.ID, row.placement.Department.ID, row.placement.SubDepartment.ID) {
+				addError("Unit kerja di luar cakupan data Anda")
+			} else if found && !existing.DeletedAt.Valid && !scope.allowsUser(existing) {
+				addError("Karyawan saat ini berada di luar cakupan data Anda")
+			}
+		}
+		if len(item.Errors) > 0 {
+			diff.Invalid = append(diff.Invalid, item)
+			continue
+		}
+
+		if !found {
+			diff.Created = append(diff.Created, item)
+			continue
+		}
+		item.User = existing
+		item.Restore = existing.DeletedAt.Valid
+		item.Changes = employeeChanges(existing, row)
+		if !item.Restore && row.Status == models.EmployeeStatusResign && existing.StatusKaryawan != models.EmployeeStatusResign {
+			// Status resign hanya diberikan oleh offboarding agar aset dan peminjamannya diperiksa lebih dulu
+			item.Changes = slices.DeleteFunc(item.Changes, func(c employeeImportChange) bool { return c.Column == "status_karyawan" })
+			diff.Resigned = append(diff.Resigned, employeeImportItem{User: existing, Reason: "Berstatus Resign di file"})
+		}
+		switch {
+		case item.Restore:
+			diff.Created = append(diff.Created, item)
+		case len(item.Changes) > 0:
+			diff.Updated = append(diff.Updated, item)
+		default:
+			diff.Unchanged = append(diff.Unchanged, item)
+		}
+	}
+
+	// NIK yang ada di file (termasuk baris yang salah) tidak dianggap resign
+	var active []models.User
+	scope.applyUsers(preloadUserOrg(server.DB, "")).Where("nik <> '' AND (status_karyawan IS NULL OR status_karyawan <> ?)", models.EmployeeStatusResign).
+		Order("nik asc").Find(&active)
+	for _, u := range active {
+		if !seenNIK[u.NIK] {
+			diff.Resigned = append(diff.Resigned, employeeImportItem{User: u, Reason: "Tidak ada di file"})
+		}
+	}
+	var resignIDs []string
+	for _, item := range diff.Resigned {
+		resignIDs = append(resignIDs, item.User.ID)
+	}
+	if len(resignIDs) > 0 {
+		type holding struct {
+			UserID string
+			Total  int64
+		}
+		var holdings []holding
+		server.DB.Model(&models.AssetKSO{}).Select("user_id, COUNT(*) AS total").
+			Where("user_id IN ?", resignIDs).Group("user_id").Scan(&holdings)
+		held := make(map[string]int64)
+		for _, h := range holdings {
+			held[h.UserID] = h.Total
+		}
+		for i := range diff.Resigned {
+			diff.Resigned[i].Assets = held[diff.Resigned[i].User.ID]
+		}
+	}
+	return diff
+}
+
+// employeeChanges membandingkan data karyawan dengan baris file; status kosong di file tidak mengubah status.
+// Penempatan dibandingkan lewat ID master data, nama hanya untuk ditampilkan di pratinjau.
+func employeeChanges(user models.User, row employeeImportRow) []employeeImportChange {
+	var changes []employeeImportChange
+	add := func(field, column, old, new string) {
+		if old != new {
+			changes = append(changes, employeeImportChange{Field: field, Column: column, Old: old, New: new, Value: new})
+		}
+	}
+	addOrg := func(field, column string, oldID *uint, old string, newID uint, new string) {
+		if uintValue(oldID) != newID {
+			changes = append(changes, employeeImportChange{Field: field, Column: column, Old: old, New: new, Value: optionalID(newID)})
+		}
+	}
+	add("Nama", "name", user.Name, row.Name)
+	if !strings.EqualFold(user.Email, row.Email) {
+		add("Email", "email", user.Email, row.Email)
+	}
+	p := row.placement
+	addOrg("Cabang", "branch_id", user.BranchID, user.Branch.Name, p.Branch.ID, p.Branch.Name)
+	addOrg("Bagian", "department_id", user.DepartmentID, user.Department.Name, p.Department.ID, p.Department.Name)
+	addOrg("Sub Bagian", "sub_department_id", user.SubDepartmentID, user.SubDepartment.Name, p.SubDepartment.ID, p.SubDepartment.Name)
+	addOrg("Jabatan", "position_id", user.PositionID, user.Position.Name, p.Position.ID, p.Position.Name)
+	if row.Status != "" {
+		add("Status", "status_karyawan", user.StatusKaryawan, row.Status)
+	}
+	return changes
+}
+
+// findEmployeeImport mengambil data impor beserta barisnya
+func (server *Server) findEmployeeImport(id string) (models.EmployeeImport, []employeeImportRow, bool) {
+	var imp models.EmployeeImport
+	server.DB.Where("id = ?", id).Limit(1).Find(&imp)
+	if imp.ID == "" {
+		return imp, nil, false
+	}
+	var rows []employeeImportRow
+	if err := json.Unmarshal([]byte(imp.Rows), &rows); err != nil {
+		return imp, nil, false
+	}
+	return imp, rows, true
+}
+
+// ImportEmployeeForm menampilkan halaman unggah master karyawan dari HR beserta riwayat impor
+func (server *Server) ImportEmployeeForm(w http.ResponseWriter, r *http.Request) {
+	var imports []models.EmployeeImport
+	server.DB.Omit("rows").Order("created_at desc").Limit(20).Find(&imports)
+
+	server.RenderHTML(w, r, http.StatusOK, "administration/employee_import", map[string]interface{}{
+		"title":   "Impor Master Karyawan",
+		"imports": imports,
+		"headers": employeeImportHeaders,
+		"error":   r.URL.Query().Get("error"),
+	})
+}
+
+// EmployeeImportTemplate mengunduh file CSV kosong dengan judul kolom impor
+func (server *Server) EmployeeImportTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"template-impor-karyawan.csv\"")
+	writer := csv.NewWriter(w)
+	writer.Write(employeeImportHeaders)
+	writer.Flush()
+}
+
+// UploadEmployeeImport membaca file master karyawan dan menyimpannya untuk dipratinjau
+func (server *Server) UploadEmployeeImport(w http.ResponseWriter, r *http.Request) {
+	fail := func(msg string) {
+		http.Redirect(w, r, "/administration/employee/import?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB max
+		fail("File terlalu besar (maksimal 10MB)")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		fail("Pilih file CSV atau XLSX")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		fail("Gagal membaca file: " + err.Error())
+		return
+	}
+
+	cells, err := readSpreadsheet(header.Filename, data)
+	if err != nil {
+		fail("Gagal membaca file: " + err.Error())
+		return
+	}
+	rows, err := parseEmployeeImport(cells)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		fail("File tidak berisi data karyawan")
+		return
+	}
+
+	encoded, _ := json.Marshal(rows)
+	adminID, username, _, _ := GetCurrentAdmin(r)
+	imp := models.EmployeeImport{
+		ID:       uuid.New().String(),
+		FileName: truncate(header.Filename, 255),
+		Rows:     string(encoded),
+		AdminID:  adminID,
+		Username: username,
+	}
+	if err := server.DB.Create(&imp).Error; err != nil {
+		fail("Gagal menyimpan file impor: " + err.Error())
+		return
+	}
+	http.Redirect(w, r, "/administration/employee/import/"+imp.ID, http.StatusSeeOther)
+}
+
+// PreviewEmployeeImport menampilkan perbedaan antara file HR dan data karyawan sebelum diterapkan
+func (server *Server) PreviewEmployeeImport(w http.ResponseWriter, r *http.Request) {
+	imp, rows, ok := server.findEmployeeImport(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/administration/employee/import?error="+url.QueryEscape("Data impor tidak ditemukan"), http.StatusSeeOther)
+		return
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "administration/employee_import_preview", map[string]interface{}{
+		"title": "Pratinjau Impor Karyawan",
+		"imp":   imp,
+		"rows":  len(rows),
+		"diff":  server.employeeImportDiff(server.dataScope(r), rows),
+		"error": r.URL.Query().Get("error"),
+	})
+}
+
+// applyOffboardingNotes menandai karyawan keluar agar tetap terlihat di daftar karyawan sampai offboarding-nya
+// selesai, dan menghapus tanda karyawan yang kembali tercantum aktif di file
+func applyOffboardingNotes(tx *gorm.DB, diff employeeImportDiff) error {
+	resigned := make(map[string]bool, len(diff.Resigned))
+	for _, item := range diff.Resigned {
+		resigned[item.User.ID] = true
+		if err := tx.Model(&models.User{}).Where("id = ?", item.User.ID).Update("offboarding_note", item.Reason).Error; err != nil {
+			return err
+		}
+	}
+	for _, items := range [][]employeeImportItem{diff.Created, diff.Updated, diff.Unchanged} {
+		for _, item := range items {
+			if item.User.ID == "" || item.User.OffboardingNote == "" || resigned[item.User.ID] {
+				continue
+			}
+			if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", item.User.ID).Update("offboarding_note", "").Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyEmployeeImport menerapkan impor: menambah dan memperbarui karyawan dalam satu transaksi.
+// Karyawan yang keluar tidak diubah; statusnya menjadi Resign setelah offboarding masing-masing diselesaikan.
+// Perbedaan dihitung ulang saat diterapkan sehingga perubahan data sejak pratinjau ikut diperhitungkan.
+func (server *Server) ApplyEmployeeImport(w http.ResponseWriter, r *http.Request) {
+	imp, rows, ok := server.findEmployeeImport(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/administration/employee/import?error="+url.QueryEscape("Data impor tidak ditemukan"), http.StatusSeeOther)
+		return
+	}
+	back := "/administration/employee/import/" + imp.ID
+	if imp.AppliedAt != nil {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Impor ini sudah diterapkan pada "+imp.AppliedAt.Format("02/01/2006 15:04")), http.StatusSeeOther)
+		return
+	}
+
+	diff := server.employeeImportDiff(server.dataScope(r), rows)
+	if len(diff.Invalid) > 0 {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Masih ada "+strconv.Itoa(len(diff.Invalid))+" baris yang salah. Perbaiki file lalu unggah ulang."), http.StatusSeeOther)
+		return
+	}
+
+	now := time.Now()
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		// Tandai impor lebih dulu agar dua request yang bersamaan tidak menerapkannya dua kali
+		res := tx.Model(&models.EmployeeImport{}).Where("id = ? AND applied_at IS NULL", imp.ID).Updates(map[string]interface{}{
+			"applied_at": now,
+			"created":    len(diff.Created),
+			"updated":    len(diff.Updated),
+			"resigned":   len(diff.Resigned),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errEmployeeImportApplied
+		}
+
+		for _, item := range diff.Created {
+			row := item.Row
+			if item.Restore {
+				values := map[string]interface{}{"deleted_at": nil}
+				for _, c := range item.Changes {
+					values[c.Column] = c.Value
+				}
+				if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", item.User.ID).Updates(values).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			user := models.User{
+				ID:             uuid.New().String(),
+				NIK:            row.NIK,
+				Name:           row.Name,
+				Email:          row.Email,
+				StatusKaryawan: row.Status,
+				Password:       "password123", // Default password, sama seperti tambah karyawan manual
+			}
+			row.placement.assign(&user)
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, item := range diff.Updated {
+			values := make(map[string]interface{})
+			for _, c := range item.Changes {
+				values[c.Column] = c.Value
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", item.User.ID).Updates(values).Error; err != nil {
+				return err
+			}
+		}
+		return applyOffboardingNotes(tx, diff)
+	})
+	if err == errEmployeeImportApplied {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Impor ini sudah diterapkan oleh admin lain"), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Gagal menerapkan impor: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	msg := "Impor diterapkan: " + strconv.Itoa(len(diff.Created)) + " karyawan baru, " +
+		strconv.Itoa(len(diff.Updated)) + " diperbarui"
+	if len(diff.Resigned) > 0 {
+		msg += ", " + strconv.Itoa(len(diff.Resigned)) + " karyawan keluar ditandai perlu offboarding"
+	}
+	http.Redirect(w, r, "/administration/employee?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
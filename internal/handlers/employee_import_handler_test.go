@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// importFixture menyiapkan master data Jakarta/IT/Staf dan karyawan kedua (Sari, NIK 1002)
+func importFixture(tb testing.TB, server *Server) {
+	tb.Helper()
+	server.DB.Create(&models.MasterBranch{ID: 1, Name: "Jakarta"})
+	server.DB.Create(&models.MasterDepartment{ID: 1, MasterBranchID: 1, Name: "IT"})
+	server.DB.Create(&models.MasterPosition{ID: 1, Name: "Staf"})
+	server.DB.Create(&models.User{ID: "u2", NIK: "1002", Name: "Sari", Email: "sari@example.com", Password: "x", StatusKaryawan: "Tetap"})
+}
+
+// storedImport menyimpan baris impor seperti UploadEmployeeImport lalu mengembalikan ID-nya
+func storedImport(tb testing.TB, server *Server, id string, rows ...employeeImportRow) string {
+	tb.Helper()
+	encoded, err := json.Marshal(rows)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := server.DB.Create(&models.EmployeeImport{ID: id, FileName: id + ".csv", Rows: string(encoded)}).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return id
+}
+
+// importRow membuat baris impor karyawan di Jakarta/IT sebagai Staf
+func importRow(nik, name, email, status string) employeeImportRow {
+	return employeeImportRow{NIK: nik, Name: name, Email: email, Branch: "Jakarta", Department: "IT", Position: "Staf", Status: status}
+}
+
+// offboardingNotes mengembalikan tanda perlu offboarding per ID karyawan
+func offboardingNotes(server *Server) map[string]string {
+	var users []models.User
+	server.DB.Order("id").Find(&users)
+	notes := make(map[string]string)
+	for _, u := range users {
+		notes[u.ID] = u.OffboardingNote
+	}
+	return notes
+}
+
+func TestApplyEmployeeImportFlagsPendingOffboarding(t *testing.T) {
+	server := newTestServer(t)
+	importFixture(t, server)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	id := storedImport(t, server, "imp-1", importRow("1001", "Budi", "budi@example.com", "Resign"))
+	w := client.do(http.MethodPost, "/administration/employee/import/"+id+"/apply", url.Values{})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != "" {
+		t.Fatalf("apply error = %q", msg)
+	}
+	notes := offboardingNotes(server)
+	if notes["u1"] != "Berstatus Resign di file" || notes["u2"] != "Tidak ada di file" {
+		t.Fatalf("offboarding notes = %v", notes)
+	}
+	var budi models.User
+	server.DB.First(&budi, "id = ?", "u1")
+	if budi.StatusKaryawan == models.EmployeeStatusResign {
+		t.Error("import set the status to Resign instead of leaving it to offboarding")
+	}
+
+	// Daftar karyawan menautkan karyawan yang ditandai ke halaman offboarding-nya
+	body := client.do(http.MethodGet, "/administration/employee", nil).Body.String()
+	for _, want := range []string{"/administration/employee?offboarding=pending", "/administration/employee/offboarding/u2\" class=\"badge"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("employee list lacks %s", want)
+		}
+	}
+	server.DB.Create(&models.User{ID: "u3", NIK: "1003", Name: "Tono Baru", Email: "tono@example.com", Password: "x"})
+	body = client.do(http.MethodGet, "/administration/employee?offboarding=pending", nil).Body.String()
+	if !strings.Contains(body, "Sari") || strings.Contains(body, "Tono Baru") {
+		t.Error("pending filter does not list only flagged employees")
+	}
+
+	// Karyawan yang kembali tercantum aktif di impor berikutnya tidak lagi ditandai
+	id = storedImport(t, server, "imp-2", importRow("1001", "Budi", "budi@example.com", "Resign"), importRow("1002", "Sari", "sari@example.com", "Tetap"))
+	client.do(http.MethodPost, "/administration/employee/import/"+id+"/apply", url.Values{})
+	if notes := offboardingNotes(server); notes["u1"] == "" || notes["u2"] != "" || notes["u3"] == "" {
+		t.Errorf("offboarding notes after second import = %v", notes)
+	}
+}
+
+func TestCompleteEmployeeOffboardingClearsImportFlag(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	server.DB.Create(&models.User{ID: "u2", NIK: "1002", Name: "Sari", Email: "sari@example.com", Password: "x", OffboardingNote: "Tidak ada di file"})
+
+	w := client.do(http.MethodPost, "/administration/employee/offboarding/u2/complete", url.Values{"resign_date": {"2026-01-31"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != "" {
+		t.Fatalf("complete error = %q", msg)
+	}
+	if notes := offboardingNotes(server); notes["u2"] != "" {
+		t.Errorf("offboarding note = %q after completion", notes["u2"])
+	}
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// goFormFieldTypes adalah tipe isian yang didukung form builder
+var goFormFieldTypes = []string{"text", "textarea", "number", "date", "email", "select", "checkbox"}
+
+// goFormOptionSources adalah sumber master data yang bisa dipakai isian select
+var goFormOptionSources = map[string]string{
+	"branch":         "Cabang",
+	"department":     "Bagian",
+	"sub_department": "Sub Bagian",
+	"position":       "Jabatan",
+	"asset_category": "Kategori Aset",
+	"employee":       "Karyawan",
+	"asset":          "Aset KSO",
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ListGoFormBuilder menampilkan daftar definisi GoForm untuk dikelola admin
+func (server *Server) ListGoFormBuilder(w http.ResponseWriter, r *http.Request) {
+	var forms []models.GoForm
+	server.DB.Preload("Fields").Order("sort_order asc, name asc").Find(&forms)
+
+	type submissionCount struct {
+		FormID string
+		Total  int64
+	}
+	var counts []submissionCount
+	server.DB.Model(&models.GoFormSubmission{}).Select("form_id, COUNT(*) as total").Group("form_id").Scan(&counts)
+	totals := map[string]int64{}
+	for _, c := range counts {
+		totals[c.FormID] = c.Total
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/builder", map[string]interface{}{
+		"title":  "GoForm Builder",
+		"forms":  forms,
+		"totals": totals,
+		"msg":    r.URL.Query().Get("msg"),
+		"error":  r.URL.Query().Get("error"),
+	})
+}
+
+// CreateGoFormBuilder menampilkan form builder untuk membuat GoForm baru
+func (server *Server) CreateGoFormBuilder(w http.ResponseWriter, r *http.Request) {
+	server.RenderHTML(w, r, http.StatusOK, "goform/builder_form", map[string]interface{}{
+		"title":         "Buat GoForm",
+		"fieldTypes":    goFormFieldTypes,
+		"optionSources": goFormOptionSources,
+		"error":         r.URL.Query().Get("error"),
+	})
+}
+
+// StoreGoFormBuilder menyimpan definisi GoForm baru beserta isiannya
+func (server *Server) StoreGoFormBuilder(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	form := models.GoForm{ID: uuid.New().String()}
+	fields, errMsg := server.bindGoFormBuilder(&form, r)
+	if errMsg != "" {
+		http.Redirect(w, r, "/goform/builder/create?error="+url.QueryEscape(errMsg), http.StatusSeeOther)
+		return
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Fields").Create(&form).Error; err != nil {
+			return err
+		}
+		return replaceGoFormFields(tx, form.ID, fields)
+	})
+	if err != nil {
+		http.Redirect(w, r, "/goform/builder/create?error="+url.QueryEscape("Gagal menyimpan form: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/goform/builder?msg="+url.QueryEscape("Form "+form.Name+" berhasil dibuat"), http.StatusSeeOther)
+}
+
+// EditGoFormBuilder menampilkan form builder untuk mengubah GoForm
+func (server *Server) EditGoFormBuilder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var form models.GoForm
+	server.DB.Preload("Fields", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order asc, id asc")
+	}).Where("id = ?", id).Limit(1).Find(&form)
+	if form.ID == "" {
+		http.Redirect(w, r, "/goform/builder?error=Form tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+
+	fieldsJSON, _ := json.Marshal(form.Fields)
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/builder_form", map[string]interface{}{
+		"title":         "Edit GoForm",
+		"form":          form,
+		"fieldsJSON":    string(fieldsJSON),
+		"fieldTypes":    goFormFieldTypes,
+		"optionSources": goFormOptionSources,
+		"error":         r.URL.Query().Get("error"),
+	})
+}
+
+// UpdateGoFormBuilder menyimpan perubahan definisi GoForm; isian lama diganti seluruhnya
+func (server *Server) UpdateGoFormBuilder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var form models.GoForm
+	server.DB.Where("id = ?", id).Limit(1).Find(&form)
+	if form.ID == "" {
+		http.Redirect(w, r, "/goform/builder?error=Form tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+
+	fields, errMsg := server.bindGoFormBuilder(&form, r)
+	if errMsg != "" {
+		http.Redirect(w, r, "/goform/builder/edit/"+id+"?error="+url.QueryEscape(errMsg), http.StatusSeeOther)
+		return
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Fields").Select("*").Updates(&form).Error; err != nil {
+			return err
+		}
+		return replaceGoFormFields(tx, form.ID, fields)
+	})
+	if err != nil {
+		http.Redirect(w, r, "/goform/builder/edit/"+id+"?error="+url.QueryEscape("Gagal menyimpan form: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/goform/builder?msg="+url.QueryEscape("Form "+form.Name+" berhasil diperbarui"), http.StatusSeeOther)
+}
+
+// DeleteGoFormBuilder menghapus definisi GoForm; form bawaan (BAST) tidak bisa dihapus
+func (server *Server) DeleteGoFormBuilder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var form models.GoForm
+	server.DB.Where("id = ?", id).Limit(1).Find(&form)
+	if form.ID == "" {
+		http.Redirect(w, r, "/goform/builder?error=Form tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if form.Handler != "" {
+		http.Redirect(w, r, "/goform/builder?error="+url.QueryEscape("Form bawaan tidak bisa dihapus, nonaktifkan saja"), http.StatusSeeOther)
+		return
+	}
+
+	server.DB.Delete(&form)
+	http.Redirect(w, r, "/goform/builder?msg="+url.QueryEscape("Form "+form.Name+" berhasil dihapus"), http.StatusSeeOther)
+}
+
+// ListGoFormSubmissions menampilkan riwayat isian sebuah GoForm
+func (server *Server) ListGoFormSubmissions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var form models.GoForm
+	server.DB.Where("id = ?", id).Limit(1).Find(&form)
+	if form.ID == "" {
+		http.Redirect(w, r, "/goform/builder?error=Form tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+
+	var submissions []models.GoFormSubmission
+	server.DB.Where("form_id = ?", form.ID).Order("created_at desc").Find(&submissions)
+
+	// Nama pengisi diambil dari akun admin yang tertaut ke data karyawan
+	adminIDs := make([]string, 0, len(submissions))
+	fileIDs := make([]string, 0, len(submissions))
+	for _, s := range submissions {
+		adminIDs = append(adminIDs, s.SubmittedByID)
+		if s.FileID != nil {
+			fileIDs = append(fileIDs, *s.FileID)
+		}
+	}
+	var admins []models.Admin
+	server.DB.Where("id IN ?", adminIDs).Find(&admins)
+	names := map[string]string{}
+	for _, a := range admins {
+		names[a.ID] = a.Username
+		if a.UserID != "" {
+			var user models.User
+			server.DB.Select("name").Where("id = ?", a.UserID).Limit(1).Find(&user)
+			if user.Name != "" {
+				names[a.ID] = user.Name
+			}
+		}
+	}
+	var files []models.DMSFile
+	server.DB.Where("id IN ?", fileIDs).Find(&files)
+	paths := map[string]string{}
+	for _, f := range files {
+		paths[f.ID] = f.FilePath
+	}
+
+	var rows []map[string]interface{}
+	for _, s := range submissions {
+		var answers []models.GoFormAnswer
+		json.Unmarshal([]byte(s.Data), &answers)
+
+		filePath := ""
+		if s.FileID != nil {
+			filePath = paths[*s.FileID]
+		}
+		rows = append(rows, map[string]interface{}{
+			"ID":          s.ID,
+			"CreatedAt":   s.CreatedAt,
+			"SubmittedBy": names[s.SubmittedByID],
+			"Answers":     answers,
+			"FilePath":    filePath,
+		})
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/submissions", map[string]interface{}{
+		"title":       "Riwayat " + form.Name,
+		"form":        form,
+		"submissions": rows,
+	})
+}
+
+// bindGoFormBuilder mengisi definisi form dari request builder dan memvalidasinya
+func (server *Server) bindGoFormBuilder(form *models.GoForm, r *http.Request) ([]models.GoFormField, string) {
+	form.Name = strings.TrimSpace(r.FormValue("name"))
+	form.Description = strings.TrimSpace(r.FormValue("description"))
+	form.Icon = strings.TrimSpace(r.FormValue("icon"))
+	form.Color = strings.TrimSpace(r.FormValue("color"))
+	form.Category = strings.TrimSpace(r.FormValue("category"))
+	form.PDFTitle = strings.TrimSpace(r.FormValue("pdf_title"))
+	form.PDFTemplate = strings.TrimSpace(r.FormValue("pdf_template"))
+	form.IsActive = r.FormValue("is_active") != ""
+	form.SortOrder = int(server.parseUint(r.FormValue("sort_order")))
+
+	if form.Name == "" {
+		return nil, "Nama form wajib diisi"
+	}
+	if form.Icon == "" {
+		form.Icon = "bi-file-earmark-text"
+	}
+	if form.Color == "" {
+		form.Color = "#3b82f6"
+	}
+
+	slug := slugifyGoForm(r.FormValue("slug"))
+	if slug == "" {
+		slug = slugifyGoForm(form.Name)
+	}
+	var count int64
+	server.DB.Model(&models.GoForm{}).Unscoped().Where("slug = ? AND id <> ?", slug, form.ID).Count(&count)
+	if count > 0 {
+		return nil, "Slug " + slug + " sudah dipakai form lain"
+	}
+	form.Slug = slug
+
+	// Form bawaan memakai isian khusus sehingga daftar isian dari builder diabaikan
+	if form.Handler != "" {
+		return nil, ""
+	}
+
+	names := r.Form["field_name[]"]
+	labels := r.Form["field_label[]"]
+	types := r.Form["field_type[]"]
+	required := r.Form["field_required[]"]
+	placeholders := r.Form["field_placeholder[]"]
+	patterns := r.Form["field_pattern[]"]
+	options := r.Form["field_options[]"]
+	sources := r.Form["field_source[]"]
+
+	var fields []models.GoFormField
+	used := map[string]bool{}
+	for i := range labels {
+		label := strings.TrimSpace(labels[i])
+		if label == "" {
+			continue
+		}
+
+		name := ""
+		if i < len(names) {
+			name = strings.ReplaceAll(slugifyGoForm(names[i]), "-", "_")
+		}
+		if name == "" {
+			name = strings.ReplaceAll(slugifyGoForm(label), "-", "_")
+		}
+		if used[name] {
+			return nil, "Nama isian " + name + " dipakai lebih dari sekali"
+		}
+		used[name] = true
+
+		field := models.GoFormField{
+			FormID:    form.ID,
+			Name:      name,
+			Label:     label,
+			Type:      valueAt(types, i),
+			Required:  valueAt(required, i) == "1",
+			SortOrder: len(fields) + 1,
+		}
+		field.Placeholder = strings.TrimSpace(valueAt(placeholders, i))
+		field.Pattern = strings.TrimSpace(valueAt(patterns, i))
+		field.Options = strings.TrimSpace(strings.ReplaceAll(valueAt(options, i), "\r\n", "\n"))
+		field.OptionSource = valueAt(sources, i)
+
+		validType := false
+		for _, t := range goFormFieldTypes {
+			if field.Type == t {
+				validType = true
+				break
+			}
+		}
+		if !validType {
+			return nil, "Tipe isian " + label + " tidak dikenal"
+		}
+		if field.OptionSource != "" {
+			if _, ok := goFormOptionSources[field.OptionSource]; !ok {
+				return nil, "Sumber data isian " + label + " tidak dikenal"
+			}
+		}
+		if field.Type == "select" && field.Options == "" && field.OptionSource == "" {
+			return nil, "Isian " + label + " membutuhkan opsi atau sumber data"
+		}
+		if field.Pattern != "" {
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return nil, "Pola validasi isian " + label + " tidak valid"
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, "Form minimal memiliki satu isian"
+	}
+
+	return fields, ""
+}
+
+// replaceGoFormFields mengganti seluruh isian milik form dengan daftar yang baru
+func replaceGoFormFields(tx *gorm.DB, formID string, fields []models.GoFormField) error {
+	if fields == nil {
+		return nil
+	}
+	if err := tx.Where("form_id = ?", formID).Delete(&models.GoFormField{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&fields).Error
+}
+
+// slugifyGoForm mengubah teks bebas menjadi slug huruf kecil dengan tanda hubung
+func slugifyGoForm(s string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(s)), "-"), "-")
+}
+
+// valueAt mengambil elemen slice secara aman
+func valueAt(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+func TestDeleteGoFormBuilderRequiresPostWithCSRF(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	server.DB.Create(&models.GoForm{ID: "f1", Slug: "form-izin", Name: "Izin", IsActive: true})
+	exists := func() bool {
+		var count int64
+		server.DB.Model(&models.GoForm{}).Where("id = ?", "f1").Count(&count)
+		return count > 0
+	}
+
+	if w := client.do(http.MethodGet, "/goform/builder/delete/f1", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if w := client.do(http.MethodPost, "/goform/builder/delete/f1", url.Values{"csrf_token": {"forged"}}); w.Code != http.StatusForbidden {
+		t.Errorf("POST with forged token = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if !exists() {
+		t.Fatal("form deleted without a valid CSRF token")
+	}
+
+	if w := client.do(http.MethodPost, "/goform/builder/delete/f1", url.Values{}); w.Code != http.StatusSeeOther {
+		t.Fatalf("POST = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if exists() {
+		t.Error("form was not deleted")
+	}
+}
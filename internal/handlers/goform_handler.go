@@ -1,120 +1,173 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
+// goFormOption adalah satu pilihan untuk isian bertipe select
+type goFormOption struct {
+	Value string
+	Label string
+}
+
+// goFormFieldView menggabungkan definisi isian dengan pilihan yang sudah di-resolve untuk template
+type goFormFieldView struct {
+	models.GoFormField
+	Choices []goFormOption
+}
+
 // ListGoForm menampilkan katalog formulir digital
 func (server *Server) ListGoForm(w http.ResponseWriter, r *http.Request) {
-	// Dummy data untuk katalog form
-	forms := []map[string]interface{}{
-		{
-			"id":          "form-maintenance",
-			"name":        "Laporan Pemeliharaan Bulanan",
-			"description": "Formulir standar untuk pencatatan rutin kondisi aset laptop & komputer.",
-			"icon":        "bi-tools",
-			"color":       "#3b82f6",
-			"category":    "IT Support",
-		},
-		{
-			"id":          "form-peminjaman",
-			"name":        "Permohonan Pinjam Aset",
-			"description": "Digunakan untuk mengajukan peminjaman aset kantor bagi karyawan.",
-			"icon":        "bi-person-badge",
-			"color":       "#10b981",
-			"category":    "Logistik",
-		},
-		{
-			"id":          "form-surat-jalan",
-			"name":        "Surat Jalan Barang",
-			"description": "Dokumen resmi pengiriman atau perpindahan aset antar cabang.",
-			"icon":        "bi-truck",
-			"color":       "#f59e0b",
-			"category":    "Logistik",
-		},
-		{
-			"id":          "form-bast",
-			"name":        "BA Serah Terima Aset",
-			"description": "Berita acara bukti penyerahan aset kepada pengguna/karyawan.",
-			"icon":        "bi-file-earmark-check",
-			"color":       "#8b5cf6",
-			"category":    "Asset Control",
-		},
-		{
-			"id":          "form-bast-laptop",
-			"name":        "BA Serah Terima Laptop/Komputer",
-			"description": "Berita acara bukti penyerahan khusus aset IT (Laptop/Komputer).",
-			"icon":        "bi-laptop",
-			"color":       "#ec4899",
-			"category":    "Asset Control",
-		},
+	var forms []models.GoForm
+	server.DB.Where("is_active = ?", true).Order("sort_order asc, name asc").Find(&forms)
+
+	var categories []string
+	seen := map[string]bool{}
+	for _, f := range forms {
+		if f.Category != "" && !seen[f.Category] {
+			seen[f.Category] = true
+			categories = append(categories, f.Category)
+		}
 	}
 
 	server.RenderHTML(w, r, http.StatusOK, "goform/index", map[string]interface{}{
-		"title": "GoForm Catalog",
-		"forms": forms,
-		"msg":   r.URL.Query().Get("msg"),
+		"title":      "GoForm Catalog",
+		"forms":      forms,
+		"categories": categories,
+		"msg":        r.URL.Query().Get("msg"),
+		"error":      r.URL.Query().Get("error"),
 	})
 }
 
 // FillGoForm menampilkan formulir untuk diisi
 func (server *Server) FillGoForm(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id := vars["id"]
-
-	// Tentukan template berdasarkan ID form
-	templateName := "goform/fill"
-	formName := ""
-
-	var employees []models.User
-	server.DB.Order("name asc").Find(&employees)
-
-	var assets []models.AssetKSO
-	query := server.DB.Order("inventory_number asc")
-	if id == "form-bast-laptop" {
-		query = query.Where("LOWER(category) = ? OR LOWER(category) = ?", "laptop", "komputer")
-	}
-	query.Find(&assets)
-
-	switch id {
-	case "form-maintenance":
-		formName = "Laporan Pemeliharaan Bulanan"
-	case "form-peminjaman":
-		formName = "Permohonan Pinjam Aset"
-	case "form-surat-jalan":
-		formName = "Surat Jalan Barang"
-	case "form-bast":
-		formName = "BA Serah Terima Aset"
-		templateName = "goform/form_bast"
-	case "form-bast-laptop":
-		formName = "BA Serah Terima Laptop/Komputer"
-		templateName = "goform/form_bast_laptop"
-	default:
-		http.Redirect(w, r, "/goform", http.StatusSeeOther)
+	slug := vars["id"]
+
+	form, ok := server.findGoForm(slug)
+	if !ok || !form.IsActive {
+		http.Redirect(w, r, "/goform?error=Formulir tidak ditemukan", http.StatusSeeOther)
 		return
 	}
 
-	server.RenderHTML(w, r, http.StatusOK, templateName, map[string]interface{}{
-		"title":     "Isi " + formName,
-		"formID":    id,
-		"formName":  formName,
-		"employees": employees,
-		"assets":    assets,
-	})
+	data := map[string]interface{}{
+		"title":    "Isi " + form.Name,
+		"formID":   form.Slug,
+		"formName": form.Name,
+		"form":     form,
+		"error":    r.URL.Query().Get("error"),
+	}
+
+	switch form.Handler {
+	case "bast", "bast-laptop":
+		scope := server.dataScope(r)
+		var employees []models.User
+		scope.applyUsers(server.DB).Order("name asc").Find(&employees)
+
+		var assets []models.AssetKSO
+		query := scope.applyAssets(server.DB).Order("inventory_number asc")
+		if form.Handler == "bast-laptop" {
+			query = query.Where("LOWER(category) = ? OR LOWER(category) = ?", "laptop", "komputer")
+		}
+		query.Find(&assets)
+
+		data["employees"] = employees
+		data["assets"] = assets
+
+		templateName := "goform/form_bast"
+		if form.Handler == "bast-laptop" {
+			templateName = "goform/form_bast_laptop"
+		}
+		server.RenderHTML(w, r, http.StatusOK, templateName, data)
+	case "bast-return":
+		var employees []models.User
+		server.dataScope(r).applyUsers(server.DB).Order("name asc").Find(&employees)
+
+		// Aset yang ditampilkan hanya aset yang masih dipegang karyawan terpilih
+		employeeID := r.URL.Query().Get("employee_id")
+		var held []models.AssetKSO
+		if employeeID != "" {
+			server.DB.Where("user_id = ?", employeeID).Order("inventory_number asc").Find(&held)
+		}
+
+		data["employees"] = employees
+		data["employeeID"] = employeeID
+		data["assets"] = held
+		data["conditions"] = returnConditions
+		server.RenderHTML(w, r, http.StatusOK, "goform/form_bast_return", data)
+	case "loan":
+		var employees []models.User
+		server.DB.Order("name asc").Find(&employees)
+
+		var assets []models.AssetKSO
+		server.DB.Where("status IN ?", []string{"Ready", models.AssetStatusOnLoan}).Order("inventory_number asc").Find(&assets)
+
+		data["employees"] = employees
+		data["assets"] = assets
+		server.RenderHTML(w, r, http.StatusOK, "goform/form_peminjaman", data)
+	case "transfer":
+		_, _, role, _ := GetCurrentAdmin(r)
+		if !server.hasPermission(role, "transfer.create") {
+			http.Error(w, "Access Denied: You don't have permission to access this resource", http.StatusForbidden)
+			return
+		}
+		scope := server.dataScope(r)
+
+		var branches []models.MasterBranch
+		server.DB.Order("name asc").Find(&branches)
+
+		// Cabang asal, pengirim dan aset dibatasi cakupan data admin; cabang tujuan boleh di luar cakupan
+		var origins []models.MasterBranch
+		for _, b := range branches {
+			if !scope.restricted || slices.Contains(scope.nodeBranches(), b.Name) {
+				origins = append(origins, b)
+			}
+		}
+
+		var employees []models.User
+		scope.applyUsers(server.DB).Order("name asc").Find(&employees)
+
+		var assets []models.AssetKSO
+		scope.applyAssets(server.DB).Where("status NOT IN ?", []string{"Hilang", models.AssetStatusOnLoan, models.AssetStatusInTransit}).Order("inventory_number asc").Find(&assets)
+
+		data["branches"] = branches
+		data["originBranches"] = origins
+		data["employees"] = employees
+		data["assets"] = assets
+		server.RenderHTML(w, r, http.StatusOK, "goform/form_surat_jalan", data)
+	default:
+		var fields []goFormFieldView
+		for _, field := range form.Fields {
+			fields = append(fields, goFormFieldView{
+				GoFormField: field,
+				Choices:     server.goFormOptions(field),
+			})
+		}
+		data["fields"] = fields
+		server.RenderHTML(w, r, http.StatusOK, "goform/fill", data)
+	}
 }
 
 // SubmitGoForm menangani pengiriman data formulir
 func (server *Server) SubmitGoForm(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id := vars["id"]
+	slug := vars["id"]
 
 	err := r.ParseForm()
 	if err != nil {
@@ -122,104 +175,175 @@ func (server *Server) SubmitGoForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 1. Dapatkan atau Buat Folder "Digital Reports" di eDoc
-	var folder models.DMSFolder
-	folderName := "Laporan Digital"
-	if err := server.DB.Where("name = ? AND parent_id IS NULL", folderName).First(&folder).Error; err != nil {
-		folder = models.DMSFolder{
-			ID:    uuid.New().String(),
-			Name:  folderName,
-			Color: "#3b82f6", // Blue
-		}
-		server.DB.Create(&folder)
+	form, ok := server.findGoForm(slug)
+	if !ok || !form.IsActive {
+		http.Redirect(w, r, "/goform?error=Formulir tidak ditemukan", http.StatusSeeOther)
+		return
 	}
 
-	// 2. Siapkan data file
-	fileID := uuid.New().String()
-	fileName := ""
-	msg := "Formulir berhasil dikirim."
-	uploadDir := filepath.Join("public", "uploads", "edoc")
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.MkdirAll(uploadDir, 0755)
+	// Peminjaman aset memiliki alur persetujuan sendiri; PDF dibuat saat disetujui
+	if form.Handler == "loan" {
+		server.submitAssetLoan(w, r, form)
+		return
 	}
-	physicalPath := filepath.Join(uploadDir, fileID+".pdf")
 
-	if id == "form-bast" || id == "form-bast-laptop" {
-		// Collect BAST Data
-		var data BASTData
+	// Surat jalan dicatat sebagai perpindahan aset yang menunggu konfirmasi penerimaan
+	if form.Handler == "transfer" {
+		server.submitAssetTransfer(w, r, form)
+		return
+	}
 
-		p1ID := r.FormValue("p1_employee_id")
-		p2ID := r.FormValue("p2_employee_id")
-		dateStr := r.FormValue("handover_date")
-		data.Notes = r.FormValue("notes")
+	adminID, _, role, _ := GetCurrentAdmin(r)
 
-		if dateStr != "" {
-			data.HandoverDate, _ = time.Parse("2006-01-02", dateStr)
-		} else {
-			data.HandoverDate = time.Now()
+	var doc goFormDocument
+	switch form.Handler {
+	case "bast", "bast-laptop", "bast-return":
+		// BAST memindahkan pemegang aset, sehingga butuh izin serah terima dan seluruh pihak serta aset
+		// harus berada di dalam cakupan data admin
+		if !server.hasPermission(role, "assignment.manage") {
+			http.Error(w, "Access Denied: You don't have permission to access this resource", http.StatusForbidden)
+			return
+		}
+		if !server.bastAllowed(server.dataScope(r), r.Form) {
+			forbidScope(w)
+			return
 		}
 
-		// Fetch P1 and P2
-		server.DB.Where("id = ?", p1ID).First(&data.P1)
-		server.DB.Where("id = ?", p2ID).First(&data.P2)
+		var errs []string
+		doc, errs = server.prepareBASTDocument(form, r.Form)
 
-		// Fetch Selected Assets
-		assetIDs := r.Form["selected_asset_ids[]"]
-		if len(assetIDs) > 0 {
-			server.DB.Where("id IN ?", assetIDs).Find(&data.Items)
+		// Tanda tangan jarak jauh: para pihak menandatangani dari perangkat masing-masing melalui tautan
+		remote := r.FormValue("remote_sign") == "1"
+		if !remote && (r.FormValue("sig_p1_data") == "" || r.FormValue("sig_p2_data") == "") {
+			errs = append(errs, "Tanda tangan kedua pihak wajib diisi")
+		}
+		if len(errs) > 0 {
+			redirect := "/goform/fill/" + form.Slug + "?error=" + url.QueryEscape(strings.Join(errs, "; "))
+			if form.Handler == "bast-return" {
+				redirect += "&employee_id=" + url.QueryEscape(r.FormValue("p1_employee_id"))
+			}
+			http.Redirect(w, r, redirect, http.StatusSeeOther)
+			return
 		}
+		if remote {
+			server.createSignatureRequest(w, r, form, doc)
+			return
+		}
+	default:
+		// Validasi isian form dinamis sebelum membuat dokumen apa pun
+		answers, errs := server.validateGoForm(form, r)
+		if len(errs) > 0 {
+			http.Redirect(w, r, "/goform/fill/"+form.Slug+"?error="+url.QueryEscape(strings.Join(errs, "; ")), http.StatusSeeOther)
+			return
+		}
+		doc = server.prepareFormDocument(form, answers, server.adminUser(adminID))
+	}
 
-		// Signature Data
-		data.SigP1Data = r.FormValue("sig_p1_data")
-		data.SigP2Data = r.FormValue("sig_p2_data")
+	docNumber, _, err := server.issueGoFormDocument(form, doc, adminID, nil)
+	if err != nil {
+		http.Error(w, "Failed to generate PDF: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		pdfTitle := "BERITA ACARA SERAH TERIMA"
-		if id == "form-bast-laptop" {
-			pdfTitle = "BERITA ACARA SERAH TERIMA\nLAPTOP/KOMPUTER"
+	http.Redirect(w, r, "/goform?msg="+url.QueryEscape(doc.Message+" Nomor: "+docNumber), http.StatusSeeOther)
+}
 
-			// Create/Find Subfolder "BAST Laptop/Komputer"
-			var subFolder models.DMSFolder
-			subFolderName := "BAST Laptop/Komputer"
-			if err := server.DB.Where("name = ? AND parent_id = ?", subFolderName, folder.ID).First(&subFolder).Error; err != nil {
-				subFolder = models.DMSFolder{
-					ID:       uuid.New().String(),
-					Name:     subFolderName,
-					ParentID: &folder.ID,
-					Color:    "#ec4899", // Pink matching the icon
-				}
-				server.DB.Create(&subFolder)
-			}
-			folder = subFolder // Re-assign folder so the file is saved here
-		}
+// goFormDocument adalah dokumen GoForm yang siap diterbitkan: jenis, cabang dan tanggal menentukan nomor surat;
+// Generate membuat PDF setelah nomor dialokasikan dan Apply menyimpan perubahan data aset bersama dokumen
+type goFormDocument struct {
+	DocType   string
+	DocBranch string
+	DocDate   time.Time
+	Title     string
+	IssuedTo  string
+	Folder    models.DMSFolder
+	FileName  string
+	Message   string
+	Answers   []models.GoFormAnswer
+	Generate  func(stamp pdfStamp, outputPath string) error
+	Apply     func(tx *gorm.DB) error
+}
 
-		// Generate PDF
-		err := server.GenerateBASTPDF(data, pdfTitle, physicalPath)
+// prepareFormDocument menyiapkan surat dari form dinamis berdasarkan template PDF dan isian yang sudah divalidasi
+func (server *Server) prepareFormDocument(form models.GoForm, answers []models.GoFormAnswer, submitter models.User) goFormDocument {
+	pdfTitle := form.PDFTitle
+	if pdfTitle == "" {
+		pdfTitle = strings.ToUpper(form.Name)
+	}
+	body := renderGoFormTemplate(form.PDFTemplate, answers)
+	docDate := time.Now()
+
+	return goFormDocument{
+		DocType:   "form",
+		DocBranch: submitter.Branch.Name,
+		DocDate:   docDate,
+		Title:     pdfTitle,
+		IssuedTo:  submitter.Name,
+		Folder:    server.ensureFolder("Laporan Digital", nil, "#3b82f6"),
+		FileName:  fmt.Sprintf("Form_%s_%s.pdf", form.Slug, time.Now().Format("20060102_150405")),
+		Message:   form.Name + " berhasil dikirim dan disimpan ke eDoc.",
+		Answers:   answers,
+		Generate: func(stamp pdfStamp, outputPath string) error {
+			return server.GenerateFormPDF(pdfTitle, stamp.DocNumber, body, answers, submitter, docDate, stamp, outputPath)
+		},
+	}
+}
+
+// issueGoFormDocument mengalokasikan nomor surat, membuat PDF, menyimpan metadata DMSFile dan isian terstruktur
+// dalam satu transaksi sehingga nomor yang gagal dipakai ikut dibatalkan dan urutan tetap tanpa loncatan.
+// finish (opsional) dijalankan di transaksi yang sama setelah file tersimpan.
+func (server *Server) issueGoFormDocument(form models.GoForm, doc goFormDocument, submittedByID string, finish func(tx *gorm.DB, file models.DMSFile) error) (string, models.DMSFile, error) {
+	uploadDir := filepath.Join("public", "uploads", "edoc")
+	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
+		os.MkdirAll(uploadDir, 0755)
+	}
+	fileID := uuid.New().String()
+	physicalPath := filepath.Join(uploadDir, fileID+".pdf")
+
+	answersJSON, _ := json.Marshal(doc.Answers)
+	var docNumber string
+	var newFile models.DMSFile
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		docNumber, err = nextDocNumber(tx, doc.DocType, doc.DocBranch, doc.DocDate)
 		if err != nil {
-			http.Error(w, "Failed to generate PDF: "+err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
-
-		// Auto-assign assets to recipient (Requirement: Update asset holder on BAST submit)
-		if len(assetIDs) > 0 && p2ID != "" {
-			if err := server.DB.Model(&models.AssetKSO{}).Where("id IN ?", assetIDs).Update("user_id", p2ID).Error; err != nil {
-				fmt.Printf("[GoForm Handler] Warning: Failed to auto-assign assets to recipient: %v\n", err)
+		stamp := server.newPDFStamp(docNumber, doc.Title, doc.IssuedTo, doc.DocDate)
+		if err := doc.Generate(stamp, physicalPath); err != nil {
+			return err
+		}
+		newFile, err = server.storeGeneratedPDF(tx, fileID, doc.Folder, doc.FileName, "Digital Form", stamp)
+		if err != nil {
+			return err
+		}
+		if doc.Apply != nil {
+			if err := doc.Apply(tx); err != nil {
+				return err
 			}
 		}
-
-		prefix := "BAST"
-		if id == "form-bast-laptop" {
-			prefix = "BAST_IT"
+		if err := tx.Create(&models.GoFormSubmission{
+			ID:            uuid.New().String(),
+			FormID:        form.ID,
+			Data:          string(answersJSON),
+			FileID:        &newFile.ID,
+			SubmittedByID: submittedByID,
+		}).Error; err != nil {
+			return err
+		}
+		if finish != nil {
+			return finish(tx, newFile)
 		}
-		fileName = fmt.Sprintf("%s_%s_%s.pdf", prefix, data.P2.Name, time.Now().Format("20060102_150405"))
-		msg = "Berita Acara Serah Terima (BAST) berhasil dibuat dan disimpan ke eDoc."
-	} else {
-		fileName = fmt.Sprintf("Form_%s_%s.pdf", id, time.Now().Format("20060102_150405"))
-		// 3. Simulasikan pembuatan file fisik (.pdf dummy) untuk form lain
-		dummyContent := []byte("%PDF-1.4\n% Dummy generated file for " + fileName)
-		os.WriteFile(physicalPath, dummyContent, 0644)
+		return nil
+	})
+	if err != nil {
+		os.Remove(physicalPath)
 	}
+	return docNumber, newFile, err
+}
 
-	// 4. Simpan metadata ke DMSFile
+// storeGeneratedPDF mencatat PDF hasil generate di public/uploads/edoc/<fileID>.pdf sebagai DMSFile beserta nomor surat dan data verifikasinya
+func (server *Server) storeGeneratedPDF(tx *gorm.DB, fileID string, folder models.DMSFolder, fileName, category string, stamp pdfStamp) (models.DMSFile, error) {
 	newFile := models.DMSFile{
 		ID:         fileID,
 		FolderID:   &folder.ID,
@@ -228,15 +352,176 @@ func (server *Server) SubmitGoForm(w http.ResponseWriter, r *http.Request) {
 		Extension:  "pdf",
 		FilePath:   "/public/uploads/edoc/" + fileID + ".pdf",
 		UploadedBy: "System",
-		Category:   "Digital Form",
+		Category:   category,
+		DocNumber:  stamp.DocNumber,
 	}
 
 	// Get actual file size
-	if info, err := os.Stat(physicalPath); err == nil {
+	if info, err := os.Stat(filepath.Join("public", "uploads", "edoc", fileID+".pdf")); err == nil {
 		newFile.Size = info.Size()
 	}
 
-	server.DB.Create(&newFile)
+	if err := tx.Create(&newFile).Error; err != nil {
+		return newFile, err
+	}
+	return newFile, recordVerification(tx, stamp, fileID)
+}
+
+// findGoForm mencari definisi form berdasarkan slug beserta isiannya yang sudah terurut
+func (server *Server) findGoForm(slug string) (models.GoForm, bool) {
+	var form models.GoForm
+	server.DB.Preload("Fields", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order asc, id asc")
+	}).Where("slug = ?", slug).Limit(1).Find(&form)
+	return form, form.ID != ""
+}
+
+// validateGoForm memvalidasi isian form dinamis dan mengubahnya menjadi daftar jawaban
+func (server *Server) validateGoForm(form models.GoForm, r *http.Request) ([]models.GoFormAnswer, []string) {
+	var answers []models.GoFormAnswer
+	var errs []string
+
+	for _, field := range form.Fields {
+		value := strings.TrimSpace(r.FormValue(field.Name))
+		display := value
+
+		if field.Type == "checkbox" {
+			if value != "" {
+				value = "1"
+				display = "Ya"
+			} else {
+				display = "Tidak"
+			}
+			answers = append(answers, models.GoFormAnswer{Field: field.Name, Label: field.Label, Value: value, Display: display})
+			continue
+		}
+
+		if value == "" {
+			if field.Required {
+				errs = append(errs, field.Label+" wajib diisi")
+			}
+			answers = append(answers, models.GoFormAnswer{Field: field.Name, Label: field.Label})
+			continue
+		}
+
+		switch field.Type {
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				errs = append(errs, field.Label+" harus berupa angka")
+			}
+		case "date":
+			if t, err := time.Parse("2006-01-02", value); err != nil {
+				errs = append(errs, field.Label+" bukan tanggal yang valid")
+			} else {
+				display = translateMonth(t.Format("02 January 2006"))
+			}
+		case "email":
+			if _, err := mail.ParseAddress(value); err != nil {
+				errs = append(errs, field.Label+" bukan email yang valid")
+			}
+		case "select":
+			found := false
+			for _, opt := range server.goFormOptions(field) {
+				if opt.Value == value {
+					found = true
+					display = opt.Label
+					break
+				}
+			}
+			if !found {
+				errs = append(errs, field.Label+" tidak ada dalam pilihan")
+			}
+		}
+
+		if field.Pattern != "" {
+			if re, err := regexp.Compile("^(?:" + field.Pattern + ")$"); err == nil && !re.MatchString(value) {
+				errs = append(errs, field.Label+" tidak sesuai format")
+			}
+		}
+
+		answers = append(answers, models.GoFormAnswer{Field: field.Name, Label: field.Label, Value: value, Display: display})
+	}
+
+	return answers, errs
+}
+
+// goFormOptions mengambil pilihan isian select dari opsi statis atau master data
+func (server *Server) goFormOptions(field models.GoFormField) []goFormOption {
+	var options []goFormOption
+
+	switch field.OptionSource {
+	case "branch":
+		var rows []models.MasterBranch
+		server.DB.Order("name asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.Name, Label: row.Name})
+		}
+	case "department":
+		var rows []models.MasterDepartment
+		server.DB.Order("name asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.Name, Label: row.Name})
+		}
+	case "sub_department":
+		var rows []models.MasterSubDepartment
+		server.DB.Order("name asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.Name, Label: row.Name})
+		}
+	case "position":
+		var rows []models.MasterPosition
+		server.DB.Order("name asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.Name, Label: row.Name})
+		}
+	case "asset_category":
+		var rows []models.MasterAssetCategory
+		server.DB.Order("name asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.Name, Label: row.Name})
+		}
+	case "employee":
+		var rows []models.User
+		server.DB.Order("name asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.ID, Label: fmt.Sprintf("%s (%s)", row.Name, row.NIK)})
+		}
+	case "asset":
+		var rows []models.AssetKSO
+		server.DB.Order("inventory_number asc").Find(&rows)
+		for _, row := range rows {
+			options = append(options, goFormOption{Value: row.ID, Label: row.InventoryNumber + " - " + row.AssetName})
+		}
+	default:
+		// Opsi statis: satu per baris, format "nilai" atau "nilai|label"
+		for _, line := range strings.Split(field.Options, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			value, label, found := strings.Cut(line, "|")
+			if !found {
+				label = value
+			}
+			options = append(options, goFormOption{Value: strings.TrimSpace(value), Label: strings.TrimSpace(label)})
+		}
+	}
+
+	return options
+}
 
-	http.Redirect(w, r, "/goform?msg="+msg, http.StatusSeeOther)
+// renderGoFormTemplate mengganti placeholder {nama_field} pada template PDF dengan jawaban pengisi
+func renderGoFormTemplate(tmpl string, answers []models.GoFormAnswer) string {
+	if tmpl == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(answers)*2)
+	for _, a := range answers {
+		display := a.Display
+		if display == "" {
+			display = "-"
+		}
+		pairs = append(pairs, "{"+a.Field+"}", display)
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
 }
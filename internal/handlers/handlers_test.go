@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/database/seeders"
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/glebarez/sqlite"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// templatesDir adalah folder templates repository
+var templatesDir string
+
+// TestMain menjalankan test dari folder sementara yang menautkan aset repository, sehingga file yang
+// diunggah atau dibuat test tidak tertulis ke public/uploads milik repository
+func TestMain(m *testing.M) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		panic(err)
+	}
+	dir, err := os.MkdirTemp("", "gokso-test-")
+	if err != nil {
+		panic(err)
+	}
+	templatesDir = filepath.Join(root, "templates")
+	setup := []error{
+		os.MkdirAll(filepath.Join(dir, "public", "uploads", "edoc"), 0755),
+		os.Symlink(filepath.Join(root, "public", "assets"), filepath.Join(dir, "public", "assets")),
+		os.Chdir(dir),
+	}
+	for _, err := range setup {
+		if err != nil {
+			panic(err)
+		}
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestServer menyiapkan Server dengan database SQLite sementara, izin role bawaan dan satu super_admin
+func newTestServer(tb testing.TB) *Server {
+	tb.Helper()
+	db, err := gorm.Open(sqlite.Open(tb.TempDir()+"/test.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, m := range models.RegisterModels() {
+		if err := db.AutoMigrate(m.Model); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := seeders.SeedPermissions(db); err != nil {
+		tb.Fatal(err)
+	}
+	db.Create(&models.User{ID: "u1", NIK: "1001", Name: "Budi", Email: "budi@example.com", Password: "x"})
+	db.Create(&models.Admin{ID: "a1", UserID: "u1", Username: "admin", Password: "x", Role: models.RoleSuperAdmin})
+
+	server := &Server{DB: db, Router: mux.NewRouter(), Renderer: newRenderer(templatesDir), Lockout: defaultLoginLockout}
+	store = newDBSessionStore(db, []byte("0123456789abcdef0123456789abcdef"), 2*time.Hour, false)
+	server.initializeRoutes()
+	return server
+}
+
+// testClient mengirim request ke router dengan cookie session admin tertentu
+type testClient struct {
+	server *Server
+	cookie string
+}
+
+// loginAs membuat session untuk admin dan mengembalikan client yang memakainya
+func loginAs(tb testing.TB, server *Server, admin models.Admin) *testClient {
+	tb.Helper()
+	return loginSession(tb, server, admin, nil)
+}
+
+// loginImpersonating membuat session super_admin impersonator yang sedang melihat sebagai admin
+func loginImpersonating(tb testing.TB, server *Server, impersonator, admin models.Admin) *testClient {
+	tb.Helper()
+	return loginSession(tb, server, admin, map[string]interface{}{
+		"impersonator_id":       impersonator.ID,
+		"impersonator_username": impersonator.Username,
+	})
+}
+
+// loginSession menyimpan session admin beserta nilai tambahan lalu mengembalikan client yang memakainya
+func loginSession(tb testing.TB, server *Server, admin models.Admin, extra map[string]interface{}) *testClient {
+	tb.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	session, _ := store.Get(r, sessionCookieName)
+	session.Values["admin_id"] = admin.ID
+	session.Values["admin_username"] = admin.Username
+	session.Values["admin_role"] = admin.Role
+	for key, value := range extra {
+		session.Values[key] = value
+	}
+	if err := session.Save(r, w); err != nil {
+		tb.Fatal(err)
+	}
+	return &testClient{server: server, cookie: strings.Split(w.Header().Get("Set-Cookie"), ";")[0]}
+}
+
+// request membuat request dengan cookie session; form diisi token CSRF bila belum ada
+func (c *testClient) request(method, path string, form url.Values) *http.Request {
+	if form == nil {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("Cookie", c.cookie)
+		return r
+	}
+	if form.Get("csrf_token") == "" {
+		probe := httptest.NewRequest(http.MethodGet, "/", nil)
+		probe.Header.Set("Cookie", c.cookie)
+		form.Set("csrf_token", csrfToken(probe))
+	}
+	r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Cookie", c.cookie)
+	return r
+}
+
+// do menjalankan request melalui router
+func (c *testClient) do(method, path string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c.server.Router.ServeHTTP(w, c.request(method, path, form))
+	return w
+}
+
+// queryCounter menghitung query SELECT per tabel yang dijalankan gorm
+type queryCounter struct {
+	mu     sync.Mutex
+	total  int
+	tables map[string]int
+}
+
+// countQueries memasang queryCounter pada database server
+func countQueries(tb testing.TB, db *gorm.DB) *queryCounter {
+	tb.Helper()
+	c := &queryCounter{tables: make(map[string]int)}
+	if err := db.Callback().Query().After("gorm:query").Register("test:count_query", c.record); err != nil {
+		tb.Fatal(err)
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("test:count_row", c.record); err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+func (c *queryCounter) record(db *gorm.DB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total++
+	c.tables[db.Statement.Table]++
+}
+
+// reset mengosongkan hitungan
+func (c *queryCounter) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total = 0
+	c.tables = make(map[string]int)
+}
+
+// count mengembalikan jumlah query ke satu tabel
+func (c *queryCounter) count(table string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tables[table]
+}
+
+// sum mengembalikan jumlah seluruh query
+func (c *queryCounter) sum() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
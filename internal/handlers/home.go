@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
 )
 
 // Home menampilkan halaman dashboard dengan ringkasan statistik aset dan karyawan
@@ -13,10 +14,14 @@ func (server *Server) Home(w http.ResponseWriter, r *http.Request) {
 	var brokenAssets int64
 	var totalEmployees int64
 
-	server.DB.Model(&models.AssetKSO{}).Count(&totalAssets)
-	server.DB.Model(&models.AssetKSO{}).Where("status = ?", "Ready").Count(&readyAssets)
-	server.DB.Model(&models.AssetKSO{}).Where("status = ?", "Rusak").Count(&brokenAssets)
-	server.DB.Model(&models.User{}).Count(&totalEmployees)
+	// Statistik mengikuti cakupan data admin
+	scope := server.dataScope(r)
+	assets := func() *gorm.DB { return scope.applyAssets(server.DB.Model(&models.AssetKSO{})) }
+
+	assets().Count(&totalAssets)
+	assets().Where("status = ?", "Ready").Count(&readyAssets)
+	assets().Where("status = ?", "Rusak").Count(&brokenAssets)
+	scope.applyUsers(server.DB.Model(&models.User{})).Count(&totalEmployees)
 
 	// Get assets by category for chart
 	type CategoryStat struct {
@@ -24,7 +29,7 @@ func (server *Server) Home(w http.ResponseWriter, r *http.Request) {
 		Count    int64
 	}
 	var categoryStats []CategoryStat
-	server.DB.Model(&models.AssetKSO{}).Select("category, count(*) as count").Group("category").Scan(&categoryStats)
+	assets().Select("category, count(*) as count").Group("category").Scan(&categoryStats)
 
 	// Get assets by status for chart
 	type StatusStat struct {
@@ -32,7 +37,7 @@ func (server *Server) Home(w http.ResponseWriter, r *http.Request) {
 		Count  int64
 	}
 	var statusStats []StatusStat
-	server.DB.Model(&models.AssetKSO{}).Select("status, count(*) as count").Group("status").Scan(&statusStats)
+	assets().Select("status, count(*) as count").Group("status").Scan(&statusStats)
 
 	server.RenderHTML(w, r, http.StatusOK, "home", map[string]interface{}{
 		"title":          "Dashboard",
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+// Aksi yang dicatat di audit log
+const (
+	auditImpersonateStart   = "impersonate.start"
+	auditImpersonateStop    = "impersonate.stop"
+	auditImpersonateRequest = "impersonate.request"
+	auditImpersonateBlocked = "impersonate.blocked"
+)
+
+// auditActionLabels adalah keterangan aksi audit log untuk ditampilkan
+var auditActionLabels = map[string]string{
+	auditImpersonateStart:   "Mulai lihat sebagai",
+	auditImpersonateStop:    "Selesai lihat sebagai",
+	auditImpersonateRequest: "Akses saat lihat sebagai",
+	auditImpersonateBlocked: "Tindakan diblokir",
+}
+
+// impersonatorOf mengembalikan super_admin yang sedang memakai mode "lihat sebagai" pada session request ini
+func impersonatorOf(r *http.Request) (adminID string, username string, ok bool) {
+	session, err := store.Get(r, sessionCookieName)
+	if err != nil {
+		return "", "", false
+	}
+	adminID, _ = session.Values["impersonator_id"].(string)
+	username, _ = session.Values["impersonator_username"].(string)
+	return adminID, username, adminID != ""
+}
+
+// recordAudit mencatat tindakan ke audit log. Selama mode "lihat sebagai", pelakunya adalah
+// super_admin pemilik session dan akun yang sedang dilihat dicatat sebagai target.
+func (server *Server) recordAudit(r *http.Request, action string, detail string) {
+	adminID, username, _, _ := GetCurrentAdmin(r)
+	entry := models.AuditLog{
+		AdminID:  adminID,
+		Username: username,
+		Action:   action,
+		Detail:   truncate(detail, 255),
+		IP:       clientIP(r),
+	}
+	if impersonatorID, impersonatorName, ok := impersonatorOf(r); ok {
+		entry.AdminID, entry.Username = impersonatorID, impersonatorName
+		entry.TargetAdminID, entry.TargetUsername = adminID, username
+	}
+	server.DB.Create(&entry)
+}
+
+// StartImpersonation mengalihkan session super_admin agar melihat aplikasi sebagai admin lain
+// untuk menelusuri masalah. Izin, cakupan data dan menu mengikuti akun yang dilihat.
+func (server *Server) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	p := server.currentPrincipal(r)
+	if p == nil || p.Admin.Role != models.RoleSuperAdmin {
+		http.Error(w, "Access Denied: Only super_admin can view as another user", http.StatusForbidden)
+		return
+	}
+
+	var target models.Admin
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&target)
+	var reason string
+	switch {
+	case target.ID == "":
+		reason = "User tidak ditemukan"
+	case target.ID == p.Admin.ID:
+		reason = "Tidak dapat melihat sebagai akun sendiri"
+	case target.Role == models.RoleSuperAdmin:
+		reason = "Tidak dapat melihat sebagai sesama super_admin"
+	case target.Disabled:
+		reason = "Akun " + target.Username + " sedang nonaktif"
+	}
+	if reason != "" {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(reason), http.StatusSeeOther)
+		return
+	}
+
+	session, _ := store.Get(r, sessionCookieName)
+	session.Values["impersonator_id"] = p.Admin.ID
+	session.Values["impersonator_username"] = p.Admin.Username
+	session.Values["impersonator_role"] = p.Admin.Role
+	session.Values["admin_id"] = target.ID
+	session.Values["admin_username"] = target.Username
+	session.Values["admin_role"] = target.Role
+	if err := session.Save(r, w); err != nil {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Gagal memulai mode lihat sebagai: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	server.recordAudit(r, auditImpersonateStart, "Role "+target.Role)
+
+	// Akun tanpa akses dashboard diarahkan ke profil agar banner dan tombol keluar tetap terlihat
+	if server.GetPermissions(target.Role)["dashboard.view"] {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// StopImpersonation mengakhiri mode "lihat sebagai" dan mengembalikan session ke akun super_admin
+func (server *Server) StopImpersonation(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := impersonatorOf(r); !ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	server.recordAudit(r, auditImpersonateStop, "")
+	session, _ := store.Get(r, sessionCookieName)
+	restoreImpersonator(session)
+	if err := session.Save(r, w); err != nil {
+		http.Error(w, "Gagal mengakhiri mode lihat sebagai: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/setting/user?msg="+url.QueryEscape("Mode lihat sebagai telah diakhiri"), http.StatusSeeOther)
+}
+
+// endStaleImpersonation mengakhiri mode "lihat sebagai" bila akun yang dilihat sudah dihapus atau
+// dinonaktifkan, agar super_admin tidak terjebak di session yang tidak bisa dipakai
+func (server *Server) endStaleImpersonation(w http.ResponseWriter, r *http.Request) bool {
+	if _, _, ok := impersonatorOf(r); !ok {
+		return false
+	}
+	server.recordAudit(r, auditImpersonateStop, "Akun yang dilihat sudah dihapus atau dinonaktifkan")
+	session, _ := store.Get(r, sessionCookieName)
+	restoreImpersonator(session)
+	return session.Save(r, w) == nil
+}
+
+// restoreImpersonator mengembalikan identitas super_admin ke session dan menghapus penanda mode "lihat sebagai"
+func restoreImpersonator(session *sessions.Session) {
+	session.Values["admin_id"] = session.Values["impersonator_id"]
+	session.Values["admin_username"] = session.Values["impersonator_username"]
+	session.Values["admin_role"] = session.Values["impersonator_role"]
+	delete(session.Values, "impersonator_id")
+	delete(session.Values, "impersonator_username")
+	delete(session.Values, "impersonator_role")
+}
+
+// NotImpersonating adalah middleware untuk tindakan sensitif (ganti password, 2FA, hapus permanen,
+// pengelolaan user dan role) yang tidak boleh dilakukan selama mode "lihat sebagai"
+func (server *Server) NotImpersonating(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := impersonatorOf(r); ok {
+			server.recordAudit(r, auditImpersonateBlocked, r.Method+" "+r.URL.Path)
+			http.Error(w, "Access Denied: This action is not available while viewing as another user", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// AuditImpersonation adalah middleware router yang mencatat setiap request selama mode "lihat sebagai",
+// termasuk GET karena sebagian rute hapus masih memakai GET, sehingga semua tindakan atas nama akun lain
+// dapat ditelusuri. File statis tidak dicatat.
+func (server *Server) AuditImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/public/") && r.URL.Path != "/impersonate/exit" {
+			if _, _, ok := impersonatorOf(r); ok {
+				server.recordAudit(r, auditImpersonateRequest, r.Method+" "+r.URL.Path)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// sessionIdentity mengembalikan admin aktif dan super_admin impersonator pada session milik cookie
+func sessionIdentity(tb testing.TB, cookie string) (adminID, impersonatorID string) {
+	tb.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", cookie)
+	session, err := store.New(r, sessionCookieName)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	adminID, _ = session.Values["admin_id"].(string)
+	impersonatorID, _ = session.Values["impersonator_id"].(string)
+	return adminID, impersonatorID
+}
+
+// auditEntries mengembalikan audit log berurutan untuk satu aksi
+func auditEntries(server *Server, action string) []models.AuditLog {
+	var entries []models.AuditLog
+	server.DB.Where("action = ?", action).Order("id").Find(&entries)
+	return entries
+}
+
+func TestImpersonationStartAndStop(t *testing.T) {
+	server := newTestServer(t)
+	createRole(t, server, "hr", "dashboard.view", "employee.view")
+	scopedAdmin(t, server, "h1", "hr")
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	w := client.do(http.MethodPost, "/setting/user/impersonate/h1", url.Values{})
+	if loc := w.Header().Get("Location"); loc != "/" {
+		t.Fatalf("start redirect = %q, want /", loc)
+	}
+	if adminID, impersonatorID := sessionIdentity(t, client.cookie); adminID != "h1" || impersonatorID != "a1" {
+		t.Fatalf("session = %q as %q, want a1 viewing as h1", impersonatorID, adminID)
+	}
+	start := auditEntries(server, auditImpersonateStart)
+	if len(start) != 1 || start[0].AdminID != "a1" || start[0].TargetAdminID != "h1" {
+		t.Errorf("start audit = %+v", start)
+	}
+
+	// Izin mengikuti akun yang dilihat dan setiap request tercatat atas nama super_admin
+	if w := client.do(http.MethodGet, "/setting/user", nil); w.Code != http.StatusForbidden {
+		t.Errorf("user settings while viewing as hr = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	requests := auditEntries(server, auditImpersonateRequest)
+	if len(requests) == 0 || requests[len(requests)-1].Detail != "GET /setting/user" || requests[len(requests)-1].AdminID != "a1" {
+		t.Errorf("request audit = %+v", requests)
+	}
+
+	w = client.do(http.MethodPost, "/impersonate/exit", url.Values{})
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/setting/user?msg=") {
+		t.Fatalf("exit redirect = %q", loc)
+	}
+	if adminID, impersonatorID := sessionIdentity(t, client.cookie); adminID != "a1" || impersonatorID != "" {
+		t.Errorf("session after exit = %q as %q, want a1", impersonatorID, adminID)
+	}
+	if stop := auditEntries(server, auditImpersonateStop); len(stop) != 1 || stop[0].TargetAdminID != "h1" {
+		t.Errorf("stop audit = %+v", stop)
+	}
+	if w := client.do(http.MethodGet, "/setting/user", nil); w.Code != http.StatusOK {
+		t.Errorf("user settings after exit = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestStartImpersonationRejectsTargets(t *testing.T) {
+	server := newTestServer(t)
+	scopedAdmin(t, server, "h1", "hr")
+	server.DB.Create(&models.Admin{ID: "a2", Username: "root2", Password: "x", Role: models.RoleSuperAdmin})
+	server.DB.Create(&models.Admin{ID: "d1", Username: "mantan", Password: "x", Role: "hr", Disabled: true})
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	for id, want := range map[string]string{
+		"nope": "User tidak ditemukan",
+		"a1":   "Tidak dapat melihat sebagai akun sendiri",
+		"a2":   "Tidak dapat melihat sebagai sesama super_admin",
+		"d1":   "Akun mantan sedang nonaktif",
+	} {
+		w := client.do(http.MethodPost, "/setting/user/impersonate/"+id, url.Values{})
+		if msg := redirectError(t, w.Header().Get("Location")); msg != want {
+			t.Errorf("impersonate %s: error = %q, want %q", id, msg, want)
+		}
+	}
+	if adminID, impersonatorID := sessionIdentity(t, client.cookie); adminID != "a1" || impersonatorID != "" {
+		t.Errorf("session = %q as %q after rejected attempts", impersonatorID, adminID)
+	}
+
+	// Pengelola user yang bukan super_admin tidak bisa memakai mode "lihat sebagai"
+	manager, _ := userManager(t, server)
+	if w := manager.do(http.MethodPost, "/setting/user/impersonate/h1", url.Values{}); w.Code != http.StatusForbidden {
+		t.Errorf("impersonate by user manager = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestImpersonationBlocksSensitiveActions(t *testing.T) {
+	server := newTestServer(t)
+	viewed := sessionAdmin(t, server, "s1", "sinta")
+	server.DB.Model(&viewed).Update("role", "hr")
+	viewed.Role = "hr"
+	client := loginImpersonating(t, server, models.Admin{ID: "a1", Username: "admin"}, viewed)
+
+	w := client.do(http.MethodPost, "/profile/password", url.Values{
+		"old_password": {"rahasia"}, "new_password": {"baru"}, "confirm_password": {"baru"},
+	})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("password change while impersonating = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "s1")
+	if admin.Password != viewed.Password {
+		t.Error("password changed while impersonating")
+	}
+	blocked := auditEntries(server, auditImpersonateBlocked)
+	if len(blocked) != 1 || blocked[0].AdminID != "a1" || blocked[0].TargetAdminID != "s1" || blocked[0].Detail != "POST /profile/password" {
+		t.Errorf("blocked audit = %+v", blocked)
+	}
+}
+
+func TestImpersonationEndsWhenTargetRemoved(t *testing.T) {
+	server := newTestServer(t)
+	createRole(t, server, "hr", "dashboard.view", "employee.view")
+	viewed := scopedAdmin(t, server, "h1", "hr")
+	client := loginImpersonating(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin}, viewed)
+	server.DB.Model(&viewed).Update("disabled", true)
+
+	w := client.do(http.MethodGet, "/administration/employee", nil)
+	if msg := redirectError(t, w.Header().Get("Location")); msg != "Akun yang dilihat sudah dihapus atau dinonaktifkan" {
+		t.Fatalf("redirect = %q", w.Header().Get("Location"))
+	}
+	if adminID, impersonatorID := sessionIdentity(t, client.cookie); adminID != "a1" || impersonatorID != "" {
+		t.Errorf("session = %q as %q, want the super_admin restored", impersonatorID, adminID)
+	}
+}
+
+func TestImpersonationSessionBelongsToImpersonator(t *testing.T) {
+	server := newTestServer(t)
+	viewed := scopedAdmin(t, server, "h1", "hr")
+	client := loginImpersonating(t, server, models.Admin{ID: "a1", Username: "admin"}, viewed)
+
+	if n := sessionCount(server, "a1"); n != 1 {
+		t.Fatalf("%d sessions stored for the impersonator, want 1", n)
+	}
+	// Mencabut session super_admin ikut mengakhiri mode "lihat sebagai"
+	if err := revokeAdminSessions(server.DB, "a1"); err != nil {
+		t.Fatal(err)
+	}
+	if adminID, _ := sessionIdentity(t, client.cookie); adminID != "" {
+		t.Errorf("impersonation session of %q survived revocation of the impersonator", adminID)
+	}
+}
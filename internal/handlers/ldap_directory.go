@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/config"
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	errDirectoryInvalidCredentials = errors.New("username atau password direktori salah")
+	errDirectoryUserNotFound       = errors.New("pengguna tidak ditemukan di direktori")
+	errDirectoryUnavailable        = errors.New("server direktori tidak dapat dihubungi")
+)
+
+// directoryUser adalah entri pengguna di direktori perusahaan beserta role gokso hasil pemetaan grup
+type directoryUser struct {
+	DN       string
+	Name     string
+	Email    string
+	NIK      string
+	Groups   []string
+	Role     string // kosong bila tidak ada grup yang dipetakan ke role gokso
+	Disabled bool
+}
+
+// directory adalah sumber autentikasi admin di luar tabel admins (LDAP / Active Directory).
+// Dibuat sebagai interface agar bisa diganti server uji atau direktori lain.
+type directory interface {
+	// Authenticate memverifikasi password pengguna dengan bind ke direktori
+	Authenticate(username, password string) (*directoryUser, error)
+	// Lookup mencari pengguna memakai akun layanan, dipakai saat sinkronisasi
+	Lookup(username string) (*directoryUser, error)
+}
+
+// ldapGroupRole memetakan satu grup direktori ke role gokso
+type ldapGroupRole struct {
+	Role    string
+	GroupDN string
+}
+
+// ldapDirectory adalah directory berbasis protokol LDAP (OpenLDAP maupun Active Directory)
+type ldapDirectory struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+	GroupFilter  string
+	NIKAttr      string
+	GroupRoles   []ldapGroupRole
+	Timeout      time.Duration
+}
+
+// newLDAPDirectory menyiapkan directory dari konfigurasi env
+func newLDAPDirectory(appConfig config.AppConfig) (*ldapDirectory, error) {
+	d := &ldapDirectory{
+		URL:          appConfig.LDAPURL,
+		StartTLS:     appConfig.LDAPStartTLS,
+		BindDN:       appConfig.LDAPBindDN,
+		BindPassword: appConfig.LDAPBindPassword,
+		BaseDN:       appConfig.LDAPBaseDN,
+		UserFilter:   appConfig.LDAPUserFilter,
+		GroupFilter:  appConfig.LDAPGroupFilter,
+		NIKAttr:      appConfig.LDAPNIKAttr,
+		Timeout:      10 * time.Second,
+	}
+	if d.BaseDN == "" {
+		return nil, fmt.Errorf("LDAP_BASE_DN wajib diisi")
+	}
+	if strings.Count(d.UserFilter, "%s") != 1 {
+		return nil, fmt.Errorf("LDAP_USER_FILTER harus berisi tepat satu %%s")
+	}
+	if d.GroupFilter != "" && !strings.Contains(d.GroupFilter, "%s") {
+		return nil, fmt.Errorf("LDAP_GROUP_FILTER harus berisi %%s")
+	}
+
+	roles, err := parseLDAPGroupRoles(appConfig.LDAPGroupRoles)
+	if err != nil {
+		return nil, err
+	}
+	d.GroupRoles = roles
+	return d, nil
+}
+
+// parseLDAPGroupRoles membaca format "role=DN grup;role=DN grup"
+func parseLDAPGroupRoles(value string) ([]ldapGroupRole, error) {
+	var roles []ldapGroupRole
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		role, groupDN, ok := strings.Cut(part, "=")
+		role, groupDN = strings.TrimSpace(role), strings.TrimSpace(groupDN)
+		if !ok || groupDN == "" {
+			return nil, fmt.Errorf("LDAP_GROUP_ROLES tidak valid: %q (format role=DN grup)", part)
+		}
+		if _, err := ldap.ParseDN(groupDN); err != nil {
+			return nil, fmt.Errorf("DN grup untuk role %s tidak valid: %v", role, err)
+		}
+		roles = append(roles, ldapGroupRole{Role: role, GroupDN: groupDN})
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("LDAP_GROUP_ROLES wajib diisi agar pengguna direktori mendapat role")
+	}
+	return roles, nil
+}
+
+// connect membuka koneksi ke server LDAP dan bind memakai akun layanan (atau anonim bila tidak diisi)
+func (d *ldapDirectory) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(d.URL, ldap.DialWithDialer(&net.Dialer{Timeout: d.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errDirectoryUnavailable, err)
+	}
+	conn.SetTimeout(d.Timeout)
+
+	if d.StartTLS {
+		host := d.URL
+		if u, err := url.Parse(d.URL); err == nil {
+			host = u.Hostname()
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: StartTLS gagal: %v", errDirectoryUnavailable, err)
+		}
+	}
+
+	if d.BindDN != "" {
+		if err := conn.Bind(d.BindDN, d.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: bind akun layanan gagal: %v", errDirectoryUnavailable, err)
+		}
+	}
+	return conn, nil
+}
+
+// userFilter mengisi LDAP_USER_FILTER dengan username yang sudah di-escape
+func (d *ldapDirectory) userFilter(username string) string {
+	return fmt.Sprintf(d.UserFilter, ldap.EscapeFilter(username))
+}
+
+// groupFilter mengisi LDAP_GROUP_FILTER dengan DN pengguna yang sudah di-escape
+func (d *ldapDirectory) groupFilter(userDN string) string {
+	return strings.ReplaceAll(d.GroupFilter, "%s", ldap.EscapeFilter(userDN))
+}
+
+// search mencari satu entri pengguna berdasarkan username beserta grupnya
+func (d *ldapDirectory) search(conn *ldap.Conn, username string) (*directoryUser, error) {
+	attrs := []string{"dn", "cn", "displayName", "mail", "memberOf", "userAccountControl", "pwdAccountLockedTime", "nsAccountLock"}
+	if d.NIKAttr != "" {
+		attrs = append(attrs, d.NIKAttr)
+	}
+	req := ldap.NewSearchRequest(
+		d.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(d.Timeout.Seconds()), false,
+		d.userFilter(username), attrs, nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, errDirectoryUserNotFound
+		}
+		return nil, fmt.Errorf("%w: pencarian pengguna gagal: %v", errDirectoryUnavailable, err)
+	}
+	// Username yang cocok dengan lebih dari satu entri ditolak agar tidak salah orang
+	if len(res.Entries) != 1 {
+		return nil, errDirectoryUserNotFound
+	}
+
+	entry := res.Entries[0]
+	user := &directoryUser{
+		DN:     entry.DN,
+		Name:   entry.GetAttributeValue("displayName"),
+		Email:  strings.ToLower(strings.TrimSpace(entry.GetAttributeValue("mail"))),
+		Groups: entry.GetAttributeValues("memberOf"),
+	}
+	if user.Name == "" {
+		user.Name = entry.GetAttributeValue("cn")
+	}
+	if d.NIKAttr != "" {
+		user.NIK = strings.TrimSpace(entry.GetAttributeValue(d.NIKAttr))
+	}
+
+	// Akun nonaktif: flag ACCOUNTDISABLE di Active Directory, ppolicy OpenLDAP, atau nsAccountLock (389 DS)
+	if uac, err := strconv.Atoi(entry.GetAttributeValue("userAccountControl")); err == nil && uac&0x2 != 0 {
+		user.Disabled = true
+	}
+	if entry.GetAttributeValue("pwdAccountLockedTime") != "" || strings.EqualFold(entry.GetAttributeValue("nsAccountLock"), "true") {
+		user.Disabled = true
+	}
+
+	// Direktori tanpa overlay memberOf: cari grup yang memuat DN pengguna
+	if d.GroupFilter != "" {
+		groupReq := ldap.NewSearchRequest(
+			d.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(d.Timeout.Seconds()), false,
+			d.groupFilter(entry.DN), []string{"dn"}, nil,
+		)
+		groups, err := conn.Search(groupReq)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pencarian grup gagal: %v", errDirectoryUnavailable, err)
+		}
+		for _, g := range groups.Entries {
+			user.Groups = append(user.Groups, g.DN)
+		}
+	}
+
+	user.Role = d.roleFor(user.Groups)
+	return user, nil
+}
+
+// roleFor memilih role gokso dari grup pengguna; pemetaan pertama yang cocok menang
+func (d *ldapDirectory) roleFor(groups []string) string {
+	for _, mapping := range d.GroupRoles {
+		want, err := ldap.ParseDN(mapping.GroupDN)
+		if err != nil {
+			continue
+		}
+		for _, group := range groups {
+			if dn, err := ldap.ParseDN(group); err == nil && want.EqualFold(dn) {
+				return mapping.Role
+			}
+		}
+	}
+	return ""
+}
+
+// Authenticate mencari entri pengguna lalu bind memakai DN dan password pengguna tersebut
+func (d *ldapDirectory) Authenticate(username, password string) (*directoryUser, error) {
+	// Password kosong akan menjadi unauthenticated bind yang selalu "berhasil" di banyak server
+	if username == "" || password == "" {
+		return nil, errDirectoryInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	user, err := d.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(user.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errDirectoryInvalidCredentials
+		}
+		return nil, fmt.Errorf("%w: %v", errDirectoryUnavailable, err)
+	}
+	return user, nil
+}
+
+// Lookup mencari pengguna memakai akun layanan tanpa memverifikasi password
+func (d *ldapDirectory) Lookup(username string) (*directoryUser, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return d.search(conn, username)
+}
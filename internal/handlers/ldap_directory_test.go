@@ -0,0 +1,40 @@
+package handlers
+
+import "testing"
+
+func TestLDAPFiltersEscapeInput(t *testing.T) {
+	d := &ldapDirectory{UserFilter: "(&(objectClass=person)(uid=%s))", GroupFilter: "(|(member=%s)(uniqueMember=%s))"}
+
+	if got, want := d.userFilter("x*)(uid=*"), `(&(objectClass=person)(uid=x\2a\29\28uid=\2a))`; got != want {
+		t.Errorf("userFilter = %q, want %q", got, want)
+	}
+	if got, want := d.groupFilter(`cn=a(b)\,dc=example`), `(|(member=cn=a\28b\29\5c,dc=example)(uniqueMember=cn=a\28b\29\5c,dc=example))`; got != want {
+		t.Errorf("groupFilter = %q, want %q", got, want)
+	}
+}
+
+func TestLDAPRoleForGroups(t *testing.T) {
+	roles, err := parseLDAPGroupRoles("super_admin=cn=Admins,dc=example,dc=com; asset_manager=cn=Aset,dc=example,dc=com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &ldapDirectory{GroupRoles: roles}
+
+	for name, tc := range map[string]struct {
+		groups []string
+		want   string
+	}{
+		"case and spacing": {[]string{"CN=Aset, DC=Example, DC=com"}, "asset_manager"},
+		"first mapping":    {[]string{"cn=Aset,dc=example,dc=com", "cn=Admins,dc=example,dc=com"}, "super_admin"},
+		"unmapped":         {[]string{"cn=Lain,dc=example,dc=com", "bukan dn"}, ""},
+		"no groups":        {nil, ""},
+	} {
+		if got := d.roleFor(tc.groups); got != tc.want {
+			t.Errorf("%s: roleFor = %q, want %q", name, got, tc.want)
+		}
+	}
+
+	if _, err := parseLDAPGroupRoles("asset_manager"); err == nil {
+		t.Error("mapping without a group DN accepted")
+	}
+}
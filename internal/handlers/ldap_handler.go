@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/config"
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Nilai Admin.AuthSource
+const (
+	authSourceLocal = "local"
+	authSourceLDAP  = "ldap"
+)
+
+// errDirectoryNoAccess dipakai saat pengguna direktori valid tetapi tidak berhak masuk gokso
+var errDirectoryNoAccess = errors.New("akun direktori tidak memiliki akses ke aplikasi ini")
+
+// initDirectory menyiapkan login LDAP bila LDAP_URL diisi
+func (server *Server) initDirectory(appConfig config.AppConfig) {
+	server.Directory = nil
+	if appConfig.LDAPURL == "" {
+		return
+	}
+	dir, err := newLDAPDirectory(appConfig)
+	if err == nil {
+		for _, mapping := range dir.GroupRoles {
+			if !server.roleExists(mapping.Role) {
+				err = fmt.Errorf("LDAP_GROUP_ROLES memakai role tidak dikenal: %s", mapping.Role)
+				break
+			}
+		}
+	}
+	if err != nil {
+		if appConfig.AppEnv == "production" {
+			log.Fatal(err)
+		}
+		log.Printf("Warning: konfigurasi LDAP tidak valid (%v), login LDAP dinonaktifkan", err)
+		return
+	}
+	server.Directory = dir
+}
+
+// usesDirectory menentukan apakah login username ini diperiksa ke direktori:
+// akun ldap yang sudah ada atau username yang belum terdaftar sama sekali.
+// Akun lokal tetap memakai password bcrypt sehingga super admin darurat tidak bergantung pada direktori.
+func (server *Server) usesDirectory(admin models.Admin) bool {
+	return server.Directory != nil && (admin.ID == "" || admin.AuthSource == authSourceLDAP)
+}
+
+// directoryLogin memverifikasi password ke direktori lalu membuat atau memperbarui Admin terkait
+func (server *Server) directoryLogin(username, password string, admin *models.Admin) error {
+	// Username direktori umumnya tidak peka huruf besar/kecil, jadi akun hasil provisioning disimpan huruf kecil
+	username = strings.ToLower(username)
+	user, err := server.Directory.Authenticate(username, password)
+	if err != nil {
+		return err
+	}
+	return server.applyDirectoryUser(admin, username, user)
+}
+
+// applyDirectoryUser menyalin status direktori ke Admin: role dari grup, status nonaktif dan tautan ke data karyawan.
+// Admin baru dibuat bila belum ada. Mengembalikan errDirectoryNoAccess bila akun nonaktif atau tidak punya role.
+func (server *Server) applyDirectoryUser(admin *models.Admin, username string, user *directoryUser) error {
+	allowed := !user.Disabled && user.Role != ""
+
+	if admin.ID == "" {
+		if !allowed {
+			return errDirectoryNoAccess
+		}
+		// Password lokal acak yang tidak pernah diketahui siapa pun; login selalu lewat direktori
+		hashed, _ := bcrypt.GenerateFromPassword([]byte(uuid.New().String()+uuid.New().String()), bcrypt.DefaultCost)
+		*admin = models.Admin{
+			ID:         uuid.New().String(),
+			Username:   username,
+			Password:   string(hashed),
+			AuthSource: authSourceLDAP,
+		}
+	}
+
+	roleChanged := allowed && admin.Role != "" && admin.Role != user.Role
+	wasDisabled := admin.Disabled
+
+	admin.DirectoryDN = user.DN
+	admin.Disabled = !allowed
+	if allowed {
+		admin.Role = user.Role
+	}
+	if admin.UserID == "" {
+		admin.UserID = server.matchDirectoryEmployee(user)
+	}
+
+	if err := server.DB.Save(admin).Error; err != nil {
+		return err
+	}
+	// Session lama membawa role lama; cabut bila role berubah atau akun dinonaktifkan
+	if roleChanged || (!allowed && !wasDisabled) {
+		revokeAdminSessions(server.DB, admin.ID)
+	}
+	if !allowed {
+		return errDirectoryNoAccess
+	}
+	return nil
+}
+
+// verifyAdminPassword memeriksa password admin sesuai sumber autentikasinya (bcrypt lokal atau bind direktori)
+func (server *Server) verifyAdminPassword(admin models.Admin, password string) bool {
+	if admin.AuthSource == authSourceLDAP {
+		if server.Directory == nil {
+			return false
+		}
+		_, err := server.Directory.Authenticate(admin.Username, password)
+		return err == nil
+	}
+	return bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)) == nil
+}
+
+// matchDirectoryEmployee mencari data karyawan berdasarkan email, lalu NIK
+func (server *Server) matchDirectoryEmployee(user *directoryUser) string {
+	var employee models.User
+	if user.Email != "" {
+		server.DB.Select("id").Where("LOWER(email) = ?", user.Email).Limit(1).Find(&employee)
+	}
+	if employee.ID == "" && user.NIK != "" {
+		server.DB.Select("id").Where("nik = ?", user.NIK).Limit(1).Find(&employee)
+	}
+	return employee.ID
+}
+
+// SyncDirectoryAdmins menyamakan semua admin ldap dengan direktori: akun yang dihapus, dinonaktifkan
+// atau dikeluarkan dari grup ikut dinonaktifkan dan session-nya dicabut.
+func (server *Server) SyncDirectoryAdmins() (checked, disabled int, err error) {
+	if server.Directory == nil {
+		return 0, 0, fmt.Errorf("login LDAP tidak dikonfigurasi (LDAP_URL kosong)")
+	}
+
+	var admins []models.Admin
+	server.DB.Where("auth_source = ?", authSourceLDAP).Find(&admins)
+	for i := range admins {
+		admin := &admins[i]
+		user, lookupErr := server.Directory.Lookup(admin.Username)
+		if errors.Is(lookupErr, errDirectoryUserNotFound) {
+			user = &directoryUser{DN: admin.DirectoryDN, Disabled: true}
+		} else if lookupErr != nil {
+			// Direktori tidak bisa dihubungi: jangan menonaktifkan siapa pun berdasarkan data yang tidak lengkap
+			return checked, disabled, lookupErr
+		}
+
+		checked++
+		if err := server.applyDirectoryUser(admin, admin.Username, user); errors.Is(err, errDirectoryNoAccess) {
+			disabled++
+		} else if err != nil {
+			return checked, disabled, err
+		}
+	}
+	return checked, disabled, nil
+}
+
+// SyncSettingUserDirectory menjalankan sinkronisasi akun LDAP dari halaman User Management
+func (server *Server) SyncSettingUserDirectory(w http.ResponseWriter, r *http.Request) {
+	checked, disabled, err := server.SyncDirectoryAdmins()
+	if err != nil {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Sinkronisasi LDAP gagal: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	msg := fmt.Sprintf("Sinkronisasi LDAP selesai: %d akun diperiksa, %d akun nonaktif", checked, disabled)
+	http.Redirect(w, r, "/setting/user?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// fakeDirectoryUser adalah entri direktori uji beserta password bind-nya
+type fakeDirectoryUser struct {
+	password string
+	user     directoryUser
+}
+
+// fakeDirectory adalah directory uji di memori; role dipetakan dari grup memakai aturan ldapDirectory
+type fakeDirectory struct {
+	mapping *ldapDirectory
+	users   map[string]fakeDirectoryUser
+	lookups []string
+}
+
+func (d *fakeDirectory) find(username string) (*directoryUser, string, error) {
+	entry, ok := d.users[username]
+	if !ok {
+		return nil, "", errDirectoryUserNotFound
+	}
+	user := entry.user
+	user.Role = d.mapping.roleFor(user.Groups)
+	return &user, entry.password, nil
+}
+
+func (d *fakeDirectory) Authenticate(username, password string) (*directoryUser, error) {
+	d.lookups = append(d.lookups, username)
+	user, want, err := d.find(username)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" || password != want {
+		return nil, errDirectoryInvalidCredentials
+	}
+	return user, nil
+}
+
+func (d *fakeDirectory) Lookup(username string) (*directoryUser, error) {
+	d.lookups = append(d.lookups, username)
+	user, _, err := d.find(username)
+	return user, err
+}
+
+// directoryServer memasang fakeDirectory dengan grup Aset (asset_manager) dan Admins (super_admin)
+func directoryServer(tb testing.TB) (*Server, *fakeDirectory) {
+	tb.Helper()
+	server := newTestServer(tb)
+	roles, err := parseLDAPGroupRoles("super_admin=cn=Admins,ou=groups,dc=example,dc=com;asset_manager=cn=Aset,ou=groups,dc=example,dc=com")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	dir := &fakeDirectory{
+		mapping: &ldapDirectory{GroupRoles: roles},
+		users: map[string]fakeDirectoryUser{
+			"dewi": {password: "dir-secret", user: directoryUser{
+				DN: "uid=dewi,ou=people,dc=example,dc=com", Email: "budi@example.com",
+				Groups: []string{"CN=Aset,OU=Groups,DC=example,DC=com"},
+			}},
+			"admin": {password: "dir-secret", user: directoryUser{
+				DN: "uid=admin,ou=people,dc=example,dc=com", Groups: []string{"cn=Admins,ou=groups,dc=example,dc=com"},
+			}},
+		},
+	}
+	server.Directory = dir
+	return server, dir
+}
+
+// directoryLoginPost mengirim form login tanpa session
+func directoryLoginPost(server *Server, username, password string) *http.Response {
+	client := &testClient{server: server}
+	return client.do(http.MethodPost, "/login", url.Values{"username": {username}, "password": {password}}).Result()
+}
+
+func TestDirectoryLoginProvisionsAdminFromGroups(t *testing.T) {
+	server, dir := directoryServer(t)
+
+	res := directoryLoginPost(server, "Dewi", "dir-secret")
+	if loc := res.Header.Get("Location"); loc != "/" {
+		t.Fatalf("login redirect = %q, want /", loc)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "username = ?", "dewi")
+	if admin.AuthSource != authSourceLDAP || admin.Role != "asset_manager" || admin.UserID != "u1" || admin.Disabled {
+		t.Errorf("admin = source %q role %q user %q disabled %v", admin.AuthSource, admin.Role, admin.UserID, admin.Disabled)
+	}
+
+	// Varian huruf memakai akun yang sama dan username dikirim ke direktori dalam huruf kecil
+	if loc := directoryLoginPost(server, "DEWI", "dir-secret").Header.Get("Location"); loc != "/" {
+		t.Fatalf("second login redirect = %q, want /", loc)
+	}
+	var count int64
+	server.DB.Model(&models.Admin{}).Where("LOWER(username) = ?", "dewi").Count(&count)
+	if count != 1 {
+		t.Errorf("%d admins for dewi, want 1", count)
+	}
+	for _, username := range dir.lookups {
+		if username != "dewi" {
+			t.Errorf("directory lookup for %q, want lowercase", username)
+		}
+	}
+}
+
+func TestDirectoryLoginRejectsWrongPassword(t *testing.T) {
+	server, _ := directoryServer(t)
+
+	loc := directoryLoginPost(server, "dewi", "salah").Header.Get("Location")
+	if msg := redirectError(t, loc); msg != "Username atau password salah" {
+		t.Fatalf("error = %q", msg)
+	}
+	var count int64
+	server.DB.Model(&models.Admin{}).Where("username = ?", "dewi").Count(&count)
+	if count != 0 {
+		t.Error("admin provisioned without a successful bind")
+	}
+}
+
+func TestDirectoryLoginKeepsLocalAdmin(t *testing.T) {
+	server, dir := directoryServer(t)
+
+	// Username lokal yang juga ada di direktori tetap diperiksa dengan password lokal, termasuk varian hurufnya
+	for _, username := range []string{"admin", "ADMIN"} {
+		loc := directoryLoginPost(server, username, "dir-secret").Header.Get("Location")
+		if msg := redirectError(t, loc); msg == "" {
+			t.Errorf("%s: directory password accepted for a local admin", username)
+		}
+	}
+	if len(dir.lookups) != 0 {
+		t.Errorf("directory consulted for a local admin: %v", dir.lookups)
+	}
+	var admins []models.Admin
+	server.DB.Where("LOWER(username) = ?", "admin").Find(&admins)
+	if len(admins) != 1 || admins[0].AuthSource == authSourceLDAP {
+		t.Errorf("admins = %+v, want only the local admin", admins)
+	}
+}
+
+func TestSyncDirectoryAdminsDisablesAccounts(t *testing.T) {
+	server, dir := directoryServer(t)
+	dir.users["keluar"] = fakeDirectoryUser{user: directoryUser{DN: "uid=keluar,ou=people,dc=example,dc=com", Groups: []string{"cn=Lain,dc=example,dc=com"}}}
+	dir.users["cuti"] = fakeDirectoryUser{user: directoryUser{DN: "uid=cuti,ou=people,dc=example,dc=com", Groups: []string{"cn=Aset,ou=groups,dc=example,dc=com"}, Disabled: true}}
+	for _, username := range []string{"dewi", "keluar", "cuti", "dihapus"} {
+		server.DB.Create(&models.Admin{ID: "ldap-" + username, Username: username, Password: "x", Role: "asset_manager", AuthSource: authSourceLDAP})
+	}
+	session := loginAs(t, server, models.Admin{ID: "ldap-cuti", Username: "cuti", Role: "asset_manager"})
+
+	checked, disabled, err := server.SyncDirectoryAdmins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 4 || disabled != 3 {
+		t.Errorf("checked = %d, disabled = %d, want 4 and 3", checked, disabled)
+	}
+	var admins []models.Admin
+	server.DB.Where("auth_source = ?", authSourceLDAP).Order("username").Find(&admins)
+	for _, admin := range admins {
+		if want := admin.Username != "dewi"; admin.Disabled != want {
+			t.Errorf("%s disabled = %v, want %v", admin.Username, admin.Disabled, want)
+		}
+	}
+	if w := session.do(http.MethodGet, "/", nil); w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/login") {
+		t.Errorf("session of a disabled account still valid: %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// loginLockout mengatur penguncian akun setelah login gagal berturut-turut
+type loginLockout struct {
+	MaxFailures int
+	Duration    time.Duration
+}
+
+var defaultLoginLockout = loginLockout{MaxFailures: 5, Duration: 15 * time.Minute}
+
+// dummyPasswordHash dibandingkan saat username tidak dikenal agar waktu respons sama dengan username terdaftar
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("gokso-dummy-password"), bcrypt.DefaultCost)
+
+// Batas percobaan login. Username diberi sedikit percobaan gratis sebelum jeda eksponensial;
+// IP diberi lebih longgar karena banyak pengguna kantor keluar lewat IP yang sama.
+const (
+	loginAttemptWindow    = 15 * time.Minute // kegagalan lebih lama dari ini tidak dihitung lagi
+	loginFreeAttemptsUser = 3
+	loginFreeAttemptsIP   = 20
+	loginMaxBackoff       = 5 * time.Minute
+	loginAttemptRetention = 180 * 24 * time.Hour
+)
+
+// Keterangan hasil percobaan login yang disimpan di log
+const (
+	loginReasonSuccess       = "Berhasil"
+	loginReason2FASuccess    = "Berhasil (2FA)"
+	loginReasonRecovery      = "Berhasil (kode pemulihan)"
+	loginReasonPassword      = "Password salah"
+	loginReasonUnknownUser   = "Username tidak dikenal"
+	loginReasonLocked        = "Akun terkunci"
+	loginReasonRateLimited   = "Terlalu banyak percobaan"
+	loginReasonSecondFactor  = "Kode 2FA salah"
+	loginReasonNoAccess      = "Akun nonaktif / tanpa akses"
+	loginReasonDirectoryDown = "Direktori tidak tersedia"
+	loginReasonSSOSuccess    = "Berhasil (SSO)"
+	loginReasonSSORejected   = "SSO ditolak"
+	loginReasonDAVSuccess    = "Berhasil (WebDAV)"
+)
+
+// loginBackoff menghitung jeda wajib setelah sejumlah kegagalan: 1 detik, 2, 4, ... hingga loginMaxBackoff
+func loginBackoff(failures, free int) time.Duration {
+	if failures < free {
+		return 0
+	}
+	exp := failures - free
+	if exp > 20 {
+		return loginMaxBackoff
+	}
+	d := time.Duration(math.Pow(2, float64(exp))) * time.Second
+	if d > loginMaxBackoff {
+		return loginMaxBackoff
+	}
+	return d
+}
+
+// loginRetryAfter mengembalikan sisa jeda sebelum username/IP ini boleh mencoba login lagi
+func (server *Server) loginRetryAfter(username, ip string, admin *models.Admin) time.Duration {
+	now := time.Now()
+	var wait time.Duration
+
+	// Per username: akun terdaftar memakai penghitung di tabel admin (direset saat berhasil atau dibuka),
+	// username tidak dikenal memakai log agar perilakunya tidak membedakan keduanya.
+	if admin != nil && admin.ID != "" {
+		if admin.LastFailedLoginAt != nil && now.Sub(*admin.LastFailedLoginAt) < loginAttemptWindow {
+			wait = loginBackoff(admin.FailedLogins, loginFreeAttemptsUser) - now.Sub(*admin.LastFailedLoginAt)
+		}
+	} else if username != "" {
+		wait = server.loginLogRetryAfter("username = ?", username, loginFreeAttemptsUser, now)
+	}
+
+	if w := server.loginLogRetryAfter("ip = ?", ip, loginFreeAttemptsIP, now); w > wait {
+		wait = w
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// loginLogRetryAfter menghitung jeda dari kegagalan yang tercatat di log dalam jendela percobaan
+func (server *Server) loginLogRetryAfter(cond string, value string, free int, now time.Time) time.Duration {
+	since := now.Add(-loginAttemptWindow)
+
+	// Untuk username, keberhasilan terakhir memulai hitungan dari nol
+	if cond == "username = ?" {
+		var last models.LoginAttempt
+		server.DB.Where(cond+" AND success = ?", value, true).Order("created_at desc").Limit(1).Find(&last)
+		if last.ID != 0 && last.CreatedAt.After(since) {
+			since = last.CreatedAt
+		}
+	}
+
+	failures := func() *gorm.DB {
+		return server.DB.Model(&models.LoginAttempt{}).
+			Where(cond+" AND success = ? AND reason <> ? AND created_at > ?", value, false, loginReasonRateLimited, since)
+	}
+	var count int64
+	failures().Count(&count)
+	if count == 0 {
+		return 0
+	}
+	var last models.LoginAttempt
+	failures().Order("created_at desc").Limit(1).Find(&last)
+	return loginBackoff(int(count), free) - now.Sub(last.CreatedAt)
+}
+
+// accountLockedFor mengembalikan sisa waktu penguncian akun; penguncian yang sudah lewat dibersihkan
+func (server *Server) accountLockedFor(admin *models.Admin) time.Duration {
+	if admin.LockedUntil == nil {
+		return 0
+	}
+	if left := time.Until(*admin.LockedUntil); left > 0 {
+		return left
+	}
+	admin.LockedUntil = nil
+	admin.FailedLogins = 0
+	server.DB.Model(admin).Updates(map[string]interface{}{"locked_until": nil, "failed_logins": 0})
+	return 0
+}
+
+// registerLoginFailure menambah penghitung gagal admin dan mengunci akun bila batas tercapai
+func (server *Server) registerLoginFailure(admin *models.Admin) {
+	now := time.Now()
+	// Kegagalan lama di luar jendela percobaan tidak ikut dihitung
+	var failed interface{} = gorm.Expr("failed_logins + 1")
+	if admin.LastFailedLoginAt == nil || now.Sub(*admin.LastFailedLoginAt) > loginAttemptWindow {
+		failed = 1
+	}
+	server.DB.Model(admin).Updates(map[string]interface{}{"failed_logins": failed, "last_failed_login_at": now})
+	server.DB.Select("failed_logins").Where("id = ?", admin.ID).Limit(1).Find(admin)
+	admin.LastFailedLoginAt = &now
+
+	if admin.FailedLogins >= server.Lockout.MaxFailures {
+		until := now.Add(server.Lockout.Duration)
+		admin.LockedUntil = &until
+		server.DB.Model(admin).Update("locked_until", until)
+	}
+}
+
+// resetLoginFailures membersihkan penghitung gagal dan penguncian setelah login berhasil atau dibuka admin
+func (server *Server) resetLoginFailures(admin *models.Admin) {
+	admin.FailedLogins = 0
+	admin.LastFailedLoginAt = nil
+	admin.LockedUntil = nil
+	server.DB.Model(admin).Updates(map[string]interface{}{"failed_logins": 0, "last_failed_login_at": nil, "locked_until": nil})
+}
+
+// recordLoginAttempt menyimpan percobaan login ke log
+func (server *Server) recordLoginAttempt(r *http.Request, username, adminID string, success bool, reason string) {
+	server.DB.Create(&models.LoginAttempt{
+		Username:  truncate(username, 100),
+		AdminID:   adminID,
+		IP:        clientIP(r),
+		UserAgent: truncate(r.UserAgent(), 255),
+		Success:   success,
+		Reason:    reason,
+	})
+	if success {
+		// Log lama dibersihkan sesekali agar tabel tidak tumbuh tanpa batas
+		server.DB.Where("created_at < ?", time.Now().Add(-loginAttemptRetention)).Delete(&models.LoginAttempt{})
+	}
+}
+
+// lockedMessage menyusun pesan untuk akun yang sedang dikunci
+func lockedMessage(left time.Duration) string {
+	return fmt.Sprintf("Akun terkunci sementara karena terlalu banyak percobaan gagal. Coba lagi dalam %d menit atau hubungi administrator", int(math.Ceil(left.Minutes())))
+}
+
+// retryMessage menyusun pesan untuk percobaan yang ditolak karena terlalu cepat
+func retryMessage(wait time.Duration) string {
+	return fmt.Sprintf("Terlalu banyak percobaan login. Coba lagi dalam %d detik", int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// loginPost mengirim form login tanpa session lalu mengembalikan pesan error redirect-nya ("" bila berhasil)
+func loginPost(tb testing.TB, server *Server, username, password string) string {
+	tb.Helper()
+	anon := &testClient{server: server}
+	w := anon.do(http.MethodPost, "/login", url.Values{"username": {username}, "password": {password}})
+	if loc := w.Header().Get("Location"); loc == "/" {
+		return ""
+	}
+	return redirectError(tb, w.Header().Get("Location"))
+}
+
+// loginAttemptReasonsFor mengembalikan keterangan log percobaan login untuk username, urut waktu
+func loginAttemptReasonsFor(server *Server, username string) []string {
+	var reasons []string
+	server.DB.Model(&models.LoginAttempt{}).Where("username = ?", username).Order("id").Pluck("reason", &reasons)
+	return reasons
+}
+
+func TestLoginBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		failures, free int
+		want           time.Duration
+	}{
+		{0, 3, 0},
+		{2, 3, 0},
+		{3, 3, time.Second},
+		{4, 3, 2 * time.Second},
+		{6, 3, 8 * time.Second},
+		{12, 3, loginMaxBackoff},
+		{100, 3, loginMaxBackoff},
+		{19, 20, 0},
+	} {
+		if got := loginBackoff(tc.failures, tc.free); got != tc.want {
+			t.Errorf("loginBackoff(%d, %d) = %v, want %v", tc.failures, tc.free, got, tc.want)
+		}
+	}
+}
+
+func TestLoginBacksOffPerUsername(t *testing.T) {
+	server := newTestServer(t)
+	sessionAdmin(t, server, "s1", "sinta")
+
+	for i := 0; i < loginFreeAttemptsUser; i++ {
+		if msg := loginPost(t, server, "sinta", "salah"); msg != "Username atau password salah" {
+			t.Fatalf("attempt %d: error = %q", i+1, msg)
+		}
+	}
+	// Password yang benar pun ditolak selama jeda berlaku, tanpa menambah penghitung gagal
+	if msg := loginPost(t, server, "sinta", "rahasia"); !strings.HasPrefix(msg, "Terlalu banyak percobaan login") {
+		t.Fatalf("error during backoff = %q", msg)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "s1")
+	if admin.FailedLogins != loginFreeAttemptsUser || admin.LockedUntil != nil {
+		t.Errorf("failed_logins = %d, locked_until = %v", admin.FailedLogins, admin.LockedUntil)
+	}
+
+	// Setelah jeda lewat login berhasil dan penghitung direset
+	server.DB.Model(&admin).Update("last_failed_login_at", time.Now().Add(-time.Minute))
+	if msg := loginPost(t, server, "sinta", "rahasia"); msg != "" {
+		t.Fatalf("login after backoff = %q", msg)
+	}
+	var reset models.Admin
+	server.DB.First(&reset, "id = ?", "s1")
+	if reset.FailedLogins != 0 || reset.LastFailedLoginAt != nil {
+		t.Errorf("failed_logins = %d, last_failed_login_at = %v after success", reset.FailedLogins, reset.LastFailedLoginAt)
+	}
+	want := []string{loginReasonPassword, loginReasonPassword, loginReasonPassword, loginReasonRateLimited, loginReasonSuccess}
+	if got := loginAttemptReasonsFor(server, "sinta"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("login attempts = %v, want %v", got, want)
+	}
+}
+
+func TestLoginBacksOffUnknownUsername(t *testing.T) {
+	server := newTestServer(t)
+
+	// Username yang tidak terdaftar diperlakukan sama agar tidak bisa dipakai menebak akun
+	for i := 0; i < loginFreeAttemptsUser; i++ {
+		if msg := loginPost(t, server, "hantu", "salah"); msg != "Username atau password salah" {
+			t.Fatalf("attempt %d: error = %q", i+1, msg)
+		}
+	}
+	if msg := loginPost(t, server, "hantu", "salah"); !strings.HasPrefix(msg, "Terlalu banyak percobaan login") {
+		t.Errorf("error after %d failures = %q, want backoff", loginFreeAttemptsUser, msg)
+	}
+}
+
+func TestLoginBacksOffPerIP(t *testing.T) {
+	server := newTestServer(t)
+	sessionAdmin(t, server, "s1", "sinta")
+	// Kegagalan untuk banyak username berbeda dari IP yang sama (httptest memakai 192.0.2.1)
+	for i := 0; i < loginFreeAttemptsIP; i++ {
+		server.DB.Create(&models.LoginAttempt{Username: "tebakan", IP: "192.0.2.1", Reason: loginReasonUnknownUser})
+	}
+
+	if msg := loginPost(t, server, "sinta", "rahasia"); !strings.HasPrefix(msg, "Terlalu banyak percobaan login") {
+		t.Errorf("error = %q, want backoff for the IP", msg)
+	}
+	// Percobaan yang ditolak karena jeda tidak ikut memperpanjang jeda
+	server.DB.Model(&models.LoginAttempt{}).Where("reason <> ?", loginReasonRateLimited).
+		Update("created_at", time.Now().Add(-loginAttemptWindow-time.Minute))
+	if msg := loginPost(t, server, "sinta", "rahasia"); msg != "" {
+		t.Errorf("login after the window = %q", msg)
+	}
+}
+
+func TestLoginLocksAccount(t *testing.T) {
+	server := newTestServer(t)
+	server.Lockout.MaxFailures = 2
+	sessionAdmin(t, server, "s1", "sinta")
+
+	loginPost(t, server, "sinta", "salah")
+	if msg := loginPost(t, server, "sinta", "salah"); !strings.HasPrefix(msg, "Akun terkunci sementara") {
+		t.Fatalf("error at the limit = %q, want lockout", msg)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "s1")
+	if admin.LockedUntil == nil || time.Until(*admin.LockedUntil) < server.Lockout.Duration-time.Minute {
+		t.Fatalf("locked_until = %v", admin.LockedUntil)
+	}
+
+	// Setelah jeda lewat, akun tetap terkunci hingga waktu penguncian berakhir
+	server.DB.Model(&admin).Update("last_failed_login_at", time.Now().Add(-time.Minute))
+	if msg := loginPost(t, server, "sinta", "rahasia"); !strings.HasPrefix(msg, "Akun terkunci sementara") {
+		t.Errorf("correct password while locked = %q", msg)
+	}
+	if reasons := loginAttemptReasonsFor(server, "sinta"); reasons[len(reasons)-1] != loginReasonLocked {
+		t.Errorf("login attempts = %v", reasons)
+	}
+
+	// Administrator membuka akun sehingga login langsung bisa dilakukan
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	client.do(http.MethodPost, "/setting/user/unlock/s1", url.Values{})
+	if msg := loginPost(t, server, "sinta", "rahasia"); msg != "" {
+		t.Errorf("login after unlock = %q", msg)
+	}
+}
+
+func TestLoginLockExpires(t *testing.T) {
+	server := newTestServer(t)
+	sessionAdmin(t, server, "s1", "sinta")
+	past := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-loginAttemptWindow - time.Minute)
+	server.DB.Model(&models.Admin{}).Where("id = ?", "s1").
+		Updates(map[string]interface{}{"failed_logins": 4, "last_failed_login_at": stale, "locked_until": past})
+
+	// Penguncian yang sudah lewat dibersihkan dan kegagalan di luar jendela tidak dihitung lagi
+	if msg := loginPost(t, server, "sinta", "salah"); msg != "Username atau password salah" {
+		t.Fatalf("error = %q", msg)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "s1")
+	if admin.FailedLogins != 1 || admin.LockedUntil != nil {
+		t.Errorf("failed_logins = %d, locked_until = %v", admin.FailedLogins, admin.LockedUntil)
+	}
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// lookupMaster adalah master data spesifikasi aset yang hanya berisi nama. Semua tabel seperti ini dikelola
+// oleh handler generik di file ini, sehingga tabel baru cukup didaftarkan di lookupMasters beserta modelnya.
+type lookupMaster struct {
+	Slug        string // segmen URL di bawah /inventori/master-data/
+	Label       string // nama yang tampil, misalnya "Tipe RAM"
+	TemplateKey string // kunci daftar pilihan pada form aset, lihat fetchAssetMasterData
+	Part        assetSpecPart
+	Prefix      bool // nilai master adalah awalan isian (keluarga prosesor), bukan isian utuh
+	New         func(name string) interface{}
+}
+
+// lookupMasters adalah daftar master data spesifikasi aset
+var lookupMasters = []lookupMaster{
+	{Slug: "processor-family", Label: "Keluarga Prosesor", TemplateKey: "processorFamilies", Part: specProcessor, Prefix: true,
+		New: func(name string) interface{} { return &models.MasterProcessorFamily{Name: name} }},
+	{Slug: "ram-type", Label: "Tipe RAM", TemplateKey: "ramTypes", Part: specRamType,
+		New: func(name string) interface{} { return &models.MasterRamType{Name: name} }},
+	{Slug: "storage-type", Label: "Tipe Penyimpanan", TemplateKey: "storageTypes", Part: specStorageType,
+		New: func(name string) interface{} { return &models.MasterStorageType{Name: name} }},
+	{Slug: "operating-system", Label: "Sistem Operasi", TemplateKey: "operatingSystems", Part: specOS,
+		New: func(name string) interface{} { return &models.MasterOperatingSystem{Name: name} }},
+}
+
+// lookupItem adalah satu baris master data spesifikasi
+type lookupItem struct {
+	ID   uint
+	Name string
+}
+
+// URL mengembalikan alamat halaman daftar master data
+func (m lookupMaster) URL() string {
+	return "/inventori/master-data/" + m.Slug
+}
+
+// model mengembalikan model kosong untuk menentukan tabel query
+func (m lookupMaster) model() interface{} {
+	return m.New("")
+}
+
+// matches memeriksa apakah isian spesifikasi memakai nilai master name
+func (m lookupMaster) matches(value, name string) bool {
+	if m.Prefix {
+		return hasSpecPrefix(value, name)
+	}
+	return value != "" && strings.EqualFold(value, name)
+}
+
+// lookupRoutePattern adalah variabel rute {kind} yang hanya cocok dengan master data terdaftar
+func lookupRoutePattern() string {
+	slugs := make([]string, len(lookupMasters))
+	for i, m := range lookupMasters {
+		slugs[i] = m.Slug
+	}
+	return "{kind:" + strings.Join(slugs, "|") + "}"
+}
+
+// lookupMasterOf mengambil master data sesuai variabel rute {kind}
+func lookupMasterOf(r *http.Request) lookupMaster {
+	kind := mux.Vars(r)["kind"]
+	for _, m := range lookupMasters {
+		if m.Slug == kind {
+			return m
+		}
+	}
+	// Tidak terjadi karena pola rute hanya menerima slug terdaftar
+	return lookupMasters[0]
+}
+
+// lookupItems mengambil semua nilai master data urut nama
+func (server *Server) lookupItems(m lookupMaster) ([]lookupItem, error) {
+	var items []lookupItem
+	err := server.DB.Model(m.model()).Order("name asc").Find(&items).Error
+	return items, err
+}
+
+// findLookupItem mencari satu nilai master data, ID 0 bila tidak ditemukan
+func (server *Server) findLookupItem(m lookupMaster, id string) lookupItem {
+	var item lookupItem
+	server.DB.Model(m.model()).Where("id = ?", id).Limit(1).Find(&item)
+	return item
+}
+
+// lookupNameError memeriksa nama baru: wajib diisi dan belum dipakai nilai lain (tanpa membedakan huruf besar/kecil)
+func (server *Server) lookupNameError(m lookupMaster, name string, excludeID uint) error {
+	if name == "" {
+		return errors.New("Nama " + m.Label + " wajib diisi")
+	}
+	var total int64
+	server.DB.Model(m.model()).Where("LOWER(name) = LOWER(?) AND id <> ?", name, excludeID).Count(&total)
+	if total > 0 {
+		return errors.New(m.Label + " " + name + " sudah ada")
+	}
+	return nil
+}
+
+// specAssets mengambil aset (termasuk yang sudah dihapus) yang spesifikasinya memakai nilai master name
+func specAssets(db *gorm.DB, m lookupMaster, name string) ([]models.AssetKSO, error) {
+	var assets, matched []models.AssetKSO
+	if err := db.Unscoped().Select("id", "specification").Where("specification <> ''").Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range assets {
+		if m.matches(assetSpecValue(a.Specification, m.Part), name) {
+			matched = append(matched, a)
+		}
+	}
+	return matched, nil
+}
+
+// rewriteSpecs mengganti nilai master from menjadi to pada spesifikasi aset. Untuk keluarga prosesor
+// hanya awalannya yang diganti sehingga seri prosesor tetap dipertahankan.
+func rewriteSpecs(tx *gorm.DB, m lookupMaster, from, to string) error {
+	assets, err := specAssets(tx, m, from)
+	if err != nil {
+		return err
+	}
+	for _, a := range assets {
+		value := to
+		if m.Prefix {
+			value = to + assetSpecValue(a.Specification, m.Part)[len(from):]
+		}
+		spec := replaceAssetSpecValue(a.Specification, m.Part, value)
+		if err := tx.Unscoped().Model(&models.AssetKSO{}).Where("id = ?", a.ID).Update("specification", spec).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupUsages menghitung aset yang spesifikasinya masih memakai nilai master
+func (server *Server) lookupUsages(m lookupMaster, name string) []orgUsage {
+	assets, _ := specAssets(server.DB, m, name)
+	return []orgUsage{{"aset", int64(len(assets))}}
+}
+
+// renderLookupMaster menampilkan daftar master data beserta form tambah atau edit
+func (server *Server) renderLookupMaster(w http.ResponseWriter, r *http.Request, m lookupMaster, item *lookupItem) {
+	items, _ := server.lookupItems(m)
+	title := "Master " + m.Label
+	if item != nil {
+		title = "Edit " + m.Label
+	}
+	server.RenderHTML(w, r, http.StatusOK, "inventori/master_data/lookup", map[string]interface{}{
+		"title":   title,
+		"master":  m,
+		"masters": lookupMasters,
+		"items":   items,
+		"item":    item,
+		"error":   r.URL.Query().Get("error"),
+		"msg":     r.URL.Query().Get("msg"),
+	})
+}
+
+// lookupRedirect kembali ke halaman daftar dengan pesan sukses atau error
+func lookupRedirect(w http.ResponseWriter, r *http.Request, m lookupMaster, err error, msg string) {
+	if err != nil {
+		http.Redirect(w, r, m.URL()+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, m.URL()+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// ListLookupMaster menampilkan daftar master data spesifikasi aset
+func (server *Server) ListLookupMaster(w http.ResponseWriter, r *http.Request) {
+	server.renderLookupMaster(w, r, lookupMasterOf(r), nil)
+}
+
+// StoreLookupMaster menyimpan nilai master data spesifikasi baru
+func (server *Server) StoreLookupMaster(w http.ResponseWriter, r *http.Request) {
+	m := lookupMasterOf(r)
+	name := strings.TrimSpace(r.FormValue("name"))
+	err := server.lookupNameError(m, name, 0)
+	if err == nil {
+		err = server.DB.Create(m.New(name)).Error
+	}
+	lookupRedirect(w, r, m, err, m.Label+" "+name+" berhasil ditambahkan")
+}
+
+// EditLookupMaster menampilkan form edit untuk satu nilai master data spesifikasi
+func (server *Server) EditLookupMaster(w http.ResponseWriter, r *http.Request) {
+	m := lookupMasterOf(r)
+	item := server.findLookupItem(m, mux.Vars(r)["id"])
+	if item.ID == 0 {
+		http.Redirect(w, r, m.URL(), http.StatusSeeOther)
+		return
+	}
+	server.renderLookupMaster(w, r, m, &item)
+}
+
+// UpdateLookupMaster mengganti nama nilai master data. Spesifikasi aset yang memakai nama lama ikut diganti
+// dalam transaksi yang sama, seperti penggantian nama kategori aset.
+func (server *Server) UpdateLookupMaster(w http.ResponseWriter, r *http.Request) {
+	m := lookupMasterOf(r)
+	item := server.findLookupItem(m, mux.Vars(r)["id"])
+	if item.ID == 0 {
+		http.Redirect(w, r, m.URL(), http.StatusSeeOther)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	err := server.lookupNameError(m, name, item.ID)
+	if err == nil && name != item.Name {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(m.model()).Where("id = ?", item.ID).Update("name", name).Error; err != nil {
+				return err
+			}
+			return rewriteSpecs(tx, m, item.Name, name)
+		})
+	}
+	lookupRedirect(w, r, m, err, m.Label+" "+name+" berhasil diperbarui")
+}
+
+// lookupDeletion menyusun halaman analisis pemakaian sebelum nilai master data dihapus atau digabung
+func (server *Server) lookupDeletion(m lookupMaster, item lookupItem) masterDeletion {
+	d := masterDeletion{
+		Label:     m.Label,
+		Name:      item.Name,
+		ListURL:   m.URL(),
+		DeleteURL: fmt.Sprintf("%s/delete/%d", m.URL(), item.ID),
+		MergeURL:  fmt.Sprintf("%s/merge/%d", m.URL(), item.ID),
+		MergeNote: "Spesifikasi aset yang memakai " + item.Name + " diganti dengan nilai tujuan.",
+		Usages:    server.lookupUsages(m, item.Name),
+	}
+	items, _ := server.lookupItems(m)
+	for _, other := range items {
+		if other.ID != item.ID {
+			d.Targets = append(d.Targets, mergeTarget{other.ID, other.Name})
+		}
+	}
+	return d
+}
+
+// ConfirmDeleteLookupMaster menampilkan pemakaian nilai master data sebelum dihapus beserta pilihan untuk menggabungkannya
+func (server *Server) ConfirmDeleteLookupMaster(w http.ResponseWriter, r *http.Request) {
+	m := lookupMasterOf(r)
+	item := server.findLookupItem(m, mux.Vars(r)["id"])
+	if item.ID == 0 {
+		http.Redirect(w, r, m.URL(), http.StatusSeeOther)
+		return
+	}
+	server.renderMasterDeletion(w, r, server.lookupDeletion(m, item))
+}
+
+// DeleteLookupMaster menghapus nilai master data bila tidak lagi dipakai spesifikasi aset mana pun
+func (server *Server) DeleteLookupMaster(w http.ResponseWriter, r *http.Request) {
+	m := lookupMasterOf(r)
+	item := server.findLookupItem(m, mux.Vars(r)["id"])
+	if item.ID == 0 {
+		http.Redirect(w, r, m.URL(), http.StatusSeeOther)
+		return
+	}
+	if msg := orgUsageError(m.Label+" "+item.Name, server.lookupUsages(m, item.Name)); msg != "" {
+		lookupRedirect(w, r, m, errors.New(msg), "")
+		return
+	}
+	err := server.DB.Unscoped().Where("id = ?", item.ID).Delete(m.model()).Error
+	lookupRedirect(w, r, m, err, m.Label+" "+item.Name+" berhasil dihapus")
+}
+
+// MergeLookupMaster menggabungkan nilai master data ke nilai lain dalam satu transaksi
+func (server *Server) MergeLookupMaster(w http.ResponseWriter, r *http.Request) {
+	m := lookupMasterOf(r)
+	from := server.findLookupItem(m, mux.Vars(r)["id"])
+	if from.ID == 0 {
+		http.Redirect(w, r, m.URL(), http.StatusSeeOther)
+		return
+	}
+	d := server.lookupDeletion(m, from)
+	var into lookupItem
+	targetID, err := server.mergeTargetID(r, from.ID)
+	if err == nil {
+		into = server.findLookupItem(m, fmt.Sprint(targetID))
+		if into.ID == 0 {
+			err = errors.New(m.Label + " tujuan tidak ditemukan")
+		}
+	}
+	if err == nil {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			if err := rewriteSpecs(tx, m, from.Name, into.Name); err != nil {
+				return err
+			}
+			return tx.Unscoped().Where("id = ?", from.ID).Delete(m.model()).Error
+		})
+	}
+	mergeRedirect(w, r, d, err, m.Label+" "+from.Name+" digabung ke "+into.Name)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
 	"github.com/gorilla/mux"
@@ -17,6 +18,8 @@ func (server *Server) ListMasterBranch(w http.ResponseWriter, r *http.Request) {
 	server.RenderHTML(w, r, http.StatusOK, "administration/master_data/branch", map[string]interface{}{
 		"title":    "Master Cabang",
 		"branches": branches,
+		"error":    r.URL.Query().Get("error"),
+		"msg":      r.URL.Query().Get("msg"),
 	})
 }
 
@@ -30,11 +33,24 @@ func (server *Server) StoreMasterBranch(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/administration/master-data/branch", http.StatusSeeOther)
 }
 
-// DeleteMasterBranch menghapus data cabang dari database
+// DeleteMasterBranch menghapus data cabang dari database. Cabang yang masih dipakai bagian, karyawan,
+// cakupan data admin atau riwayat pemeliharaan tidak dapat dihapus.
 func (server *Server) DeleteMasterBranch(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	server.DB.Unscoped().Delete(&models.MasterBranch{}, id)
+
+	var branch models.MasterBranch
+	server.DB.Where("id = ?", id).Limit(1).Find(&branch)
+	if branch.ID != 0 {
+		if msg := orgUsageError("Cabang "+branch.Name, server.orgNodeUsages(models.ScopeLevelBranch, branch.ID)); msg != "" {
+			http.Redirect(w, r, "/administration/master-data/branch?error="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
+		if err := server.DB.Unscoped().Delete(&branch).Error; err != nil {
+			http.Redirect(w, r, "/administration/master-data/branch?error="+url.QueryEscape("Gagal menghapus cabang "+branch.Name+": "+err.Error()), http.StatusSeeOther)
+			return
+		}
+	}
 	http.Redirect(w, r, "/administration/master-data/branch", http.StatusSeeOther)
 }
 
@@ -59,7 +75,8 @@ func (server *Server) EditMasterBranch(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// UpdateMasterBranch memperbarui data nama cabang dan melakukan update cascading pada data karyawan
+// UpdateMasterBranch memperbarui nama cabang. Karyawan menunjuk cabang lewat ID sehingga nama baru
+// langsung berlaku, sedangkan lokasi aset dan surat jalan yang menyimpan nama cabang ikut diganti.
 func (server *Server) UpdateMasterBranch(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -71,22 +88,13 @@ func (server *Server) UpdateMasterBranch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	oldName := branch.Name
-	newName := r.FormValue("name")
-
-	server.DB.Transaction(func(tx *gorm.DB) error {
-		branch.Name = newName
-		if err := tx.Save(&branch).Error; err != nil {
-			return err
-		}
-
-		if oldName != newName {
-			if err := tx.Model(&models.User{}).Where("branch = ?", oldName).Update("branch", newName).Error; err != nil {
-				return err
-			}
-		}
-		return nil
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		return renameBranch(tx, &branch, r.FormValue("name"))
 	})
+	if err != nil {
+		http.Redirect(w, r, "/administration/master-data/branch?error="+url.QueryEscape("Gagal mengubah cabang: "+err.Error()), http.StatusSeeOther)
+		return
+	}
 
 	http.Redirect(w, r, "/administration/master-data/branch", http.StatusSeeOther)
 }
@@ -104,6 +112,8 @@ func (server *Server) ListMasterDepartment(w http.ResponseWriter, r *http.Reques
 		"title":       "Master Bagian",
 		"departments": departments,
 		"branches":    branches,
+		"error":       r.URL.Query().Get("error"),
+		"msg":         r.URL.Query().Get("msg"),
 	})
 }
 
@@ -119,11 +129,23 @@ func (server *Server) StoreMasterDepartment(w http.ResponseWriter, r *http.Reque
 	http.Redirect(w, r, "/administration/master-data/department", http.StatusSeeOther)
 }
 
-// DeleteMasterDepartment menghapus data bagian (departemen) dari database
+// DeleteMasterDepartment menghapus data bagian (departemen) dari database bila tidak lagi dipakai
 func (server *Server) DeleteMasterDepartment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	server.DB.Unscoped().Delete(&models.MasterDepartment{}, id)
+
+	var department models.MasterDepartment
+	server.DB.Where("id = ?", id).Limit(1).Find(&department)
+	if department.ID != 0 {
+		if msg := orgUsageError("Bagian "+department.Name, server.orgNodeUsages(models.ScopeLevelDepartment, department.ID)); msg != "" {
+			http.Redirect(w, r, "/administration/master-data/department?error="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
+		if err := server.DB.Unscoped().Delete(&department).Error; err != nil {
+			http.Redirect(w, r, "/administration/master-data/department?error="+url.QueryEscape("Gagal menghapus bagian "+department.Name+": "+err.Error()), http.StatusSeeOther)
+			return
+		}
+	}
 	http.Redirect(w, r, "/administration/master-data/department", http.StatusSeeOther)
 }
 
@@ -162,24 +184,17 @@ func (server *Server) UpdateMasterDepartment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	oldName := department.Name
-	newName := r.FormValue("name")
-	branchID := r.FormValue("master_branch_id")
-
-	server.DB.Transaction(func(tx *gorm.DB) error {
-		department.Name = newName
-		department.MasterBranchID = server.parseUint(branchID)
-		if err := tx.Save(&department).Error; err != nil {
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveDepartment(tx, &department, server.parseUint(r.FormValue("master_branch_id"))); err != nil {
 			return err
 		}
-
-		if oldName != newName {
-			if err := tx.Model(&models.User{}).Where("department = ?", oldName).Update("department", newName).Error; err != nil {
-				return err
-			}
-		}
-		return nil
+		department.Name = r.FormValue("name")
+		return tx.Save(&department).Error
 	})
+	if err != nil {
+		http.Redirect(w, r, "/administration/master-data/department?error="+url.QueryEscape("Gagal mengubah bagian: "+err.Error()), http.StatusSeeOther)
+		return
+	}
 
 	http.Redirect(w, r, "/administration/master-data/department", http.StatusSeeOther)
 }
@@ -197,6 +212,8 @@ func (server *Server) ListMasterSubDepartment(w http.ResponseWriter, r *http.Req
 		"title":       "Master Sub Bagian",
 		"subDepts":    subDepts,
 		"departments": departments,
+		"error":       r.URL.Query().Get("error"),
+		"msg":         r.URL.Query().Get("msg"),
 	})
 }
 
@@ -214,7 +231,19 @@ func (server *Server) StoreMasterSubDepartment(w http.ResponseWriter, r *http.Re
 func (server *Server) DeleteMasterSubDepartment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	server.DB.Unscoped().Delete(&models.MasterSubDepartment{}, id)
+
+	var subDept models.MasterSubDepartment
+	server.DB.Where("id = ?", id).Limit(1).Find(&subDept)
+	if subDept.ID != 0 {
+		if msg := orgUsageError("Sub bagian "+subDept.Name, server.orgNodeUsages(models.ScopeLevelSubDepartment, subDept.ID)); msg != "" {
+			http.Redirect(w, r, "/administration/master-data/sub-department?error="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
+		if err := server.DB.Unscoped().Delete(&subDept).Error; err != nil {
+			http.Redirect(w, r, "/administration/master-data/sub-department?error="+url.QueryEscape("Gagal menghapus sub bagian "+subDept.Name+": "+err.Error()), http.StatusSeeOther)
+			return
+		}
+	}
 	http.Redirect(w, r, "/administration/master-data/sub-department", http.StatusSeeOther)
 }
 
@@ -253,24 +282,24 @@ func (server *Server) UpdateMasterSubDepartment(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	oldName := subDept.Name
-	newName := r.FormValue("name")
-	deptID := r.FormValue("master_department_id")
+	var department models.MasterDepartment
+	server.DB.Where("id = ?", server.parseUint(r.FormValue("master_department_id"))).Limit(1).Find(&department)
+	if department.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/sub-department", http.StatusSeeOther)
+		return
+	}
 
-	server.DB.Transaction(func(tx *gorm.DB) error {
-		subDept.Name = newName
-		subDept.MasterDepartmentID = server.parseUint(deptID)
-		if err := tx.Save(&subDept).Error; err != nil {
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveSubDepartment(tx, &subDept, department); err != nil {
 			return err
 		}
-
-		if oldName != newName {
-			if err := tx.Model(&models.User{}).Where("sub_department = ?", oldName).Update("sub_department", newName).Error; err != nil {
-				return err
-			}
-		}
-		return nil
+		subDept.Name = r.FormValue("name")
+		return tx.Save(&subDept).Error
 	})
+	if err != nil {
+		http.Redirect(w, r, "/administration/master-data/sub-department?error="+url.QueryEscape("Gagal mengubah sub bagian: "+err.Error()), http.StatusSeeOther)
+		return
+	}
 
 	http.Redirect(w, r, "/administration/master-data/sub-department", http.StatusSeeOther)
 }
@@ -284,6 +313,8 @@ func (server *Server) ListMasterPosition(w http.ResponseWriter, r *http.Request)
 	server.RenderHTML(w, r, http.StatusOK, "administration/master_data/position", map[string]interface{}{
 		"title":     "Master Jabatan",
 		"positions": positions,
+		"error":     r.URL.Query().Get("error"),
+		"msg":       r.URL.Query().Get("msg"),
 	})
 }
 
@@ -299,7 +330,16 @@ func (server *Server) StoreMasterPosition(w http.ResponseWriter, r *http.Request
 func (server *Server) DeleteMasterPosition(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	server.DB.Unscoped().Delete(&models.MasterPosition{}, id)
+
+	var position models.MasterPosition
+	server.DB.Where("id = ?", id).Limit(1).Find(&position)
+	if position.ID != 0 {
+		if msg := orgUsageError("Jabatan "+position.Name, server.orgNodeUsages("position", position.ID)); msg != "" {
+			http.Redirect(w, r, "/administration/master-data/position?error="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
+		server.DB.Unscoped().Delete(&position)
+	}
 	http.Redirect(w, r, "/administration/master-data/position", http.StatusSeeOther)
 }
 
@@ -334,22 +374,8 @@ func (server *Server) UpdateMasterPosition(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	oldName := position.Name
-	newName := r.FormValue("name")
-
-	server.DB.Transaction(func(tx *gorm.DB) error {
-		position.Name = newName
-		if err := tx.Save(&position).Error; err != nil {
-			return err
-		}
-
-		if oldName != newName {
-			if err := tx.Model(&models.User{}).Where("position = ?", oldName).Update("position", newName).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	position.Name = r.FormValue("name")
+	server.DB.Save(&position)
 
 	http.Redirect(w, r, "/administration/master-data/position", http.StatusSeeOther)
 }
@@ -363,6 +389,8 @@ func (server *Server) ListMasterAssetCategory(w http.ResponseWriter, r *http.Req
 	server.RenderHTML(w, r, http.StatusOK, "inventori/master_data/asset_category", map[string]interface{}{
 		"title":      "Master Kategori Aset",
 		"categories": categories,
+		"error":      r.URL.Query().Get("error"),
+		"msg":        r.URL.Query().Get("msg"),
 	})
 }
 
@@ -375,10 +403,20 @@ func (server *Server) StoreMasterAssetCategory(w http.ResponseWriter, r *http.Re
 	http.Redirect(w, r, "/inventori/master-data/asset-category", http.StatusSeeOther)
 }
 
+// DeleteMasterAssetCategory menghapus kategori aset bila tidak lagi dipakai aset mana pun
 func (server *Server) DeleteMasterAssetCategory(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	server.DB.Unscoped().Delete(&models.MasterAssetCategory{}, id)
+
+	var category models.MasterAssetCategory
+	server.DB.Where("id = ?", id).Limit(1).Find(&category)
+	if category.ID != 0 {
+		if msg := orgUsageError("Kategori "+category.Name, server.assetCategoryUsages(category)); msg != "" {
+			http.Redirect(w, r, "/inventori/master-data/asset-category?error="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
+		server.DB.Unscoped().Delete(&category)
+	}
 	http.Redirect(w, r, "/inventori/master-data/asset-category", http.StatusSeeOther)
 }
 
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// mergeTarget adalah pilihan master data tujuan penggabungan
+type mergeTarget struct {
+	ID   uint
+	Name string
+}
+
+// masterDeletion adalah isi halaman analisis pemakaian sebelum satu master data dihapus atau digabung
+type masterDeletion struct {
+	Label     string // jenis master data, misalnya "Cabang"
+	Name      string
+	ListURL   string
+	DeleteURL string
+	MergeURL  string
+	MergeNote string // penjelasan apa saja yang dialihkan saat digabung
+	Usages    []orgUsage
+	Targets   []mergeTarget
+}
+
+// InUse menandakan master data masih dipakai sehingga hanya dapat digabung, tidak dihapus
+func (d masterDeletion) InUse() bool {
+	for _, u := range d.Usages {
+		if u.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// renderMasterDeletion menampilkan halaman analisis pemakaian master data
+func (server *Server) renderMasterDeletion(w http.ResponseWriter, r *http.Request, d masterDeletion) {
+	server.RenderHTML(w, r, http.StatusOK, "administration/master_data/delete", map[string]interface{}{
+		"title":    "Hapus " + d.Label,
+		"deletion": d,
+		"error":    r.URL.Query().Get("error"),
+	})
+}
+
+// mergeRedirect mengarahkan kembali setelah penggabungan: ke halaman analisis bila gagal, ke daftar bila berhasil
+func mergeRedirect(w http.ResponseWriter, r *http.Request, d masterDeletion, err error, msg string) {
+	if err != nil {
+		http.Redirect(w, r, d.DeleteURL+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, d.ListURL+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// mergeTargetID membaca master data tujuan dari form dan menolak penggabungan ke dirinya sendiri
+func (server *Server) mergeTargetID(r *http.Request, fromID uint) (uint, error) {
+	id := server.parseUint(r.FormValue("target_id"))
+	if id == 0 {
+		return 0, errors.New("Pilih data tujuan penggabungan")
+	}
+	if id == fromID {
+		return 0, errors.New("Data tidak dapat digabung ke dirinya sendiri")
+	}
+	return id, nil
+}
+
+// Master Branch
+
+func (server *Server) branchDeletion(branch models.MasterBranch) masterDeletion {
+	d := masterDeletion{
+		Label:     "Cabang",
+		Name:      branch.Name,
+		ListURL:   "/administration/master-data/branch",
+		DeleteURL: fmt.Sprintf("/administration/master-data/branch/delete/%d", branch.ID),
+		MergeURL:  fmt.Sprintf("/administration/master-data/branch/merge/%d", branch.ID),
+		MergeNote: "Bagian dengan nama yang sama digabung, bagian lainnya dipindah ke cabang tujuan. Karyawan, cakupan data admin, riwayat pemeliharaan dan lokasi aset dialihkan ke cabang tujuan.",
+		Usages:    server.orgNodeUsages(models.ScopeLevelBranch, branch.ID),
+	}
+	var branches []models.MasterBranch
+	server.DB.Where("id <> ?", branch.ID).Order("name").Find(&branches)
+	for _, b := range branches {
+		d.Targets = append(d.Targets, mergeTarget{b.ID, b.Name})
+	}
+	return d
+}
+
+// ConfirmDeleteMasterBranch menampilkan pemakaian cabang sebelum dihapus beserta pilihan untuk menggabungkannya
+func (server *Server) ConfirmDeleteMasterBranch(w http.ResponseWriter, r *http.Request) {
+	var branch models.MasterBranch
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&branch)
+	if branch.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/branch", http.StatusSeeOther)
+		return
+	}
+	server.renderMasterDeletion(w, r, server.branchDeletion(branch))
+}
+
+// MergeMasterBranch menggabungkan cabang ke cabang lain dalam satu transaksi
+func (server *Server) MergeMasterBranch(w http.ResponseWriter, r *http.Request) {
+	var from, into models.MasterBranch
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&from)
+	if from.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/branch", http.StatusSeeOther)
+		return
+	}
+	d := server.branchDeletion(from)
+	targetID, err := server.mergeTargetID(r, from.ID)
+	if err == nil {
+		server.DB.Where("id = ?", targetID).Limit(1).Find(&into)
+		if into.ID == 0 {
+			err = errors.New("Cabang tujuan tidak ditemukan")
+		}
+	}
+	if err == nil {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			return mergeBranch(tx, from, into)
+		})
+	}
+	mergeRedirect(w, r, d, err, "Cabang "+from.Name+" digabung ke "+into.Name)
+}
+
+// Master Department
+
+func (server *Server) departmentDeletion(department models.MasterDepartment) masterDeletion {
+	d := masterDeletion{
+		Label:     "Bagian",
+		Name:      department.MasterBranch.Name + " / " + department.Name,
+		ListURL:   "/administration/master-data/department",
+		DeleteURL: fmt.Sprintf("/administration/master-data/department/delete/%d", department.ID),
+		MergeURL:  fmt.Sprintf("/administration/master-data/department/merge/%d", department.ID),
+		MergeNote: "Sub bagian dengan nama yang sama digabung, sub bagian lainnya dipindah ke bagian tujuan. Karyawan, cakupan data admin dan riwayat pemeliharaan dialihkan ke bagian tujuan beserta cabangnya.",
+		Usages:    server.orgNodeUsages(models.ScopeLevelDepartment, department.ID),
+	}
+	var departments []models.MasterDepartment
+	server.DB.Preload("MasterBranch").Where("id <> ?", department.ID).Order("name").Find(&departments)
+	for _, dept := range departments {
+		d.Targets = append(d.Targets, mergeTarget{dept.ID, dept.MasterBranch.Name + " / " + dept.Name})
+	}
+	return d
+}
+
+// ConfirmDeleteMasterDepartment menampilkan pemakaian bagian sebelum dihapus beserta pilihan untuk menggabungkannya
+func (server *Server) ConfirmDeleteMasterDepartment(w http.ResponseWriter, r *http.Request) {
+	var department models.MasterDepartment
+	server.DB.Preload("MasterBranch").Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&department)
+	if department.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/department", http.StatusSeeOther)
+		return
+	}
+	server.renderMasterDeletion(w, r, server.departmentDeletion(department))
+}
+
+// MergeMasterDepartment menggabungkan bagian ke bagian lain dalam satu transaksi
+func (server *Server) MergeMasterDepartment(w http.ResponseWriter, r *http.Request) {
+	var from, into models.MasterDepartment
+	server.DB.Preload("MasterBranch").Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&from)
+	if from.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/department", http.StatusSeeOther)
+		return
+	}
+	d := server.departmentDeletion(from)
+	targetID, err := server.mergeTargetID(r, from.ID)
+	if err == nil {
+		server.DB.Where("id = ?", targetID).Limit(1).Find(&into)
+		if into.ID == 0 {
+			err = errors.New("Bagian tujuan tidak ditemukan")
+		}
+	}
+	if err == nil {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			return mergeDepartment(tx, from, into)
+		})
+	}
+	mergeRedirect(w, r, d, err, "Bagian "+from.Name+" digabung ke "+into.Name)
+}
+
+// Master Sub-Department
+
+func (server *Server) subDepartmentDeletion(subDept models.MasterSubDepartment) masterDeletion {
+	d := masterDeletion{
+		Label:     "Sub Bagian",
+		Name:      subDept.MasterDepartment.MasterBranch.Name + " / " + subDept.MasterDepartment.Name + " / " + subDept.Name,
+		ListURL:   "/administration/master-data/sub-department",
+		DeleteURL: fmt.Sprintf("/administration/master-data/sub-department/delete/%d", subDept.ID),
+		MergeURL:  fmt.Sprintf("/administration/master-data/sub-department/merge/%d", subDept.ID),
+		MergeNote: "Karyawan, cakupan data admin dan riwayat pemeliharaan dialihkan ke sub bagian tujuan beserta bagian dan cabangnya.",
+		Usages:    server.orgNodeUsages(models.ScopeLevelSubDepartment, subDept.ID),
+	}
+	var subDepts []models.MasterSubDepartment
+	server.DB.Preload("MasterDepartment.MasterBranch").Where("id <> ?", subDept.ID).Order("name").Find(&subDepts)
+	for _, s := range subDepts {
+		d.Targets = append(d.Targets, mergeTarget{s.ID, s.MasterDepartment.MasterBranch.Name + " / " + s.MasterDepartment.Name + " / " + s.Name})
+	}
+	return d
+}
+
+// ConfirmDeleteMasterSubDepartment menampilkan pemakaian sub bagian sebelum dihapus beserta pilihan untuk menggabungkannya
+func (server *Server) ConfirmDeleteMasterSubDepartment(w http.ResponseWriter, r *http.Request) {
+	var subDept models.MasterSubDepartment
+	server.DB.Preload("MasterDepartment.MasterBranch").Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&subDept)
+	if subDept.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/sub-department", http.StatusSeeOther)
+		return
+	}
+	server.renderMasterDeletion(w, r, server.subDepartmentDeletion(subDept))
+}
+
+// MergeMasterSubDepartment menggabungkan sub bagian ke sub bagian lain dalam satu transaksi
+func (server *Server) MergeMasterSubDepartment(w http.ResponseWriter, r *http.Request) {
+	var from, into models.MasterSubDepartment
+	server.DB.Preload("MasterDepartment.MasterBranch").Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&from)
+	if from.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/sub-department", http.StatusSeeOther)
+		return
+	}
+	d := server.subDepartmentDeletion(from)
+	targetID, err := server.mergeTargetID(r, from.ID)
+	if err == nil {
+		server.DB.Preload("MasterDepartment").Where("id = ?", targetID).Limit(1).Find(&into)
+		if into.ID == 0 {
+			err = errors.New("Sub bagian tujuan tidak ditemukan")
+		}
+	}
+	if err == nil {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			return mergeSubDepartment(tx, from, into, into.MasterDepartment)
+		})
+	}
+	mergeRedirect(w, r, d, err, "Sub bagian "+from.Name+" digabung ke "+into.Name)
+}
+
+// Master Position
+
+func (server *Server) positionDeletion(position models.MasterPosition) masterDeletion {
+	d := masterDeletion{
+		Label:     "Jabatan",
+		Name:      position.Name,
+		ListURL:   "/administration/master-data/position",
+		DeleteURL: fmt.Sprintf("/administration/master-data/position/delete/%d", position.ID),
+		MergeURL:  fmt.Sprintf("/administration/master-data/position/merge/%d", position.ID),
+		MergeNote: "Karyawan dan laporan pemeliharaan dialihkan ke jabatan tujuan.",
+		Usages:    server.orgNodeUsages("position", position.ID),
+	}
+	var positions []models.MasterPosition
+	server.DB.Where("id <> ?", position.ID).Order("name").Find(&positions)
+	for _, p := range positions {
+		d.Targets = append(d.Targets, mergeTarget{p.ID, p.Name})
+	}
+	return d
+}
+
+// ConfirmDeleteMasterPosition menampilkan pemakaian jabatan sebelum dihapus beserta pilihan untuk menggabungkannya
+func (server *Server) ConfirmDeleteMasterPosition(w http.ResponseWriter, r *http.Request) {
+	var position models.MasterPosition
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&position)
+	if position.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/position", http.StatusSeeOther)
+		return
+	}
+	server.renderMasterDeletion(w, r, server.positionDeletion(position))
+}
+
+// MergeMasterPosition menggabungkan jabatan ke jabatan lain dalam satu transaksi
+func (server *Server) MergeMasterPosition(w http.ResponseWriter, r *http.Request) {
+	var from, into models.MasterPosition
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&from)
+	if from.ID == 0 {
+		http.Redirect(w, r, "/administration/master-data/position", http.StatusSeeOther)
+		return
+	}
+	d := server.positionDeletion(from)
+	targetID, err := server.mergeTargetID(r, from.ID)
+	if err == nil {
+		server.DB.Where("id = ?", targetID).Limit(1).Find(&into)
+		if into.ID == 0 {
+			err = errors.New("Jabatan tujuan tidak ditemukan")
+		}
+	}
+	if err == nil {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			return mergePosition(tx, from, into)
+		})
+	}
+	mergeRedirect(w, r, d, err, "Jabatan "+from.Name+" digabung ke "+into.Name)
+}
+
+// Master Asset Category
+
+// assetCategoryUsages menghitung aset yang masih memakai kategori, termasuk aset yang sudah dihapus karena masih dapat dipulihkan
+func (server *Server) assetCategoryUsages(category models.MasterAssetCategory) []orgUsage {
+	var total int64
+	server.DB.Unscoped().Model(&models.AssetKSO{}).Where("category = ?", category.Name).Count(&total)
+	return []orgUsage{{"aset", total}}
+}
+
+func (server *Server) assetCategoryDeletion(category models.MasterAssetCategory) masterDeletion {
+	d := masterDeletion{
+		Label:     "Kategori Aset",
+		Name:      category.Name,
+		ListURL:   "/inventori/master-data/asset-category",
+		DeleteURL: fmt.Sprintf("/inventori/master-data/asset-category/delete/%d", category.ID),
+		MergeURL:  fmt.Sprintf("/inventori/master-data/asset-category/merge/%d", category.ID),
+		MergeNote: "Kategori aset dialihkan ke kategori tujuan.",
+		Usages:    server.assetCategoryUsages(category),
+	}
+	var categories []models.MasterAssetCategory
+	server.DB.Where("id <> ?", category.ID).Order("name").Find(&categories)
+	for _, c := range categories {
+		d.Targets = append(d.Targets, mergeTarget{c.ID, c.Name})
+	}
+	return d
+}
+
+// ConfirmDeleteMasterAssetCategory menampilkan pemakaian kategori aset sebelum dihapus beserta pilihan untuk menggabungkannya
+func (server *Server) ConfirmDeleteMasterAssetCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.MasterAssetCategory
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&category)
+	if category.ID == 0 {
+		http.Redirect(w, r, "/inventori/master-data/asset-category", http.StatusSeeOther)
+		return
+	}
+	server.renderMasterDeletion(w, r, server.assetCategoryDeletion(category))
+}
+
+// MergeMasterAssetCategory menggabungkan kategori aset ke kategori lain dalam satu transaksi
+func (server *Server) MergeMasterAssetCategory(w http.ResponseWriter, r *http.Request) {
+	var from, into models.MasterAssetCategory
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&from)
+	if from.ID == 0 {
+		http.Redirect(w, r, "/inventori/master-data/asset-category", http.StatusSeeOther)
+		return
+	}
+	d := server.assetCategoryDeletion(from)
+	targetID, err := server.mergeTargetID(r, from.ID)
+	if err == nil {
+		server.DB.Where("id = ?", targetID).Limit(1).Find(&into)
+		if into.ID == 0 {
+			err = errors.New("Kategori tujuan tidak ditemukan")
+		}
+	}
+	if err == nil {
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Model(&models.AssetKSO{}).Where("category = ?", from.Name).Update("category", into.Name).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&from).Error
+		})
+	}
+	mergeRedirect(w, r, d, err, "Kategori "+from.Name+" digabung ke "+into.Name)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 )
 
 // AuthRequired middleware checks if user is logged in
@@ -45,23 +46,22 @@ func (server *Server) RoleRequired(roles []string, next http.HandlerFunc) http.H
 }
 
 // PermissionRequired middleware checks if user's role has access to a specific resource
-// PermissionRequired adalah middleware untuk memeriksa izin akses terhadap sumber daya (resource) tertentu
-func (server *Server) PermissionRequired(resource string, next http.HandlerFunc) http.HandlerFunc {
+// PermissionRequired adalah middleware untuk memeriksa izin role terhadap satu aksi (misalnya "asset.create")
+func (server *Server) PermissionRequired(action string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, _, userRole, isLoggedIn := GetCurrentAdmin(r)
-		if !isLoggedIn {
+		p := server.currentPrincipal(r)
+		if p == nil {
+			// Akun yang sedang dilihat lewat mode "lihat sebagai" sudah tidak ada: kembali ke akun super_admin
+			if server.endStaleImpersonation(w, r) {
+				http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Akun yang dilihat sudah dihapus atau dinonaktifkan"), http.StatusSeeOther)
+				return
+			}
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
 
-		// Super Admin always has access to everything
-		if userRole == "super_admin" {
-			next(w, r)
-			return
-		}
-
-		perms := server.GetPermissions(userRole)
-		if allowed, ok := perms[resource]; ok && allowed {
+		// Izin super_admin selalu berisi semua aksi
+		if p.Permissions[action] {
 			next(w, r)
 			return
 		}
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/config"
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"github.com/gorilla/securecookie"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+)
+
+// authSourceOIDC adalah Admin.AuthSource untuk akun yang dibuat otomatis dari login SSO
+const authSourceOIDC = "oidc"
+
+// ssoStateCookie menyimpan state, nonce dan PKCE verifier selama pengguna berada di halaman identity provider
+const ssoStateCookie = "gokso-sso"
+
+// oidcRoleMapping memetakan satu nilai claim role/grup ke role gokso
+type oidcRoleMapping struct {
+	Role  string
+	Value string
+}
+
+// oidcClient adalah klien OpenID Connect (authorization code + PKCE).
+// Provider di-discover saat pertama dipakai sehingga aplikasi tetap bisa start walau identity provider sedang mati.
+type oidcClient struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	RoleClaim    string
+	RoleMapping  []oidcRoleMapping
+	AutoCreate   bool
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// ssoState adalah isi cookie ssoStateCookie
+type ssoState struct {
+	State     string
+	Nonce     string
+	Verifier  string
+	LinkAdmin string // admin yang menautkan SSO dari halaman profil; kosong untuk login biasa
+}
+
+// oidcClaims adalah claim ID token yang dipakai gokso
+type oidcClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// initSSO menyiapkan login SSO bila OIDC_ISSUER diisi
+func (server *Server) initSSO(appConfig config.AppConfig) {
+	server.SSO = nil
+	if appConfig.OIDCIssuer == "" {
+		return
+	}
+	client, err := newOIDCClient(appConfig, server.AppURL)
+	if err == nil {
+		for _, mapping := range client.RoleMapping {
+			if !server.roleExists(mapping.Role) {
+				err = fmt.Errorf("OIDC_ROLE_MAPPING memakai role tidak dikenal: %s", mapping.Role)
+				break
+			}
+		}
+	}
+	if err != nil {
+		if appConfig.AppEnv == "production" {
+			log.Fatal(err)
+		}
+		log.Printf("Warning: konfigurasi OIDC tidak valid (%v), login SSO dinonaktifkan", err)
+		return
+	}
+	server.SSO = client
+}
+
+// newOIDCClient membaca konfigurasi OIDC dari env
+func newOIDCClient(appConfig config.AppConfig, appURL string) (*oidcClient, error) {
+	c := &oidcClient{
+		Issuer:       strings.TrimRight(appConfig.OIDCIssuer, "/"),
+		ClientID:     appConfig.OIDCClientID,
+		ClientSecret: appConfig.OIDCClientSecret,
+		RedirectURL:  appConfig.OIDCRedirectURL,
+		Scopes:       strings.Fields(appConfig.OIDCScopes),
+		RoleClaim:    appConfig.OIDCRoleClaim,
+		AutoCreate:   appConfig.OIDCAutoCreate,
+	}
+	if c.ClientID == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID wajib diisi")
+	}
+	if c.RedirectURL == "" {
+		c.RedirectURL = appURL + "/login/sso/callback"
+	}
+	if !slices.Contains(c.Scopes, oidc.ScopeOpenID) {
+		c.Scopes = append([]string{oidc.ScopeOpenID}, c.Scopes...)
+	}
+
+	for _, part := range strings.Split(appConfig.OIDCRoleMapping, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		role, value, ok := strings.Cut(part, "=")
+		role, value = strings.TrimSpace(role), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("OIDC_ROLE_MAPPING tidak valid: %q (format role=nilai claim)", part)
+		}
+		c.RoleMapping = append(c.RoleMapping, oidcRoleMapping{Role: role, Value: value})
+	}
+	if c.AutoCreate && len(c.RoleMapping) == 0 {
+		return nil, fmt.Errorf("OIDC_ROLE_MAPPING wajib diisi bila OIDC_AUTO_CREATE aktif")
+	}
+	return c, nil
+}
+
+// oauthConfig men-discover provider (sekali) dan menyusun konfigurasi OAuth2
+func (c *oidcClient) oauthConfig(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.provider == nil {
+		provider, err := oidc.NewProvider(ctx, c.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.provider = provider
+	}
+	return &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Endpoint:     c.provider.Endpoint(),
+		Scopes:       c.Scopes,
+	}, c.provider, nil
+}
+
+// roleFor memilih role gokso dari claim role/grup; pemetaan pertama yang cocok menang
+func (c *oidcClient) roleFor(raw map[string]interface{}) string {
+	var values []string
+	switch v := raw[c.RoleClaim].(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	for _, mapping := range c.RoleMapping {
+		if slices.Contains(values, mapping.Value) {
+			return mapping.Role
+		}
+	}
+	return ""
+}
+
+// LoginSSO mengarahkan pengguna ke identity provider
+func (server *Server) LoginSSO(w http.ResponseWriter, r *http.Request) {
+	if server.SSO == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if msg := server.startSSO(w, r, ""); msg != "" {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+}
+
+// LinkSSO menautkan identitas SSO ke akun yang sedang login. Akun yang sudah ada hanya dapat ditautkan
+// dari sini, tidak otomatis lewat email saat login SSO.
+func (server *Server) LinkSSO(w http.ResponseWriter, r *http.Request) {
+	if server.SSO == nil {
+		http.NotFound(w, r)
+		return
+	}
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	if msg := server.startSSO(w, r, adminID); msg != "" {
+		http.Redirect(w, r, "/profile?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+}
+
+// startSSO menyimpan state di cookie lalu mengarahkan ke identity provider.
+// Mengembalikan pesan error bila login SSO tidak dapat dimulai.
+func (server *Server) startSSO(w http.ResponseWriter, r *http.Request, linkAdmin string) string {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	cfg, _, err := server.SSO.oauthConfig(ctx)
+	if err != nil {
+		log.Printf("OIDC: discovery gagal: %v", err)
+		return "Layanan SSO tidak dapat dihubungi, gunakan login password atau coba lagi nanti"
+	}
+
+	state := ssoState{State: newSessionToken(), Nonce: newSessionToken(), Verifier: oauth2.GenerateVerifier(), LinkAdmin: linkAdmin}
+	encoded, err := securecookie.EncodeMulti(ssoStateCookie, state, store.challenge)
+	if err != nil {
+		return "Gagal memulai login SSO"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    encoded,
+		Path:     "/login/sso",
+		MaxAge:   int(loginChallengeTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   store.Options.Secure,
+		SameSite: http.SameSiteLaxMode, // harus Lax agar cookie ikut terkirim saat redirect balik dari provider
+	})
+
+	http.Redirect(w, r, cfg.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier)), http.StatusFound)
+	return ""
+}
+
+// LoginSSOCallback menukar authorization code, memverifikasi ID token lalu membuat session admin
+func (server *Server) LoginSSOCallback(w http.ResponseWriter, r *http.Request) {
+	if server.SSO == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var state ssoState
+	cookie, err := r.Cookie(ssoStateCookie)
+	if err != nil || securecookie.DecodeMulti(ssoStateCookie, cookie.Value, &state, store.challenge) != nil {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi login SSO berakhir, silakan coba lagi"), http.StatusSeeOther)
+		return
+	}
+	back := "/login"
+	if state.LinkAdmin != "" {
+		back = "/profile"
+	}
+	fail := func(msg string) {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+	http.SetCookie(w, &http.Cookie{Name: ssoStateCookie, Value: "", Path: "/login/sso", MaxAge: -1, HttpOnly: true})
+
+	q := r.URL.Query()
+	if q.Get("state") != state.State {
+		fail("Respons SSO tidak valid, silakan coba lagi")
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		log.Printf("OIDC: provider menolak login: %s %s", e, q.Get("error_description"))
+		fail("Login SSO dibatalkan atau ditolak oleh penyedia identitas")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	cfg, provider, err := server.SSO.oauthConfig(ctx)
+	if err != nil {
+		log.Printf("OIDC: discovery gagal: %v", err)
+		fail("Layanan SSO tidak dapat dihubungi, gunakan login password atau coba lagi nanti")
+		return
+	}
+	token, err := cfg.Exchange(ctx, q.Get("code"), oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		log.Printf("OIDC: penukaran code gagal: %v", err)
+		fail("Login SSO gagal, silakan coba lagi")
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: server.SSO.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != state.Nonce {
+		log.Printf("OIDC: ID token tidak valid: %v", err)
+		fail("Login SSO gagal, token tidak valid")
+		return
+	}
+
+	var claims oidcClaims
+	var raw map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil || idToken.Claims(&raw) != nil {
+		fail("Login SSO gagal, data identitas tidak terbaca")
+		return
+	}
+	claims.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+
+	if state.LinkAdmin != "" {
+		// Penautan hanya berlaku untuk session yang memulainya
+		if adminID, _, _, ok := GetCurrentAdmin(r); !ok || adminID != state.LinkAdmin {
+			fail("Session berubah selama penautan SSO, silakan coba lagi")
+			return
+		}
+		if err := server.linkSSO(state.LinkAdmin, claims.Subject); err != nil {
+			fail(err.Error())
+			return
+		}
+		http.Redirect(w, r, "/profile?msg="+url.QueryEscape("Akun berhasil ditautkan ke SSO"), http.StatusSeeOther)
+		return
+	}
+
+	admin, err := server.ssoAdmin(claims, server.SSO.roleFor(raw))
+	label := claims.Email
+	if label == "" {
+		label = claims.Subject
+	}
+	if err != nil {
+		server.recordLoginAttempt(r, label, admin.ID, false, loginReasonSSORejected)
+		fail(err.Error())
+		return
+	}
+	if left := server.accountLockedFor(&admin); left > 0 {
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonLocked)
+		fail(lockedMessage(left))
+		return
+	}
+
+	// 2FA gokso tetap berlaku untuk akun yang mengaktifkannya atau role yang mewajibkannya
+	if admin.TOTPEnabled || server.requires2FA(admin.Role) {
+		if err := server.startLoginChallenge(w, admin.ID); err != nil {
+			fail("Gagal memulai verifikasi dua langkah")
+			return
+		}
+		next := "/login/2fa"
+		if !admin.TOTPEnabled {
+			next = "/login/2fa/setup"
+		}
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	if err := server.createLoginSession(w, r, admin); err != nil {
+		fail("Gagal membuat session login")
+		return
+	}
+	server.resetLoginFailures(&admin)
+	server.recordLoginAttempt(r, admin.Username, admin.ID, true, loginReasonSSOSuccess)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// ssoAdmin mencari Admin yang sudah tertaut ke subject SSO. Akun lokal yang belum tertaut tidak pernah
+// ditautkan otomatis lewat email; pemiliknya login dengan password lalu menautkan SSO dari halaman profil.
+// Bila belum ada akun dan OIDC_AUTO_CREATE aktif, admin baru dibuat dengan role dari claim.
+func (server *Server) ssoAdmin(claims oidcClaims, role string) (models.Admin, error) {
+	var admin models.Admin
+	if claims.Subject == "" {
+		return admin, errors.New("Login SSO gagal, identitas tidak lengkap")
+	}
+
+	server.DB.Where("oidc_subject = ?", claims.Subject).Limit(1).Find(&admin)
+
+	// Email hanya dipercaya untuk menemukan karyawan bila provider menyatakannya terverifikasi;
+	// claim email_verified yang tidak dikirim dianggap belum terverifikasi
+	var employee models.User
+	if claims.Email != "" && claims.EmailVerified {
+		server.DB.Where("LOWER(email) = ?", claims.Email).Limit(1).Find(&employee)
+	}
+
+	if admin.ID == "" {
+		if claims.Email == "" || !claims.EmailVerified {
+			return admin, errors.New("Email akun SSO belum terverifikasi atau tidak tersedia")
+		}
+		if employee.ID != "" {
+			var count int64
+			server.DB.Model(&models.Admin{}).Where("user_id = ?", employee.ID).Count(&count)
+			if count > 0 {
+				return admin, errors.New(errSSONotLinked)
+			}
+		}
+	}
+
+	if admin.ID == "" {
+		if !server.SSO.AutoCreate {
+			return admin, errors.New("Belum ada akun gokso untuk email ini. Hubungi administrator")
+		}
+		if role == "" {
+			return admin, errors.New("Akun SSO Anda tidak memiliki akses ke aplikasi ini. Hubungi administrator")
+		}
+		hashed, _ := bcrypt.GenerateFromPassword([]byte(uuid.New().String()+uuid.New().String()), bcrypt.DefaultCost)
+		admin = models.Admin{
+			ID:         uuid.New().String(),
+			UserID:     employee.ID,
+			Username:   server.ssoUsername(claims),
+			Password:   string(hashed),
+			Role:       role,
+			AuthSource: authSourceOIDC,
+		}
+	}
+
+	if admin.Disabled {
+		return admin, errors.New("Akun Anda dinonaktifkan. Hubungi administrator")
+	}
+
+	// Role akun hasil SSO mengikuti claim; akun lokal yang ditautkan tetap memakai role yang diatur di gokso
+	if admin.AuthSource == authSourceOIDC && len(server.SSO.RoleMapping) > 0 {
+		if role == "" {
+			return admin, errors.New("Akun SSO Anda tidak memiliki akses ke aplikasi ini. Hubungi administrator")
+		}
+		if admin.Role != "" && admin.Role != role {
+			revokeAdminSessions(server.DB, admin.ID)
+		}
+		admin.Role = role
+	}
+	admin.OIDCSubject = claims.Subject
+
+	if err := server.DB.Save(&admin).Error; err != nil {
+		return admin, errors.New("Gagal menyimpan akun SSO")
+	}
+	return admin, nil
+}
+
+// errSSONotLinked ditampilkan saat identitas SSO cocok dengan karyawan yang sudah memiliki akun lokal
+const errSSONotLinked = "Akun gokso untuk email ini sudah ada tetapi belum tertaut ke SSO. Login dengan password, lalu tautkan SSO dari halaman profil"
+
+// linkSSO menautkan subject SSO ke admin; subject yang sudah dipakai admin lain ditolak
+func (server *Server) linkSSO(adminID, subject string) error {
+	if subject == "" {
+		return errors.New("Penautan SSO gagal, identitas tidak lengkap")
+	}
+	var owner models.Admin
+	server.DB.Where("oidc_subject = ?", subject).Limit(1).Find(&owner)
+	if owner.ID != "" && owner.ID != adminID {
+		return errors.New("Identitas SSO ini sudah tertaut ke akun lain. Hubungi administrator")
+	}
+	if err := server.DB.Model(&models.Admin{}).Where("id = ?", adminID).Update("oidc_subject", subject).Error; err != nil {
+		return errors.New("Gagal menyimpan penautan SSO")
+	}
+	return nil
+}
+
+var ssoUsernamePattern = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// ssoUsername menurunkan username unik dari preferred_username atau bagian lokal email
+func (server *Server) ssoUsername(claims oidcClaims) string {
+	base := claims.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = ssoUsernamePattern.ReplaceAllString(strings.ToLower(base), "")
+	if base == "" {
+		base = "sso"
+	}
+	base = truncate(base, 40)
+
+	username := base
+	for i := 2; ; i++ {
+		var count int64
+		server.DB.Model(&models.Admin{}).Where("username = ?", username).Count(&count)
+		if count == 0 {
+			return username
+		}
+		username = fmt.Sprintf("%s-%d", base, i)
+	}
+}
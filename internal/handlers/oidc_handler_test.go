@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// mockOIDCProvider adalah identity provider OIDC minimal: discovery, token endpoint dan JWKS.
+// Claims berikutnya diatur lewat next dan dikirim sebagai ID token bertanda tangan RS256.
+type mockOIDCProvider struct {
+	*httptest.Server
+	key  *rsa.PrivateKey
+	next map[string]interface{}
+}
+
+func newMockOIDCProvider(tb testing.TB) *mockOIDCProvider {
+	tb.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p := &mockOIDCProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/auth",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.sign(tb, p.next),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	tb.Cleanup(p.Close)
+	return p
+}
+
+// sign membuat JWT RS256 dari claims dengan iss, aud dan masa berlaku standar
+func (p *mockOIDCProvider) sign(tb testing.TB, claims map[string]interface{}) string {
+	tb.Helper()
+	payload := map[string]interface{}{"iss": p.URL, "aud": "gokso", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+	for k, v := range claims {
+		payload[k] = v
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	body, _ := json.Marshal(payload)
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// ssoServer menyiapkan server test dengan login SSO ke mock provider; staf_it dipetakan dari grup "it"
+func ssoServer(tb testing.TB, autoCreate bool) (*Server, *mockOIDCProvider) {
+	tb.Helper()
+	server := newTestServer(tb)
+	provider := newMockOIDCProvider(tb)
+	server.SSO = &oidcClient{
+		Issuer:      provider.URL,
+		ClientID:    "gokso",
+		RedirectURL: "http://gokso.test/login/sso/callback",
+		Scopes:      []string{"openid", "email"},
+		RoleClaim:   "groups",
+		RoleMapping: []oidcRoleMapping{{Role: "staf_it", Value: "it"}},
+		AutoCreate:  autoCreate,
+	}
+	return server, provider
+}
+
+// ssoRoundTrip memulai SSO lewat start (dengan cookie session bila ada), membuat provider mengirim claims
+// untuk nonce yang diminta, lalu memanggil callback. Mengembalikan respons callback.
+func ssoRoundTrip(tb testing.TB, server *Server, provider *mockOIDCProvider, start *http.Request, claims map[string]interface{}) *httptest.ResponseRecorder {
+	tb.Helper()
+	w := httptest.NewRecorder()
+	server.Router.ServeHTTP(w, start)
+	if w.Code != http.StatusFound {
+		tb.Fatalf("start SSO = %d, want %d (%s)", w.Code, http.StatusFound, w.Header().Get("Location"))
+	}
+	auth, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var stateCookie string
+	for _, c := range w.Result().Cookies() {
+		if c.Name == ssoStateCookie {
+			stateCookie = c.Name + "=" + c.Value
+		}
+	}
+
+	provider.next = map[string]interface{}{"nonce": auth.Query().Get("nonce")}
+	for k, v := range claims {
+		provider.next[k] = v
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, "/login/sso/callback?code=c1&state="+url.QueryEscape(auth.Query().Get("state")), nil)
+	cookies := []string{stateCookie}
+	if session := start.Header.Get("Cookie"); session != "" {
+		cookies = append(cookies, session)
+	}
+	callback.Header.Set("Cookie", strings.Join(cookies, "; "))
+	w = httptest.NewRecorder()
+	server.Router.ServeHTTP(w, callback)
+	return w
+}
+
+// ssoLogin menjalankan login SSO dari halaman login
+func ssoLogin(tb testing.TB, server *Server, provider *mockOIDCProvider, claims map[string]interface{}) *httptest.ResponseRecorder {
+	return ssoRoundTrip(tb, server, provider, httptest.NewRequest(http.MethodGet, "/login/sso", nil), claims)
+}
+
+func TestSSOCallbackLogsInLinkedAdmin(t *testing.T) {
+	server, provider := ssoServer(t, false)
+	server.DB.Create(&models.Admin{ID: "a2", Username: "staf", Password: "x", Role: "staf_it", OIDCSubject: "sub-staf"})
+
+	w := ssoLogin(t, server, provider, map[string]interface{}{"sub": "sub-staf", "email": "staf@example.com", "email_verified": true})
+	if loc := w.Header().Get("Location"); loc != "/" {
+		t.Fatalf("redirect = %q, want /", loc)
+	}
+	var count int64
+	server.DB.Model(&models.AdminSession{}).Where("admin_id = ?", "a2").Count(&count)
+	if count != 1 {
+		t.Errorf("sessions = %d, want 1", count)
+	}
+}
+
+func TestSSOCallbackDoesNotLinkExistingAccountByEmail(t *testing.T) {
+	for _, autoCreate := range []bool{false, true} {
+		server, provider := ssoServer(t, autoCreate)
+
+		// Email karyawan u1 dimiliki super_admin a1 yang belum tertaut ke SSO
+		w := ssoLogin(t, server, provider, map[string]interface{}{"sub": "sub-attacker", "email": "BUDI@example.com", "email_verified": true, "groups": []string{"it"}})
+		if msg := redirectError(t, w.Header().Get("Location")); msg != errSSONotLinked {
+			t.Fatalf("auto create %v: error = %q, want %q", autoCreate, msg, errSSONotLinked)
+		}
+		var admin models.Admin
+		server.DB.First(&admin, "id = ?", "a1")
+		if admin.OIDCSubject != "" {
+			t.Errorf("auto create %v: super_admin linked to %q", autoCreate, admin.OIDCSubject)
+		}
+		var count int64
+		server.DB.Model(&models.Admin{}).Count(&count)
+		if count != 1 {
+			t.Errorf("auto create %v: admins = %d, want 1", autoCreate, count)
+		}
+	}
+}
+
+func TestSSOCallbackRejectsUnverifiedEmail(t *testing.T) {
+	server, provider := ssoServer(t, true)
+
+	w := ssoLogin(t, server, provider, map[string]interface{}{"sub": "sub-new", "email": "baru@example.com", "groups": []string{"it"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg == "" {
+		t.Fatal("login with unverified email was accepted")
+	}
+	var count int64
+	server.DB.Model(&models.Admin{}).Where("oidc_subject = ?", "sub-new").Count(&count)
+	if count != 0 {
+		t.Error("admin created for unverified email")
+	}
+}
+
+func TestSSOCallbackRejectsWrongNonce(t *testing.T) {
+	server, provider := ssoServer(t, false)
+	server.DB.Create(&models.Admin{ID: "a2", Username: "staf", Password: "x", Role: "staf_it", OIDCSubject: "sub-staf"})
+
+	w := ssoLogin(t, server, provider, map[string]interface{}{"sub": "sub-staf", "nonce": "replayed"})
+	if msg := redirectError(t, w.Header().Get("Location")); msg == "" {
+		t.Fatal("ID token with a foreign nonce was accepted")
+	}
+}
+
+func TestSSOCallbackAutoCreatesAdmin(t *testing.T) {
+	server, provider := ssoServer(t, true)
+
+	w := ssoLogin(t, server, provider, map[string]interface{}{"sub": "sub-new", "email": "baru@example.com", "email_verified": true, "preferred_username": "baru", "groups": []string{"it"}})
+	if loc := w.Header().Get("Location"); loc != "/" {
+		t.Fatalf("redirect = %q, want /", loc)
+	}
+	var admin models.Admin
+	if err := server.DB.First(&admin, "oidc_subject = ?", "sub-new").Error; err != nil {
+		t.Fatal(err)
+	}
+	if admin.Role != "staf_it" || admin.AuthSource != authSourceOIDC || admin.Username != "baru" {
+		t.Errorf("admin = role %q, source %q, username %q", admin.Role, admin.AuthSource, admin.Username)
+	}
+}
+
+func TestLinkSSOFromProfile(t *testing.T) {
+	server, provider := ssoServer(t, false)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	link := func() *http.Request { return client.request(http.MethodPost, "/profile/sso/link", url.Values{}) }
+
+	w := ssoRoundTrip(t, server, provider, link(), map[string]interface{}{"sub": "sub-budi", "email": "budi@example.com", "email_verified": true})
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/profile?msg=") {
+		t.Fatalf("redirect = %q, want profile success", loc)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "a1")
+	if admin.OIDCSubject != "sub-budi" {
+		t.Fatalf("oidc_subject = %q, want sub-budi", admin.OIDCSubject)
+	}
+
+	// Identitas yang sudah tertaut ke admin lain tidak dapat diambil alih
+	server.DB.Create(&models.Admin{ID: "a2", Username: "staf", Password: "x", Role: "staf_it", OIDCSubject: "sub-staf"})
+	w = ssoRoundTrip(t, server, provider, link(), map[string]interface{}{"sub": "sub-staf"})
+	if msg := redirectError(t, w.Header().Get("Location")); msg == "" {
+		t.Fatal("linking a subject owned by another admin was accepted")
+	}
+	server.DB.First(&admin, "id = ?", "a1")
+	if admin.OIDCSubject != "sub-budi" {
+		t.Errorf("oidc_subject = %q, want sub-budi", admin.OIDCSubject)
+	}
+}
+
+func TestLinkSSORequiresStartingSession(t *testing.T) {
+	server, provider := ssoServer(t, false)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	start := client.request(http.MethodPost, "/profile/sso/link", url.Values{})
+
+	// Callback tanpa session yang memulai penautan ditolak
+	w := httptest.NewRecorder()
+	server.Router.ServeHTTP(w, start)
+	auth, _ := url.Parse(w.Header().Get("Location"))
+	provider.next = map[string]interface{}{"sub": "sub-x", "nonce": auth.Query().Get("nonce")}
+	callback := httptest.NewRequest(http.MethodGet, "/login/sso/callback?code=c1&state="+url.QueryEscape(auth.Query().Get("state")), nil)
+	for _, c := range w.Result().Cookies() {
+		callback.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	server.Router.ServeHTTP(w, callback)
+	if msg := redirectError(t, w.Header().Get("Location")); msg == "" {
+		t.Fatal("link callback without the session was accepted")
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "a1")
+	if admin.OIDCSubject != "" {
+		t.Errorf("oidc_subject = %q, want empty", admin.OIDCSubject)
+	}
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// orgChartURL adalah halaman struktur organisasi
+const orgChartURL = "/administration/master-data/org-chart"
+
+// orgTreeNode adalah satu node pada pohon struktur organisasi beserta jumlah karyawan dan aset di dalamnya
+type orgTreeNode struct {
+	Level     string // models.ScopeLevel*
+	ID        uint
+	Name      string
+	Employees int64
+	Assets    int64
+	Children  []*orgTreeNode
+}
+
+// orgCount adalah hasil hitung per kombinasi cabang, bagian dan sub bagian
+type orgCount struct {
+	BranchID        *uint
+	DepartmentID    *uint
+	SubDepartmentID *uint
+	Total           int64
+}
+
+// orgTree menyusun pohon cabang > bagian > sub bagian. Karyawan dan aset dihitung ke node tempat karyawannya
+// ditempatkan beserta node induknya; stok aset tanpa pemegang dihitung ke cabang sesuai lokasinya.
+func (server *Server) orgTree() []*orgTreeNode {
+	var branches []models.MasterBranch
+	server.DB.Preload("Departments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name asc")
+	}).Preload("Departments.SubDepartments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name asc")
+	}).Order("name asc").Find(&branches)
+
+	type key struct {
+		level string
+		id    uint
+	}
+	employees := make(map[key]int64)
+	assets := make(map[key]int64)
+	tally := func(counts map[key]int64, rows []orgCount) {
+		for _, row := range rows {
+			counts[key{models.ScopeLevelBranch, uintValue(row.BranchID)}] += row.Total
+			counts[key{models.ScopeLevelDepartment, uintValue(row.DepartmentID)}] += row.Total
+			counts[key{models.ScopeLevelSubDepartment, uintValue(row.SubDepartmentID)}] += row.Total
+		}
+	}
+
+	var userRows []orgCount
+	server.DB.Model(&models.User{}).Select("branch_id, department_id, sub_department_id, COUNT(*) AS total").
+		Group("branch_id, department_id, sub_department_id").Scan(&userRows)
+	tally(employees, userRows)
+
+	var assetRows []orgCount
+	server.DB.Model(&models.AssetKSO{}).
+		Select("users.branch_id, users.department_id, users.sub_department_id, COUNT(*) AS total").
+		Joins("JOIN users ON users.id = asset_kso.user_id").
+		Group("users.branch_id, users.department_id, users.sub_department_id").Scan(&assetRows)
+	tally(assets, assetRows)
+
+	var stock []struct {
+		Location string
+		Total    int64
+	}
+	server.DB.Model(&models.AssetKSO{}).Select("location, COUNT(*) AS total").
+		Where("user_id IS NULL OR user_id = ''").Group("location").Scan(&stock)
+	stockByBranch := make(map[string]int64)
+	for _, row := range stock {
+		stockByBranch[row.Location] += row.Total
+	}
+
+	var tree []*orgTreeNode
+	for _, b := range branches {
+		branch := &orgTreeNode{Level: models.ScopeLevelBranch, ID: b.ID, Name: b.Name,
+			Employees: employees[key{models.ScopeLevelBranch, b.ID}],
+			Assets:    assets[key{models.ScopeLevelBranch, b.ID}] + stockByBranch[b.Name]}
+		for _, d := range b.Departments {
+			dept := &orgTreeNode{Level: models.ScopeLevelDepartment, ID: d.ID, Name: d.Name,
+				Employees: employees[key{models.ScopeLevelDepartment, d.ID}],
+				Assets:    assets[key{models.ScopeLevelDepartment, d.ID}]}
+			for _, s := range d.SubDepartments {
+				dept.Children = append(dept.Children, &orgTreeNode{Level: models.ScopeLevelSubDepartment, ID: s.ID, Name: s.Name,
+					Employees: employees[key{models.ScopeLevelSubDepartment, s.ID}],
+					Assets:    assets[key{models.ScopeLevelSubDepartment, s.ID}]})
+			}
+			branch.Children = append(branch.Children, dept)
+		}
+		tree = append(tree, branch)
+	}
+	return tree
+}
+
+// OrgChart menampilkan struktur organisasi (cabang, bagian dan sub bagian) dalam satu pohon
+func (server *Server) OrgChart(w http.ResponseWriter, r *http.Request) {
+	server.RenderHTML(w, r, http.StatusOK, "administration/master_data/org_chart", map[string]interface{}{
+		"title": "Struktur Organisasi",
+		"tree":  server.orgTree(),
+		"msg":   r.URL.Query().Get("msg"),
+		"error": r.URL.Query().Get("error"),
+	})
+}
+
+// orgNameTaken memeriksa nama kembar di bawah induk yang sama; excludeID untuk mengabaikan node yang sedang diubah
+func (server *Server) orgNameTaken(level string, parentID uint, name string, excludeID uint) bool {
+	var total int64
+	switch level {
+	case models.ScopeLevelBranch:
+		server.DB.Model(&models.MasterBranch{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, excludeID).Count(&total)
+	case models.ScopeLevelDepartment:
+		server.DB.Model(&models.MasterDepartment{}).Where("master_branch_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", parentID, name, excludeID).Count(&total)
+	case models.ScopeLevelSubDepartment:
+		server.DB.Model(&models.MasterSubDepartment{}).Where("master_department_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", parentID, name, excludeID).Count(&total)
+	}
+	return total > 0
+}
+
+// orgChartRedirect kembali ke halaman struktur organisasi dengan pesan sukses atau galat
+func orgChartRedirect(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if err != nil {
+		http.Redirect(w, r, orgChartURL+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, orgChartURL+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// StoreOrgNode menambah cabang, bagian atau sub bagian langsung dari pohon organisasi
+func (server *Server) StoreOrgNode(w http.ResponseWriter, r *http.Request) {
+	level := r.FormValue("level")
+	name := strings.TrimSpace(r.FormValue("name"))
+	parentID := server.parseUint(r.FormValue("parent_id"))
+	if name == "" {
+		orgChartRedirect(w, r, errors.New("Nama wajib diisi"), "")
+		return
+	}
+	if server.orgNameTaken(level, parentID, name, 0) {
+		orgChartRedirect(w, r, errors.New(name+" sudah ada"), "")
+		return
+	}
+
+	var err error
+	switch level {
+	case models.ScopeLevelBranch:
+		err = server.DB.Create(&models.MasterBranch{Name: name}).Error
+	case models.ScopeLevelDepartment:
+		var branch models.MasterBranch
+		server.DB.Where("id = ?", parentID).Limit(1).Find(&branch)
+		if branch.ID == 0 {
+			err = errors.New("Cabang tidak ditemukan")
+			break
+		}
+		err = server.DB.Create(&models.MasterDepartment{Name: name, MasterBranchID: branch.ID}).Error
+	case models.ScopeLevelSubDepartment:
+		var department models.MasterDepartment
+		server.DB.Where("id = ?", parentID).Limit(1).Find(&department)
+		if department.ID == 0 {
+			err = errors.New("Bagian tidak ditemukan")
+			break
+		}
+		err = server.DB.Create(&models.MasterSubDepartment{Name: name, MasterDepartmentID: department.ID}).Error
+	default:
+		err = errors.New("Jenis node tidak dikenal")
+	}
+	orgChartRedirect(w, r, err, name+" berhasil ditambahkan")
+}
+
+// RenameOrgNode mengganti nama node. Karyawan menunjuk node lewat ID; untuk cabang, lokasi aset dan surat jalan
+// yang menyimpan nama cabang ikut diganti.
+func (server *Server) RenameOrgNode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := server.parseUint(vars["id"])
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		orgChartRedirect(w, r, errors.New("Nama wajib diisi"), "")
+		return
+	}
+
+	var model interface{}
+	var parentID uint
+	var branch models.MasterBranch
+	switch vars["level"] {
+	case models.ScopeLevelBranch:
+		server.DB.Where("id = ?", id).Limit(1).Find(&branch)
+		model = &branch
+		id = branch.ID
+	case models.ScopeLevelDepartment:
+		var department models.MasterDepartment
+		server.DB.Where("id = ?", id).Limit(1).Find(&department)
+		model, parentID, id = &department, department.MasterBranchID, department.ID
+	case models.ScopeLevelSubDepartment:
+		var subDept models.MasterSubDepartment
+		server.DB.Where("id = ?", id).Limit(1).Find(&subDept)
+		model, parentID, id = &subDept, subDept.MasterDepartmentID, subDept.ID
+	}
+	if model == nil || id == 0 {
+		orgChartRedirect(w, r, errors.New("Node tidak ditemukan"), "")
+		return
+	}
+	if server.orgNameTaken(vars["level"], parentID, name, id) {
+		orgChartRedirect(w, r, errors.New(name+" sudah ada"), "")
+		return
+	}
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if branch.ID != 0 {
+			return renameBranch(tx, &branch, name)
+		}
+		return tx.Model(model).Update("name", name).Error
+	})
+	orgChartRedirect(w, r, err, "Nama diganti menjadi "+name)
+}
+
+// MoveOrgNode memindahkan bagian ke cabang lain atau sub bagian ke bagian lain (drag-and-drop di pohon)
+func (server *Server) MoveOrgNode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	parentID := server.parseUint(r.FormValue("parent_id"))
+
+	var err error
+	var msg string
+	switch vars["level"] {
+	case models.ScopeLevelDepartment:
+		var department models.MasterDepartment
+		var branch models.MasterBranch
+		server.DB.Where("id = ?", id).Limit(1).Find(&department)
+		server.DB.Where("id = ?", parentID).Limit(1).Find(&branch)
+		switch {
+		case department.ID == 0 || branch.ID == 0:
+			err = errors.New("Bagian atau cabang tujuan tidak ditemukan")
+		case department.MasterBranchID != branch.ID && server.orgNameTaken(models.ScopeLevelDepartment, branch.ID, department.Name, department.ID):
+			err = errors.New("Cabang " + branch.Name + " sudah memiliki bagian " + department.Name)
+		default:
+			err = server.DB.Transaction(func(tx *gorm.DB) error {
+				return moveDepartment(tx, &department, branch.ID)
+			})
+			msg = "Bagian " + department.Name + " dipindah ke cabang " + branch.Name
+		}
+	case models.ScopeLevelSubDepartment:
+		var subDept models.MasterSubDepartment
+		var department models.MasterDepartment
+		server.DB.Where("id = ?", id).Limit(1).Find(&subDept)
+		server.DB.Where("id = ?", parentID).Limit(1).Find(&department)
+		switch {
+		case subDept.ID == 0 || department.ID == 0:
+			err = errors.New("Sub bagian atau bagian tujuan tidak ditemukan")
+		case subDept.MasterDepartmentID != department.ID && server.orgNameTaken(models.ScopeLevelSubDepartment, department.ID, subDept.Name, subDept.ID):
+			err = errors.New("Bagian " + department.Name + " sudah memiliki sub bagian " + subDept.Name)
+		default:
+			err = server.DB.Transaction(func(tx *gorm.DB) error {
+				return moveSubDepartment(tx, &subDept, department)
+			})
+			msg = "Sub bagian " + subDept.Name + " dipindah ke bagian " + department.Name
+		}
+	default:
+		err = errors.New("Hanya bagian dan sub bagian yang dapat dipindah")
+	}
+	orgChartRedirect(w, r, err, msg)
+}
+
+// DeleteOrgNode menghapus node yang sudah tidak dipakai karyawan, aset, laporan maupun node di bawahnya
+func (server *Server) DeleteOrgNode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	level := vars["level"]
+
+	var model interface{}
+	var id uint
+	var label string
+	switch level {
+	case models.ScopeLevelBranch:
+		var branch models.MasterBranch
+		server.DB.Where("id = ?", vars["id"]).Limit(1).Find(&branch)
+		model, id, label = &branch, branch.ID, "Cabang "+branch.Name
+	case models.ScopeLevelDepartment:
+		var department models.MasterDepartment
+		server.DB.Where("id = ?", vars["id"]).Limit(1).Find(&department)
+		model, id, label = &department, department.ID, "Bagian "+department.Name
+	case models.ScopeLevelSubDepartment:
+		var subDept models.MasterSubDepartment
+		server.DB.Where("id = ?", vars["id"]).Limit(1).Find(&subDept)
+		model, id, label = &subDept, subDept.ID, "Sub bagian "+subDept.Name
+	}
+	if model == nil || id == 0 {
+		orgChartRedirect(w, r, errors.New("Node tidak ditemukan"), "")
+		return
+	}
+	if msg := orgUsageError(label, server.orgNodeUsages(level, id)); msg != "" {
+		orgChartRedirect(w, r, errors.New(msg), "")
+		return
+	}
+	err := server.DB.Unscoped().Delete(model).Error
+	orgChartRedirect(w, r, err, label+" berhasil dihapus")
+}
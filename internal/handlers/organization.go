@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+// userOrgRelations adalah relasi organisasi karyawan yang ditampilkan bersama datanya
+var userOrgRelations = []string{"Branch", "Department", "SubDepartment", "Position"}
+
+// preloadUserOrg memuat cabang, bagian, sub bagian dan jabatan karyawan dari master data. relation kosong untuk
+// query karyawan langsung, atau jalur relasi karyawan seperti "User", "Borrower" dan "Signers.User".
+func preloadUserOrg(db *gorm.DB, relation string) *gorm.DB {
+	for _, name := range userOrgRelations {
+		if relation != "" {
+			name = relation + "." + name
+		}
+		db = db.Preload(name, func(tx *gorm.DB) *gorm.DB {
+			return tx.Unscoped()
+		})
+	}
+	return db
+}
+
+// uintValue mengembalikan isi ID relasi yang boleh kosong, 0 bila kosong
+func uintValue(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// optionalID mengembalikan ID untuk kolom relasi yang boleh kosong, nil bila 0
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// orgPlacement adalah penempatan karyawan (cabang, bagian, sub bagian dan jabatan) yang sudah dicocokkan dengan master data
+type orgPlacement struct {
+	Branch        models.MasterBranch
+	Department    models.MasterDepartment
+	SubDepartment models.MasterSubDepartment
+	Position      models.MasterPosition
+}
+
+// columns mengembalikan kolom relasi penempatan untuk Updates
+func (p orgPlacement) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"branch_id":         optionalID(p.Branch.ID),
+		"department_id":     optionalID(p.Department.ID),
+		"sub_department_id": optionalID(p.SubDepartment.ID),
+		"position_id":       optionalID(p.Position.ID),
+	}
+}
+
+// assign mengisi relasi penempatan pada karyawan baru
+func (p orgPlacement) assign(user *models.User) {
+	user.BranchID = optionalID(p.Branch.ID)
+	user.DepartmentID = optionalID(p.Department.ID)
+	user.SubDepartmentID = optionalID(p.SubDepartment.ID)
+	user.PositionID = optionalID(p.Position.ID)
+}
+
+// orgPlacementFromForm membaca penempatan karyawan dari form (branch_id, department_id, sub_department_id, position_id).
+// Bagian harus berada di cabang yang dipilih dan sub bagian di bagian yang dipilih; sub bagian boleh kosong.
+func (server *Server) orgPlacementFromForm(r *http.Request) (orgPlacement, error) {
+	var p orgPlacement
+	server.DB.Where("id = ?", server.parseUint(r.FormValue("branch_id"))).Limit(1).Find(&p.Branch)
+	if p.Branch.ID == 0 {
+		return p, errors.New("Cabang tidak ditemukan di master data")
+	}
+	server.DB.Where("id = ? AND master_branch_id = ?", server.parseUint(r.FormValue("department_id")), p.Branch.ID).Limit(1).Find(&p.Department)
+	if p.Department.ID == 0 {
+		return p, errors.New("Bagian tidak ditemukan di cabang " + p.Branch.Name)
+	}
+	if subID := server.parseUint(r.FormValue("sub_department_id")); subID != 0 {
+		server.DB.Where("id = ? AND master_department_id = ?", subID, p.Department.ID).Limit(1).Find(&p.SubDepartment)
+		if p.SubDepartment.ID == 0 {
+			return p, errors.New("Sub bagian tidak ditemukan di bagian " + p.Department.Name)
+		}
+	}
+	server.DB.Where("id = ?", server.parseUint(r.FormValue("position_id"))).Limit(1).Find(&p.Position)
+	if p.Position.ID == 0 {
+		return p, errors.New("Jabatan tidak ditemukan di master data")
+	}
+	return p, nil
+}
+
+// findOrgNode mencari ID cabang, bagian dan sub bagian dari namanya, misalnya dari filter halaman pemeliharaan.
+// Nama yang tidak ditemukan menghasilkan ID 0 sehingga tidak cocok dengan data mana pun.
+func (server *Server) findOrgNode(branch, dept, subDept string) orgNode {
+	node := orgNode{Branch: branch, Department: dept, SubDepartment: subDept}
+	if branch == "" {
+		return node
+	}
+	var b models.MasterBranch
+	server.DB.Where("name = ?", branch).Limit(1).Find(&b)
+	node.BranchID = b.ID
+	if dept == "" || b.ID == 0 {
+		return node
+	}
+	var d models.MasterDepartment
+	server.DB.Where("name = ? AND master_branch_id = ?", dept, b.ID).Limit(1).Find(&d)
+	node.DepartmentID = d.ID
+	if subDept == "" || d.ID == 0 {
+		return node
+	}
+	var s models.MasterSubDepartment
+	server.DB.Where("name = ? AND master_department_id = ?", subDept, d.ID).Limit(1).Find(&s)
+	node.SubDepartmentID = s.ID
+	return node
+}
+
+// matches memeriksa posisi (ID cabang, bagian, sub bagian) terhadap node hasil findOrgNode yang dipakai sebagai filter:
+// nama kosong berarti tingkat itu tidak difilter, sedangkan nama yang tidak ditemukan tidak cocok dengan apa pun
+func (n orgNode) matches(branchID, deptID, subDeptID uint) bool {
+	if n.Branch != "" && (n.BranchID == 0 || n.BranchID != branchID) {
+		return false
+	}
+	if n.Department != "" && (n.DepartmentID == 0 || n.DepartmentID != deptID) {
+		return false
+	}
+	if n.SubDepartment != "" && (n.SubDepartmentID == 0 || n.SubDepartmentID != subDeptID) {
+		return false
+	}
+	return true
+}
+
+// orgUsage adalah jumlah data yang masih memakai satu master data organisasi
+type orgUsage struct {
+	Label string
+	Count int64
+}
+
+// orgUsageError menyusun pesan penolakan hapus bila master data masih dipakai, kosong bila aman dihapus
+func orgUsageError(name string, usages []orgUsage) string {
+	var parts []string
+	for _, u := range usages {
+		if u.Count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", u.Count, u.Label))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return name + " tidak dapat dihapus karena masih dipakai oleh " + strings.Join(parts, ", ") + "."
+}
+
+// orgNodeUsages menghitung data yang masih menunjuk ke satu master data organisasi. level mengikuti
+// models.ScopeLevel* ditambah "position" untuk jabatan. Karyawan yang sudah dihapus ikut dihitung karena
+// masih dapat dipulihkan, begitu pula bagian, sub bagian, aset yang sudah dihapus dan dokumen pemeliharaan
+// yang menjadi riwayat.
+func (server *Server) orgNodeUsages(level string, id uint) []orgUsage {
+	count := func(db *gorm.DB, query string, args ...interface{}) int64 {
+		var total int64
+		db.Where(query, args...).Count(&total)
+		return total
+	}
+	users := server.DB.Unscoped().Model(&models.User{})
+	assets := server.DB.Unscoped().Model(&models.AssetKSO{})
+	scopes := server.DB.Model(&models.AdminScope{})
+	documents := server.DB.Unscoped().Model(&models.MaintenanceDocument{})
+	reports := server.DB.Unscoped().Model(&models.MaintenanceReport{})
+
+	switch level {
+	case models.ScopeLevelBranch:
+		return []orgUsage{
+			{"bagian", count(server.DB.Unscoped().Model(&models.MasterDepartment{}), "master_branch_id = ?", id)},
+			{"karyawan", count(users, "branch_id = ?", id)},
+			// Aset dihitung dari pemegangnya, ditambah stok tanpa pemegang yang berlokasi di cabang ini
+			{"aset", count(assets, "user_id IN (SELECT id FROM users WHERE branch_id = ?) OR ((user_id IS NULL OR user_id = '') AND location IN (SELECT name FROM master_branches WHERE id = ?))", id, id)},
+			{"cakupan data admin", count(scopes, "level = ? AND node_id = ?", level, id)},
+			{"dokumen pemeliharaan", count(documents, "branch_id = ?", id)},
+			{"laporan pemeliharaan", count(reports, "user_branch_id = ?", id)},
+		}
+	case models.ScopeLevelDepartment:
+		return []orgUsage{
+			{"sub bagian", count(server.DB.Unscoped().Model(&models.MasterSubDepartment{}), "master_department_id = ?", id)},
+			{"karyawan", count(users, "department_id = ?", id)},
+			{"aset", count(assets, "user_id IN (SELECT id FROM users WHERE department_id = ?)", id)},
+			{"cakupan data admin", count(scopes, "level = ? AND node_id = ?", level, id)},
+			{"dokumen pemeliharaan", count(documents, "department_id = ?", id)},
+			{"laporan pemeliharaan", count(reports, "user_department_id = ?", id)},
+		}
+	case models.ScopeLevelSubDepartment:
+		return []orgUsage{
+			{"karyawan", count(users, "sub_department_id = ?", id)},
+			{"aset", count(assets, "user_id IN (SELECT id FROM users WHERE sub_department_id = ?)", id)},
+			{"cakupan data admin", count(scopes, "level = ? AND node_id = ?", level, id)},
+			{"dokumen pemeliharaan", count(documents, "sub_department_id = ?", id)},
+			{"laporan pemeliharaan", count(reports, "user_sub_department_id = ?", id)},
+		}
+	case "position":
+		return []orgUsage{
+			{"karyawan", count(users, "position_id = ?", id)},
+			{"laporan pemeliharaan", count(reports, "user_position_id = ?", id)},
+		}
+	}
+	return nil
+}
+
+// moveDepartment memindahkan bagian ke cabang lain. Cabang karyawan serta dokumen dan laporan pemeliharaan
+// di bagian itu ikut dipindah agar penempatannya tetap konsisten dengan struktur organisasi.
+func moveDepartment(tx *gorm.DB, department *models.MasterDepartment, branchID uint) error {
+	if department.MasterBranchID == branchID {
+		return nil
+	}
+	department.MasterBranchID = branchID
+	if err := tx.Model(department).Update("master_branch_id", branchID).Error; err != nil {
+		return err
+	}
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.User{}).Where("department_id = ?", department.ID).Update("branch_id", branchID),
+		tx.Unscoped().Model(&models.MaintenanceDocument{}).Where("department_id = ?", department.ID).Update("branch_id", branchID),
+		tx.Unscoped().Model(&models.MaintenanceReport{}).Where("user_department_id = ?", department.ID).Update("user_branch_id", branchID),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	return nil
+}
+
+// moveSubDepartment memindahkan sub bagian ke bagian lain beserta bagian dan cabang karyawan serta
+// dokumen dan laporan pemeliharaannya
+func moveSubDepartment(tx *gorm.DB, subDept *models.MasterSubDepartment, department models.MasterDepartment) error {
+	if subDept.MasterDepartmentID == department.ID {
+		return nil
+	}
+	subDept.MasterDepartmentID = department.ID
+	if err := tx.Model(subDept).Update("master_department_id", department.ID).Error; err != nil {
+		return err
+	}
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.User{}).Where("sub_department_id = ?", subDept.ID).Updates(map[string]interface{}{
+			"department_id": department.ID,
+			"branch_id":     department.MasterBranchID,
+		}),
+		tx.Unscoped().Model(&models.MaintenanceDocument{}).Where("sub_department_id = ?", subDept.ID).Updates(map[string]interface{}{
+			"department_id": department.ID,
+			"branch_id":     department.MasterBranchID,
+		}),
+		tx.Unscoped().Model(&models.MaintenanceReport{}).Where("user_sub_department_id = ?", subDept.ID).Updates(map[string]interface{}{
+			"user_department_id": department.ID,
+			"user_branch_id":     department.MasterBranchID,
+		}),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	return nil
+}
+
+// renameBranch mengganti nama cabang beserta data yang menyimpan nama cabang, bukan ID-nya
+func renameBranch(tx *gorm.DB, branch *models.MasterBranch, name string) error {
+	if branch.Name == name {
+		return nil
+	}
+	if err := renameBranchReferences(tx, branch.Name, name); err != nil {
+		return err
+	}
+	branch.Name = name
+	return tx.Model(branch).Update("name", name).Error
+}
+
+// renameBranchReferences mengganti nama cabang pada lokasi aset dan cabang asal/tujuan surat jalan.
+// Surat jalan yang masih dalam perjalanan memakai nama tujuan untuk lokasi aset saat diterima.
+func renameBranchReferences(tx *gorm.DB, from, into string) error {
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.AssetKSO{}).Where("location = ?", from).Update("location", into),
+		tx.Unscoped().Model(&models.AssetTransfer{}).Where("origin_branch = ?", from).Update("origin_branch", into),
+		tx.Unscoped().Model(&models.AssetTransfer{}).Where("destination_branch = ?", from).Update("destination_branch", into),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	return nil
+}
+
+// mergeScopes mengalihkan cakupan data admin dari satu node ke node lain. Admin yang sudah memiliki
+// cakupan node tujuan cukup kehilangan cakupan lamanya agar tidak tercatat dua kali.
+func mergeScopes(tx *gorm.DB, level string, fromID, intoID uint) error {
+	if err := tx.Where("level = ? AND node_id = ? AND admin_id IN (?)", level, fromID,
+		tx.Model(&models.AdminScope{}).Select("admin_id").Where("level = ? AND node_id = ?", level, intoID)).
+		Delete(&models.AdminScope{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.AdminScope{}).Where("level = ? AND node_id = ?", level, fromID).Update("node_id", intoID).Error
+}
+
+// sameOrgName membandingkan nama master data tanpa membedakan huruf besar/kecil dan spasi berlebih
+func sameOrgName(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// mergeBranch menggabungkan cabang from ke cabang into lalu menghapus from. Bagian yang namanya sudah ada
+// di cabang tujuan ikut digabung, sisanya dipindah. Karyawan, cakupan data admin, dokumen dan laporan
+// pemeliharaan, lokasi aset serta cabang surat jalan dialihkan ke cabang tujuan; nama snapshot pada riwayat
+// pemeliharaan tetap.
+func mergeBranch(tx *gorm.DB, from, into models.MasterBranch) error {
+	var departments, existing []models.MasterDepartment
+	tx.Where("master_branch_id = ?", from.ID).Find(&departments)
+	tx.Where("master_branch_id = ?", into.ID).Find(&existing)
+	for i := range departments {
+		dept := departments[i]
+		merged := false
+		for _, target := range existing {
+			if sameOrgName(dept.Name, target.Name) {
+				if err := mergeDepartment(tx, dept, target); err != nil {
+					return err
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			if err := moveDepartment(tx, &dept, into.ID); err != nil {
+				return err
+			}
+			existing = append(existing, dept)
+		}
+	}
+
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.User{}).Where("branch_id = ?", from.ID).Update("branch_id", into.ID),
+		tx.Unscoped().Model(&models.MaintenanceDocument{}).Where("branch_id = ?", from.ID).Update("branch_id", into.ID),
+		tx.Unscoped().Model(&models.MaintenanceReport{}).Where("user_branch_id = ?", from.ID).Update("user_branch_id", into.ID),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	if err := renameBranchReferences(tx, from.Name, into.Name); err != nil {
+		return err
+	}
+	if err := mergeScopes(tx, models.ScopeLevelBranch, from.ID, into.ID); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&from).Error
+}
+
+// mergeDepartment menggabungkan bagian from ke bagian into (boleh di cabang lain) lalu menghapus from.
+// Sub bagian yang namanya sudah ada di bagian tujuan ikut digabung, sisanya dipindah. Cabang karyawan dan
+// riwayat pemeliharaan disesuaikan dengan cabang bagian tujuan.
+func mergeDepartment(tx *gorm.DB, from, into models.MasterDepartment) error {
+	var subs, existing []models.MasterSubDepartment
+	tx.Where("master_department_id = ?", from.ID).Find(&subs)
+	tx.Where("master_department_id = ?", into.ID).Find(&existing)
+	for i := range subs {
+		sub := subs[i]
+		merged := false
+		for _, target := range existing {
+			if sameOrgName(sub.Name, target.Name) {
+				if err := mergeSubDepartment(tx, sub, target, into); err != nil {
+					return err
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			if err := moveSubDepartment(tx, &sub, into); err != nil {
+				return err
+			}
+			existing = append(existing, sub)
+		}
+	}
+
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.User{}).Where("department_id = ?", from.ID).Updates(map[string]interface{}{
+			"department_id": into.ID,
+			"branch_id":     into.MasterBranchID,
+		}),
+		tx.Unscoped().Model(&models.MaintenanceDocument{}).Where("department_id = ?", from.ID).Updates(map[string]interface{}{
+			"department_id": into.ID,
+			"branch_id":     into.MasterBranchID,
+		}),
+		tx.Unscoped().Model(&models.MaintenanceReport{}).Where("user_department_id = ?", from.ID).Updates(map[string]interface{}{
+			"user_department_id": into.ID,
+			"user_branch_id":     into.MasterBranchID,
+		}),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	if err := mergeScopes(tx, models.ScopeLevelDepartment, from.ID, into.ID); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&from).Error
+}
+
+// mergeSubDepartment menggabungkan sub bagian from ke sub bagian into (yang berada di bagian department)
+// lalu menghapus from. Bagian dan cabang karyawan serta riwayat pemeliharaan ikut disesuaikan.
+func mergeSubDepartment(tx *gorm.DB, from, into models.MasterSubDepartment, department models.MasterDepartment) error {
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.User{}).Where("sub_department_id = ?", from.ID).Updates(map[string]interface{}{
+			"sub_department_id": into.ID,
+			"department_id":     department.ID,
+			"branch_id":         department.MasterBranchID,
+		}),
+		tx.Unscoped().Model(&models.MaintenanceDocument{}).Where("sub_department_id = ?", from.ID).Updates(map[string]interface{}{
+			"sub_department_id": into.ID,
+			"department_id":     department.ID,
+			"branch_id":         department.MasterBranchID,
+		}),
+		tx.Unscoped().Model(&models.MaintenanceReport{}).Where("user_sub_department_id = ?", from.ID).Updates(map[string]interface{}{
+			"user_sub_department_id": into.ID,
+			"user_department_id":     department.ID,
+			"user_branch_id":         department.MasterBranchID,
+		}),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	if err := mergeScopes(tx, models.ScopeLevelSubDepartment, from.ID, into.ID); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&from).Error
+}
+
+// mergePosition menggabungkan jabatan from ke jabatan into lalu menghapus from
+func mergePosition(tx *gorm.DB, from, into models.MasterPosition) error {
+	if err := tx.Unscoped().Model(&models.User{}).Where("position_id = ?", from.ID).Update("position_id", into.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.MaintenanceReport{}).Where("user_position_id = ?", from.ID).Update("user_position_id", into.ID).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&from).Error
+}
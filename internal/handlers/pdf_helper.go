@@ -10,12 +10,13 @@ import (
 	"image/jpeg"
 	_ "image/png"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/boombuler/barcode/qr"
 	"github.com/jung-kurt/gofpdf"
+	"github.com/jung-kurt/gofpdf/contrib/barcode"
 )
 
 type BASTData struct {
@@ -25,64 +26,52 @@ type BASTData struct {
 	P2           models.User
 	Items        []models.AssetKSO
 	Notes        string
-	SigP1Data    string // Base64
-	SigP2Data    string // Base64
+	SigP1Data    string            // Base64
+	SigP2Data    string            // Base64
+	Return       bool              // BAST pengembalian: PIHAK PERTAMA mengembalikan aset kepada PIHAK KEDUA
+	Conditions   map[string]string // kondisi per AssetKSO.ID saat dikembalikan
+	ItemNotes    map[string]string // catatan per AssetKSO.ID saat dikembalikan
 }
 
-func (server *Server) GenerateBASTPDF(data BASTData, title string, outputPath string) error {
+// GenerateBASTPDF membuat PDF BAST mengikuti template dokumen: kop, paragraf, tabel barang dan blok tanda tangan
+func (server *Server) GenerateBASTPDF(data BASTData, tmpl models.DocumentTemplate, stamp pdfStamp, outputPath string) error {
+	logos, err := parseTemplateLogos(tmpl.Logos)
+	if err != nil {
+		return err
+	}
+	columns, err := parseTemplateColumns(tmpl.Columns)
+	if err != nil {
+		return err
+	}
+	vars := bastTemplateVars(data, tmpl.City)
+
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(20, 20, 20)
+	renderPageFooter(pdf, stamp)
 	pdf.AddPage()
 
-	// Header Logos
-	logoDir := filepath.Join("public", "assets", "img")
-
-	// Danantara Logo (Left)
-	danantaraPath := filepath.Join(logoDir, "logo-danantara.png")
-	if err := registerLogo(pdf, danantaraPath, "logo1"); err == nil {
-		fmt.Println("[PDF Helper] Danantara logo registered successfully")
-		pdf.Image("logo1", 20, 15, 38, 0, false, "", 0, "")
-	} else {
-		fmt.Printf("[PDF Helper] Danantara logo FAILED: %v (Path: %s)\n", err, danantaraPath)
+	renderHeaderLogos(pdf, logos)
+	if len(logos) > 0 {
+		pdf.SetY(45)
 	}
 
-	// IDSurvey Logo (Center)
-	if err := registerLogo(pdf, filepath.Join(logoDir, "logo-idsurvey.png"), "logo2"); err == nil {
-		fmt.Println("[PDF Helper] IDSurvey logo registered successfully")
-		pdf.Image("logo2", 85, 15, 40, 0, false, "", 0, "")
-	}
-
-	// KSOSCISI Logo (Right)
-	if err := registerLogo(pdf, filepath.Join(logoDir, "logo-ksoscisi.png"), "logo3"); err == nil {
-		fmt.Println("[PDF Helper] KSOSCISI logo registered successfully")
-		pdf.Image("logo3", 145, 15, 45, 0, false, "", 0, "")
-	}
-
-	pdf.SetY(45)
-
 	// Title
 	pdf.SetFont("Arial", "B", 14)
-	if title == "" {
-		title = "BERITA ACARA SERAH TERIMA"
-	}
-	pdf.MultiCell(0, 7, title, "", "C", false)
+	pdf.MultiCell(0, 7, tmpl.Title, "", "C", false)
+	renderDocNumber(pdf, data.DocNumber)
 	pdf.Ln(4)
 
 	// Opening text
 	pdf.SetFont("Arial", "", 11)
-	dayName := getIndonesianDay(data.HandoverDate)
-	dateStr := data.HandoverDate.Format("02 January 2006")
-	// Translate month to Indonesian
-	dateStr = translateMonth(dateStr)
-
-	openingText := fmt.Sprintf("Pada hari ini %s, tanggal %s, Kami yang bertanda tangan dibawah ini:", dayName, dateStr)
-	pdf.MultiCell(0, 6, openingText, "", "L", false)
+	for _, p := range templateParagraphs(tmpl.Opening) {
+		pdf.MultiCell(0, 6, fillTemplate(p, vars), "", "L", false)
+	}
 	pdf.Ln(2)
 
 	// Pihak Pertama
 	renderPerson(pdf, "PIHAK PERTAMA", data.P1)
 	pdf.SetFont("Arial", "I", 10)
-	pdf.CellFormat(0, 6, "Selanjutnya disebut sebagai \"PIHAK PERTAMA\"", "", 1, "L", false, 0, "")
+	pdf.MultiCell(0, 6, fillTemplate(tmpl.FirstParty, vars), "", "L", false)
 
 	pdf.SetFont("Arial", "", 11)
 	pdf.CellFormat(0, 6, "dan", "", 1, "C", false, 0, "")
@@ -90,55 +79,472 @@ func (server *Server) GenerateBASTPDF(data BASTData, title string, outputPath st
 	// Pihak Kedua
 	renderPerson(pdf, "PIHAK KEDUA", data.P2)
 	pdf.SetFont("Arial", "I", 10)
-	pdf.CellFormat(0, 6, "Selanjutnya disebut sebagai \"PIHAK KEDUA\"", "", 1, "L", false, 0, "")
+	pdf.MultiCell(0, 6, fillTemplate(tmpl.SecondParty, vars), "", "L", false)
 	pdf.Ln(4)
 
 	// Middle Text
 	pdf.SetFont("Arial", "", 11)
-	middleText := "PIHAK PERTAMA telah menyerahkan barang kepada PIHAK KEDUA, dan PIHAK KEDUA menyatakan telah menerima barang dari PIHAK PERTAMA berupa daftar terlampir."
-	pdf.MultiCell(0, 6, middleText, "", "J", false)
-	pdf.Ln(4)
+	for _, p := range templateParagraphs(tmpl.Body) {
+		pdf.MultiCell(0, 6, fillTemplate(p, vars), "", "J", false)
+		pdf.Ln(4)
+	}
 
 	// Table
 	pdf.SetFont("Arial", "B", 10)
 	pdf.SetFillColor(230, 230, 230)
-	pdf.CellFormat(10, 8, "NO", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(90, 8, "NAMA BARANG", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(20, 8, "Jumlah", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(50, 8, "KETERANGAN", "1", 1, "C", true, 0, "")
+	for i, col := range columns {
+		ln := 0
+		if i == len(columns)-1 {
+			ln = 1
+		}
+		pdf.CellFormat(col.Width, 8, col.Header, "1", ln, "C", true, 0, "")
+	}
 
 	pdf.SetFont("Arial", "", 10)
 	for i, item := range data.Items {
-		pdf.CellFormat(10, 8, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(90, 8, fmt.Sprintf("%s - %s", item.AssetName, item.SerialNumber), "1", 0, "L", false, 0, "")
-		pdf.CellFormat(20, 8, "1 Unit", "1", 0, "C", false, 0, "")
+		for j, col := range columns {
+			ln := 0
+			if j == len(columns)-1 {
+				ln = 1
+			}
+			pdf.CellFormat(col.Width, 8, bastColumnValue(data, i, item, col.Field), "1", ln, col.Align, false, 0, "")
+		}
+	}
+	pdf.Ln(6)
+
+	// Closing Text
+	for _, p := range templateParagraphs(tmpl.Closing) {
+		pdf.MultiCell(0, 6, fillTemplate(p, vars), "", "J", false)
+		pdf.Ln(4)
+	}
+	pdf.Ln(6)
 
-		// Use Label (DeviceName) if available, otherwise Category
-		keterangan := item.DeviceName
-		if keterangan == "" {
-			keterangan = item.Category
+	// Signature Section
+	renderSignatures(pdf, data, tmpl, vars)
+
+	if err := pdf.OutputFileAndClose(outputPath); err != nil {
+		return err
+	}
+	if stamp.Draft {
+		return nil
+	}
+	return server.signPDF(outputPath)
+}
+
+// GenerateFormPDF membuat PDF untuk formulir GoForm dinamis: judul, isi template, tabel isian dan tanda tangan pengisi
+func (server *Server) GenerateFormPDF(title, docNumber string, body string, answers []models.GoFormAnswer, submitter models.User, date time.Time, stamp pdfStamp, outputPath string) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	renderPageFooter(pdf, stamp)
+	pdf.AddPage()
+
+	renderHeaderLogos(pdf, defaultHeaderLogos)
+
+	pdf.SetY(45)
+
+	// Title
+	pdf.SetFont("Arial", "B", 14)
+	pdf.MultiCell(0, 7, title, "", "C", false)
+	renderDocNumber(pdf, docNumber)
+	pdf.Ln(4)
+
+	// Body paragraphs
+	pdf.SetFont("Arial", "", 11)
+	if body != "" {
+		for _, paragraph := range strings.Split(body, "\n") {
+			pdf.MultiCell(0, 6, paragraph, "", "J", false)
+		}
+		pdf.Ln(4)
+	}
+
+	// Answers table
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(10, 8, "NO", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(60, 8, "ISIAN", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(100, 8, "KETERANGAN", "1", 1, "C", true, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	for i, answer := range answers {
+		display := answer.Display
+		if display == "" {
+			display = "-"
+		}
+		lines := pdf.SplitLines([]byte(display), 98)
+		height := float64(len(lines)) * 6
+		if height < 8 {
+			height = 8
 		}
-		pdf.CellFormat(50, 8, keterangan, "1", 1, "L", false, 0, "")
+		x, y := pdf.GetXY()
+		pdf.CellFormat(10, height, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(60, height, answer.Label, "1", 0, "L", false, 0, "")
+		pdf.Rect(x+70, y, 100, height, "D")
+		pdf.SetXY(x+71, y+(height-float64(len(lines))*6)/2)
+		pdf.MultiCell(98, 6, display, "", "L", false)
+		pdf.SetXY(x, y+height)
+	}
+	pdf.Ln(10)
+
+	// Signature of submitter
+	dateStr := translateMonth(date.Format("02 January 2006"))
+	pdf.SetX(115)
+	pdf.CellFormat(85, 6, fmt.Sprintf("Jakarta, %s", dateStr), "", 1, "C", false, 0, "")
+	pdf.SetX(115)
+	pdf.CellFormat(85, 6, "Yang Mengajukan,", "", 1, "C", false, 0, "")
+	pdf.Ln(25)
+	pdf.SetX(115)
+	pdf.SetFont("Arial", "BU", 11)
+	pdf.CellFormat(85, 6, submitter.Name, "", 1, "C", false, 0, "")
+	pdf.SetX(115)
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(85, 5, submitter.Position.Name, "", 1, "C", false, 0, "")
+
+	if err := pdf.OutputFileAndClose(outputPath); err != nil {
+		return err
+	}
+	return server.signPDF(outputPath)
+}
+
+// GenerateLoanPDF membuat surat peminjaman aset untuk permohonan yang sudah disetujui
+func (server *Server) GenerateLoanPDF(loan models.AssetLoan, approver models.User, stamp pdfStamp, outputPath string) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	renderPageFooter(pdf, stamp)
+	pdf.AddPage()
+
+	renderHeaderLogos(pdf, defaultHeaderLogos)
+
+	pdf.SetY(45)
+
+	// Title
+	pdf.SetFont("Arial", "B", 14)
+	pdf.MultiCell(0, 7, "SURAT PEMINJAMAN ASET", "", "C", false)
+	renderDocNumber(pdf, loan.Number)
+	pdf.Ln(4)
+
+	// Opening text
+	approvedAt := time.Now()
+	if loan.ApprovedAt != nil {
+		approvedAt = *loan.ApprovedAt
+	}
+	pdf.SetFont("Arial", "", 11)
+	openingText := fmt.Sprintf("Pada hari ini %s, tanggal %s, telah disetujui peminjaman aset kepada:", getIndonesianDay(approvedAt), translateMonth(approvedAt.Format("02 January 2006")))
+	pdf.MultiCell(0, 6, openingText, "", "L", false)
+	pdf.Ln(2)
+
+	renderPerson(pdf, "PEMINJAM", loan.Borrower)
+	pdf.CellFormat(35, 6, "Periode", "", 0, "L", false, 0, "")
+	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+	period := fmt.Sprintf("%s s/d %s", translateMonth(loan.StartDate.Format("02 January 2006")), translateMonth(loan.EndDate.Format("02 January 2006")))
+	pdf.CellFormat(0, 6, period, "", 1, "L", false, 0, "")
+	pdf.CellFormat(35, 6, "Keperluan", "", 0, "L", false, 0, "")
+	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+	pdf.MultiCell(0, 6, loan.Purpose, "", "L", false)
+	pdf.Ln(4)
+
+	// Table
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(10, 8, "NO", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(45, 8, "NO. INVENTARIS", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(75, 8, "NAMA BARANG", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 8, "SERIAL NUMBER", "1", 1, "C", true, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	for i, item := range loan.Items {
+		pdf.CellFormat(10, 8, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(45, 8, item.Asset.InventoryNumber, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(75, 8, item.Asset.AssetName, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 8, item.Asset.SerialNumber, "1", 1, "L", false, 0, "")
 	}
 	pdf.Ln(6)
 
 	// Closing Text
-	closingText := "Demikian berita acara serah terima barang ini kami buat oleh kedua belah pihak, adapun barang-barang tersebut dalam keadaan baik dan cukup. Maka barang tersebut menjadi tanggung jawab PIHAK KEDUA, memelihara/ merawat dengan baik serta dipergunakan sebagaimana mestinya."
+	closingText := "Peminjam bertanggung jawab menjaga aset tersebut selama masa peminjaman dan wajib mengembalikannya dalam keadaan baik paling lambat pada tanggal berakhirnya periode peminjaman."
 	pdf.MultiCell(0, 6, closingText, "", "J", false)
 	pdf.Ln(10)
 
 	// Signature Section
-	renderSignatures(pdf, data)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Jakarta, %s", translateMonth(approvedAt.Format("02 January 2006"))), "", 1, "R", false, 0, "")
+	pdf.Ln(5)
+	y := pdf.GetY()
+	pdf.SetX(20)
+	pdf.CellFormat(85, 6, "Peminjam,", "", 0, "C", false, 0, "")
+	pdf.SetX(115)
+	pdf.CellFormat(85, 6, "Menyetujui,", "", 1, "C", false, 0, "")
+	pdf.SetY(y + 30)
+
+	y = pdf.GetY()
+	pdf.SetX(20)
+	pdf.SetFont("Arial", "BU", 11)
+	pdf.CellFormat(85, 6, loan.Borrower.Name, "", 1, "C", false, 0, "")
+	pdf.SetX(20)
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(85, 5, loan.Borrower.Position.Name, "", 0, "C", false, 0, "")
+
+	pdf.SetY(y)
+	pdf.SetX(115)
+	pdf.SetFont("Arial", "BU", 11)
+	pdf.CellFormat(85, 6, approver.Name, "", 1, "C", false, 0, "")
+	pdf.SetX(115)
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(85, 5, approver.Position.Name, "", 1, "C", false, 0, "")
+
+	if err := pdf.OutputFileAndClose(outputPath); err != nil {
+		return err
+	}
+	return server.signPDF(outputPath)
+}
+
+// GenerateTransferPDF membuat surat jalan perpindahan aset; tanda tangan penerima ikut dicetak setelah barang diterima
+func (server *Server) GenerateTransferPDF(transfer models.AssetTransfer, stamp pdfStamp, outputPath string) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	renderPageFooter(pdf, stamp)
+	pdf.AddPage()
+
+	renderHeaderLogos(pdf, defaultHeaderLogos)
+
+	pdf.SetY(45)
+
+	// Title
+	pdf.SetFont("Arial", "B", 14)
+	pdf.CellFormat(0, 7, "SURAT JALAN", "", 1, "C", false, 0, "")
+	renderDocNumber(pdf, transfer.Number)
+	pdf.Ln(4)
+
+	// Shipment info
+	info := [][2]string{
+		{"Cabang Asal", transfer.OriginBranch},
+		{"Cabang Tujuan", transfer.DestinationBranch},
+		{"Kurir / Ekspedisi", transfer.Courier},
+		{"Tanggal Kirim", translateMonth(transfer.ShipDate.Format("02 January 2006"))},
+	}
+	if transfer.ReceivedAt != nil {
+		info = append(info, [2]string{"Tanggal Terima", translateMonth(transfer.ReceivedAt.Format("02 January 2006"))})
+	}
+	for _, row := range info {
+		pdf.CellFormat(40, 6, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, row[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Table
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(10, 8, "NO", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 8, "NO. INVENTARIS", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(60, 8, "NAMA BARANG", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(35, 8, "SERIAL NUMBER", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(25, 8, "DITERIMA", "1", 1, "C", true, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	for i, item := range transfer.Items {
+		received := ""
+		if transfer.ReceivedAt != nil {
+			received = item.Condition
+		}
+		pdf.CellFormat(10, 8, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(40, 8, item.Asset.InventoryNumber, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(60, 8, item.Asset.AssetName, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(35, 8, item.Asset.SerialNumber, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(25, 8, received, "1", 1, "C", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	if transfer.Notes != "" {
+		pdf.MultiCell(0, 6, "Catatan: "+transfer.Notes, "", "L", false)
+		pdf.Ln(2)
+	}
+	pdf.Ln(6)
+
+	// Signature Section: Pengirim, Kurir, Penerima
+	y := pdf.GetY()
+	columns := []struct {
+		x         float64
+		title     string
+		name      string
+		signature string
+		imageName string
+	}{
+		{20, "Pengirim,", transfer.Sender.Name, transfer.SenderSignature, "sig_sender"},
+		{77, "Kurir,", transfer.Courier, "", ""},
+		{134, "Penerima,", transfer.Receiver.Name, transfer.ReceiverSignature, "sig_receiver"},
+	}
+	pdf.SetFont("Arial", "", 11)
+	for _, col := range columns {
+		pdf.SetXY(col.x, y)
+		pdf.CellFormat(56, 6, col.title, "", 0, "C", false, 0, "")
+		if col.signature != "" {
+			if err := registerBase64Image(pdf, col.signature, col.imageName); err == nil {
+				pdf.Image(col.imageName, col.x+8, y+8, 40, 0, false, "", 0, "")
+			}
+		}
+	}
+	for _, col := range columns {
+		pdf.SetXY(col.x, y+35)
+		pdf.SetFont("Arial", "BU", 11)
+		name := col.name
+		if name == "" {
+			name = "(..............................)"
+		}
+		pdf.CellFormat(56, 6, name, "", 0, "C", false, 0, "")
+	}
+
+	if err := pdf.OutputFileAndClose(outputPath); err != nil {
+		return err
+	}
+	return server.signPDF(outputPath)
+}
+
+// GenerateClearancePDF membuat surat keterangan bebas aset untuk karyawan yang keluar
+func (server *Server) GenerateClearancePDF(user models.User, clearance models.EmployeeClearance, checklist []offboardingCheck, signer models.User, stamp pdfStamp, outputPath string) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	renderPageFooter(pdf, stamp)
+	pdf.AddPage()
+
+	renderHeaderLogos(pdf, defaultHeaderLogos)
+
+	pdf.SetY(45)
+
+	pdf.SetFont("Arial", "B", 14)
+	pdf.MultiCell(0, 7, "SURAT KETERANGAN BEBAS ASET", "", "C", false)
+	renderDocNumber(pdf, clearance.Number)
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "", 11)
+	pdf.MultiCell(0, 6, "Yang bertanda tangan di bawah ini menerangkan bahwa karyawan berikut:", "", "L", false)
+	pdf.Ln(2)
+
+	pdf.CellFormat(35, 6, "NIK", "", 0, "L", false, 0, "")
+	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, user.NIK, "", 1, "L", false, 0, "")
+	renderPerson(pdf, "KARYAWAN", user)
+	pdf.CellFormat(35, 6, "Cabang", "", 0, "L", false, 0, "")
+	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, user.Branch.Name, "", 1, "L", false, 0, "")
+	pdf.CellFormat(35, 6, "Tanggal Keluar", "", 0, "L", false, 0, "")
+	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, translateMonth(clearance.ResignDate.Format("02 January 2006")), "", 1, "L", false, 0, "")
+	if clearance.Reason != "" {
+		pdf.CellFormat(35, 6, "Keterangan", "", 0, "L", false, 0, "")
+		pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
+		pdf.MultiCell(0, 6, clearance.Reason, "", "L", false)
+	}
+	pdf.Ln(4)
+
+	createdAt := clearance.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	openingText := fmt.Sprintf("telah menyelesaikan kewajiban pengembalian aset perusahaan. Per tanggal %s, hasil pemeriksaan offboarding adalah sebagai berikut:", translateMonth(createdAt.Format("02 January 2006")))
+	pdf.MultiCell(0, 6, openingText, "", "J", false)
+	pdf.Ln(2)
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(10, 8, "NO", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(70, 8, "PEMERIKSAAN", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(90, 8, "HASIL", "1", 1, "C", true, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	for i, check := range checklist {
+		pdf.CellFormat(10, 8, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(70, 8, check.Label, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(90, 8, check.Result, "1", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	closingText := "Dengan demikian karyawan tersebut dinyatakan bebas dari tanggungan aset perusahaan. Surat keterangan ini dibuat untuk keperluan administrasi kepegawaian."
+	pdf.MultiCell(0, 6, closingText, "", "J", false)
+	pdf.Ln(10)
+
+	pdf.CellFormat(0, 6, fmt.Sprintf("Jakarta, %s", translateMonth(createdAt.Format("02 January 2006"))), "", 1, "R", false, 0, "")
+	pdf.Ln(5)
+	pdf.SetX(115)
+	pdf.CellFormat(85, 6, "Yang menerangkan,", "", 1, "C", false, 0, "")
+	pdf.Ln(24)
+	pdf.SetX(115)
+	pdf.SetFont("Arial", "BU", 11)
+	pdf.CellFormat(85, 6, signer.Name, "", 1, "C", false, 0, "")
+	pdf.SetX(115)
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(85, 5, signer.Position.Name, "", 1, "C", false, 0, "")
+
+	if err := pdf.OutputFileAndClose(outputPath); err != nil {
+		return err
+	}
+	return server.signPDF(outputPath)
+}
+
+// renderDocNumber mencetak nomor surat di bawah judul dokumen
+func renderDocNumber(pdf *gofpdf.Fpdf, number string) {
+	if number == "" {
+		return
+	}
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(0, 6, "Nomor: "+number, "", 1, "C", false, 0, "")
+}
+
+// renderPageFooter memasang QR code verifikasi, hash dokumen dan nomor halaman "Hal. x dari y" di setiap halaman
+func renderPageFooter(pdf *gofpdf.Fpdf, stamp pdfStamp) {
+	var qrKey string
+	if stamp.URL != "" {
+		qrKey = barcode.RegisterQR(pdf, stamp.URL, qr.M, qr.Unicode)
+		// Sisakan ruang bawah agar isi dokumen tidak menimpa QR code
+		pdf.SetAutoPageBreak(true, 30)
+	}
 
-	// Footer (Page Number)
 	pdf.SetFooterFunc(func() {
+		if stamp.Draft {
+			renderDraftWatermark(pdf)
+		}
+		if qrKey != "" {
+			_, pageH := pdf.GetPageSize()
+			left, _, _, _ := pdf.GetMargins()
+			barcode.Barcode(pdf, qrKey, left, pageH-27, 18, 18, false)
+
+			pdf.SetXY(left+21, pageH-25)
+			pdf.SetFont("Arial", "", 7)
+			pdf.CellFormat(0, 4, "Pindai QR code atau kunjungi tautan berikut untuk memverifikasi keaslian dokumen:", "", 2, "L", false, 0, "")
+			pdf.CellFormat(0, 4, stamp.URL, "", 2, "L", false, 0, "")
+			pdf.SetFont("Courier", "", 6.5)
+			pdf.CellFormat(0, 4, "Hash: "+stamp.Fingerprint, "", 2, "L", false, 0, "")
+		}
+
 		pdf.SetY(-15)
 		pdf.SetFont("Arial", "I", 8)
 		pdf.CellFormat(0, 10, fmt.Sprintf("Hal. %d dari {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
 	})
 	pdf.AliasNbPages("{nb}")
+}
+
+// renderDraftWatermark menulis watermark DRAFT miring di tengah halaman pratinjau
+func renderDraftWatermark(pdf *gofpdf.Fpdf) {
+	pageW, pageH := pdf.GetPageSize()
+	pdf.SetAlpha(0.15, "Normal")
+	pdf.SetTextColor(200, 0, 0)
+	pdf.SetFont("Arial", "B", 90)
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, pageW/2, pageH/2)
+	text := "DRAFT"
+	pdf.Text(pageW/2-pdf.GetStringWidth(text)/2, pageH/2+10, text)
+	pdf.TransformEnd()
+	pdf.SetAlpha(1, "Normal")
+	pdf.SetTextColor(0, 0, 0)
+}
 
-	return pdf.OutputFileAndClose(outputPath)
+// renderHeaderLogos menggambar logo kop surat di bagian atas halaman
+func renderHeaderLogos(pdf *gofpdf.Fpdf, logos []templateLogo) {
+	for i, logo := range logos {
+		name := fmt.Sprintf("logo%d", i+1)
+		if err := registerLogo(pdf, logo.Path, name); err != nil {
+			fmt.Printf("[PDF Helper] Logo FAILED: %v (Path: %s)\n", err, logo.Path)
+			continue
+		}
+		pdf.Image(name, logo.X, logo.Y, logo.Width, 0, false, "", 0, "")
+	}
 }
 
 func renderPerson(pdf *gofpdf.Fpdf, title string, user models.User) {
@@ -152,72 +558,92 @@ func renderPerson(pdf *gofpdf.Fpdf, title string, user models.User) {
 
 	pdf.CellFormat(35, 6, "Nama Bagian", "", 0, "L", false, 0, "")
 	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
-	pdf.CellFormat(0, 6, user.Department, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, user.Department.Name, "", 1, "L", false, 0, "")
 
 	pdf.CellFormat(35, 6, "Jabatan", "", 0, "L", false, 0, "")
 	pdf.CellFormat(5, 6, ":", "", 0, "L", false, 0, "")
-	pdf.CellFormat(0, 6, user.Position, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, user.Position.Name, "", 1, "L", false, 0, "")
 }
 
-func renderSignatures(pdf *gofpdf.Fpdf, data BASTData) {
+// renderSignatures menggambar blok tanda tangan dua pihak sesuai template; pihak kiri dan kanan dapat ditukar
+func renderSignatures(pdf *gofpdf.Fpdf, data BASTData, tmpl models.DocumentTemplate, vars map[string]string) {
+	// Blok tanda tangan (~80mm) tidak boleh terpotong ke halaman berikutnya
+	_, pageH := pdf.GetPageSize()
+	_, bottom := pdf.GetAutoPageBreak()
+	if pdf.GetY()+80 > pageH-bottom {
+		pdf.AddPage()
+	}
+
 	pdf.Ln(5)
-	dateStr := data.HandoverDate.Format("02 January 2006")
-	dateStr = translateMonth(dateStr)
-	pdf.CellFormat(0, 6, fmt.Sprintf("Jakarta, %s", dateStr), "", 1, "R", false, 0, "")
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(0, 6, fillTemplate(tmpl.SignaturePlace, vars), "", 1, "R", false, 0, "")
 	pdf.Ln(5)
 
-	y := pdf.GetY()
-
-	// Left side (Penerima)
-	pdf.SetX(20)
-	pdf.SetFont("Arial", "", 11)
-	pdf.CellFormat(85, 6, "Yang Menerima", "", 1, "C", false, 0, "")
-	pdf.SetX(20)
-	pdf.SetFont("Arial", "B", 11)
-	pdf.CellFormat(85, 6, "PIHAK KEDUA,", "", 0, "C", false, 0, "")
+	type signer struct {
+		X       float64
+		Caption string
+		Person  models.User
+		Sig     string
+	}
+	party := func(code string) (models.User, string) {
+		if code == "p1" {
+			return data.P1, data.SigP1Data
+		}
+		return data.P2, data.SigP2Data
+	}
+	leftUser, leftSig := party(tmpl.LeftParty)
+	rightUser, rightSig := party(tmpl.RightParty)
+	signers := []signer{
+		{X: 20, Caption: tmpl.SignatureLeft, Person: leftUser, Sig: leftSig},
+		{X: 115, Caption: tmpl.SignatureRight, Person: rightUser, Sig: rightSig},
+	}
 
-	// Right side (Menyerahkan)
-	pdf.SetY(y)
-	pdf.SetX(115)
-	pdf.SetFont("Arial", "", 11)
-	pdf.CellFormat(85, 6, "Yang Menyerahkan", "", 1, "C", false, 0, "")
-	pdf.SetX(115)
-	pdf.SetFont("Arial", "B", 11)
-	pdf.CellFormat(85, 6, "PIHAK PERTAMA,", "", 1, "C", false, 0, "")
+	// Keterangan: baris terakhir dicetak tebal (mis. "PIHAK KEDUA,")
+	y := pdf.GetY()
+	bottomY := y
+	for _, sg := range signers {
+		pdf.SetY(y)
+		lines := templateLines(fillTemplate(sg.Caption, vars))
+		for i, line := range lines {
+			pdf.SetX(sg.X)
+			if i == len(lines)-1 {
+				pdf.SetFont("Arial", "B", 11)
+			} else {
+				pdf.SetFont("Arial", "", 11)
+			}
+			pdf.CellFormat(85, 6, line, "", 1, "C", false, 0, "")
+		}
+		if pdf.GetY() > bottomY {
+			bottomY = pdf.GetY()
+		}
+	}
 
-	// Render Signatures if data exists
+	// Render Signatures if data exists; gambar 65 mm di tengah blok 85 mm
 	sigY := y + 18
-	if data.SigP2Data != "" {
-		if err := registerBase64Image(pdf, data.SigP2Data, "sig_p2"); err == nil {
-			// Center in left block (20-105): Center is 62.5. Width 65 -> X = 30
-			pdf.Image("sig_p2", 30, sigY, 65, 0, false, "", 0, "")
+	for i, sg := range signers {
+		if sg.Sig == "" {
+			continue
 		}
-	}
-	if data.SigP1Data != "" {
-		if err := registerBase64Image(pdf, data.SigP1Data, "sig_p1"); err == nil {
-			// Center in right block (115-200): Center is 157.5. Width 65 -> X = 125
-			pdf.Image("sig_p1", 125, sigY, 65, 0, false, "", 0, "")
+		name := fmt.Sprintf("sig_%d", i+1)
+		if err := registerBase64Image(pdf, sg.Sig, name); err == nil {
+			pdf.Image(name, sg.X+10, sigY, 65, 0, false, "", 0, "")
 		}
 	}
 
+	pdf.SetY(bottomY)
 	pdf.Ln(40) // Space for signature
 
 	// Names
 	y = pdf.GetY()
-	pdf.SetX(20)
-	pdf.SetFont("Arial", "BU", 11)
-	pdf.CellFormat(85, 6, data.P2.Name, "", 1, "C", false, 0, "")
-	pdf.SetX(20)
-	pdf.SetFont("Arial", "", 10)
-	pdf.CellFormat(85, 5, data.P2.Position, "", 0, "C", false, 0, "")
-
-	pdf.SetY(y)
-	pdf.SetX(115)
-	pdf.SetFont("Arial", "BU", 11)
-	pdf.CellFormat(85, 6, data.P1.Name, "", 1, "C", false, 0, "")
-	pdf.SetX(115)
-	pdf.SetFont("Arial", "", 10)
-	pdf.CellFormat(85, 5, data.P1.Position, "", 1, "C", false, 0, "")
+	for _, sg := range signers {
+		pdf.SetY(y)
+		pdf.SetX(sg.X)
+		pdf.SetFont("Arial", "BU", 11)
+		pdf.CellFormat(85, 6, sg.Person.Name, "", 1, "C", false, 0, "")
+		pdf.SetX(sg.X)
+		pdf.SetFont("Arial", "", 10)
+		pdf.CellFormat(85, 5, sg.Person.Position.Name, "", 1, "C", false, 0, "")
+	}
 }
 
 func registerBase64Image(pdf *gofpdf.Fpdf, base64Str, name string) error {
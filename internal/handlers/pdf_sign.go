@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// pdfSignatureSize adalah ruang (byte) yang dicadangkan untuk CMS signature di dalam /Contents
+const pdfSignatureSize = 8192
+
+// oidSigningCertificateV2 adalah atribut ESS signing-certificate-v2 yang diwajibkan PAdES (ETSI.CAdES.detached)
+var oidSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+
+type essCertIDv2 struct {
+	CertHash []byte // hashAlgorithm default SHA-256 sehingga tidak ditulis
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// pdfSigner menyimpan sertifikat X.509 dan kunci privat organisasi untuk menandatangani PDF secara digital
+type pdfSigner struct {
+	Cert     *x509.Certificate
+	Chain    []*x509.Certificate
+	Key      crypto.Signer
+	Reason   string
+	Location string // kosong berarti /Location tidak dicantumkan
+}
+
+// loadPDFSigner membaca sertifikat (beserta rantai CA, jika ada) dan kunci privat berformat PEM
+func loadPDFSigner(certPath, keyPath string) (*pdfSigner, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("tidak ada sertifikat di %s", certPath)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("kunci privat di %s bukan PEM", keyPath)
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var signer crypto.Signer
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signer = k
+	case *ecdsa.PrivateKey:
+		signer = k
+	default:
+		return nil, errors.New("kunci privat harus RSA atau ECDSA")
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(certs[0].PublicKey) {
+		return nil, errors.New("kunci privat tidak cocok dengan sertifikat")
+	}
+
+	return &pdfSigner{
+		Cert:  certs[0],
+		Chain: certs[1:],
+		Key:   signer,
+	}, nil
+}
+
+// GenerateDevSigningCert membuat sertifikat self-signed beserta kunci privatnya untuk pengembangan lokal
+func GenerateDevSigningCert(certPath, keyPath, organization string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   organization + " Document Signing (Development)",
+			Organization: []string{organization},
+			Country:      []string{"ID"},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(2, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true, // self-signed: sertifikat menandatangani dirinya sendiri
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{certPath, keyPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+// signPDF menandatangani file PDF hasil generator; dilewati jika sertifikat organisasi tidak dikonfigurasi
+func (server *Server) signPDF(path string) error {
+	if server.PDFSigner == nil {
+		return nil
+	}
+	return server.PDFSigner.SignFile(path, time.Now())
+}
+
+// SignFile menambahkan tanda tangan digital ke file PDF dan menimpa file aslinya
+func (s *pdfSigner) SignFile(path string, signedAt time.Time) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	signed, err := s.Sign(data, signedAt)
+	if err != nil {
+		return fmt.Errorf("gagal menandatangani PDF: %w", err)
+	}
+	return os.WriteFile(path, signed, 0644)
+}
+
+// Sign menambahkan tanda tangan CMS (PAdES, ETSI.CAdES.detached) tak terlihat lewat incremental update,
+// sehingga isi PDF asli tidak berubah dan pembaca PDF menampilkannya sebagai dokumen bertanda tangan
+func (s *pdfSigner) Sign(data []byte, signedAt time.Time) ([]byte, error) {
+	xrefOffset, trailer, err := pdfTrailer(data)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := pdfXref(data, xrefOffset)
+	if err != nil {
+		return nil, err
+	}
+	size, err := pdfDictInt(trailer, "/Size")
+	if err != nil {
+		return nil, err
+	}
+	rootNum, err := pdfDictInt(trailer, "/Root")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := pdfObject(data, offsets, rootNum)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(catalog, "/AcroForm") {
+		return nil, errors.New("PDF sudah memiliki AcroForm")
+	}
+	pagesNum, err := pdfDictInt(catalog, "/Pages")
+	if err != nil {
+		return nil, err
+	}
+	pages, err := pdfObject(data, offsets, pagesNum)
+	if err != nil {
+		return nil, err
+	}
+	pageNum, err := pdfDictInt(pages, "/Kids")
+	if err != nil {
+		return nil, err
+	}
+	page, err := pdfObject(data, offsets, pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	sigNum, fieldNum := size, size+1
+	var buf bytes.Buffer
+	buf.Write(data)
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	newOffsets := map[int]int{}
+	writeObject := func(num int, body string) {
+		newOffsets[num] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", num, body)
+	}
+
+	byteRangePlaceholder := "/ByteRange [0 0000000000 0000000000 0000000000]"
+	location := ""
+	if s.Location != "" {
+		location = fmt.Sprintf("/Location (%s)\n", pdfEscape(s.Location))
+	}
+	writeObject(sigNum, fmt.Sprintf("<<\n/Type /Sig\n/Filter /Adobe.PPKLite\n/SubFilter /ETSI.CAdES.detached\n%s\n/Contents <%s>\n/M (%s)\n/Name (%s)\n/Reason (%s)\n%s>>",
+		byteRangePlaceholder, strings.Repeat("0", pdfSignatureSize*2), pdfDate(signedAt),
+		pdfEscape(s.Cert.Subject.CommonName), pdfEscape(s.Reason), location))
+	writeObject(fieldNum, fmt.Sprintf("<<\n/Type /Annot\n/Subtype /Widget\n/FT /Sig\n/T (Tanda Tangan Digital)\n/V %d 0 R\n/P %d 0 R\n/Rect [0 0 0 0]\n/F 132\n>>", sigNum, pageNum))
+	writeObject(rootNum, pdfDictAppend(catalog, fmt.Sprintf("/AcroForm << /Fields [%d 0 R] /SigFlags 3 >>", fieldNum)))
+	if i := strings.Index(page, "/Annots ["); i >= 0 {
+		i += len("/Annots [")
+		writeObject(pageNum, page[:i]+fmt.Sprintf("%d 0 R ", fieldNum)+page[i:])
+	} else {
+		writeObject(pageNum, pdfDictAppend(page, fmt.Sprintf("/Annots [%d 0 R]", fieldNum)))
+	}
+
+	nums := make([]int, 0, len(newOffsets))
+	for num := range newOffsets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	newXref := buf.Len()
+	buf.WriteString("xref\n")
+	for _, num := range nums {
+		fmt.Fprintf(&buf, "%d 1\n%010d 00000 n \n", num, newOffsets[num])
+	}
+	info := ""
+	if m := regexp.MustCompile(`/Info\s+\d+\s+\d+\s+R`).FindString(trailer); m != "" {
+		info = m + "\n"
+	}
+	fmt.Fprintf(&buf, "trailer\n<<\n/Size %d\n/Root %d 0 R\n%s/Prev %d\n>>\nstartxref\n%d\n%%%%EOF\n", fieldNum+1, rootNum, info, xrefOffset, newXref)
+
+	out := buf.Bytes()
+	sigStart := newOffsets[sigNum]
+	contentsStart := sigStart + bytes.Index(out[sigStart:], []byte("/Contents <")) + len("/Contents ")
+	contentsEnd := contentsStart + pdfSignatureSize*2 + 2
+	byteRange := fmt.Sprintf("/ByteRange [0 %d %d %d]", contentsStart, contentsEnd, len(out)-contentsEnd)
+	byteRangeStart := sigStart + bytes.Index(out[sigStart:], []byte(byteRangePlaceholder))
+	copy(out[byteRangeStart:], byteRange+strings.Repeat(" ", len(byteRangePlaceholder)-len(byteRange)))
+
+	content := make([]byte, 0, len(out)-(contentsEnd-contentsStart))
+	content = append(content, out[:contentsStart]...)
+	content = append(content, out[contentsEnd:]...)
+	cms, err := s.cms(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) > pdfSignatureSize {
+		return nil, fmt.Errorf("ukuran signature %d byte melebihi ruang %d byte", len(cms), pdfSignatureSize)
+	}
+	copy(out[contentsStart+1:], hex.EncodeToString(cms))
+
+	return out, nil
+}
+
+// cms membuat CMS SignedData detached atas byte range PDF
+func (s *pdfSigner) cms(content []byte) ([]byte, error) {
+	sd, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, err
+	}
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	certHash := sha256.Sum256(s.Cert.Raw)
+	config := pkcs7.SignerInfoConfig{
+		ExtraSignedAttributes: []pkcs7.Attribute{{
+			Type:  oidSigningCertificateV2,
+			Value: signingCertificateV2{Certs: []essCertIDv2{{CertHash: certHash[:]}}},
+		}},
+	}
+	if err := sd.AddSignerChain(s.Cert, s.Key, s.Chain, config); err != nil {
+		return nil, err
+	}
+	sd.Detach()
+	return sd.Finish()
+}
+
+// pdfTrailer mengambil posisi xref terakhir dan isi dictionary trailer
+func pdfTrailer(data []byte) (int, string, error) {
+	i := bytes.LastIndex(data, []byte("startxref"))
+	if i < 0 {
+		return 0, "", errors.New("startxref tidak ditemukan")
+	}
+	fields := strings.Fields(string(data[i+len("startxref"):]))
+	if len(fields) == 0 {
+		return 0, "", errors.New("startxref tidak valid")
+	}
+	xrefOffset, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", err
+	}
+	t := bytes.LastIndex(data[:i], []byte("trailer"))
+	if t < 0 {
+		return 0, "", errors.New("trailer tidak ditemukan")
+	}
+	return xrefOffset, string(data[t:i]), nil
+}
+
+// pdfXref membaca tabel xref klasik menjadi peta nomor objek -> offset
+func pdfXref(data []byte, offset int) (map[int]int, error) {
+	if offset < 0 || offset >= len(data) || !bytes.HasPrefix(data[offset:], []byte("xref")) {
+		return nil, errors.New("tabel xref tidak ditemukan")
+	}
+	lines := strings.Split(string(data[offset:]), "\n")
+	offsets := map[int]int{}
+	for i := 1; i < len(lines); {
+		header := strings.Fields(lines[i])
+		if len(header) != 2 {
+			break
+		}
+		start, err1 := strconv.Atoi(header[0])
+		count, err2 := strconv.Atoi(header[1])
+		if err1 != nil || err2 != nil {
+			break
+		}
+		for j := 0; j < count && i+1+j < len(lines); j++ {
+			entry := strings.Fields(lines[i+1+j])
+			if len(entry) == 3 && entry[2] == "n" {
+				off, _ := strconv.Atoi(entry[0])
+				offsets[start+j] = off
+			}
+		}
+		i += 1 + count
+	}
+	return offsets, nil
+}
+
+// pdfObject mengambil isi objek (tanpa "N 0 obj" dan "endobj")
+func pdfObject(data []byte, offsets map[int]int, num int) (string, error) {
+	off, ok := offsets[num]
+	if !ok || off >= len(data) {
+		return "", fmt.Errorf("objek %d tidak ditemukan", num)
+	}
+	body := data[off:]
+	start := bytes.Index(body, []byte("obj"))
+	end := bytes.Index(body, []byte("endobj"))
+	if start < 0 || end < start {
+		return "", fmt.Errorf("objek %d tidak valid", num)
+	}
+	return strings.TrimSpace(string(body[start+len("obj") : end])), nil
+}
+
+// pdfDictInt mengambil angka pertama setelah key, misalnya "/Root 14 0 R" -> 14 atau "/Kids [3 0 R" -> 3
+func pdfDictInt(dict, key string) (int, error) {
+	m := regexp.MustCompile(regexp.QuoteMeta(key) + `\s*\[?\s*(\d+)`).FindStringSubmatch(dict)
+	if m == nil {
+		return 0, fmt.Errorf("%s tidak ditemukan", key)
+	}
+	return strconv.Atoi(m[1])
+}
+
+// pdfDictAppend menambahkan entri baru sebelum penutup ">>" dictionary
+func pdfDictAppend(dict, entry string) string {
+	i := strings.LastIndex(dict, ">>")
+	if i < 0 {
+		return dict
+	}
+	return dict[:i] + "\n" + entry + "\n" + dict[i:]
+}
+
+// pdfDate memformat waktu menjadi format tanggal PDF, misalnya D:20240131153000+07'00'
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// pdfEscape meng-escape karakter khusus pada literal string PDF
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"go.mozilla.org/pkcs7"
+)
+
+// devPDFSigner membuat sertifikat pengembangan seperti perintah pdf:dev-cert lalu memuatnya
+func devPDFSigner(tb testing.TB) *pdfSigner {
+	tb.Helper()
+	dir := tb.TempDir()
+	certPath, keyPath := filepath.Join(dir, "dev-signing.crt"), filepath.Join(dir, "dev-signing.key")
+	if err := GenerateDevSigningCert(certPath, keyPath, "gokso"); err != nil {
+		tb.Fatal(err)
+	}
+	signer, err := loadPDFSigner(certPath, keyPath)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	signer.Reason = "Dokumen resmi gokso"
+	return signer
+}
+
+// samplePDF membuat PDF dua halaman dengan gofpdf seperti generator dokumen aplikasi
+func samplePDF(tb testing.TB) []byte {
+	tb.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	for _, text := range []string{"BERITA ACARA SERAH TERIMA", "Lampiran"} {
+		pdf.AddPage()
+		pdf.SetFont("Arial", "B", 14)
+		pdf.Cell(0, 10, text)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var byteRangePattern = regexp.MustCompile(`/ByteRange \[(\d+) (\d+) (\d+) (\d+)\s*\]`)
+
+func TestPDFSignerSignsGeneratedPDF(t *testing.T) {
+	signer := devPDFSigner(t)
+	signer.Location = "Bandung"
+	original := samplePDF(t)
+
+	signed, err := signer.Sign(original, time.Date(2026, 1, 31, 15, 30, 0, 0, time.FixedZone("WIB", 7*3600)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(signed, original) {
+		t.Fatal("original PDF bytes changed; signature must be an incremental update")
+	}
+
+	m := byteRangePattern.FindSubmatch(signed)
+	if m == nil {
+		t.Fatal("/ByteRange not found")
+	}
+	var r [4]int
+	for i := range r {
+		r[i], _ = strconv.Atoi(string(m[i+1]))
+	}
+	if r[0] != 0 || r[2]+r[3] != len(signed) || r[1] >= r[2] {
+		t.Fatalf("/ByteRange %v does not cover the file of %d bytes", r, len(signed))
+	}
+	contents := signed[r[1]:r[2]]
+	if contents[0] != '<' || contents[len(contents)-1] != '>' {
+		t.Fatalf("/ByteRange gap is not the /Contents hex string")
+	}
+	padded, err := hex.DecodeString(string(contents[1 : len(contents)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CMS ditulis di awal ruang /Contents lalu diikuti padding nol
+	var cms asn1.RawValue
+	if _, err := asn1.Unmarshal(padded, &cms); err != nil {
+		t.Fatal(err)
+	}
+
+	p7, err := pkcs7.Parse(cms.FullBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p7.Content = append(append([]byte{}, signed[r[0]:r[1]]...), signed[r[2]:r[2]+r[3]]...)
+	if err := p7.Verify(); err != nil {
+		t.Fatalf("CMS signature does not verify over /ByteRange: %v", err)
+	}
+	if cert := p7.GetOnlySigner(); cert == nil || !cert.Equal(signer.Cert) {
+		t.Error("CMS signer is not the development certificate")
+	}
+
+	// Perubahan satu byte di luar /Contents harus membatalkan tanda tangan
+	tampered := append([]byte{}, p7.Content...)
+	tampered[len(original)/2] ^= 0x01
+	p7.Content = tampered
+	if err := p7.Verify(); err == nil {
+		t.Error("signature verifies over modified content")
+	}
+
+	for _, want := range []string{"/SubFilter /ETSI.CAdES.detached", "/Reason (Dokumen resmi gokso)", "/Location (Bandung)", "/M (D:20260131153000+07'00')"} {
+		if !bytes.Contains(signed[len(original):], []byte(want)) {
+			t.Errorf("signature dictionary lacks %s", want)
+		}
+	}
+}
+
+func TestPDFSignerOmitsEmptyLocation(t *testing.T) {
+	signer := devPDFSigner(t)
+	original := samplePDF(t)
+
+	signed, err := signer.Sign(original, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(signed[len(original):], []byte("/Location")) {
+		t.Error("/Location written without PDF_SIGN_LOCATION")
+	}
+}
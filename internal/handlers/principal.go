@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// principal adalah admin yang sedang login beserta data yang sering dibutuhkan selama satu request
+type principal struct {
+	Admin       models.Admin
+	User        models.User     // karyawan yang terhubung; kosong bila akun tidak terhubung ke karyawan
+	Permissions map[string]bool // dari permissionCache, jangan diubah
+
+	ImpersonatorID string // super_admin yang sedang memakai mode "lihat sebagai"; kosong bila tidak
+	Impersonator   string // username super_admin tersebut
+
+	server    *Server
+	scopeOnce sync.Once
+	scope     dataScope
+}
+
+// Scope memuat cakupan data admin saat pertama kali dibutuhkan
+func (p *principal) Scope() dataScope {
+	p.scopeOnce.Do(func() {
+		p.scope = p.server.adminDataScope(p.Admin.ID, p.Admin.Role)
+	})
+	return p.scope
+}
+
+// Name mengembalikan nama karyawan yang terhubung atau username bila tidak ada
+func (p *principal) Name() string {
+	if p.User.Name != "" {
+		return p.User.Name
+	}
+	return p.Admin.Username
+}
+
+type principalKey struct{}
+
+// principalHolder menyimpan principal dalam context request dan memuatnya paling banyak sekali
+type principalHolder struct {
+	once      sync.Once
+	principal *principal
+}
+
+// WithPrincipal adalah middleware router yang menyiapkan tempat principal di context request.
+// Principal baru dimuat saat pertama kali dipakai, sehingga rute publik tidak menambah query.
+func (server *Server) WithPrincipal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), principalKey{}, &principalHolder{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// currentPrincipal mengembalikan admin yang sedang login, atau nil bila belum login atau akunnya sudah tidak ada
+func (server *Server) currentPrincipal(r *http.Request) *principal {
+	holder, ok := r.Context().Value(principalKey{}).(*principalHolder)
+	if !ok {
+		// Request tanpa middleware WithPrincipal (misalnya dipanggil langsung) dimuat tanpa cache
+		return server.loadPrincipal(r)
+	}
+	holder.once.Do(func() {
+		holder.principal = server.loadPrincipal(r)
+	})
+	return holder.principal
+}
+
+// loadPrincipal memuat admin dari session, karyawan yang terhubung dan izin role-nya
+func (server *Server) loadPrincipal(r *http.Request) *principal {
+	adminID, _, _, ok := GetCurrentAdmin(r)
+	if !ok {
+		return nil
+	}
+
+	p := &principal{server: server}
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&p.Admin)
+	if p.Admin.ID == "" {
+		return nil
+	}
+	if impersonatorID, impersonator, ok := impersonatorOf(r); ok {
+		// Akun yang sedang dilihat sudah dinonaktifkan; anggap tidak login agar mode ini diakhiri
+		if p.Admin.Disabled {
+			return nil
+		}
+		p.ImpersonatorID, p.Impersonator = impersonatorID, impersonator
+	}
+	if p.Admin.UserID != "" {
+		server.DB.Where("id = ?", p.Admin.UserID).Limit(1).Find(&p.User)
+	}
+	p.Permissions = server.GetPermissions(p.Admin.Role)
+	return p
+}
+
+// permissionCacheTTL membatasi umur cache izin agar perubahan dari instance aplikasi lain tetap terbaca
+const permissionCacheTTL = time.Minute
+
+// permissionCache menyimpan izin per role di memori. Dikosongkan setiap kali role atau izinnya diubah.
+type permissionCache struct {
+	mu      sync.RWMutex
+	roles   map[string]map[string]bool
+	expires time.Time
+}
+
+// get mengembalikan izin role dari cache
+func (c *permissionCache) get(role string) (map[string]bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if time.Now().After(c.expires) {
+		return nil, false
+	}
+	perms, ok := c.roles[role]
+	return perms, ok
+}
+
+// set menyimpan izin role ke cache
+func (c *permissionCache) set(role string, perms map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.roles == nil || time.Now().After(c.expires) {
+		c.roles = make(map[string]map[string]bool)
+		c.expires = time.Now().Add(permissionCacheTTL)
+	}
+	c.roles[role] = perms
+}
+
+// invalidate mengosongkan seluruh cache
+func (c *permissionCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roles = nil
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// stafIT membuat admin dengan role staf_it
+func stafIT(server *Server) models.Admin {
+	admin := models.Admin{ID: "a2", Username: "staf", Password: "x", Role: "staf_it"}
+	server.DB.Create(&admin)
+	return admin
+}
+
+func TestPrincipalLoadedOncePerRequest(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, stafIT(server))
+	queries := countQueries(t, server.DB)
+
+	if w := client.do(http.MethodGet, "/", nil); w.Code != http.StatusOK {
+		t.Fatalf("GET / = %d, want %d", w.Code, http.StatusOK)
+	}
+	if n := queries.count("admins"); n != 1 {
+		t.Errorf("admins queried %d times per request, want 1", n)
+	}
+}
+
+func TestPermissionCacheServesRepeatedRequests(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, stafIT(server))
+	queries := countQueries(t, server.DB)
+
+	client.do(http.MethodGet, "/", nil)
+	if n := queries.count("role_permissions"); n != 1 {
+		t.Errorf("first request queried role_permissions %d times, want 1", n)
+	}
+
+	queries.reset()
+	client.do(http.MethodGet, "/", nil)
+	if n := queries.count("role_permissions"); n != 0 {
+		t.Errorf("cached request queried role_permissions %d times, want 0", n)
+	}
+}
+
+func TestUpdateSettingRoleInvalidatesPermissionCache(t *testing.T) {
+	server := newTestServer(t)
+	staf := loginAs(t, server, stafIT(server))
+	admin := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	if w := staf.do(http.MethodGet, "/inventori/aset-laptop", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("before update = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	form := url.Values{"role": {"staf_it"}}
+	for key, allowed := range server.GetPermissions("staf_it") {
+		if allowed {
+			form.Set("perm_"+key, "on")
+		}
+	}
+	form.Set("perm_asset.view", "on")
+	if w := admin.do(http.MethodPost, "/setting/role/update", form); w.Code != http.StatusSeeOther {
+		t.Fatalf("update role = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+
+	if w := staf.do(http.MethodGet, "/inventori/aset-laptop", nil); w.Code != http.StatusOK {
+		t.Errorf("after update = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestPendingApprovalsCount(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	for i, status := range []string{"Submitted", "Submitted", "Draft", "Approved"} {
+		server.DB.Create(&models.MaintenanceDocument{ID: fmt.Sprintf("doc%d", i), Category: "Laptop", Branch: "Jakarta", Period: "S1-2026", Status: status})
+	}
+
+	queries := countQueries(t, server.DB)
+	data := server.GetAdminData(client.request(http.MethodGet, "/", nil))
+	if got := data["PendingApprovalsCount"]; got != int64(2) {
+		t.Errorf("PendingApprovalsCount = %v, want 2", got)
+	}
+	if n := queries.count("maintenance_documents"); n != 1 {
+		t.Errorf("maintenance_documents queried %d times, want 1", n)
+	}
+}
+
+// BenchmarkDashboardRequest mengukur query per request halaman dashboard dengan dan tanpa cache izin
+func BenchmarkDashboardRequest(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		cached bool
+	}{{"cached", true}, {"uncached", false}} {
+		b.Run(bc.name, func(b *testing.B) {
+			server := newTestServer(b)
+			client := loginAs(b, server, stafIT(server))
+			queries := countQueries(b, server.DB)
+			client.do(http.MethodGet, "/", nil)
+
+			queries.reset()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !bc.cached {
+					server.permCache.invalidate()
+				}
+				client.do(http.MethodGet, "/", nil)
+			}
+			b.ReportMetric(float64(queries.sum())/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// BenchmarkPermissionRequired mengukur query yang dibutuhkan middleware izin untuk satu request
+func BenchmarkPermissionRequired(b *testing.B) {
+	server := newTestServer(b)
+	client := loginAs(b, server, stafIT(server))
+	handler := server.WithPrincipal(server.PermissionRequired("dashboard.view", func(w http.ResponseWriter, r *http.Request) {
+		// Handler yang memeriksa izin lagi memakai principal yang sama
+		server.hasPermission(server.currentPrincipal(r).Admin.Role, "dashboard.view")
+		w.WriteHeader(http.StatusOK)
+	}))
+	queries := countQueries(b, server.DB)
+	server.GetPermissions("staf_it")
+
+	queries.reset()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, client.request(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("status = %d", w.Code)
+		}
+	}
+	b.ReportMetric(float64(queries.sum())/float64(b.N), "queries/op")
+}
+
+// BenchmarkGetPermissions membandingkan izin dari cache dengan izin yang dimuat dari database
+func BenchmarkGetPermissions(b *testing.B) {
+	server := newTestServer(b)
+	queries := countQueries(b, server.DB)
+
+	b.Run("cached", func(b *testing.B) {
+		server.GetPermissions("staf_it")
+		queries.reset()
+		for i := 0; i < b.N; i++ {
+			server.GetPermissions("staf_it")
+		}
+		b.ReportMetric(float64(queries.sum())/float64(b.N), "queries/op")
+	})
+	b.Run("uncached", func(b *testing.B) {
+		queries.reset()
+		for i := 0; i < b.N; i++ {
+			server.permCache.invalidate()
+			server.GetPermissions("staf_it")
+		}
+		b.ReportMetric(float64(queries.sum())/float64(b.N), "queries/op")
+	})
+}
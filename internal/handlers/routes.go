@@ -8,130 +8,234 @@ import (
 
 func (server *Server) initializeRoutes() {
 	server.Router = mux.NewRouter()
+	server.Router.Use(server.WithPrincipal)
+	server.Router.Use(server.AuditImpersonation)
 
 	// Rute Autentikasi (Login, Logout)
 	server.Router.HandleFunc("/login", server.LoginForm).Methods("GET")
 	server.Router.HandleFunc("/login", server.Login).Methods("POST")
+	server.Router.HandleFunc("/login/2fa", server.LoginTwoFactorForm).Methods("GET")
+	server.Router.HandleFunc("/login/2fa", server.LoginTwoFactor).Methods("POST")
+	server.Router.HandleFunc("/login/2fa/setup", server.LoginTwoFactorSetupForm).Methods("GET")
+	server.Router.HandleFunc("/login/2fa/setup", server.LoginTwoFactorSetup).Methods("POST")
+	server.Router.HandleFunc("/login/sso", server.LoginSSO).Methods("GET")
+	server.Router.HandleFunc("/login/sso/callback", server.LoginSSOCallback).Methods("GET")
 	server.Router.HandleFunc("/logout", server.Logout).Methods("GET")
 
+	// Rute Verifikasi Dokumen (Publik, dipakai QR code pada PDF)
+	server.Router.HandleFunc("/verify/{id}", server.VerifyDocument).Methods("GET")
+	server.Router.HandleFunc("/verify/{id}", server.VerifyDocumentUpload).Methods("POST")
+
+	// Rute Tanda Tangan Elektronik (Publik, diakses lewat tautan pribadi setiap pihak)
+	server.Router.HandleFunc("/sign/{token}", server.ShowSignPage).Methods("GET")
+	server.Router.HandleFunc("/sign/{token}", server.SubmitSignature).Methods("POST")
+
 	// Rute Dashboard dan Utama (Terproteksi)
-	server.Router.HandleFunc("/", server.PermissionRequired("dashboard", server.Home)).Methods("GET")
+	server.Router.HandleFunc("/", server.PermissionRequired("dashboard.view", server.Home)).Methods("GET")
 
 	// Manajemen Administrasi dan Karyawan
-	server.Router.HandleFunc("/administration/employee", server.PermissionRequired("administration", server.ListEmployees)).Methods("GET")
-	server.Router.HandleFunc("/administration/employee/create", server.PermissionRequired("administration", server.CreateEmployeeForm)).Methods("GET")
-	server.Router.HandleFunc("/administration/employee", server.PermissionRequired("administration", server.StoreEmployee)).Methods("POST")
-	server.Router.HandleFunc("/administration/employee/edit/{id}", server.PermissionRequired("administration", server.EditEmployeeForm)).Methods("GET")
-	server.Router.HandleFunc("/administration/employee/update/{id}", server.PermissionRequired("administration", server.UpdateEmployee)).Methods("POST")
-	server.Router.HandleFunc("/administration/employee/delete/{id}", server.PermissionRequired("administration", server.DeleteEmployee)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee", server.PermissionRequired("employee.view", server.ListEmployees)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/create", server.PermissionRequired("employee.create", server.CreateEmployeeForm)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee", server.PermissionRequired("employee.create", server.StoreEmployee)).Methods("POST")
+	server.Router.HandleFunc("/administration/employee/edit/{id}", server.PermissionRequired("employee.update", server.EditEmployeeForm)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/update/{id}", server.PermissionRequired("employee.update", server.UpdateEmployee)).Methods("POST")
+	server.Router.HandleFunc("/administration/employee/delete/{id}", server.PermissionRequired("employee.delete", server.NotImpersonating(server.DeleteEmployee))).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/offboarding/{id}", server.PermissionRequired("employee.offboarding", server.EmployeeOffboarding)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/offboarding/{id}/reassign", server.PermissionRequired("assignment.manage", server.ReassignOffboardingAsset)).Methods("POST")
+	server.Router.HandleFunc("/administration/employee/offboarding/{id}/complete", server.PermissionRequired("employee.offboarding", server.CompleteEmployeeOffboarding)).Methods("POST")
+	server.Router.HandleFunc("/administration/employee/import", server.PermissionRequired("employee.import", server.ImportEmployeeForm)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/import", server.PermissionRequired("employee.import", server.UploadEmployeeImport)).Methods("POST")
+	server.Router.HandleFunc("/administration/employee/import/template", server.PermissionRequired("employee.import", server.EmployeeImportTemplate)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/import/{id}", server.PermissionRequired("employee.import", server.PreviewEmployeeImport)).Methods("GET")
+	server.Router.HandleFunc("/administration/employee/import/{id}/apply", server.PermissionRequired("employee.import", server.ApplyEmployeeImport)).Methods("POST")
 
 	// Data Master Administrasi (Cabang, Departemen, dll)
-	server.Router.HandleFunc("/administration/master-data/branch", server.PermissionRequired("administration", server.ListMasterBranch)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/branch/store", server.PermissionRequired("administration", server.StoreMasterBranch)).Methods("POST")
-	server.Router.HandleFunc("/administration/master-data/branch/delete/{id}", server.PermissionRequired("administration", server.DeleteMasterBranch)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/branch/edit/{id}", server.PermissionRequired("administration", server.EditMasterBranch)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/branch/update/{id}", server.PermissionRequired("administration", server.UpdateMasterBranch)).Methods("POST")
-
-	server.Router.HandleFunc("/administration/master-data/department", server.PermissionRequired("administration", server.ListMasterDepartment)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/department/store", server.PermissionRequired("administration", server.StoreMasterDepartment)).Methods("POST")
-	server.Router.HandleFunc("/administration/master-data/department/delete/{id}", server.PermissionRequired("administration", server.DeleteMasterDepartment)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/department/edit/{id}", server.PermissionRequired("administration", server.EditMasterDepartment)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/department/update/{id}", server.PermissionRequired("administration", server.UpdateMasterDepartment)).Methods("POST")
-
-	server.Router.HandleFunc("/administration/master-data/sub-department", server.PermissionRequired("administration", server.ListMasterSubDepartment)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/sub-department/store", server.PermissionRequired("administration", server.StoreMasterSubDepartment)).Methods("POST")
-	server.Router.HandleFunc("/administration/master-data/sub-department/delete/{id}", server.PermissionRequired("administration", server.DeleteMasterSubDepartment)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/sub-department/edit/{id}", server.PermissionRequired("administration", server.EditMasterSubDepartment)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/sub-department/update/{id}", server.PermissionRequired("administration", server.UpdateMasterSubDepartment)).Methods("POST")
-
-	server.Router.HandleFunc("/administration/master-data/position", server.PermissionRequired("administration", server.ListMasterPosition)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/position/store", server.PermissionRequired("administration", server.StoreMasterPosition)).Methods("POST")
-	server.Router.HandleFunc("/administration/master-data/position/delete/{id}", server.PermissionRequired("administration", server.DeleteMasterPosition)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/position/edit/{id}", server.PermissionRequired("administration", server.EditMasterPosition)).Methods("GET")
-	server.Router.HandleFunc("/administration/master-data/position/update/{id}", server.PermissionRequired("administration", server.UpdateMasterPosition)).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/branch", server.PermissionRequired("employee.view", server.ListMasterBranch)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/branch/store", server.PermissionRequired("master_data.manage", server.StoreMasterBranch)).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/branch/delete/{id}", server.PermissionRequired("master_data.manage", server.ConfirmDeleteMasterBranch)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/branch/delete/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteMasterBranch)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/branch/merge/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.MergeMasterBranch)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/branch/edit/{id}", server.PermissionRequired("master_data.manage", server.EditMasterBranch)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/branch/update/{id}", server.PermissionRequired("master_data.manage", server.UpdateMasterBranch)).Methods("POST")
+
+	server.Router.HandleFunc("/administration/master-data/department", server.PermissionRequired("employee.view", server.ListMasterDepartment)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/department/store", server.PermissionRequired("master_data.manage", server.StoreMasterDepartment)).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/department/delete/{id}", server.PermissionRequired("master_data.manage", server.ConfirmDeleteMasterDepartment)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/department/delete/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteMasterDepartment)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/department/merge/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.MergeMasterDepartment)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/department/edit/{id}", server.PermissionRequired("master_data.manage", server.EditMasterDepartment)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/department/update/{id}", server.PermissionRequired("master_data.manage", server.UpdateMasterDepartment)).Methods("POST")
+
+	server.Router.HandleFunc("/administration/master-data/sub-department", server.PermissionRequired("employee.view", server.ListMasterSubDepartment)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/sub-department/store", server.PermissionRequired("master_data.manage", server.StoreMasterSubDepartment)).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/sub-department/delete/{id}", server.PermissionRequired("master_data.manage", server.ConfirmDeleteMasterSubDepartment)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/sub-department/delete/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteMasterSubDepartment)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/sub-department/merge/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.MergeMasterSubDepartment)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/sub-department/edit/{id}", server.PermissionRequired("master_data.manage", server.EditMasterSubDepartment)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/sub-department/update/{id}", server.PermissionRequired("master_data.manage", server.UpdateMasterSubDepartment)).Methods("POST")
+
+	server.Router.HandleFunc("/administration/master-data/org-chart", server.PermissionRequired("employee.view", server.OrgChart)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/org-chart/store", server.PermissionRequired("master_data.manage", server.CSRFProtect(server.StoreOrgNode))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/org-chart/rename/{level}/{id}", server.PermissionRequired("master_data.manage", server.CSRFProtect(server.RenameOrgNode))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/org-chart/move/{level}/{id}", server.PermissionRequired("master_data.manage", server.CSRFProtect(server.MoveOrgNode))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/org-chart/delete/{level}/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteOrgNode)))).Methods("POST")
+
+	server.Router.HandleFunc("/administration/master-data/position", server.PermissionRequired("employee.view", server.ListMasterPosition)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/position/store", server.PermissionRequired("master_data.manage", server.StoreMasterPosition)).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/position/delete/{id}", server.PermissionRequired("master_data.manage", server.ConfirmDeleteMasterPosition)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/position/delete/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteMasterPosition)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/position/merge/{id}", server.PermissionRequired("master_data.manage", server.NotImpersonating(server.CSRFProtect(server.MergeMasterPosition)))).Methods("POST")
+	server.Router.HandleFunc("/administration/master-data/position/edit/{id}", server.PermissionRequired("master_data.manage", server.EditMasterPosition)).Methods("GET")
+	server.Router.HandleFunc("/administration/master-data/position/update/{id}", server.PermissionRequired("master_data.manage", server.UpdateMasterPosition)).Methods("POST")
 
 	// Rute Inventori (Data Dasar Aset)
-	server.Router.HandleFunc("/inventori/master-data/asset-category", server.PermissionRequired("inventori", server.ListMasterAssetCategory)).Methods("GET")
-	server.Router.HandleFunc("/inventori/master-data/asset-category/store", server.PermissionRequired("inventori", server.StoreMasterAssetCategory)).Methods("POST")
-	server.Router.HandleFunc("/inventori/master-data/asset-category/delete/{id}", server.PermissionRequired("inventori", server.DeleteMasterAssetCategory)).Methods("GET")
-	server.Router.HandleFunc("/inventori/master-data/asset-category/edit/{id}", server.PermissionRequired("inventori", server.EditMasterAssetCategory)).Methods("GET")
-	server.Router.HandleFunc("/inventori/master-data/asset-category/update/{id}", server.PermissionRequired("inventori", server.UpdateMasterAssetCategory)).Methods("POST")
-
-	server.Router.HandleFunc("/inventori/aset-laptop", server.PermissionRequired("inventori", server.ListAssetKSO)).Methods("GET")
-	server.Router.HandleFunc("/inventori/aset-laptop/create", server.PermissionRequired("inventori", server.CreateAssetKSOForm)).Methods("GET")
-	server.Router.HandleFunc("/inventori/aset-laptop/bulk-create", server.PermissionRequired("inventori", server.CreateAssetKSOBulkForm)).Methods("GET")
-	server.Router.HandleFunc("/inventori/aset-laptop", server.PermissionRequired("inventori", server.StoreAssetKSO)).Methods("POST")
-	server.Router.HandleFunc("/inventori/aset-laptop/bulk-store", server.PermissionRequired("inventori", server.StoreAssetKSOBulk)).Methods("POST")
-	server.Router.HandleFunc("/inventori/aset-laptop/edit/{id}", server.PermissionRequired("inventori", server.EditAssetKSOForm)).Methods("GET")
-	server.Router.HandleFunc("/inventori/aset-laptop/update/{id}", server.PermissionRequired("inventori", server.UpdateAssetKSO)).Methods("POST")
-	server.Router.HandleFunc("/inventori/aset-laptop/delete/{id}", server.PermissionRequired("inventori", server.DeleteAssetKSO)).Methods("GET")
-	server.Router.HandleFunc("/inventori/aset-laptop/bulk-delete", server.PermissionRequired("inventori", server.BulkDeleteAssetKSO)).Methods("POST")
+	server.Router.HandleFunc("/inventori/master-data/asset-category", server.PermissionRequired("asset.view", server.ListMasterAssetCategory)).Methods("GET")
+	server.Router.HandleFunc("/inventori/master-data/asset-category/store", server.PermissionRequired("asset_category.manage", server.StoreMasterAssetCategory)).Methods("POST")
+	server.Router.HandleFunc("/inventori/master-data/asset-category/delete/{id}", server.PermissionRequired("asset_category.manage", server.ConfirmDeleteMasterAssetCategory)).Methods("GET")
+	server.Router.HandleFunc("/inventori/master-data/asset-category/delete/{id}", server.PermissionRequired("asset_category.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteMasterAssetCategory)))).Methods("POST")
+	server.Router.HandleFunc("/inventori/master-data/asset-category/merge/{id}", server.PermissionRequired("asset_category.manage", server.NotImpersonating(server.CSRFProtect(server.MergeMasterAssetCategory)))).Methods("POST")
+	server.Router.HandleFunc("/inventori/master-data/asset-category/edit/{id}", server.PermissionRequired("asset_category.manage", server.EditMasterAssetCategory)).Methods("GET")
+	server.Router.HandleFunc("/inventori/master-data/asset-category/update/{id}", server.PermissionRequired("asset_category.manage", server.UpdateMasterAssetCategory)).Methods("POST")
+
+	// Master spesifikasi aset (RAM, penyimpanan, prosesor, sistem operasi) memakai handler generik
+	lookup := "/inventori/master-data/" + lookupRoutePattern()
+	server.Router.HandleFunc(lookup, server.PermissionRequired("asset.view", server.ListLookupMaster)).Methods("GET")
+	server.Router.HandleFunc(lookup+"/store", server.PermissionRequired("asset_category.manage", server.StoreLookupMaster)).Methods("POST")
+	server.Router.HandleFunc(lookup+"/edit/{id}", server.PermissionRequired("asset_category.manage", server.EditLookupMaster)).Methods("GET")
+	server.Router.HandleFunc(lookup+"/update/{id}", server.PermissionRequired("asset_category.manage", server.UpdateLookupMaster)).Methods("POST")
+	server.Router.HandleFunc(lookup+"/delete/{id}", server.PermissionRequired("asset_category.manage", server.ConfirmDeleteLookupMaster)).Methods("GET")
+	server.Router.HandleFunc(lookup+"/delete/{id}", server.PermissionRequired("asset_category.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteLookupMaster)))).Methods("POST")
+	server.Router.HandleFunc(lookup+"/merge/{id}", server.PermissionRequired("asset_category.manage", server.NotImpersonating(server.CSRFProtect(server.MergeLookupMaster)))).Methods("POST")
+
+	server.Router.HandleFunc("/inventori/aset-laptop", server.PermissionRequired("asset.view", server.ListAssetKSO)).Methods("GET")
+	server.Router.HandleFunc("/inventori/aset-laptop/create", server.PermissionRequired("asset.create", server.CreateAssetKSOForm)).Methods("GET")
+	server.Router.HandleFunc("/inventori/aset-laptop/bulk-create", server.PermissionRequired("asset.create", server.CreateAssetKSOBulkForm)).Methods("GET")
+	server.Router.HandleFunc("/inventori/aset-laptop", server.PermissionRequired("asset.create", server.StoreAssetKSO)).Methods("POST")
+	server.Router.HandleFunc("/inventori/aset-laptop/bulk-store", server.PermissionRequired("asset.create", server.StoreAssetKSOBulk)).Methods("POST")
+	server.Router.HandleFunc("/inventori/aset-laptop/edit/{id}", server.PermissionRequired("asset.update", server.EditAssetKSOForm)).Methods("GET")
+	server.Router.HandleFunc("/inventori/aset-laptop/update/{id}", server.PermissionRequired("asset.update", server.UpdateAssetKSO)).Methods("POST")
+	server.Router.HandleFunc("/inventori/aset-laptop/delete/{id}", server.PermissionRequired("asset.delete", server.NotImpersonating(server.DeleteAssetKSO))).Methods("GET")
+	server.Router.HandleFunc("/inventori/aset-laptop/bulk-delete", server.PermissionRequired("asset.delete", server.NotImpersonating(server.BulkDeleteAssetKSO))).Methods("POST")
 
 	// Manajemen Aset (Laptop & Komputer)
-	server.Router.HandleFunc("/asset-management/laptop", server.PermissionRequired("asset_management", server.ListAssetLaptop)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/laptop/create", server.PermissionRequired("asset_management", server.CreateAssetLaptopForm)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/laptop/edit/{id}", server.PermissionRequired("asset_management", server.EditAssetLaptopForm)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/laptop/delete/{id}", server.PermissionRequired("asset_management", server.DeleteAssetLaptop)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/laptop/assign", server.PermissionRequired("asset_management", server.AssignAssetLaptop)).Methods("POST")
-	server.Router.HandleFunc("/asset-management/update-label", server.PermissionRequired("asset_management", server.UpdateAssetLabel)).Methods("POST")
-	server.Router.HandleFunc("/asset-management/bulk-update-label", server.PermissionRequired("asset_management", server.BulkUpdateAssetLabel)).Methods("POST")
-
-	server.Router.HandleFunc("/asset-management/komputer", server.PermissionRequired("asset_management", server.ListAssetKomputer)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/komputer/create", server.PermissionRequired("asset_management", server.CreateAssetKomputerForm)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/komputer/edit/{id}", server.PermissionRequired("asset_management", server.EditAssetKomputerForm)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/komputer/delete/{id}", server.PermissionRequired("asset_management", server.DeleteAssetKomputer)).Methods("GET")
-	server.Router.HandleFunc("/asset-management/komputer/assign", server.PermissionRequired("asset_management", server.AssignAssetKomputer)).Methods("POST")
+	server.Router.HandleFunc("/asset-management/laptop", server.PermissionRequired("assignment.view", server.ListAssetLaptop)).Methods("GET")
+	server.Router.HandleFunc("/asset-management/laptop/create", server.PermissionRequired("asset.create", server.CreateAssetLaptopForm)).Methods("GET")
+	server.Router.HandleFunc("/asset-management/laptop/edit/{id}", server.PermissionRequired("asset.update", server.EditAssetLaptopForm)).Methods("GET")
+	server.Router.HandleFunc("/asset-management/laptop/delete/{id}", server.PermissionRequired("asset.delete", server.NotImpersonating(server.DeleteAssetLaptop))).Methods("GET")
+	server.Router.HandleFunc("/asset-management/laptop/assign", server.PermissionRequired("assignment.manage", server.AssignAssetLaptop)).Methods("POST")
+	server.Router.HandleFunc("/asset-management/update-label", server.PermissionRequired("assignment.manage", server.UpdateAssetLabel)).Methods("POST")
+	server.Router.HandleFunc("/asset-management/bulk-update-label", server.PermissionRequired("assignment.manage", server.BulkUpdateAssetLabel)).Methods("POST")
+
+	server.Router.HandleFunc("/asset-management/komputer", server.PermissionRequired("assignment.view", server.ListAssetKomputer)).Methods("GET")
+	server.Router.HandleFunc("/asset-management/komputer/create", server.PermissionRequired("asset.create", server.CreateAssetKomputerForm)).Methods("GET")
+	server.Router.HandleFunc("/asset-management/komputer/edit/{id}", server.PermissionRequired("asset.update", server.EditAssetKomputerForm)).Methods("GET")
+	server.Router.HandleFunc("/asset-management/komputer/delete/{id}", server.PermissionRequired("asset.delete", server.NotImpersonating(server.DeleteAssetKomputer))).Methods("GET")
+	server.Router.HandleFunc("/asset-management/komputer/assign", server.PermissionRequired("assignment.manage", server.AssignAssetKomputer)).Methods("POST")
 
 	// Rute Maintenance (Laporan Rutin)
-	server.Router.HandleFunc("/maintenance/laptop", server.PermissionRequired("maintenance", server.MaintenanceLaptop)).Methods("GET")
-	server.Router.HandleFunc("/maintenance/laptop/store", server.PermissionRequired("maintenance", server.StoreMaintenanceLaptop)).Methods("POST")
-	server.Router.HandleFunc("/maintenance/submit", server.PermissionRequired("maintenance", server.SubmitMaintenance)).Methods("POST")
-	server.Router.HandleFunc("/maintenance/approve", server.PermissionRequired("maintenance", server.ApproveMaintenance)).Methods("POST")
-	server.Router.HandleFunc("/maintenance/komputer", server.PermissionRequired("maintenance", server.MaintenanceKomputer)).Methods("GET")
-	server.Router.HandleFunc("/maintenance/history", server.PermissionRequired("maintenance", server.MaintenanceHistory)).Methods("GET")
-	server.Router.HandleFunc("/maintenance/history/detail/{id}", server.PermissionRequired("maintenance", server.MaintenanceHistoryDetail)).Methods("GET")
+	server.Router.HandleFunc("/maintenance/laptop", server.PermissionRequired("maintenance.view", server.MaintenanceLaptop)).Methods("GET")
+	server.Router.HandleFunc("/maintenance/laptop/store", server.PermissionRequired("maintenance.create", server.StoreMaintenanceLaptop)).Methods("POST")
+	server.Router.HandleFunc("/maintenance/submit", server.PermissionRequired("maintenance.create", server.SubmitMaintenance)).Methods("POST")
+	server.Router.HandleFunc("/maintenance/approve", server.PermissionRequired("maintenance.approve", server.ApproveMaintenance)).Methods("POST")
+	server.Router.HandleFunc("/maintenance/komputer", server.PermissionRequired("maintenance.view", server.MaintenanceKomputer)).Methods("GET")
+	server.Router.HandleFunc("/maintenance/history", server.PermissionRequired("maintenance.view", server.MaintenanceHistory)).Methods("GET")
+	server.Router.HandleFunc("/maintenance/history/detail/{id}", server.PermissionRequired("maintenance.view", server.MaintenanceHistoryDetail)).Methods("GET")
 
 	// Rute GoDMS / DMS
-	server.Router.HandleFunc("/godms/doc", server.AuthRequired(server.ListEDoc)).Methods("GET")
-	server.Router.HandleFunc("/godms/doc/{id}", server.AuthRequired(server.ListFolderContent)).Methods("GET")
-	server.Router.HandleFunc("/godms/folder/store", server.AuthRequired(server.StoreFolder)).Methods("POST")
-	server.Router.HandleFunc("/godms/folder/rename", server.AuthRequired(server.RenameFolder)).Methods("POST")
-	server.Router.HandleFunc("/godms/folder/trash", server.AuthRequired(server.MoveFolderToTrash)).Methods("POST")
-	server.Router.HandleFunc("/godms/folder/restore", server.AuthRequired(server.RestoreFolder)).Methods("POST")
-	server.Router.HandleFunc("/godms/folder/delete-permanent", server.AuthRequired(server.DeleteFolderPermanently)).Methods("POST")
-	server.Router.HandleFunc("/godms/file/rename", server.AuthRequired(server.RenameFile)).Methods("POST")
-	server.Router.HandleFunc("/godms/file/trash", server.AuthRequired(server.MoveFileToTrash)).Methods("POST")
-	server.Router.HandleFunc("/godms/file/restore", server.AuthRequired(server.RestoreFile)).Methods("POST")
-	server.Router.HandleFunc("/godms/file/delete-permanent", server.AuthRequired(server.DeleteFilePermanently)).Methods("POST")
-	server.Router.HandleFunc("/godms/file/upload", server.AuthRequired(server.UploadFile)).Methods("POST")
-	server.Router.HandleFunc("/godms/folder/upload", server.AuthRequired(server.UploadFolder)).Methods("POST")
-	server.Router.HandleFunc("/godms/bulk-move", server.AuthRequired(server.BulkMove)).Methods("POST")
-	server.Router.HandleFunc("/godms/bulk-trash", server.AuthRequired(server.BulkTrash)).Methods("POST")
-	server.Router.HandleFunc("/godms/bulk-restore", server.AuthRequired(server.BulkRestore)).Methods("POST")
-	server.Router.HandleFunc("/godms/bulk-delete-permanent", server.AuthRequired(server.BulkDeletePermanent)).Methods("POST")
-	server.Router.HandleFunc("/godms/bulk-download", server.AuthRequired(server.BulkDownload)).Methods("POST")
-	server.Router.HandleFunc("/godms/folder-list", server.AuthRequired(server.GetFolderList)).Methods("GET")
-	server.Router.HandleFunc("/godms/trash", server.AuthRequired(server.ViewTrash)).Methods("GET")
-	server.Router.HandleFunc("/goform", server.AuthRequired(server.ListGoForm)).Methods("GET")
-	server.Router.HandleFunc("/goform/fill/{id}", server.AuthRequired(server.FillGoForm)).Methods("GET")
-	server.Router.HandleFunc("/goform/submit/{id}", server.AuthRequired(server.SubmitGoForm)).Methods("POST")
-
-	// Pengaturan Pengguna (Hanya Super Admin)
-	server.Router.HandleFunc("/setting/user", server.RoleRequired([]string{"super_admin"}, server.ListSettingUser)).Methods("GET")
-	server.Router.HandleFunc("/setting/user/create", server.RoleRequired([]string{"super_admin"}, server.CreateSettingUserForm)).Methods("GET")
-	server.Router.HandleFunc("/setting/user/store", server.RoleRequired([]string{"super_admin"}, server.StoreSettingUser)).Methods("POST")
-	server.Router.HandleFunc("/setting/user/edit/{id}", server.RoleRequired([]string{"super_admin"}, server.EditSettingUserForm)).Methods("GET")
-	server.Router.HandleFunc("/setting/user/update/{id}", server.RoleRequired([]string{"super_admin"}, server.UpdateSettingUser)).Methods("POST")
-	server.Router.HandleFunc("/setting/user/delete/{id}", server.RoleRequired([]string{"super_admin"}, server.DeleteSettingUser)).Methods("GET")
-
-	server.Router.HandleFunc("/setting/role", server.RoleRequired([]string{"super_admin"}, server.ListSettingRole)).Methods("GET")
-	server.Router.HandleFunc("/setting/role/update", server.RoleRequired([]string{"super_admin"}, server.UpdateSettingRole)).Methods("POST")
+	server.Router.HandleFunc("/godms/doc", server.PermissionRequired("dms.view", server.ListEDoc)).Methods("GET")
+	server.Router.HandleFunc("/godms/doc/{id}", server.PermissionRequired("dms.view", server.ListFolderContent)).Methods("GET")
+	server.Router.HandleFunc("/godms/folder/store", server.PermissionRequired("dms.upload", server.StoreFolder)).Methods("POST")
+	server.Router.HandleFunc("/godms/folder/rename", server.PermissionRequired("dms.upload", server.RenameFolder)).Methods("POST")
+	server.Router.HandleFunc("/godms/folder/trash", server.PermissionRequired("dms.delete", server.MoveFolderToTrash)).Methods("POST")
+	server.Router.HandleFunc("/godms/folder/restore", server.PermissionRequired("dms.delete", server.RestoreFolder)).Methods("POST")
+	server.Router.HandleFunc("/godms/folder/delete-permanent", server.PermissionRequired("dms.delete_permanent", server.NotImpersonating(server.DeleteFolderPermanently))).Methods("POST")
+	server.Router.HandleFunc("/godms/file/rename", server.PermissionRequired("dms.upload", server.RenameFile)).Methods("POST")
+	server.Router.HandleFunc("/godms/file/trash", server.PermissionRequired("dms.delete", server.MoveFileToTrash)).Methods("POST")
+	server.Router.HandleFunc("/godms/file/restore", server.PermissionRequired("dms.delete", server.RestoreFile)).Methods("POST")
+	server.Router.HandleFunc("/godms/file/delete-permanent", server.PermissionRequired("dms.delete_permanent", server.NotImpersonating(server.DeleteFilePermanently))).Methods("POST")
+	server.Router.HandleFunc("/godms/file/upload", server.PermissionRequired("dms.upload", server.UploadFile)).Methods("POST")
+	server.Router.HandleFunc("/godms/folder/upload", server.PermissionRequired("dms.upload", server.UploadFolder)).Methods("POST")
+	server.Router.HandleFunc("/godms/bulk-move", server.PermissionRequired("dms.upload", server.BulkMove)).Methods("POST")
+	server.Router.HandleFunc("/godms/bulk-trash", server.PermissionRequired("dms.delete", server.BulkTrash)).Methods("POST")
+	server.Router.HandleFunc("/godms/bulk-restore", server.PermissionRequired("dms.delete", server.BulkRestore)).Methods("POST")
+	server.Router.HandleFunc("/godms/bulk-delete-permanent", server.PermissionRequired("dms.delete_permanent", server.NotImpersonating(server.BulkDeletePermanent))).Methods("POST")
+	server.Router.HandleFunc("/godms/bulk-download", server.PermissionRequired("dms.view", server.BulkDownload)).Methods("POST")
+	server.Router.HandleFunc("/godms/folder-list", server.PermissionRequired("dms.view", server.GetFolderList)).Methods("GET")
+	server.Router.HandleFunc("/godms/trash", server.PermissionRequired("dms.view", server.ViewTrash)).Methods("GET")
+	server.Router.HandleFunc("/goform", server.PermissionRequired("goform.view", server.ListGoForm)).Methods("GET")
+	server.Router.HandleFunc("/goform/fill/{id}", server.PermissionRequired("goform.view", server.FillGoForm)).Methods("GET")
+	server.Router.HandleFunc("/goform/submit/{id}", server.PermissionRequired("goform.view", server.SubmitGoForm)).Methods("POST")
+	server.Router.HandleFunc("/goform/builder", server.PermissionRequired("goform.manage", server.ListGoFormBuilder)).Methods("GET")
+	server.Router.HandleFunc("/goform/builder/create", server.PermissionRequired("goform.manage", server.CreateGoFormBuilder)).Methods("GET")
+	server.Router.HandleFunc("/goform/builder/store", server.PermissionRequired("goform.manage", server.StoreGoFormBuilder)).Methods("POST")
+	server.Router.HandleFunc("/goform/builder/edit/{id}", server.PermissionRequired("goform.manage", server.EditGoFormBuilder)).Methods("GET")
+	server.Router.HandleFunc("/goform/builder/update/{id}", server.PermissionRequired("goform.manage", server.UpdateGoFormBuilder)).Methods("POST")
+	server.Router.HandleFunc("/goform/builder/delete/{id}", server.PermissionRequired("goform.manage", server.NotImpersonating(server.CSRFProtect(server.DeleteGoFormBuilder)))).Methods("POST")
+	server.Router.HandleFunc("/goform/submissions/{id}", server.PermissionRequired("goform.manage", server.ListGoFormSubmissions)).Methods("GET")
+	server.Router.HandleFunc("/goform/peminjaman", server.PermissionRequired("goform.view", server.ListAssetLoans)).Methods("GET")
+	server.Router.HandleFunc("/goform/peminjaman/{id}", server.PermissionRequired("goform.view", server.ShowAssetLoan)).Methods("GET")
+	server.Router.HandleFunc("/goform/peminjaman/approve/{id}", server.PermissionRequired("loan.approve", server.ApproveAssetLoan)).Methods("POST")
+	server.Router.HandleFunc("/goform/peminjaman/reject/{id}", server.PermissionRequired("loan.approve", server.RejectAssetLoan)).Methods("POST")
+	server.Router.HandleFunc("/goform/peminjaman/checkout/{id}", server.PermissionRequired("loan.handover", server.CheckOutAssetLoan)).Methods("POST")
+	server.Router.HandleFunc("/goform/peminjaman/checkin/{id}", server.PermissionRequired("loan.handover", server.CheckInAssetLoan)).Methods("POST")
+	server.Router.HandleFunc("/goform/surat-jalan", server.PermissionRequired("goform.view", server.ListAssetTransfers)).Methods("GET")
+	server.Router.HandleFunc("/goform/surat-jalan/{id}", server.PermissionRequired("goform.view", server.ShowAssetTransfer)).Methods("GET")
+	server.Router.HandleFunc("/goform/surat-jalan/receive/{id}", server.PermissionRequired("transfer.receive", server.ReceiveAssetTransfer)).Methods("POST")
+	server.Router.HandleFunc("/goform/signature", server.PermissionRequired("goform.view", server.ListSignatureRequests)).Methods("GET")
+	server.Router.HandleFunc("/goform/signature/{id}", server.PermissionRequired("goform.view", server.ShowSignatureRequest)).Methods("GET")
+	server.Router.HandleFunc("/goform/signature/remind/{id}", server.PermissionRequired("signature.manage", server.CSRFProtect(server.RemindSignatureRequest))).Methods("POST")
+	server.Router.HandleFunc("/goform/signature/retry/{id}", server.PermissionRequired("signature.manage", server.CSRFProtect(server.RetrySignatureRequest))).Methods("POST")
+	server.Router.HandleFunc("/goform/signature/cancel/{id}", server.PermissionRequired("signature.manage", server.CSRFProtect(server.CancelSignatureRequest))).Methods("POST")
+
+	// Pengaturan Pengguna, Role dan Dokumen
+	server.Router.HandleFunc("/setting/user", server.PermissionRequired("setting.user", server.ListSettingUser)).Methods("GET")
+	server.Router.HandleFunc("/setting/user/create", server.PermissionRequired("setting.user", server.CreateSettingUserForm)).Methods("GET")
+	server.Router.HandleFunc("/setting/user/store", server.PermissionRequired("setting.user", server.NotImpersonating(server.StoreSettingUser))).Methods("POST")
+	server.Router.HandleFunc("/setting/user/edit/{id}", server.PermissionRequired("setting.user", server.EditSettingUserForm)).Methods("GET")
+	server.Router.HandleFunc("/setting/user/update/{id}", server.PermissionRequired("setting.user", server.NotImpersonating(server.UpdateSettingUser))).Methods("POST")
+	server.Router.HandleFunc("/setting/user/delete/{id}", server.PermissionRequired("setting.user", server.NotImpersonating(server.DeleteSettingUser))).Methods("GET")
+	server.Router.HandleFunc("/setting/user/ldap-sync", server.PermissionRequired("setting.user", server.NotImpersonating(server.SyncSettingUserDirectory))).Methods("POST")
+	server.Router.HandleFunc("/setting/user/unlock/{id}", server.PermissionRequired("setting.user", server.NotImpersonating(server.UnlockSettingUser))).Methods("POST")
+	server.Router.HandleFunc("/setting/user/impersonate/{id}", server.PermissionRequired("setting.user", server.NotImpersonating(server.StartImpersonation))).Methods("POST")
+	server.Router.HandleFunc("/setting/login-log", server.PermissionRequired("setting.user", server.ListLoginAttempts)).Methods("GET")
+	server.Router.HandleFunc("/setting/audit-log", server.PermissionRequired("setting.user", server.ListAuditLogs)).Methods("GET")
+
+	server.Router.HandleFunc("/setting/role", server.PermissionRequired("setting.role", server.ListSettingRole)).Methods("GET")
+	server.Router.HandleFunc("/setting/role/update", server.PermissionRequired("setting.role", server.NotImpersonating(server.UpdateSettingRole))).Methods("POST")
+	server.Router.HandleFunc("/setting/role/store", server.PermissionRequired("setting.role", server.NotImpersonating(server.StoreSettingRole))).Methods("POST")
+	server.Router.HandleFunc("/setting/role/rename", server.PermissionRequired("setting.role", server.NotImpersonating(server.RenameSettingRole))).Methods("POST")
+	server.Router.HandleFunc("/setting/role/delete", server.PermissionRequired("setting.role", server.NotImpersonating(server.DeleteSettingRole))).Methods("POST")
+	server.Router.HandleFunc("/setting/role/policy", server.PermissionRequired("setting.role", server.NotImpersonating(server.UpdateSettingRolePolicy))).Methods("POST")
+
+	server.Router.HandleFunc("/setting/doc-number", server.PermissionRequired("setting.document", server.ListSettingDocNumber)).Methods("GET")
+	server.Router.HandleFunc("/setting/doc-number/update", server.PermissionRequired("setting.document", server.UpdateSettingDocNumber)).Methods("POST")
+
+	server.Router.HandleFunc("/setting/doc-template", server.PermissionRequired("setting.document", server.ListSettingDocTemplate)).Methods("GET")
+	server.Router.HandleFunc("/setting/doc-template/activate/{id}", server.PermissionRequired("setting.document", server.NotImpersonating(server.CSRFProtect(server.ActivateSettingDocTemplate)))).Methods("POST")
+	server.Router.HandleFunc("/setting/doc-template/preview/{id}", server.PermissionRequired("setting.document", server.PreviewSettingDocTemplateVersion)).Methods("GET")
+	server.Router.HandleFunc("/setting/doc-template/{type}", server.PermissionRequired("setting.document", server.EditSettingDocTemplate)).Methods("GET")
+	server.Router.HandleFunc("/setting/doc-template/{type}/save", server.PermissionRequired("setting.document", server.StoreSettingDocTemplate)).Methods("POST")
+	server.Router.HandleFunc("/setting/doc-template/{type}/preview", server.PermissionRequired("setting.document", server.PreviewSettingDocTemplate)).Methods("POST")
 
 	// Profile routes - Available for all logged in users
 	server.Router.HandleFunc("/profile", server.AuthRequired(server.Profile)).Methods("GET")
-	server.Router.HandleFunc("/profile/password", server.AuthRequired(server.UpdatePassword)).Methods("POST")
+	server.Router.HandleFunc("/profile/password", server.AuthRequired(server.NotImpersonating(server.UpdatePassword))).Methods("POST")
+	server.Router.HandleFunc("/profile/sessions/revoke/{id}", server.AuthRequired(server.NotImpersonating(server.RevokeSession))).Methods("POST")
+	server.Router.HandleFunc("/profile/sessions/logout-all", server.AuthRequired(server.NotImpersonating(server.LogoutEverywhere))).Methods("POST")
+	server.Router.HandleFunc("/profile/2fa", server.AuthRequired(server.TwoFactorSettings)).Methods("GET")
+	server.Router.HandleFunc("/profile/2fa/enable", server.AuthRequired(server.NotImpersonating(server.EnableTwoFactor))).Methods("POST")
+	server.Router.HandleFunc("/profile/2fa/disable", server.AuthRequired(server.NotImpersonating(server.DisableTwoFactor))).Methods("POST")
+	server.Router.HandleFunc("/profile/2fa/recovery-codes", server.AuthRequired(server.NotImpersonating(server.RegenerateRecoveryCodes))).Methods("POST")
 	server.Router.HandleFunc("/profile/avatar", server.AuthRequired(server.UpdateAvatar)).Methods("POST")
-	server.Router.HandleFunc("/profile/signature", server.AuthRequired(server.UpdateSignature)).Methods("POST")
+	server.Router.HandleFunc("/profile/signature", server.AuthRequired(server.NotImpersonating(server.UpdateSignature))).Methods("POST")
+	server.Router.HandleFunc("/profile/sso/link", server.AuthRequired(server.NotImpersonating(server.CSRFProtect(server.LinkSSO)))).Methods("POST")
+	server.Router.HandleFunc("/profile/webdav-token", server.AuthRequired(server.NotImpersonating(server.GenerateWebDAVToken))).Methods("POST")
+
+	// Keluar dari mode "lihat sebagai" (tombol pada banner)
+	server.Router.HandleFunc("/impersonate/exit", server.AuthRequired(server.StopImpersonation)).Methods("POST")
+
+	// WebDAV untuk pohon GoDMS (autentikasi HTTP Basic dengan password atau token WebDAV)
+	server.Router.PathPrefix("/dav/").Handler(server.WebDAVHandler())
 
 	// Static files
 	staticFileDirectory := http.Dir("./public")
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"gorm.io/gorm"
+)
+
+// sessionCookieName adalah nama cookie session login admin
+const sessionCookieName = "gokso-session"
+
+// sessionLifetime adalah umur maksimal satu session login, terlepas dari aktivitasnya
+const sessionLifetime = 7 * 24 * time.Hour
+
+// dbSessionStore adalah sessions.Store yang menyimpan session di tabel admin_sessions.
+// Cookie hanya berisi token acak yang ditandatangani dengan secret dari env, sehingga session dapat dicabut dari server.
+type dbSessionStore struct {
+	db          *gorm.DB
+	secret      []byte
+	codecs      []securecookie.Codec
+	challenge   securecookie.Codec // cookie langkah kedua login (2FA) yang belum menjadi session
+	Options     *sessions.Options
+	IdleTimeout time.Duration // 0 berarti tanpa batas waktu menganggur
+}
+
+// newDBSessionStore menyiapkan session store berbasis database
+func newDBSessionStore(db *gorm.DB, secret []byte, idleTimeout time.Duration, secure bool) *dbSessionStore {
+	codec := securecookie.New(secret, nil)
+	codec.MaxAge(int(sessionLifetime.Seconds()))
+
+	challengeKey := hmac.New(sha256.New, secret)
+	challengeKey.Write([]byte("login-challenge"))
+	challenge := securecookie.New(challengeKey.Sum(nil), nil)
+	challenge.MaxAge(int(loginChallengeTTL.Seconds()))
+
+	return &dbSessionStore{
+		db:        db,
+		secret:    secret,
+		codecs:    []securecookie.Codec{codec},
+		challenge: challenge,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(sessionLifetime.Seconds()),
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteLaxMode,
+		},
+		IdleTimeout: idleTimeout,
+	}
+}
+
+// Get mengambil session dari cache request atau memuatnya dari database
+func (s *dbSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New memuat session milik cookie request. Session yang tidak dikenal, kedaluwarsa atau
+// terlalu lama menganggur menghasilkan session kosong sehingga pengguna harus login ulang.
+func (s *dbSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	var row models.AdminSession
+	s.db.Where("id = ?", s.key(token)).Limit(1).Find(&row)
+	if row.ID == "" {
+		return session, nil
+	}
+
+	now := time.Now()
+	if now.After(row.ExpiresAt) || (s.IdleTimeout > 0 && now.Sub(row.LastSeenAt) > s.IdleTimeout) {
+		s.db.Delete(&row)
+		return session, nil
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(row.Data, &session.Values); err != nil {
+		return session, nil
+	}
+	session.ID = token
+	session.IsNew = false
+
+	// Aktivitas terakhir cukup dicatat per menit agar tidak menulis ke database di setiap request
+	if now.Sub(row.LastSeenAt) > time.Minute {
+		s.db.Model(&row).Updates(map[string]interface{}{"last_seen_at": now, "ip": clientIP(r)})
+	}
+	return session, nil
+}
+
+// Save menyimpan isi session ke database dan mengirim cookie token; MaxAge < 0 menghapus session
+func (s *dbSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			s.db.Where("id = ?", s.key(session.ID)).Delete(&models.AdminSession{})
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	adminID, _ := session.Values["admin_id"].(string)
+	// Selama mode "lihat sebagai", session tetap milik super_admin sehingga ikut tercabut bersama session-nya
+	if impersonatorID, ok := session.Values["impersonator_id"].(string); ok && impersonatorID != "" {
+		adminID = impersonatorID
+	}
+	now := time.Now()
+
+	if session.ID == "" {
+		session.ID = newSessionToken()
+		row := models.AdminSession{
+			ID:         s.key(session.ID),
+			AdminID:    adminID,
+			Data:       data,
+			UserAgent:  truncate(r.UserAgent(), 255),
+			IP:         clientIP(r),
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(time.Duration(session.Options.MaxAge) * time.Second),
+		}
+		if err := s.db.Create(&row).Error; err != nil {
+			return err
+		}
+		s.purge()
+	} else {
+		err := s.db.Model(&models.AdminSession{}).Where("id = ?", s.key(session.ID)).
+			Updates(map[string]interface{}{"admin_id": adminID, "data": data, "last_seen_at": now}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Rotate membuang session lama sehingga Save berikutnya menerbitkan token baru (mencegah session fixation)
+func (s *dbSessionStore) Rotate(session *sessions.Session) {
+	if session.ID != "" {
+		s.db.Where("id = ?", s.key(session.ID)).Delete(&models.AdminSession{})
+	}
+	session.ID = ""
+}
+
+// key menurunkan ID baris database dari token cookie
+func (s *dbSessionStore) key(token string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// purge menghapus session yang sudah kedaluwarsa atau terlalu lama menganggur
+func (s *dbSessionStore) purge() {
+	query := s.db.Where("expires_at < ?", time.Now())
+	if s.IdleTimeout > 0 {
+		query = query.Or("last_seen_at < ?", time.Now().Add(-s.IdleTimeout))
+	}
+	query.Delete(&models.AdminSession{})
+}
+
+// revokeAdminSessions mencabut semua session login milik admin, mis. setelah password diganti atau akun dihapus
+func revokeAdminSessions(db *gorm.DB, adminID string) error {
+	return db.Where("admin_id = ?", adminID).Delete(&models.AdminSession{}).Error
+}
+
+// currentSessionKey mengembalikan ID baris session milik request ini
+func currentSessionKey(r *http.Request) string {
+	session, err := store.Get(r, sessionCookieName)
+	if err != nil || session.ID == "" {
+		return ""
+	}
+	return store.key(session.ID)
+}
+
+// newSessionToken membuat token session acak 256 bit
+func newSessionToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// clientIP mengambil alamat IP pengakses dari koneksi
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ip
+}
+
+// describeUserAgent meringkas User-Agent menjadi nama browser dan sistem operasi
+func describeUserAgent(ua string) string {
+	browser := "Browser lain"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+
+	os := "perangkat tidak dikenal"
+	switch {
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+	return browser + " di " + os
+}
+
+// truncate memotong teks agar muat di kolom database
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// Mundur ke awal karakter agar karakter multi-byte tidak terpotong di tengah
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionAdmin membuat admin lokal dengan password bcrypt "rahasia"
+func sessionAdmin(tb testing.TB, server *Server, id, username string) models.Admin {
+	tb.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("rahasia"), bcrypt.MinCost)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	admin := models.Admin{ID: id, Username: username, Password: string(hashed), Role: models.RoleSuperAdmin}
+	if err := server.DB.Create(&admin).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return admin
+}
+
+// loadSession memuat session milik cookie langsung dari store
+func loadSession(tb testing.TB, cookie string) (adminID string, isNew bool) {
+	tb.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", cookie)
+	session, err := store.New(r, sessionCookieName)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	adminID, _ = session.Values["admin_id"].(string)
+	return adminID, session.IsNew
+}
+
+// sessionCount mengembalikan jumlah baris session milik admin
+func sessionCount(server *Server, adminID string) int64 {
+	var count int64
+	server.DB.Model(&models.AdminSession{}).Where("admin_id = ?", adminID).Count(&count)
+	return count
+}
+
+func TestDBSessionStoreKeepsValuesServerSide(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	var row models.AdminSession
+	server.DB.First(&row, "admin_id = ?", "a1")
+	value := strings.TrimPrefix(client.cookie, sessionCookieName+"=")
+	if row.ID == "" || strings.Contains(value, row.ID) {
+		t.Fatalf("session row %q is not keyed by a hash of the cookie token", row.ID)
+	}
+	if adminID, isNew := loadSession(t, client.cookie); adminID != "a1" || isNew {
+		t.Errorf("loaded session = %q (new %v), want a1", adminID, isNew)
+	}
+
+	// Cookie yang diubah atau baris yang sudah dihapus tidak menghasilkan session
+	tampered := client.cookie[:len(client.cookie)-2] + "xx"
+	if adminID, isNew := loadSession(t, tampered); adminID != "" || !isNew {
+		t.Errorf("tampered cookie loaded session of %q", adminID)
+	}
+	server.DB.Delete(&row)
+	if w := client.do(http.MethodGet, "/profile", nil); w.Header().Get("Location") != "/login" {
+		t.Errorf("deleted session = %d %q, want redirect to /login", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestDBSessionStoreExpiresSessions(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		column string
+		value  time.Time
+	}{
+		{"idle", "last_seen_at", time.Now().Add(-3 * time.Hour)},
+		{"lifetime", "expires_at", time.Now().Add(-time.Minute)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t)
+			client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+			server.DB.Model(&models.AdminSession{}).Where("admin_id = ?", "a1").Update(tc.column, tc.value)
+
+			if adminID, isNew := loadSession(t, client.cookie); adminID != "" || !isNew {
+				t.Errorf("expired session loaded for %q", adminID)
+			}
+			if n := sessionCount(server, "a1"); n != 0 {
+				t.Errorf("%d expired sessions left in the database", n)
+			}
+		})
+	}
+}
+
+func TestDBSessionStoreRefreshesLastSeen(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	before := time.Now().Add(-90 * time.Minute)
+	server.DB.Model(&models.AdminSession{}).Where("admin_id = ?", "a1").Update("last_seen_at", before)
+
+	if w := client.do(http.MethodGet, "/profile", nil); w.Code != http.StatusOK {
+		t.Fatalf("profile = %d, want %d", w.Code, http.StatusOK)
+	}
+	var row models.AdminSession
+	server.DB.First(&row, "admin_id = ?", "a1")
+	if !row.LastSeenAt.After(before.Add(time.Minute)) {
+		t.Errorf("last_seen_at = %v, not refreshed by the request", row.LastSeenAt)
+	}
+}
+
+func TestLoginRotatesPlantedSession(t *testing.T) {
+	server := newTestServer(t)
+	sessionAdmin(t, server, "s1", "sinta")
+	// Penyerang menanam cookie session miliknya sendiri sebelum korban login
+	planted := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	w := planted.do(http.MethodPost, "/login", url.Values{"username": {"sinta"}, "password": {"rahasia"}})
+	if loc := w.Header().Get("Location"); loc != "/" {
+		t.Fatalf("login redirect = %q, want /", loc)
+	}
+	cookie := strings.Split(w.Header().Get("Set-Cookie"), ";")[0]
+	if cookie == planted.cookie {
+		t.Fatal("login kept the planted session token")
+	}
+	if adminID, _ := loadSession(t, planted.cookie); adminID != "" {
+		t.Errorf("planted cookie still loads the session of %q", adminID)
+	}
+	if adminID, _ := loadSession(t, cookie); adminID != "s1" {
+		t.Errorf("new cookie loads the session of %q, want s1", adminID)
+	}
+}
+
+func TestUpdatePasswordRevokesOtherSessions(t *testing.T) {
+	server := newTestServer(t)
+	admin := sessionAdmin(t, server, "s1", "sinta")
+	laptop := loginAs(t, server, admin)
+	phone := loginAs(t, server, admin)
+	other := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+
+	w := laptop.do(http.MethodPost, "/profile/password", url.Values{
+		"old_password": {"rahasia"}, "new_password": {"baru"}, "confirm_password": {"baru"},
+	})
+	if loc := w.Header().Get("Location"); !strings.Contains(loc, "msg=") {
+		t.Fatalf("update password redirect = %q", loc)
+	}
+	if adminID, _ := loadSession(t, phone.cookie); adminID != "" {
+		t.Error("session on another device survived the password change")
+	}
+	if adminID, _ := loadSession(t, laptop.cookie); adminID != "" {
+		t.Error("old token of the current device is still valid")
+	}
+	// Perangkat yang mengganti password mendapat token baru, session admin lain tidak tersentuh
+	current := strings.Split(w.Header().Get("Set-Cookie"), ";")[0]
+	if adminID, _ := loadSession(t, current); adminID != "s1" {
+		t.Errorf("rotated cookie loads the session of %q, want s1", adminID)
+	}
+	if n := sessionCount(server, "s1"); n != 1 {
+		t.Errorf("%d sessions for s1, want 1", n)
+	}
+	if adminID, _ := loadSession(t, other.cookie); adminID != "a1" {
+		t.Error("password change revoked the session of another admin")
+	}
+}
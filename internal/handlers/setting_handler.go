@@ -3,11 +3,19 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // User Management
@@ -17,6 +25,9 @@ func (server *Server) ListSettingUser(w http.ResponseWriter, r *http.Request) {
 		models.Admin
 		EmployeeName string
 		NIK          string
+		Locked       bool
+		Scoped       bool     // admin dibatasi cakupan data
+		Scope        []string // nama node cakupan data
 	}
 
 	var admins []models.Admin
@@ -28,29 +39,65 @@ func (server *Server) ListSettingUser(w http.ResponseWriter, r *http.Request) {
 		if admin.UserID != "" {
 			server.DB.Select("name", "nik").Where("id = ?", admin.UserID).First(&user)
 		}
+		dataScope := server.adminDataScope(admin.ID, admin.Role)
+		var scope []string
+		for _, node := range dataScope.Nodes() {
+			scope = append(scope, node.Label())
+		}
 		data = append(data, AdminWithUser{
 			Admin:        admin,
 			EmployeeName: user.Name,
 			NIK:          user.NIK,
+			Locked:       admin.LockedUntil != nil && admin.LockedUntil.After(time.Now()),
+			Scoped:       dataScope.Restricted(),
+			Scope:        scope,
 		})
 	}
 
 	server.RenderHTML(w, r, http.StatusOK, "setting/user", map[string]interface{}{
-		"title":  "User Management",
-		"admins": data,
-		"error":  r.URL.Query().Get("error"),
-		"msg":    r.URL.Query().Get("msg"),
+		"title":       "User Management",
+		"admins":      data,
+		"ldapEnabled": server.Directory != nil,
+		"roleLabels":  server.roleLabels(),
+		"error":       r.URL.Query().Get("error"),
+		"msg":         r.URL.Query().Get("msg"),
 	})
 }
 
+// canManageRoles memeriksa apakah admin yang sedang login boleh menyentuh akun dengan role tersebut.
+// Akun super_admin (sebagai target maupun role baru) hanya boleh dibuat, diubah atau dihapus oleh super_admin,
+// agar pemegang izin setting.user tidak dapat menaikkan hak aksesnya sendiri.
+func (server *Server) canManageRoles(r *http.Request, roles ...string) bool {
+	for _, role := range roles {
+		if role == models.RoleSuperAdmin {
+			p := server.currentPrincipal(r)
+			return p != nil && p.Admin.Role == models.RoleSuperAdmin
+		}
+	}
+	return true
+}
+
+// errSuperAdminOnly adalah pesan penolakan perubahan akun super_admin oleh admin lain
+const errSuperAdminOnly = "Hanya super_admin yang dapat mengelola akun super_admin"
+
+// errOwnAccount adalah pesan penolakan perubahan akun sendiri lewat pengaturan user
+const errOwnAccount = "Akun Anda sendiri tidak dapat diubah di sini. Ubah password dan 2FA lewat halaman profil"
+
+// errScopeBeyondActor adalah pesan penolakan cakupan data yang lebih luas dari cakupan admin yang mengatur
+const errScopeBeyondActor = "Cakupan data tidak boleh lebih luas dari cakupan data Anda sendiri"
+
 // CreateSettingUserForm menampilkan form untuk menambah user admin baru
 func (server *Server) CreateSettingUserForm(w http.ResponseWriter, r *http.Request) {
+	scope := server.dataScope(r)
 	var employees []models.User
 	server.DB.Select("id", "name", "nik").Find(&employees)
 
 	server.RenderHTML(w, r, http.StatusOK, "setting/user_form", map[string]interface{}{
-		"title":     "Tambah User Admin",
-		"employees": employees,
+		"title":         "Tambah User Admin",
+		"employees":     employees,
+		"roles":         server.listRoles(),
+		"orgNodes":      scope.scopedOrgNodes(server.orgNodes()),
+		"scopeRequired": scope.Restricted(),
 	})
 }
 
@@ -62,6 +109,19 @@ func (server *Server) StoreSettingUser(w http.ResponseWriter, r *http.Request) {
 	role := r.FormValue("role")
 	userID := r.FormValue("user_id")
 
+	if !server.roleExists(role) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Role tidak valid"), http.StatusSeeOther)
+		return
+	}
+	if !server.canManageRoles(r, role) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errSuperAdminOnly), http.StatusSeeOther)
+		return
+	}
+	if !server.grantableScope(r, r.Form["scope"]) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errScopeBeyondActor), http.StatusSeeOther)
+		return
+	}
+
 	// Check if username already exists
 	var count int64
 	server.DB.Model(&models.Admin{}).Where("username = ?", username).Count(&count)
@@ -86,6 +146,10 @@ func (server *Server) StoreSettingUser(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/setting/user?error=Gagal menyimpan user: "+err.Error(), http.StatusSeeOther)
 		return
 	}
+	if err := server.saveAdminScopes(admin.ID, r.Form["scope"]); err != nil {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Gagal menyimpan cakupan data: "+err.Error()), http.StatusSeeOther)
+		return
+	}
 
 	http.Redirect(w, r, "/setting/user?msg=User berhasil dibuat", http.StatusSeeOther)
 }
@@ -100,14 +164,28 @@ func (server *Server) EditSettingUserForm(w http.ResponseWriter, r *http.Request
 		http.Redirect(w, r, "/setting/user", http.StatusSeeOther)
 		return
 	}
+	if p := server.currentPrincipal(r); p != nil && p.Admin.ID == admin.ID {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errOwnAccount), http.StatusSeeOther)
+		return
+	}
+	scope := server.dataScope(r)
+	if !scope.covers(server.adminDataScope(admin.ID, admin.Role)) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errScopeBeyondActor), http.StatusSeeOther)
+		return
+	}
 
 	var employees []models.User
 	server.DB.Select("id", "name", "nik").Find(&employees)
 
 	server.RenderHTML(w, r, http.StatusOK, "setting/user_form", map[string]interface{}{
-		"title":     "Edit User Admin",
-		"admin":     admin,
-		"employees": employees,
+		"title":         "Edit User Admin",
+		"admin":         admin,
+		"employees":     employees,
+		"ssoRoleMapped": server.SSO != nil && len(server.SSO.RoleMapping) > 0,
+		"roles":         server.listRoles(),
+		"orgNodes":      scope.scopedOrgNodes(server.orgNodes()),
+		"scopeRequired": scope.Restricted(),
+		"scopeValues":   server.adminScopeValues(admin.ID),
 	})
 }
 
@@ -122,11 +200,31 @@ func (server *Server) UpdateSettingUser(w http.ResponseWriter, r *http.Request)
 		http.Redirect(w, r, "/setting/user?error=User tidak ditemukan", http.StatusSeeOther)
 		return
 	}
+	// Role, cakupan data dan password akun sendiri tidak boleh diubah lewat pengaturan user
+	if p := server.currentPrincipal(r); p == nil || p.Admin.ID == admin.ID {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errOwnAccount), http.StatusSeeOther)
+		return
+	}
 
 	username := r.FormValue("username")
 	role := r.FormValue("role")
 	userID := r.FormValue("user_id")
 
+	if !server.roleExists(role) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Role tidak valid"), http.StatusSeeOther)
+		return
+	}
+	// Termasuk reset password dan 2FA akun super_admin
+	if !server.canManageRoles(r, admin.Role, role) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errSuperAdminOnly), http.StatusSeeOther)
+		return
+	}
+	// Admin dengan cakupan lebih luas tidak dapat diatur, dan cakupan baru tidak boleh melebihi cakupan sendiri
+	if !server.dataScope(r).covers(server.adminDataScope(admin.ID, admin.Role)) || !server.grantableScope(r, r.Form["scope"]) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errScopeBeyondActor), http.StatusSeeOther)
+		return
+	}
+
 	// Check if username already exists for OTHER users
 	var count int64
 	server.DB.Model(&models.Admin{}).Where("username = ? AND id != ?", username, id).Count(&count)
@@ -135,20 +233,41 @@ func (server *Server) UpdateSettingUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Session yang sedang berjalan menyimpan username dan role lama, sehingga harus login ulang
+	revoke := admin.Username != username || admin.Role != role
+
 	admin.UserID = userID
 	admin.Username = username
 	admin.Role = role
 
 	password := r.FormValue("password")
 	if password != "" {
+		revoke = true
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 		admin.Password = string(hashedPassword)
 	}
 
+	// Reset 2FA untuk admin yang kehilangan perangkat authenticator dan kode pemulihannya
+	if r.FormValue("reset_2fa") == "on" && admin.TOTPEnabled {
+		revoke = true
+		admin.TOTPEnabled = false
+		admin.TOTPSecret = ""
+		admin.TOTPLastStep = 0
+		admin.RecoveryCodes = ""
+	}
+
 	if err := server.DB.Save(&admin).Error; err != nil {
 		http.Redirect(w, r, "/setting/user?error=Gagal memperbarui user: "+err.Error(), http.StatusSeeOther)
 		return
 	}
+	if revoke {
+		revokeAdminSessions(server.DB, admin.ID)
+	}
+	// Cakupan data dibaca ulang setiap request sehingga tidak perlu login ulang
+	if err := server.saveAdminScopes(admin.ID, r.Form["scope"]); err != nil {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("Gagal menyimpan cakupan data: "+err.Error()), http.StatusSeeOther)
+		return
+	}
 
 	http.Redirect(w, r, "/setting/user?msg=User berhasil diperbarui", http.StatusSeeOther)
 }
@@ -157,62 +276,722 @@ func (server *Server) UpdateSettingUser(w http.ResponseWriter, r *http.Request)
 func (server *Server) DeleteSettingUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
+
+	var admin models.Admin
+	server.DB.Where("id = ?", id).Limit(1).Find(&admin)
+	if !server.canManageRoles(r, admin.Role) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errSuperAdminOnly), http.StatusSeeOther)
+		return
+	}
 	server.DB.Delete(&models.Admin{}, "id = ?", id)
+	server.DB.Where("admin_id = ?", id).Delete(&models.AdminScope{})
+	revokeAdminSessions(server.DB, id)
 	http.Redirect(w, r, "/setting/user", http.StatusSeeOther)
 }
 
+// UnlockSettingUser membuka akun yang terkunci karena login gagal berulang
+func (server *Server) UnlockSettingUser(w http.ResponseWriter, r *http.Request) {
+	var admin models.Admin
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&admin)
+	if admin.ID == "" {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape("User tidak ditemukan"), http.StatusSeeOther)
+		return
+	}
+	if !server.canManageRoles(r, admin.Role) {
+		http.Redirect(w, r, "/setting/user?error="+url.QueryEscape(errSuperAdminOnly), http.StatusSeeOther)
+		return
+	}
+
+	server.resetLoginFailures(&admin)
+	http.Redirect(w, r, "/setting/user?msg="+url.QueryEscape("Akun "+admin.Username+" berhasil dibuka"), http.StatusSeeOther)
+}
+
+// ListLoginAttempts menampilkan log percobaan login untuk peninjauan keamanan
+func (server *Server) ListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := server.DB.Model(&models.LoginAttempt{})
+	if username := strings.TrimSpace(q.Get("username")); username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if ip := strings.TrimSpace(q.Get("ip")); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	switch q.Get("status") {
+	case "success":
+		query = query.Where("success = ?", true)
+	case "failed":
+		query = query.Where("success = ?", false)
+	}
+
+	var attempts []models.LoginAttempt
+	query.Order("created_at desc").Limit(1000).Find(&attempts)
+
+	// Ringkasan 24 jam terakhir untuk melihat pola serangan
+	since := time.Now().Add(-24 * time.Hour)
+	var failed24h, success24h int64
+	server.DB.Model(&models.LoginAttempt{}).Where("created_at > ? AND success = ?", since, false).Count(&failed24h)
+	server.DB.Model(&models.LoginAttempt{}).Where("created_at > ? AND success = ?", since, true).Count(&success24h)
+
+	type ipCount struct {
+		IP    string
+		Total int64
+	}
+	var topIPs []ipCount
+	server.DB.Model(&models.LoginAttempt{}).Select("ip, COUNT(*) AS total").
+		Where("created_at > ? AND success = ?", since, false).
+		Group("ip").Order("total desc").Limit(5).Scan(&topIPs)
+
+	var locked []models.Admin
+	server.DB.Where("locked_until > ?", time.Now()).Find(&locked)
+
+	server.RenderHTML(w, r, http.StatusOK, "setting/login_log", map[string]interface{}{
+		"title":      "Log Login",
+		"attempts":   attempts,
+		"failed24h":  failed24h,
+		"success24h": success24h,
+		"topIPs":     topIPs,
+		"locked":     locked,
+		"filter": map[string]string{
+			"username": q.Get("username"),
+			"ip":       q.Get("ip"),
+			"status":   q.Get("status"),
+		},
+	})
+}
+
+// ListAuditLogs menampilkan audit log, termasuk jejak mode "lihat sebagai" super_admin
+func (server *Server) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := server.DB.Model(&models.AuditLog{})
+	if username := strings.TrimSpace(q.Get("username")); username != "" {
+		query = query.Where("username = ? OR target_username = ?", username, username)
+	}
+	if action := q.Get("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var logs []models.AuditLog
+	query.Order("created_at desc").Limit(1000).Find(&logs)
+
+	server.RenderHTML(w, r, http.StatusOK, "setting/audit_log", map[string]interface{}{
+		"title":        "Audit Log",
+		"logs":         logs,
+		"actionLabels": auditActionLabels,
+		"filter": map[string]string{
+			"username": q.Get("username"),
+			"action":   q.Get("action"),
+		},
+	})
+}
+
 // Role Permission Management
-// ListSettingRole menampilkan halaman pengaturan izin akses (permission) untuk setiap peran
-func (server *Server) ListSettingRole(w http.ResponseWriter, r *http.Request) {
-	roles := []string{"super_admin", "asset_manager", "staf_it", "support"}
+// roleNamePattern membatasi kode role agar aman dipakai di form, env LDAP/OIDC dan session
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// listRoles mengembalikan semua role, role sistem lebih dulu
+func (server *Server) listRoles() []models.Role {
+	var roles []models.Role
+	server.DB.Order("is_system desc, label asc").Find(&roles)
+	return roles
+}
+
+// roleLabels memetakan kode role ke nama tampilannya
+func (server *Server) roleLabels() map[string]string {
+	labels := make(map[string]string)
+	for _, role := range server.listRoles() {
+		labels[role.Name] = role.Label
+	}
+	return labels
+}
+
+// roleExists memeriksa apakah role terdaftar di tabel roles
+func (server *Server) roleExists(name string) bool {
+	var count int64
+	server.DB.Model(&models.Role{}).Where("name = ?", name).Count(&count)
+	return count > 0
+}
+
+// roleMappedExternally memeriksa apakah role dipakai pada pemetaan grup LDAP atau claim SSO di env
+func (server *Server) roleMappedExternally(name string) bool {
+	if dir, ok := server.Directory.(*ldapDirectory); ok {
+		for _, mapping := range dir.GroupRoles {
+			if mapping.Role == name {
+				return true
+			}
+		}
+	}
+	if server.SSO != nil {
+		for _, mapping := range server.SSO.RoleMapping {
+			if mapping.Role == name {
+				return true
+			}
+		}
+	}
+	return false
+}
 
+// ListSettingRole menampilkan halaman pengaturan role beserta izin per aksi
+func (server *Server) ListSettingRole(w http.ResponseWriter, r *http.Request) {
 	type RoleWithPerms struct {
-		Role        string
+		models.Role
 		Permissions map[string]bool
+		Admins      int64
+		Require2FA  bool
+		Without2FA  int64 // admin pada role ini yang belum mengaktifkan 2FA
 	}
 
 	var data []RoleWithPerms
-	for _, role := range roles {
+	for _, role := range server.listRoles() {
+		var admins, without int64
+		server.DB.Model(&models.Admin{}).Where("role = ?", role.Name).Count(&admins)
+		server.DB.Model(&models.Admin{}).Where("role = ? AND totp_enabled = ?", role.Name, false).Count(&without)
 		data = append(data, RoleWithPerms{
 			Role:        role,
-			Permissions: server.GetPermissions(role),
+			Permissions: server.GetPermissions(role.Name),
+			Admins:      admins,
+			Require2FA:  server.requires2FA(role.Name),
+			Without2FA:  without,
 		})
 	}
 
 	server.RenderHTML(w, r, http.StatusOK, "setting/role", map[string]interface{}{
-		"title": "Role Permission Setting",
-		"roles": data,
-		"resources": []string{
-			"dashboard",
-			"inventori",
-			"asset_management",
-			"maintenance",
-			"administration",
-			"setting",
-		},
+		"title":  "Role Permission Setting",
+		"roles":  data,
+		"groups": models.PermissionCatalog,
+		"msg":    r.URL.Query().Get("msg"),
+		"error":  r.URL.Query().Get("error"),
 	})
 }
 
+// errUngrantable adalah awalan pesan penolakan pemberian izin yang tidak dimiliki admin yang sedang login
+const errUngrantable = "Anda tidak dapat memberikan izin yang tidak Anda miliki: "
+
+// ungrantablePermissions mengembalikan aksi pada granted yang tidak dimiliki actor, urut sesuai katalog izin
+func ungrantablePermissions(actor, granted map[string]bool) []string {
+	var denied []string
+	for _, key := range models.PermissionKeys() {
+		if granted[key] && !actor[key] {
+			denied = append(denied, key)
+		}
+	}
+	return denied
+}
+
+// UpdateSettingRole menyimpan izin per aksi untuk satu role
 func (server *Server) UpdateSettingRole(w http.ResponseWriter, r *http.Request) {
 	_ = r.ParseForm()
 	role := r.FormValue("role")
-	resources := []string{
-		"dashboard",
-		"inventori",
-		"asset_management",
-		"maintenance",
-		"administration",
-		"setting",
+	if role == models.RoleSuperAdmin || !server.roleExists(role) {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Role tidak dapat diubah"), http.StatusSeeOther)
+		return
+	}
+	// Izin role sendiri tidak boleh diubah agar pemegang setting.role tidak dapat memberi dirinya semua izin
+	p := server.currentPrincipal(r)
+	if p == nil || p.Admin.Role == role {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Izin role Anda sendiri tidak dapat diubah"), http.StatusSeeOther)
+		return
+	}
+
+	current := server.GetPermissions(role)
+	granted := make(map[string]bool)
+	for _, key := range models.PermissionKeys() {
+		if r.FormValue("perm_"+key) == "on" && !current[key] {
+			granted[key] = true
+		}
+	}
+	if denied := ungrantablePermissions(p.Permissions, granted); len(denied) > 0 {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape(errUngrantable+strings.Join(denied, ", ")), http.StatusSeeOther)
+		return
+	}
+
+	for _, key := range models.PermissionKeys() {
+		// Izin yang tidak dimiliki admin yang sedang login tidak dapat diubah, termasuk dicabut
+		if !p.Permissions[key] {
+			continue
+		}
+		canAccess := r.FormValue("perm_"+key) == "on"
+
+		var perm models.RolePermission
+		server.DB.Where("role = ? AND resource = ?", role, key).Limit(1).Find(&perm)
+		if perm.ID == 0 {
+			server.DB.Create(&models.RolePermission{Role: role, Resource: key, CanAccess: canAccess})
+			continue
+		}
+		server.DB.Model(&perm).Update("can_access", canAccess)
+	}
+
+	server.permCache.invalidate()
+	http.Redirect(w, r, "/setting/role?msg="+url.QueryEscape("Izin role "+role+" berhasil disimpan"), http.StatusSeeOther)
+}
+
+// StoreSettingRole membuat role baru, opsional menyalin izin dari role lain
+func (server *Server) StoreSettingRole(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(strings.TrimSpace(r.FormValue("name")))
+	label := strings.TrimSpace(r.FormValue("label"))
+	copyFrom := r.FormValue("copy_from")
+
+	if !roleNamePattern.MatchString(name) {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Kode role hanya boleh huruf kecil, angka dan garis bawah (2-50 karakter, diawali huruf)"), http.StatusSeeOther)
+		return
+	}
+	if label == "" {
+		label = name
+	}
+	if server.roleExists(name) {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Role "+name+" sudah ada"), http.StatusSeeOther)
+		return
+	}
+
+	var source map[string]bool
+	if copyFrom != "" {
+		// super_admin tidak memiliki baris izin; menyalinnya berarti memberi semua aksi
+		if copyFrom == models.RoleSuperAdmin || !server.roleExists(copyFrom) {
+			http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Izin role "+copyFrom+" tidak dapat disalin"), http.StatusSeeOther)
+			return
+		}
+		source = server.GetPermissions(copyFrom)
+	}
+	p := server.currentPrincipal(r)
+	if p == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if denied := ungrantablePermissions(p.Permissions, source); len(denied) > 0 {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape(errUngrantable+strings.Join(denied, ", ")), http.StatusSeeOther)
+		return
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Role{Name: name, Label: label, Description: strings.TrimSpace(r.FormValue("description"))}).Error; err != nil {
+			return err
+		}
+		for _, key := range models.PermissionKeys() {
+			if err := tx.Create(&models.RolePermission{Role: name, Resource: key, CanAccess: source[key]}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Gagal membuat role: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	server.permCache.invalidate()
+	http.Redirect(w, r, "/setting/role?msg="+url.QueryEscape("Role "+label+" berhasil dibuat"), http.StatusSeeOther)
+}
+
+// RenameSettingRole mengubah nama tampilan dan/atau kode role.
+// Perubahan kode ikut diterapkan ke admin, izin dan kebijakan role; session admin role tersebut dicabut.
+func (server *Server) RenameSettingRole(w http.ResponseWriter, r *http.Request) {
+	oldName := r.FormValue("role")
+	newName := strings.ToLower(strings.TrimSpace(r.FormValue("name")))
+	label := strings.TrimSpace(r.FormValue("label"))
+	fail := func(msg string) {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+
+	var role models.Role
+	server.DB.Where("name = ?", oldName).Limit(1).Find(&role)
+	if role.Name == "" {
+		fail("Role tidak ditemukan")
+		return
+	}
+	if newName == "" {
+		newName = role.Name
+	}
+	if label == "" {
+		label = role.Label
+	}
+
+	if newName != role.Name {
+		switch {
+		case role.IsSystem:
+			fail("Kode role sistem tidak dapat diubah")
+			return
+		case !roleNamePattern.MatchString(newName):
+			fail("Kode role hanya boleh huruf kecil, angka dan garis bawah (2-50 karakter, diawali huruf)")
+			return
+		case server.roleExists(newName):
+			fail("Role " + newName + " sudah ada")
+			return
+		case server.roleMappedExternally(role.Name):
+			fail("Role " + role.Name + " dipakai pada pemetaan LDAP/SSO. Ubah konfigurasi env terlebih dahulu")
+			return
+		}
+	}
+
+	var adminIDs []string
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Role{}).Where("name = ?", role.Name).
+			Updates(map[string]interface{}{"name": newName, "label": label, "description": strings.TrimSpace(r.FormValue("description"))}).Error; err != nil {
+			return err
+		}
+		if newName == role.Name {
+			return nil
+		}
+		tx.Model(&models.Admin{}).Where("role = ?", role.Name).Pluck("id", &adminIDs)
+		if err := tx.Model(&models.Admin{}).Where("role = ?", role.Name).Update("role", newName).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RolePermission{}).Where("role = ?", role.Name).Update("role", newName).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RolePolicy{}).Where("role = ?", role.Name).Update("role", newName).Error
+	})
+	if err != nil {
+		fail("Gagal mengubah role: " + err.Error())
+		return
+	}
+
+	// Session menyimpan kode role lama
+	for _, id := range adminIDs {
+		revokeAdminSessions(server.DB, id)
+	}
+	server.permCache.invalidate()
+	http.Redirect(w, r, "/setting/role?msg="+url.QueryEscape("Role "+label+" berhasil diperbarui"), http.StatusSeeOther)
+}
+
+// DeleteSettingRole menghapus role yang tidak lagi dipakai admin mana pun
+func (server *Server) DeleteSettingRole(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("role")
+	fail := func(msg string) {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+
+	var role models.Role
+	server.DB.Where("name = ?", name).Limit(1).Find(&role)
+	if role.Name == "" {
+		fail("Role tidak ditemukan")
+		return
+	}
+	if role.IsSystem {
+		fail("Role sistem tidak dapat dihapus")
+		return
+	}
+	var admins int64
+	server.DB.Model(&models.Admin{}).Where("role = ?", name).Count(&admins)
+	if admins > 0 {
+		fail(fmt.Sprintf("Role %s masih dipakai oleh %d admin. Pindahkan admin tersebut ke role lain terlebih dahulu", role.Label, admins))
+		return
+	}
+	if server.roleMappedExternally(name) {
+		fail("Role " + name + " dipakai pada pemetaan LDAP/SSO. Ubah konfigurasi env terlebih dahulu")
+		return
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role = ?", name).Delete(&models.RolePolicy{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("name = ?", name).Delete(&models.Role{}).Error
+	})
+	if err != nil {
+		fail("Gagal menghapus role: " + err.Error())
+		return
+	}
+	server.permCache.invalidate()
+	http.Redirect(w, r, "/setting/role?msg="+url.QueryEscape("Role "+role.Label+" berhasil dihapus"), http.StatusSeeOther)
+}
+
+// UpdateSettingRolePolicy menyimpan kebijakan wajib 2FA untuk satu role.
+// Saat diwajibkan, session admin role tersebut yang belum memakai 2FA dicabut agar mendaftar pada login berikutnya.
+func (server *Server) UpdateSettingRolePolicy(w http.ResponseWriter, r *http.Request) {
+	role := r.FormValue("role")
+	require := r.FormValue("require_2fa") == "on"
+	if !server.roleExists(role) {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Role tidak ditemukan"), http.StatusSeeOther)
+		return
+	}
+
+	policy := models.RolePolicy{Role: role, Require2FA: require}
+	if err := server.DB.Save(&policy).Error; err != nil {
+		http.Redirect(w, r, "/setting/role?error="+url.QueryEscape("Gagal menyimpan kebijakan role"), http.StatusSeeOther)
+		return
+	}
+
+	msg := "Kebijakan 2FA role " + role + " dinonaktifkan"
+	if require {
+		var adminIDs []string
+		server.DB.Model(&models.Admin{}).Where("role = ? AND totp_enabled = ?", role, false).Pluck("id", &adminIDs)
+		for _, id := range adminIDs {
+			revokeAdminSessions(server.DB, id)
+		}
+		msg = fmt.Sprintf("Role %s kini wajib 2FA; %d admin harus mendaftarkan authenticator saat login berikutnya", role, len(adminIDs))
+	}
+	http.Redirect(w, r, "/setting/role?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// Document Numbering
+// ListSettingDocNumber menampilkan pola penomoran surat untuk setiap jenis dokumen
+func (server *Server) ListSettingDocNumber(w http.ResponseWriter, r *http.Request) {
+	type DocNumberRow struct {
+		Type       docNumberType
+		Format     models.DocumentNumberFormat
+		Example    string
+		LastNumber int
+	}
+
+	now := time.Now()
+	var rows []DocNumberRow
+	for _, t := range docNumberTypes {
+		format := docNumberFormat(server.DB, t.Code)
+
+		var seq models.DocumentSequence
+		server.DB.Where("doc_type = ? AND year = ?", t.Code, now.Year()).Limit(1).Find(&seq)
+
+		rows = append(rows, DocNumberRow{
+			Type:       t,
+			Format:     format,
+			Example:    formatDocNumber(format, seq.LastNumber+1, "Jakarta", now),
+			LastNumber: seq.LastNumber,
+		})
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "setting/doc_number", map[string]interface{}{
+		"title":  "Penomoran Surat",
+		"rows":   rows,
+		"tokens": docNumberTokens,
+		"year":   now.Year(),
+		"error":  r.URL.Query().Get("error"),
+		"msg":    r.URL.Query().Get("msg"),
+	})
+}
+
+// UpdateSettingDocNumber menyimpan pola penomoran surat satu jenis dokumen
+func (server *Server) UpdateSettingDocNumber(w http.ResponseWriter, r *http.Request) {
+	docType := r.FormValue("doc_type")
+	pattern := strings.TrimSpace(r.FormValue("pattern"))
+	padding, _ := strconv.Atoi(r.FormValue("padding"))
+
+	known := false
+	for _, t := range docNumberTypes {
+		if t.Code == docType {
+			known = true
+		}
+	}
+	if !known {
+		http.Redirect(w, r, "/setting/doc-number?error="+url.QueryEscape("Jenis dokumen tidak dikenal"), http.StatusSeeOther)
+		return
+	}
+	if err := validateDocNumberPattern(pattern); err != nil {
+		http.Redirect(w, r, "/setting/doc-number?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	if padding < 1 || padding > 6 {
+		padding = 3
 	}
 
-	for _, res := range resources {
-		val := r.FormValue("perm_" + res)
-		canAccess := val == "on"
+	format := models.DocumentNumberFormat{DocType: docType, Pattern: pattern, Padding: padding}
+	if err := server.DB.Save(&format).Error; err != nil {
+		http.Redirect(w, r, "/setting/doc-number?error="+url.QueryEscape("Gagal menyimpan pola: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/setting/doc-number?msg="+url.QueryEscape("Pola penomoran berhasil disimpan"), http.StatusSeeOther)
+}
+
+// ListSettingDocTemplate menampilkan daftar template PDF beserta versi yang sedang aktif
+func (server *Server) ListSettingDocTemplate(w http.ResponseWriter, r *http.Request) {
+	type DocTemplateRow struct {
+		Code     string
+		Label    string
+		Active   models.DocumentTemplate
+		Versions int64
+	}
+
+	var rows []DocTemplateRow
+	for _, code := range documentTemplateTypes {
+		row := DocTemplateRow{Code: code, Label: documentTemplateLabel(code), Active: documentTemplateFor(server.DB, code)}
+		server.DB.Model(&models.DocumentTemplate{}).Where("doc_type = ?", code).Count(&row.Versions)
+		rows = append(rows, row)
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "setting/doc_template", map[string]interface{}{
+		"title": "Template Dokumen",
+		"rows":  rows,
+		"error": r.URL.Query().Get("error"),
+		"msg":   r.URL.Query().Get("msg"),
+	})
+}
+
+// EditSettingDocTemplate menampilkan editor template PDF dan riwayat versinya.
+// Parameter ?version= memuat isi versi lama ke editor untuk diubah kembali.
+func (server *Server) EditSettingDocTemplate(w http.ResponseWriter, r *http.Request) {
+	docType := mux.Vars(r)["type"]
+	if !slices.Contains(documentTemplateTypes, docType) {
+		http.Redirect(w, r, "/setting/doc-template?error="+url.QueryEscape("Jenis dokumen tidak dikenal"), http.StatusSeeOther)
+		return
+	}
+
+	tmpl := documentTemplateFor(server.DB, docType)
+	if version := r.URL.Query().Get("version"); version != "" {
+		var old models.DocumentTemplate
+		server.DB.Where("doc_type = ? AND version = ?", docType, version).Limit(1).Find(&old)
+		if old.ID != 0 {
+			tmpl = old
+		}
+	}
 
-		server.DB.Model(&models.RolePermission{}).
-			Where("role = ? AND resource = ?", role, res).
-			Update("can_access", canAccess)
+	var versions []models.DocumentTemplate
+	server.DB.Where("doc_type = ?", docType).Order("version desc").Find(&versions)
+
+	creators := map[string]string{}
+	for _, v := range versions {
+		if v.CreatedByID != "" {
+			var admin models.Admin
+			server.DB.Select("id", "username").Where("id = ?", v.CreatedByID).Limit(1).Find(&admin)
+			creators[v.CreatedByID] = admin.Username
+		}
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "setting/doc_template_form", map[string]interface{}{
+		"title":    "Template " + documentTemplateLabel(docType),
+		"docType":  docType,
+		"label":    documentTemplateLabel(docType),
+		"tmpl":     tmpl,
+		"versions": versions,
+		"creators": creators,
+		"tokens":   documentTemplateTokens,
+		"fields":   documentTemplateFields,
+		"error":    r.URL.Query().Get("error"),
+		"msg":      r.URL.Query().Get("msg"),
+	})
+}
+
+// StoreSettingDocTemplate menyimpan isi editor sebagai versi baru yang langsung aktif
+func (server *Server) StoreSettingDocTemplate(w http.ResponseWriter, r *http.Request) {
+	docType := mux.Vars(r)["type"]
+	tmpl := bindDocumentTemplate(r, docType)
+	redirect := "/setting/doc-template/" + docType
+
+	if err := validateDocumentTemplate(tmpl); err != nil {
+		http.Redirect(w, r, redirect+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
 	}
 
-	http.Redirect(w, r, "/setting/role", http.StatusSeeOther)
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	tmpl.CreatedByID = adminID
+	tmpl.IsActive = true
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		var last models.DocumentTemplate
+		tx.Where("doc_type = ?", docType).Order("version desc").Limit(1).Find(&last)
+		tmpl.Version = last.Version + 1
+
+		if err := tx.Model(&models.DocumentTemplate{}).Where("doc_type = ?", docType).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Create(&tmpl).Error
+	})
+	if err != nil {
+		http.Redirect(w, r, redirect+"?error="+url.QueryEscape("Gagal menyimpan template: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, redirect+"?msg="+url.QueryEscape(fmt.Sprintf("Template versi %d disimpan dan diaktifkan", tmpl.Version)), http.StatusSeeOther)
+}
+
+// ActivateSettingDocTemplate mengaktifkan kembali versi template tertentu (rollback)
+func (server *Server) ActivateSettingDocTemplate(w http.ResponseWriter, r *http.Request) {
+	var tmpl models.DocumentTemplate
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&tmpl)
+	if tmpl.ID == 0 {
+		http.Redirect(w, r, "/setting/doc-template?error="+url.QueryEscape("Versi template tidak ditemukan"), http.StatusSeeOther)
+		return
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DocumentTemplate{}).Where("doc_type = ?", tmpl.DocType).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&tmpl).Update("is_active", true).Error
+	})
+	redirect := "/setting/doc-template/" + tmpl.DocType
+	if err != nil {
+		http.Redirect(w, r, redirect+"?error="+url.QueryEscape("Gagal mengaktifkan template: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, redirect+"?msg="+url.QueryEscape(fmt.Sprintf("Template versi %d diaktifkan", tmpl.Version)), http.StatusSeeOther)
+}
+
+// PreviewSettingDocTemplate menampilkan PDF contoh dari isi editor tanpa menyimpannya
+func (server *Server) PreviewSettingDocTemplate(w http.ResponseWriter, r *http.Request) {
+	tmpl := bindDocumentTemplate(r, mux.Vars(r)["type"])
+	if err := validateDocumentTemplate(tmpl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	server.writeDocTemplatePreview(w, r, tmpl)
+}
+
+// PreviewSettingDocTemplateVersion menampilkan PDF contoh dari versi template yang tersimpan
+func (server *Server) PreviewSettingDocTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	var tmpl models.DocumentTemplate
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&tmpl)
+	if tmpl.ID == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	server.writeDocTemplatePreview(w, r, tmpl)
+}
+
+// writeDocTemplatePreview merender template dengan data contoh ke file sementara lalu mengirimkannya inline.
+// Pratinjau tidak memiliki ID verifikasi dan tidak ditandatangani, melainkan diberi watermark DRAFT.
+func (server *Server) writeDocTemplatePreview(w http.ResponseWriter, r *http.Request, tmpl models.DocumentTemplate) {
+	data := sampleBASTData(tmpl.DocType)
+	data.DocNumber = formatDocNumber(docNumberFormat(server.DB, tmpl.DocType), 1, data.P1.Branch.Name, data.HandoverDate)
+	stamp := pdfStamp{Draft: true}
+
+	tmp, err := os.CreateTemp("", "doc-template-*.pdf")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := server.GenerateBASTPDF(data, tmpl, stamp, tmp.Name()); err != nil {
+		http.Error(w, "Gagal membuat pratinjau: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "inline; filename=\"pratinjau-"+tmpl.DocType+".pdf\"")
+	http.ServeFile(w, r, tmp.Name())
+}
+
+// bindDocumentTemplate membaca isi editor template dari form
+func bindDocumentTemplate(r *http.Request, docType string) models.DocumentTemplate {
+	return models.DocumentTemplate{
+		DocType:        docType,
+		Title:          strings.TrimSpace(r.FormValue("title")),
+		City:           strings.TrimSpace(r.FormValue("city")),
+		Logos:          strings.TrimSpace(r.FormValue("logos")),
+		Opening:        strings.TrimSpace(r.FormValue("opening")),
+		FirstParty:     strings.TrimSpace(r.FormValue("first_party")),
+		SecondParty:    strings.TrimSpace(r.FormValue("second_party")),
+		Body:           strings.TrimSpace(r.FormValue("body")),
+		Columns:        strings.TrimSpace(r.FormValue("columns")),
+		Closing:        strings.TrimSpace(r.FormValue("closing")),
+		SignaturePlace: strings.TrimSpace(r.FormValue("signature_place")),
+		SignatureLeft:  strings.TrimSpace(r.FormValue("signature_left")),
+		SignatureRight: strings.TrimSpace(r.FormValue("signature_right")),
+		LeftParty:      r.FormValue("left_party"),
+		RightParty:     r.FormValue("right_party"),
+		Note:           strings.TrimSpace(r.FormValue("note")),
+	}
+}
+
+// documentTemplateLabel mengembalikan nama jenis dokumen sesuai daftar penomoran surat
+func documentTemplateLabel(code string) string {
+	for _, t := range docNumberTypes {
+		if t.Code == code {
+			return t.Label
+		}
+	}
+	return code
 }
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// createRole membuat role dengan izin yang diberikan
+func createRole(tb testing.TB, server *Server, name string, keys ...string) {
+	tb.Helper()
+	if err := server.DB.Create(&models.Role{Name: name, Label: name}).Error; err != nil {
+		tb.Fatal(err)
+	}
+	for _, key := range keys {
+		server.DB.Create(&models.RolePermission{Role: name, Resource: key, CanAccess: true})
+	}
+	server.permCache.invalidate()
+}
+
+// redirectError mengembalikan pesan error pada redirect
+func redirectError(tb testing.TB, location string) string {
+	tb.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return u.Query().Get("error")
+}
+
+// userManager membuat admin pengelola user dan role tanpa izin lainnya
+func userManager(tb testing.TB, server *Server) (*testClient, models.Admin) {
+	createRole(tb, server, "user_admin", "dashboard.view", "setting.user", "setting.role")
+	admin := models.Admin{ID: "m1", Username: "manager", Password: "x", Role: "user_admin"}
+	server.DB.Create(&admin)
+	return loginAs(tb, server, admin), admin
+}
+
+func TestUpdateSettingUserRejectsOwnAccount(t *testing.T) {
+	server := newTestServer(t)
+	client, manager := userManager(t, server)
+	createRole(t, server, "full_copy", models.PermissionKeys()...)
+
+	w := client.do(http.MethodPost, "/setting/user/update/"+manager.ID, url.Values{"username": {"manager"}, "role": {"full_copy"}, "password": {"new-password"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != errOwnAccount {
+		t.Fatalf("error = %q, want %q", msg, errOwnAccount)
+	}
+	var saved models.Admin
+	server.DB.First(&saved, "id = ?", manager.ID)
+	if saved.Role != "user_admin" || saved.Password != "x" {
+		t.Errorf("own account changed: role %q", saved.Role)
+	}
+}
+
+func TestStoreSettingRoleRejectsSuperAdminCopy(t *testing.T) {
+	server := newTestServer(t)
+	client, _ := userManager(t, server)
+
+	w := client.do(http.MethodPost, "/setting/role/store", url.Values{"name": {"root_copy"}, "copy_from": {models.RoleSuperAdmin}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg == "" {
+		t.Fatal("copying super_admin permissions was accepted")
+	}
+	if server.roleExists("root_copy") {
+		t.Error("role root_copy was created")
+	}
+}
+
+func TestStoreSettingRoleRejectsPermissionsBeyondActor(t *testing.T) {
+	server := newTestServer(t)
+	client, _ := userManager(t, server)
+
+	w := client.do(http.MethodPost, "/setting/role/store", url.Values{"name": {"auditor"}, "copy_from": {"asset_manager"}})
+	if msg := redirectError(t, w.Header().Get("Location")); !strings.HasPrefix(msg, errUngrantable) {
+		t.Fatalf("error = %q, want prefix %q", msg, errUngrantable)
+	}
+	if server.roleExists("auditor") {
+		t.Error("role auditor was created")
+	}
+
+	w = client.do(http.MethodPost, "/setting/role/store", url.Values{"name": {"helper"}, "copy_from": {"user_admin"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != "" {
+		t.Fatalf("copy of own permissions rejected: %q", msg)
+	}
+	if !server.GetPermissions("helper")["setting.user"] {
+		t.Error("helper did not receive setting.user")
+	}
+}
+
+func TestUpdateSettingRoleRejectsPermissionsBeyondActor(t *testing.T) {
+	server := newTestServer(t)
+	client, _ := userManager(t, server)
+
+	form := url.Values{"role": {"support"}, "perm_dashboard.view": {"on"}, "perm_asset.delete": {"on"}}
+	w := client.do(http.MethodPost, "/setting/role/update", form)
+	if msg := redirectError(t, w.Header().Get("Location")); !strings.HasPrefix(msg, errUngrantable) {
+		t.Fatalf("error = %q, want prefix %q", msg, errUngrantable)
+	}
+	if server.GetPermissions("support")["asset.delete"] {
+		t.Error("support received asset.delete")
+	}
+
+	// Izin yang tidak dimiliki pengelola tetap seperti semula walaupun tidak dikirim form
+	form = url.Values{"role": {"support"}, "perm_dashboard.view": {"on"}, "perm_setting.user": {"on"}}
+	w = client.do(http.MethodPost, "/setting/role/update", form)
+	if msg := redirectError(t, w.Header().Get("Location")); msg != "" {
+		t.Fatalf("update rejected: %q", msg)
+	}
+	perms := server.GetPermissions("support")
+	if !perms["setting.user"] || !perms["dms.view"] {
+		t.Errorf("setting.user = %v, dms.view = %v, want both true", perms["setting.user"], perms["dms.view"])
+	}
+}
+
+func TestActivateSettingDocTemplateRequiresPostWithCSRF(t *testing.T) {
+	server := newTestServer(t)
+	root := models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin}
+	client := loginAs(t, server, root)
+	server.DB.Create(&models.DocumentTemplate{ID: 1, DocType: "bast", Version: 1, IsActive: true})
+	server.DB.Create(&models.DocumentTemplate{ID: 2, DocType: "bast", Version: 2})
+	active := func() uint {
+		var tmpl models.DocumentTemplate
+		server.DB.Where("doc_type = ? AND is_active = ?", "bast", true).First(&tmpl)
+		return tmpl.ID
+	}
+
+	if w := client.do(http.MethodGet, "/setting/doc-template/activate/2", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if w := client.do(http.MethodPost, "/setting/doc-template/activate/2", url.Values{"csrf_token": {"forged"}}); w.Code != http.StatusForbidden {
+		t.Errorf("POST with forged token = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	server.DB.Create(&models.Admin{ID: "a2", Username: "staf", Password: "x", Role: models.RoleSuperAdmin})
+	viewer := loginImpersonating(t, server, root, models.Admin{ID: "a2", Username: "staf", Role: models.RoleSuperAdmin})
+	if w := viewer.do(http.MethodPost, "/setting/doc-template/activate/2", url.Values{}); w.Code != http.StatusForbidden {
+		t.Errorf("POST while impersonating = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if id := active(); id != 1 {
+		t.Fatalf("active version = %d, want 1", id)
+	}
+
+	if w := client.do(http.MethodPost, "/setting/doc-template/activate/2", url.Values{}); w.Code != http.StatusSeeOther {
+		t.Fatalf("POST = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if id := active(); id != 2 {
+		t.Errorf("active version = %d, want 2", id)
+	}
+}
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// signLinkTTL adalah masa berlaku tautan tanda tangan sejak dibuat atau sejak pengingat terakhir
+const signLinkTTL = 7 * 24 * time.Hour
+
+// errSignatureRequestClosed dikembalikan bila permintaan sudah diselesaikan atau dibatalkan oleh proses lain
+var errSignatureRequestClosed = errors.New("permintaan tanda tangan sudah ditutup")
+
+// createSignatureRequest menyimpan isian BAST sebagai draf dan mengirim tautan tanda tangan ke setiap pihak
+func (server *Server) createSignatureRequest(w http.ResponseWriter, r *http.Request, form models.GoForm, doc goFormDocument) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+
+	// Tanda tangan yang mungkin sudah digambar di layar pembuat tidak dipakai; setiap pihak menandatangani sendiri
+	values := url.Values{}
+	for key, v := range r.Form {
+		if key != "sig_p1_data" && key != "sig_p2_data" && key != "remote_sign" {
+			values[key] = v
+		}
+	}
+	summary, _ := json.Marshal(doc.Answers)
+
+	request := models.SignatureRequest{
+		ID:          uuid.New().String(),
+		FormID:      form.ID,
+		Title:       strings.ReplaceAll(doc.Title, "\n", " "),
+		Summary:     string(summary),
+		FormData:    values.Encode(),
+		Status:      models.SignatureStatusPending,
+		CreatedByID: adminID,
+		Signers: []models.SignatureRequestSigner{
+			{Role: "p1", Label: "PIHAK PERTAMA", UserID: values.Get("p1_employee_id"), Token: newSignToken()},
+			{Role: "p2", Label: "PIHAK KEDUA", UserID: values.Get("p2_employee_id"), Token: newSignToken()},
+		},
+	}
+	if err := server.DB.Create(&request).Error; err != nil {
+		http.Redirect(w, r, "/goform/fill/"+form.Slug+"?error="+url.QueryEscape("Gagal membuat permintaan tanda tangan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	for i := range request.Signers {
+		server.notifySigner(request, &request.Signers[i])
+	}
+
+	http.Redirect(w, r, "/goform/signature/"+request.ID+"?msg="+url.QueryEscape("Draf dibuat. Permintaan tanda tangan tampil di notifikasi aplikasi setiap pihak; bagikan tautan di bawah kepada pihak yang tidak memiliki akun."), http.StatusSeeOther)
+}
+
+// notifySigner mengaktifkan (kembali) tautan tanda tangan satu pihak selama signLinkTTL. Permintaan tampil di
+// notifikasi aplikasi bagi admin yang terhubung dengan karyawan tersebut; tautannya sendiri hanya ditampilkan
+// kepada pembuat permintaan.
+func (server *Server) notifySigner(request models.SignatureRequest, signer *models.SignatureRequestSigner) {
+	now := time.Now()
+	expires := now.Add(signLinkTTL)
+	signer.NotifiedAt = &now
+	signer.ExpiresAt = &expires
+	server.DB.Model(&models.SignatureRequestSigner{}).Where("id = ? AND request_id = ?", signer.ID, request.ID).
+		Updates(map[string]interface{}{"notified_at": &now, "expires_at": &expires})
+}
+
+// revokeSignLinks menonaktifkan semua tautan tanda tangan milik permintaan yang sudah selesai atau dibatalkan
+func revokeSignLinks(db *gorm.DB, requestID string) error {
+	return db.Model(&models.SignatureRequestSigner{}).Where("request_id = ?", requestID).Update("token", nil).Error
+}
+
+// signLink mengembalikan tautan tanda tangan pribadi milik satu pihak
+func (server *Server) signLink(signer models.SignatureRequestSigner) string {
+	return server.AppURL + "/sign/" + signer.Token
+}
+
+// newSignToken membuat token acak untuk tautan tanda tangan
+func newSignToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ListSignatureRequests menampilkan daftar draf dokumen yang menunggu atau sudah selesai ditandatangani
+func (server *Server) ListSignatureRequests(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	query := server.DB.Preload("Form").Preload("Signers.User").Order("created_at desc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []models.SignatureRequest
+	query.Find(&requests)
+
+	scope := server.dataScope(r)
+	if scope.restricted {
+		allowed := requests[:0]
+		for _, request := range requests {
+			if server.signatureRequestAllowed(scope, request) {
+				allowed = append(allowed, request)
+			}
+		}
+		requests = allowed
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/signatures", map[string]interface{}{
+		"title":    "Permintaan Tanda Tangan",
+		"requests": requests,
+		"status":   status,
+		"statuses": []string{models.SignatureStatusPending, models.SignatureStatusCompleted, models.SignatureStatusCancelled},
+		"msg":      r.URL.Query().Get("msg"),
+		"error":    r.URL.Query().Get("error"),
+	})
+}
+
+// ShowSignatureRequest menampilkan status tanda tangan setiap pihak beserta tautannya
+func (server *Server) ShowSignatureRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := server.findSignatureRequest(mux.Vars(r)["id"])
+	if !ok {
+		http.Redirect(w, r, "/goform/signature?error=Permintaan tanda tangan tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.signatureRequestAllowed(server.dataScope(r), request) {
+		forbidScope(w)
+		return
+	}
+
+	var file models.DMSFile
+	if request.FileID != nil {
+		server.DB.Where("id = ?", *request.FileID).Limit(1).Find(&file)
+	}
+
+	// Tautan tanda tangan bersifat rahasia dan hanya ditampilkan kepada pembuat permintaan
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	links := map[uint]string{}
+	if adminID == request.CreatedByID {
+		for _, signer := range request.Signers {
+			if signer.Token != "" {
+				links[signer.ID] = server.signLink(signer)
+			}
+		}
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "goform/signature_detail", map[string]interface{}{
+		"title":   "Permintaan Tanda Tangan",
+		"request": request,
+		"answers": signatureSummary(request),
+		"links":   links,
+		"file":    file,
+		"msg":     r.URL.Query().Get("msg"),
+		"error":   r.URL.Query().Get("error"),
+	})
+}
+
+// RemindSignatureRequest memperpanjang masa berlaku tautan pihak yang belum menandatangani sehingga permintaan
+// kembali tampil di notifikasi aplikasinya. Tidak ada pesan yang dikirim; tautan dibagikan ulang oleh pembuat.
+func (server *Server) RemindSignatureRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := server.findSignatureRequest(mux.Vars(r)["id"])
+	if !ok || request.Status != models.SignatureStatusPending {
+		http.Redirect(w, r, "/goform/signature?error=Tautan permintaan tanda tangan tidak dapat diperpanjang", http.StatusSeeOther)
+		return
+	}
+	if !server.signatureRequestAllowed(server.dataScope(r), request) {
+		forbidScope(w)
+		return
+	}
+
+	count := 0
+	for i := range request.Signers {
+		if request.Signers[i].SignedAt == nil {
+			server.notifySigner(request, &request.Signers[i])
+			count++
+		}
+	}
+
+	http.Redirect(w, r, "/goform/signature/"+request.ID+"?msg="+url.QueryEscape(fmt.Sprintf("Masa berlaku tautan %d pihak diperpanjang %d hari. Tidak ada pesan yang dikirim; bagikan ulang tautan kepada pihak yang belum menandatangani", count, int(signLinkTTL.Hours()/24))), http.StatusSeeOther)
+}
+
+// RetrySignatureRequest mencoba kembali menerbitkan dokumen yang sudah ditandatangani semua pihak
+// namun sebelumnya gagal diterbitkan (misalnya kepemilikan aset berubah)
+func (server *Server) RetrySignatureRequest(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !server.signatureRequestIDAllowed(r, id) {
+		forbidScope(w)
+		return
+	}
+	docNumber, err := server.finalizeSignatureRequest(id)
+	if err != nil {
+		http.Redirect(w, r, "/goform/signature/"+id+"?error="+url.QueryEscape("Dokumen belum dapat diterbitkan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	if docNumber == "" {
+		http.Redirect(w, r, "/goform/signature/"+id+"?error="+url.QueryEscape("Masih ada pihak yang belum menandatangani"), http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/goform/signature/"+id+"?msg="+url.QueryEscape("Dokumen diterbitkan dengan Nomor: "+docNumber), http.StatusSeeOther)
+}
+
+// CancelSignatureRequest membatalkan draf yang belum selesai ditandatangani
+func (server *Server) CancelSignatureRequest(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !server.signatureRequestIDAllowed(r, id) {
+		forbidScope(w)
+		return
+	}
+	result := server.DB.Model(&models.SignatureRequest{}).
+		Where("id = ? AND status = ?", id, models.SignatureStatusPending).
+		Update("status", models.SignatureStatusCancelled)
+	if result.RowsAffected == 0 {
+		http.Redirect(w, r, "/goform/signature/"+id+"?error="+url.QueryEscape("Hanya permintaan yang masih menunggu tanda tangan yang dapat dibatalkan"), http.StatusSeeOther)
+		return
+	}
+	revokeSignLinks(server.DB, id)
+
+	http.Redirect(w, r, "/goform/signature/"+id+"?msg=Permintaan tanda tangan dibatalkan", http.StatusSeeOther)
+}
+
+// ShowSignPage menampilkan halaman tanda tangan publik untuk pemegang tautan
+func (server *Server) ShowSignPage(w http.ResponseWriter, r *http.Request) {
+	signer, request, ok := server.findSigner(mux.Vars(r)["token"])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	server.renderSignPage(w, r, signer, request, r.URL.Query().Get("msg"))
+}
+
+// renderSignPage menampilkan halaman tanda tangan beserta status permintaannya
+func (server *Server) renderSignPage(w http.ResponseWriter, r *http.Request, signer models.SignatureRequestSigner, request models.SignatureRequest, msg string) {
+	server.RenderHTML(w, r, http.StatusOK, "sign/show", map[string]interface{}{
+		"title":           "Tanda Tangan " + request.Title,
+		"signer":          signer,
+		"request":         request,
+		"answers":         signatureSummary(request),
+		"storedSignature": server.storedSignature(r, signer) != "",
+		"msg":             msg,
+		"error":           r.URL.Query().Get("error"),
+	})
+}
+
+// SubmitSignature menyimpan tanda tangan satu pihak dan menerbitkan PDF bila semua pihak sudah menandatangani
+func (server *Server) SubmitSignature(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	signer, request, ok := server.findSigner(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	back := "/sign/" + token
+
+	if request.Status != models.SignatureStatusPending {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Dokumen ini sudah "+strings.ToLower(request.Status)), http.StatusSeeOther)
+		return
+	}
+
+	// Tanda tangan digambar di perangkat sendiri, atau memakai tanda tangan tersimpan milik akun admin penandatangan
+	method := "digambar"
+	signature := r.FormValue("signature_data")
+	if r.FormValue("use_stored") == "1" {
+		method = "tersimpan"
+		signature = server.storedSignature(r, signer)
+		if signature == "" {
+			http.Redirect(w, r, back+"?error="+url.QueryEscape("Tanda tangan tersimpan hanya dapat dipakai setelah login dengan akun milik penandatangan"), http.StatusSeeOther)
+			return
+		}
+	} else if !strings.HasPrefix(signature, "data:image/png;base64,") {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Tanda tangan wajib diisi"), http.StatusSeeOther)
+		return
+	}
+
+	ip := clientIP(r)
+	now := time.Now()
+	result := server.DB.Model(&models.SignatureRequestSigner{}).
+		Where("id = ? AND signed_at IS NULL", signer.ID).
+		Updates(map[string]interface{}{"signature": signature, "method": method, "signed_at": &now, "signed_ip": ip})
+	if result.Error != nil || result.RowsAffected == 0 {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Dokumen ini sudah Anda tandatangani"), http.StatusSeeOther)
+		return
+	}
+
+	msg := "Tanda tangan berhasil disimpan."
+	docNumber, err := server.finalizeSignatureRequest(request.ID)
+	if err != nil && err != errSignatureRequestClosed {
+		http.Redirect(w, r, back+"?msg="+url.QueryEscape(msg)+"&error="+url.QueryEscape("Dokumen belum dapat diterbitkan: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	if docNumber != "" {
+		// Tautan sudah dinonaktifkan begitu dokumen terbit, sehingga hasilnya ditampilkan langsung
+		msg += " Semua pihak telah menandatangani, dokumen diterbitkan dengan Nomor: " + docNumber
+		request, _ = server.findSignatureRequest(request.ID)
+		server.DB.Where("id = ?", signer.ID).Limit(1).Find(&signer)
+		server.renderSignPage(w, r, signer, request, msg)
+		return
+	}
+
+	http.Redirect(w, r, back+"?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// finalizeSignatureRequest menerbitkan PDF setelah semua pihak menandatangani. Mengembalikan nomor surat
+// bila dokumen diterbitkan, atau string kosong bila masih ada pihak yang belum menandatangani.
+func (server *Server) finalizeSignatureRequest(id string) (string, error) {
+	request, ok := server.findSignatureRequest(id)
+	if !ok || request.Status != models.SignatureStatusPending {
+		return "", errSignatureRequestClosed
+	}
+
+	values, err := url.ParseQuery(request.FormData)
+	if err != nil {
+		return "", err
+	}
+	for _, signer := range request.Signers {
+		if signer.SignedAt == nil {
+			return "", nil
+		}
+		values.Set("sig_"+signer.Role+"_data", signer.Signature)
+	}
+
+	// Isian dibangun ulang dan divalidasi kembali karena kepemilikan aset bisa berubah selama menunggu tanda tangan
+	doc, errs := server.prepareBASTDocument(request.Form, values)
+	if len(errs) > 0 {
+		err := errors.New(strings.Join(errs, "; "))
+		server.DB.Model(&models.SignatureRequest{}).Where("id = ?", request.ID).Update("last_error", err.Error())
+		return "", err
+	}
+
+	docNumber, _, err := server.issueGoFormDocument(request.Form, doc, request.CreatedByID, func(tx *gorm.DB, file models.DMSFile) error {
+		now := time.Now()
+		result := tx.Model(&models.SignatureRequest{}).
+			Where("id = ? AND status = ?", request.ID, models.SignatureStatusPending).
+			Updates(map[string]interface{}{
+				"status":       models.SignatureStatusCompleted,
+				"file_id":      file.ID,
+				"doc_number":   file.DocNumber,
+				"last_error":   "",
+				"completed_at": &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errSignatureRequestClosed
+		}
+		return revokeSignLinks(tx, request.ID)
+	})
+	if err != nil {
+		if err != errSignatureRequestClosed {
+			server.DB.Model(&models.SignatureRequest{}).Where("id = ?", request.ID).Update("last_error", err.Error())
+		}
+		return "", err
+	}
+	return docNumber, nil
+}
+
+// storedSignature mengembalikan tanda tangan tersimpan (data URL PNG) bila yang membuka tautan sedang login
+// sebagai admin yang terhubung dengan karyawan penandatangan
+func (server *Server) storedSignature(r *http.Request, signer models.SignatureRequestSigner) string {
+	adminID, _, _, isLoggedIn := GetCurrentAdmin(r)
+	if !isLoggedIn {
+		return ""
+	}
+
+	var admin models.Admin
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&admin)
+	if admin.UserID == "" || admin.UserID != signer.UserID || admin.Signature == "" {
+		return ""
+	}
+
+	content, err := os.ReadFile(filepath.Join("public", "uploads", "signatures", admin.Signature))
+	if err != nil {
+		return ""
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(content)
+}
+
+// signatureSummary membaca ringkasan isian draf untuk ditampilkan
+func signatureSummary(request models.SignatureRequest) []models.GoFormAnswer {
+	var answers []models.GoFormAnswer
+	json.Unmarshal([]byte(request.Summary), &answers)
+	return answers
+}
+
+// findSignatureRequest mencari permintaan tanda tangan beserta form dan para pihaknya
+func (server *Server) findSignatureRequest(id string) (models.SignatureRequest, bool) {
+	var request models.SignatureRequest
+	server.DB.Preload("Form").Preload("Signers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("role asc")
+	}).Preload("Signers.User").Where("id = ?", id).Limit(1).Find(&request)
+	return request, request.ID != ""
+}
+
+// signatureRequestAllowed memeriksa bahwa kedua pihak dan setiap aset pada isian BAST permintaan berada di dalam
+// cakupan data admin, sama seperti saat BAST diajukan
+func (server *Server) signatureRequestAllowed(scope dataScope, request models.SignatureRequest) bool {
+	if !scope.restricted {
+		return true
+	}
+	values, err := url.ParseQuery(request.FormData)
+	return err == nil && server.bastAllowed(scope, values)
+}
+
+// signatureRequestIDAllowed memeriksa cakupan permintaan tanda tangan berdasarkan ID; permintaan yang tidak ditemukan
+// dianggap diizinkan agar penanganannya tetap seperti semula
+func (server *Server) signatureRequestIDAllowed(r *http.Request, id string) bool {
+	var request models.SignatureRequest
+	server.DB.Select("id", "form_data").Where("id = ?", id).Limit(1).Find(&request)
+	return request.ID == "" || server.signatureRequestAllowed(server.dataScope(r), request)
+}
+
+// findSigner mencari pihak penandatangan berdasarkan token tautan yang masih berlaku
+func (server *Server) findSigner(token string) (models.SignatureRequestSigner, models.SignatureRequest, bool) {
+	var signer models.SignatureRequestSigner
+	if token == "" {
+		return signer, models.SignatureRequest{}, false
+	}
+	server.DB.Preload("User").Where("token = ? AND expires_at > ?", token, time.Now()).Limit(1).Find(&signer)
+	if signer.ID == 0 {
+		return signer, models.SignatureRequest{}, false
+	}
+	request, ok := server.findSignatureRequest(signer.RequestID)
+	return signer, request, ok
+}
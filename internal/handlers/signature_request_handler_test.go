@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+)
+
+// pendingSignatureRequest membuat permintaan tanda tangan dengan satu pihak yang tautannya hampir habis
+func pendingSignatureRequest(server *Server) models.SignatureRequestSigner {
+	expires := time.Now().Add(time.Hour)
+	signer := models.SignatureRequestSigner{Role: "p1", Label: "PIHAK PERTAMA", UserID: "u1", Token: newSignToken(), ExpiresAt: &expires}
+	server.DB.Create(&models.SignatureRequest{ID: "sr1", Title: "BAST", Status: models.SignatureStatusPending, Signers: []models.SignatureRequestSigner{signer}})
+	server.DB.Where("request_id = ?", "sr1").First(&signer)
+	return signer
+}
+
+func TestSignatureRequestActionsRequirePostWithCSRF(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	pendingSignatureRequest(server)
+
+	for _, action := range []string{"remind", "retry", "cancel"} {
+		path := "/goform/signature/" + action + "/sr1"
+		if w := client.do(http.MethodGet, path, nil); w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET %s = %d, want %d", action, w.Code, http.StatusMethodNotAllowed)
+		}
+		if w := client.do(http.MethodPost, path, url.Values{"csrf_token": {"forged"}}); w.Code != http.StatusForbidden {
+			t.Errorf("POST %s with forged token = %d, want %d", action, w.Code, http.StatusForbidden)
+		}
+	}
+	var request models.SignatureRequest
+	server.DB.First(&request, "id = ?", "sr1")
+	if request.Status != models.SignatureStatusPending {
+		t.Errorf("status = %q, want %q", request.Status, models.SignatureStatusPending)
+	}
+}
+
+func TestRemindSignatureRequestExtendsLinks(t *testing.T) {
+	server := newTestServer(t)
+	client := loginAs(t, server, models.Admin{ID: "a1", Username: "admin", Role: models.RoleSuperAdmin})
+	signer := pendingSignatureRequest(server)
+
+	if w := client.do(http.MethodPost, "/goform/signature/remind/sr1", url.Values{}); w.Code != http.StatusSeeOther {
+		t.Fatalf("remind = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	server.DB.First(&signer, "id = ?", signer.ID)
+	if signer.ExpiresAt == nil || time.Until(*signer.ExpiresAt) < signLinkTTL-time.Minute {
+		t.Errorf("expires_at = %v, want about %v from now", signer.ExpiresAt, signLinkTTL)
+	}
+
+	if w := client.do(http.MethodPost, "/goform/signature/cancel/sr1", url.Values{}); w.Code != http.StatusSeeOther {
+		t.Fatalf("cancel = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	var request models.SignatureRequest
+	server.DB.First(&request, "id = ?", "sr1")
+	if request.Status != models.SignatureStatusCancelled {
+		t.Errorf("status = %q, want %q", request.Status, models.SignatureStatusCancelled)
+	}
+}
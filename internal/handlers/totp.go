@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image/png"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// Parameter TOTP (RFC 6238) yang didukung semua aplikasi authenticator umum
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1 // toleransi satu langkah sebelum/sesudah untuk selisih jam perangkat
+)
+
+// recoveryCodeCount adalah jumlah kode pemulihan yang diterbitkan sekaligus
+const recoveryCodeCount = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTOTPSecret membuat secret acak 160 bit dalam format base32
+func newTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// totpCode menghitung kode TOTP untuk satu langkah waktu
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// verifyTOTP memeriksa kode pada waktu t dan mengembalikan langkah waktunya.
+// Langkah yang tidak lebih baru dari lastStep ditolak agar kode yang sama tidak bisa dipakai dua kali.
+func verifyTOTP(secret, code string, lastStep int64, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI membangun URI otpauth:// yang dipindai aplikasi authenticator
+func totpURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// qrDataURI merender teks menjadi QR code PNG dalam bentuk data URI untuk tag <img>
+func qrDataURI(text string, size int) (string, error) {
+	code, err := qr.Encode(text, qr.M, qr.Auto)
+	if err != nil {
+		return "", err
+	}
+	code, err = barcode.Scale(code, size, size)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// newRecoveryCodes membuat kode pemulihan sekali pakai beserta hash yang disimpan di database
+func newRecoveryCodes() ([]string, string, error) {
+	var codes, hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "", err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(buf))
+		code = code[:4] + "-" + code[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, strings.Join(hashes, "\n"), nil
+}
+
+// hashRecoveryCode menormalkan lalu meng-hash kode pemulihan
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// consumeRecoveryCode mencocokkan kode pemulihan dan mengembalikan daftar hash tanpa kode tersebut
+func consumeRecoveryCode(stored, code string) (string, bool) {
+	target := hashRecoveryCode(code)
+	var rest []string
+	found := false
+	for _, h := range strings.Split(stored, "\n") {
+		if h == "" {
+			continue
+		}
+		if !found && subtle.ConstantTimeCompare([]byte(h), []byte(target)) == 1 {
+			found = true
+			continue
+		}
+		rest = append(rest, h)
+	}
+	return strings.Join(rest, "\n"), found
+}
+
+// recoveryCodesLeft menghitung kode pemulihan yang belum terpakai
+func recoveryCodesLeft(stored string) int {
+	n := 0
+	for _, h := range strings.Split(stored, "\n") {
+		if h != "" {
+			n++
+		}
+	}
+	return n
+}
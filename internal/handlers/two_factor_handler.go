@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/gorilla/securecookie"
+	"github.com/unrolled/render"
+)
+
+// loginChallengeCookie menyimpan admin yang sudah lolos password tetapi belum lolos verifikasi dua langkah
+const loginChallengeCookie = "gokso-2fa"
+
+// loginChallengeTTL adalah batas waktu menyelesaikan langkah kedua login
+const loginChallengeTTL = 5 * time.Minute
+
+// errTOTPInvalid dipakai saat kode authenticator salah atau sudah kedaluwarsa
+var errTOTPInvalid = errors.New("Kode verifikasi salah atau sudah kedaluwarsa")
+
+// loginChallenge adalah isi cookie langkah kedua login. Hash nonce disimpan di admin sehingga cookie
+// hanya berlaku untuk challenge terakhir dan tidak bisa dipakai lagi setelah login berhasil.
+type loginChallenge struct {
+	AdminID string
+	Nonce   string
+}
+
+// loginChallengeHash meng-hash nonce challenge untuk disimpan di tabel admins
+func loginChallengeHash(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// startLoginChallenge menandai bahwa password admin sudah benar; session baru dibuat setelah kode 2FA valid
+func (server *Server) startLoginChallenge(w http.ResponseWriter, adminID string) error {
+	nonce := newSessionToken()
+	if err := server.DB.Model(&models.Admin{}).Where("id = ?", adminID).Update("login_challenge", loginChallengeHash(nonce)).Error; err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(loginChallengeCookie, loginChallenge{AdminID: adminID, Nonce: nonce}, store.challenge)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginChallengeCookie,
+		Value:    encoded,
+		Path:     "/login",
+		MaxAge:   int(loginChallengeTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   store.Options.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// clearLoginChallenge menghapus cookie langkah kedua login
+func clearLoginChallenge(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: loginChallengeCookie, Value: "", Path: "/login", MaxAge: -1, HttpOnly: true})
+}
+
+// loginChallengeAdmin mengambil admin yang sedang menjalani langkah kedua login
+func (server *Server) loginChallengeAdmin(r *http.Request) (models.Admin, bool) {
+	var admin models.Admin
+	cookie, err := r.Cookie(loginChallengeCookie)
+	if err != nil {
+		return admin, false
+	}
+	var challenge loginChallenge
+	if err := securecookie.DecodeMulti(loginChallengeCookie, cookie.Value, &challenge, store.challenge); err != nil {
+		return admin, false
+	}
+	server.DB.Where("id = ?", challenge.AdminID).Limit(1).Find(&admin)
+	if admin.ID == "" || admin.LoginChallenge == "" {
+		return admin, false
+	}
+	return admin, subtle.ConstantTimeCompare([]byte(admin.LoginChallenge), []byte(loginChallengeHash(challenge.Nonce))) == 1
+}
+
+// consumeLoginChallenge memakai challenge admin dengan update bersyarat sehingga satu cookie hanya menghasilkan satu session
+func (server *Server) consumeLoginChallenge(admin models.Admin) bool {
+	result := server.DB.Model(&models.Admin{}).
+		Where("id = ? AND login_challenge = ?", admin.ID, admin.LoginChallenge).
+		Update("login_challenge", "")
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// createLoginSession menerbitkan session login baru untuk admin
+func (server *Server) createLoginSession(w http.ResponseWriter, r *http.Request, admin models.Admin) error {
+	// Token lama selalu diganti agar session tidak bisa ditanam sebelum login
+	session, _ := store.Get(r, sessionCookieName)
+	store.Rotate(session)
+	session.Values["admin_id"] = admin.ID
+	session.Values["admin_username"] = admin.Username
+	session.Values["admin_role"] = admin.Role
+	return session.Save(r, w)
+}
+
+// requires2FA memeriksa kebijakan role apakah admin wajib memakai verifikasi dua langkah
+func (server *Server) requires2FA(role string) bool {
+	var policy models.RolePolicy
+	server.DB.Where("role = ?", role).Limit(1).Find(&policy)
+	return policy.Require2FA
+}
+
+// ensureTOTPSecret menyiapkan secret TOTP yang belum dikonfirmasi; secret yang sama dipakai ulang saat halaman dimuat ulang
+func (server *Server) ensureTOTPSecret(admin *models.Admin) error {
+	if admin.TOTPSecret != "" && !admin.TOTPEnabled {
+		return nil
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return err
+	}
+	admin.TOTPSecret = secret
+	return server.DB.Model(admin).Update("totp_secret", secret).Error
+}
+
+// totpEnrolment menyiapkan data QR code pendaftaran authenticator untuk template
+func (server *Server) totpEnrolment(admin models.Admin) map[string]interface{} {
+	issuer := server.AppName
+	if issuer == "" {
+		issuer = "gokso"
+	}
+	uri := totpURI(issuer, admin.Username, admin.TOTPSecret)
+	qr, _ := qrDataURI(uri, 220)
+
+	// Secret ditampilkan berkelompok 4 karakter agar mudah diketik manual
+	var groups []string
+	for i := 0; i < len(admin.TOTPSecret); i += 4 {
+		end := i + 4
+		if end > len(admin.TOTPSecret) {
+			end = len(admin.TOTPSecret)
+		}
+		groups = append(groups, admin.TOTPSecret[i:end])
+	}
+	return map[string]interface{}{
+		"QR":     template.URL(qr), // data URI hasil render sendiri, aman ditandai sebagai URL
+		"Secret": strings.Join(groups, " "),
+	}
+}
+
+// enableTwoFactor mengaktifkan 2FA bila kode dari authenticator cocok dan menerbitkan kode pemulihan
+func (server *Server) enableTwoFactor(admin *models.Admin, code string) ([]string, error) {
+	if admin.TOTPSecret == "" || admin.TOTPEnabled {
+		return nil, fmt.Errorf("Mulai ulang pendaftaran verifikasi dua langkah")
+	}
+	step, ok := verifyTOTP(admin.TOTPSecret, code, 0, time.Now())
+	if !ok {
+		return nil, errTOTPInvalid
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	// Update bersyarat agar pendaftaran yang dikirim bersamaan tidak saling menimpa kode pemulihan
+	result := server.DB.Model(&models.Admin{}).Where("id = ? AND totp_enabled = ?", admin.ID, false).
+		Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step, "recovery_codes": hashes})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, fmt.Errorf("Mulai ulang pendaftaran verifikasi dua langkah")
+	}
+	admin.TOTPEnabled = true
+	admin.TOTPLastStep = step
+	admin.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// checkSecondFactor memverifikasi kode authenticator atau kode pemulihan milik admin.
+// Pemakaian dicatat dengan update bersyarat sehingga satu kode tidak bisa dipakai dua kali secara bersamaan.
+func (server *Server) checkSecondFactor(admin models.Admin, code, recovery string) (usedRecovery bool, ok bool) {
+	if !admin.TOTPEnabled {
+		return false, false
+	}
+	if strings.TrimSpace(recovery) != "" {
+		rest, found := consumeRecoveryCode(admin.RecoveryCodes, recovery)
+		if !found {
+			return true, false
+		}
+		result := server.DB.Model(&models.Admin{}).
+			Where("id = ? AND recovery_codes = ?", admin.ID, admin.RecoveryCodes).
+			Update("recovery_codes", rest)
+		return true, result.Error == nil && result.RowsAffected == 1
+	}
+
+	step, valid := verifyTOTP(admin.TOTPSecret, code, admin.TOTPLastStep, time.Now())
+	if !valid {
+		return false, false
+	}
+	result := server.DB.Model(&models.Admin{}).
+		Where("id = ? AND totp_last_step < ?", admin.ID, step).
+		Update("totp_last_step", step)
+	return false, result.Error == nil && result.RowsAffected == 1
+}
+
+// LoginTwoFactorForm menampilkan langkah kedua login: kode authenticator atau kode pemulihan
+func (server *Server) LoginTwoFactorForm(w http.ResponseWriter, r *http.Request) {
+	admin, ok := server.loginChallengeAdmin(r)
+	if !ok {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi verifikasi berakhir, silakan login kembali"), http.StatusSeeOther)
+		return
+	}
+	if !admin.TOTPEnabled {
+		http.Redirect(w, r, "/login/2fa/setup", http.StatusSeeOther)
+		return
+	}
+
+	_ = server.Renderer.HTML(w, http.StatusOK, "auth/login_2fa", map[string]interface{}{
+		"Error":    r.URL.Query().Get("error"),
+		"Username": admin.Username,
+	}, render.HTMLOptions{Layout: ""})
+}
+
+// LoginTwoFactor memverifikasi langkah kedua login lalu membuat session
+func (server *Server) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	admin, ok := server.loginChallengeAdmin(r)
+	if !ok {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi verifikasi berakhir, silakan login kembali"), http.StatusSeeOther)
+		return
+	}
+
+	// Kode 6 digit mudah ditebak bila tidak dibatasi, sehingga langkah kedua memakai penghitung yang sama dengan password
+	if left := server.accountLockedFor(&admin); left > 0 {
+		clearLoginChallenge(w)
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonLocked)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(lockedMessage(left)), http.StatusSeeOther)
+		return
+	}
+	if wait := server.loginRetryAfter(admin.Username, clientIP(r), &admin); wait > 0 {
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonRateLimited)
+		http.Redirect(w, r, "/login/2fa?error="+url.QueryEscape(retryMessage(wait)), http.StatusSeeOther)
+		return
+	}
+
+	usedRecovery, valid := server.checkSecondFactor(admin, r.FormValue("code"), r.FormValue("recovery_code"))
+	if !valid {
+		server.registerLoginFailure(&admin)
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonSecondFactor)
+		if admin.LockedUntil != nil {
+			clearLoginChallenge(w)
+			http.Redirect(w, r, "/login?error="+url.QueryEscape(lockedMessage(server.Lockout.Duration)), http.StatusSeeOther)
+			return
+		}
+		msg := "Kode verifikasi salah atau sudah dipakai"
+		if usedRecovery {
+			msg = "Kode pemulihan tidak valid"
+		}
+		http.Redirect(w, r, "/login/2fa?error="+url.QueryEscape(msg), http.StatusSeeOther)
+		return
+	}
+
+	clearLoginChallenge(w)
+	if !server.consumeLoginChallenge(admin) {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi verifikasi berakhir, silakan login kembali"), http.StatusSeeOther)
+		return
+	}
+	if err := server.createLoginSession(w, r, admin); err != nil {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Gagal membuat session login"), http.StatusSeeOther)
+		return
+	}
+	server.resetLoginFailures(&admin)
+	reason := loginReason2FASuccess
+	if usedRecovery {
+		reason = loginReasonRecovery
+	}
+	server.recordLoginAttempt(r, admin.Username, admin.ID, true, reason)
+
+	if usedRecovery {
+		var fresh models.Admin
+		server.DB.Select("recovery_codes").Where("id = ?", admin.ID).Limit(1).Find(&fresh)
+		msg := fmt.Sprintf("Login memakai kode pemulihan. Sisa kode pemulihan: %d", recoveryCodesLeft(fresh.RecoveryCodes))
+		http.Redirect(w, r, "/profile/2fa?msg="+url.QueryEscape(msg), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// LoginTwoFactorSetupForm menampilkan pendaftaran authenticator saat role admin mewajibkan 2FA
+func (server *Server) LoginTwoFactorSetupForm(w http.ResponseWriter, r *http.Request) {
+	admin, ok := server.loginChallengeAdmin(r)
+	if !ok {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi verifikasi berakhir, silakan login kembali"), http.StatusSeeOther)
+		return
+	}
+	if admin.TOTPEnabled {
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+	if err := server.ensureTOTPSecret(&admin); err != nil {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Gagal menyiapkan verifikasi dua langkah"), http.StatusSeeOther)
+		return
+	}
+
+	data := server.totpEnrolment(admin)
+	data["Error"] = r.URL.Query().Get("error")
+	data["Username"] = admin.Username
+	_ = server.Renderer.HTML(w, http.StatusOK, "auth/login_2fa_setup", data, render.HTMLOptions{Layout: ""})
+}
+
+// LoginTwoFactorSetup mengonfirmasi pendaftaran authenticator, membuat session lalu menampilkan kode pemulihan
+func (server *Server) LoginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	admin, ok := server.loginChallengeAdmin(r)
+	if !ok {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi verifikasi berakhir, silakan login kembali"), http.StatusSeeOther)
+		return
+	}
+
+	// Pendaftaran juga menebak kode 6 digit, sehingga dibatasi dengan penghitung yang sama seperti LoginTwoFactor
+	if left := server.accountLockedFor(&admin); left > 0 {
+		clearLoginChallenge(w)
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonLocked)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape(lockedMessage(left)), http.StatusSeeOther)
+		return
+	}
+	if wait := server.loginRetryAfter(admin.Username, clientIP(r), &admin); wait > 0 {
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonRateLimited)
+		http.Redirect(w, r, "/login/2fa/setup?error="+url.QueryEscape(retryMessage(wait)), http.StatusSeeOther)
+		return
+	}
+
+	codes, err := server.enableTwoFactor(&admin, r.FormValue("code"))
+	if errors.Is(err, errTOTPInvalid) {
+		server.registerLoginFailure(&admin)
+		server.recordLoginAttempt(r, admin.Username, admin.ID, false, loginReasonSecondFactor)
+		if admin.LockedUntil != nil {
+			clearLoginChallenge(w)
+			http.Redirect(w, r, "/login?error="+url.QueryEscape(lockedMessage(server.Lockout.Duration)), http.StatusSeeOther)
+			return
+		}
+	}
+	if err != nil {
+		http.Redirect(w, r, "/login/2fa/setup?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	clearLoginChallenge(w)
+	if !server.consumeLoginChallenge(admin) {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Sesi verifikasi berakhir, silakan login kembali"), http.StatusSeeOther)
+		return
+	}
+	if err := server.createLoginSession(w, r, admin); err != nil {
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Gagal membuat session login"), http.StatusSeeOther)
+		return
+	}
+	server.resetLoginFailures(&admin)
+	server.recordLoginAttempt(r, admin.Username, admin.ID, true, loginReason2FASuccess)
+	server.renderTwoFactorPage(w, r, admin, codes, "Verifikasi dua langkah aktif. Simpan kode pemulihan berikut sebelum melanjutkan.", "")
+}
+
+// TwoFactorSettings menampilkan status verifikasi dua langkah admin yang sedang login
+func (server *Server) TwoFactorSettings(w http.ResponseWriter, r *http.Request) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	var admin models.Admin
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&admin)
+
+	if !admin.TOTPEnabled {
+		if err := server.ensureTOTPSecret(&admin); err != nil {
+			http.Redirect(w, r, "/profile?error="+url.QueryEscape("Gagal menyiapkan verifikasi dua langkah"), http.StatusSeeOther)
+			return
+		}
+	}
+	server.renderTwoFactorPage(w, r, admin, nil, r.URL.Query().Get("msg"), r.URL.Query().Get("error"))
+}
+
+// EnableTwoFactor mengaktifkan verifikasi dua langkah dari halaman profil
+func (server *Server) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	var admin models.Admin
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&admin)
+
+	codes, err := server.enableTwoFactor(&admin, r.FormValue("code"))
+	if err != nil {
+		http.Redirect(w, r, "/profile/2fa?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	server.renderTwoFactorPage(w, r, admin, codes, "Verifikasi dua langkah berhasil diaktifkan. Simpan kode pemulihan berikut.", "")
+}
+
+// RegenerateRecoveryCodes menerbitkan kode pemulihan baru; kode lama tidak berlaku lagi
+func (server *Server) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	var admin models.Admin
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&admin)
+
+	if _, ok := server.checkSecondFactor(admin, r.FormValue("code"), ""); !ok {
+		http.Redirect(w, r, "/profile/2fa?error="+url.QueryEscape("Kode verifikasi salah atau sudah dipakai"), http.StatusSeeOther)
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Redirect(w, r, "/profile/2fa?error="+url.QueryEscape("Gagal membuat kode pemulihan"), http.StatusSeeOther)
+		return
+	}
+	server.DB.Model(&admin).Update("recovery_codes", hashes)
+	server.renderTwoFactorPage(w, r, admin, codes, "Kode pemulihan baru berhasil dibuat. Kode lama tidak berlaku lagi.", "")
+}
+
+// DisableTwoFactor menonaktifkan verifikasi dua langkah setelah password dan kode dikonfirmasi
+func (server *Server) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	adminID, _, role, _ := GetCurrentAdmin(r)
+	var admin models.Admin
+	server.DB.Where("id = ?", adminID).Limit(1).Find(&admin)
+
+	if server.requires2FA(role) {
+		http.Redirect(w, r, "/profile/2fa?error="+url.QueryEscape("Role Anda mewajibkan verifikasi dua langkah"), http.StatusSeeOther)
+		return
+	}
+	// Akun SSO tidak punya password yang diketahui pemiliknya; cukup kode authenticator
+	if admin.AuthSource != authSourceOIDC && !server.verifyAdminPassword(admin, r.FormValue("password")) {
+		http.Redirect(w, r, "/profile/2fa?error="+url.QueryEscape("Password salah"), http.StatusSeeOther)
+		return
+	}
+	if _, ok := server.checkSecondFactor(admin, r.FormValue("code"), r.FormValue("recovery_code")); !ok {
+		http.Redirect(w, r, "/profile/2fa?error="+url.QueryEscape("Kode verifikasi salah atau sudah dipakai"), http.StatusSeeOther)
+		return
+	}
+
+	server.DB.Model(&admin).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0, "recovery_codes": ""})
+	http.Redirect(w, r, "/profile?msg="+url.QueryEscape("Verifikasi dua langkah dinonaktifkan"), http.StatusSeeOther)
+}
+
+// renderTwoFactorPage menampilkan halaman 2FA di profil; kode pemulihan hanya tampil sekali setelah diterbitkan
+func (server *Server) renderTwoFactorPage(w http.ResponseWriter, r *http.Request, admin models.Admin, codes []string, msg, errMsg string) {
+	data := map[string]interface{}{
+		"title":         "Verifikasi Dua Langkah",
+		"admin":         admin,
+		"recoveryCodes": codes,
+		"codesLeft":     recoveryCodesLeft(admin.RecoveryCodes),
+		"required":      server.requires2FA(admin.Role),
+		"msg":           msg,
+		"error":         errMsg,
+	}
+	if !admin.TOTPEnabled {
+		for k, v := range server.totpEnrolment(admin) {
+			data[k] = v
+		}
+	}
+	server.RenderHTML(w, r, http.StatusOK, "auth/two_factor", data)
+}
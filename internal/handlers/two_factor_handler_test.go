@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// twoFactorAdmin membuat admin asset_manager yang role-nya mewajibkan 2FA namun belum mendaftarkan authenticator
+func twoFactorAdmin(tb testing.TB, server *Server) models.Admin {
+	tb.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("rahasia"), bcrypt.MinCost)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	admin := models.Admin{ID: "t1", Username: "tono", Password: string(hashed), Role: "asset_manager"}
+	server.DB.Create(&admin)
+	server.DB.Create(&models.RolePolicy{Role: "asset_manager", Require2FA: true})
+	return admin
+}
+
+// startChallenge login dengan password lalu mengembalikan client yang membawa cookie langkah kedua
+func startChallenge(tb testing.TB, server *Server) *testClient {
+	tb.Helper()
+	anon := &testClient{server: server}
+	w := anon.do(http.MethodPost, "/login", url.Values{"username": {"tono"}, "password": {"rahasia"}})
+	if loc := w.Header().Get("Location"); loc != "/login/2fa/setup" {
+		tb.Fatalf("login redirect = %q, want /login/2fa/setup", loc)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == loginChallengeCookie {
+			return &testClient{server: server, cookie: cookie.Name + "=" + cookie.Value}
+		}
+	}
+	tb.Fatal("challenge cookie not set")
+	return nil
+}
+
+// currentTOTP menyiapkan secret lewat halaman pendaftaran lalu menghitung kode saat ini
+func currentTOTP(tb testing.TB, server *Server, client *testClient) string {
+	tb.Helper()
+	if w := client.do(http.MethodGet, "/login/2fa/setup", nil); w.Code != http.StatusOK {
+		tb.Fatalf("setup form = %d, want %d", w.Code, http.StatusOK)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "t1")
+	code, err := totpCode(admin.TOTPSecret, time.Now().Unix()/totpPeriod)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return code
+}
+
+// loginAttemptReasons mengembalikan keterangan log percobaan login admin, urut waktu
+func loginAttemptReasons(server *Server, adminID string) []string {
+	var reasons []string
+	server.DB.Model(&models.LoginAttempt{}).Where("admin_id = ?", adminID).Order("id").Pluck("reason", &reasons)
+	return reasons
+}
+
+func TestLoginTwoFactorSetupLimitsGuesses(t *testing.T) {
+	server := newTestServer(t)
+	twoFactorAdmin(t, server)
+	client := startChallenge(t, server)
+	currentTOTP(t, server, client)
+
+	for i := 0; i < loginFreeAttemptsUser; i++ {
+		w := client.do(http.MethodPost, "/login/2fa/setup", url.Values{"code": {"000000"}})
+		if msg := redirectError(t, w.Header().Get("Location")); msg != errTOTPInvalid.Error() {
+			t.Fatalf("attempt %d: error = %q", i+1, msg)
+		}
+	}
+	w := client.do(http.MethodPost, "/login/2fa/setup", url.Values{"code": {"000000"}})
+	if msg := redirectError(t, w.Header().Get("Location")); !strings.Contains(msg, "Terlalu banyak percobaan") {
+		t.Errorf("error after %d failures = %q, want backoff", loginFreeAttemptsUser, msg)
+	}
+
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "t1")
+	if admin.FailedLogins != loginFreeAttemptsUser || admin.TOTPEnabled {
+		t.Errorf("failed_logins = %d, totp_enabled = %v", admin.FailedLogins, admin.TOTPEnabled)
+	}
+	reasons := loginAttemptReasons(server, "t1")
+	if len(reasons) != loginFreeAttemptsUser+1 || reasons[0] != loginReasonSecondFactor || reasons[len(reasons)-1] != loginReasonRateLimited {
+		t.Errorf("login attempts = %v", reasons)
+	}
+}
+
+func TestLoginTwoFactorSetupLocksAccount(t *testing.T) {
+	server := newTestServer(t)
+	server.Lockout.MaxFailures = 1
+	twoFactorAdmin(t, server)
+	client := startChallenge(t, server)
+	code := currentTOTP(t, server, client)
+
+	w := client.do(http.MethodPost, "/login/2fa/setup", url.Values{"code": {"000000"}})
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/login?") {
+		t.Fatalf("redirect = %q, want the login page", loc)
+	}
+	// Kode yang benar tidak lagi diterima selama akun terkunci
+	w = client.do(http.MethodPost, "/login/2fa/setup", url.Values{"code": {code}})
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/login?") {
+		t.Errorf("redirect while locked = %q, want the login page", loc)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "t1")
+	if admin.LockedUntil == nil || admin.TOTPEnabled {
+		t.Errorf("locked_until = %v, totp_enabled = %v", admin.LockedUntil, admin.TOTPEnabled)
+	}
+}
+
+func TestLoginChallengeCannotBeReplayed(t *testing.T) {
+	server := newTestServer(t)
+	twoFactorAdmin(t, server)
+	stale := startChallenge(t, server)
+
+	// Login baru mengganti nonce sehingga cookie langkah kedua yang lama tidak berlaku
+	client := startChallenge(t, server)
+	if w := stale.do(http.MethodGet, "/login/2fa/setup", nil); !strings.HasPrefix(w.Header().Get("Location"), "/login?") {
+		t.Errorf("superseded challenge = %d %q, want the login page", w.Code, w.Header().Get("Location"))
+	}
+
+	code := currentTOTP(t, server, client)
+	w := client.do(http.MethodPost, "/login/2fa/setup", url.Values{"code": {code}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("setup = %d %q, want %d", w.Code, w.Header().Get("Location"), http.StatusOK)
+	}
+
+	// Cookie yang sama tidak bisa dipakai lagi setelah session terbit
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "t1")
+	if admin.LoginChallenge != "" {
+		t.Error("challenge not consumed after a successful setup")
+	}
+	next, err := totpCode(admin.TOTPSecret, admin.TOTPLastStep+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := client.do(http.MethodPost, "/login/2fa", url.Values{"code": {next}}); !strings.HasPrefix(w.Header().Get("Location"), "/login?") {
+		t.Errorf("replayed challenge = %d %q, want the login page", w.Code, w.Header().Get("Location"))
+	}
+}
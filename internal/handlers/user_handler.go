@@ -1,24 +1,46 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/AbsoluteZero24/gokso/internal/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// ListEmployees menampilkan daftar semua karyawan
+// ListEmployees menampilkan daftar semua karyawan; ?offboarding=pending hanya menampilkan karyawan
+// yang ditandai impor HR perlu offboarding
 func (server *Server) ListEmployees(w http.ResponseWriter, r *http.Request) {
+	scope := server.dataScope(r)
+	pendingOnly := r.URL.Query().Get("offboarding") == "pending"
+
+	query := scope.applyUsers(preloadUserOrg(server.DB, ""))
+	if pendingOnly {
+		query = query.Where("users.offboarding_note <> ''")
+	}
 	var users []models.User
-	server.DB.Find(&users)
+	query.Find(&users)
+
+	var pending int64
+	scope.applyUsers(server.DB.Model(&models.User{})).Where("users.offboarding_note <> ''").Count(&pending)
 
 	server.RenderHTML(w, r, http.StatusOK, "administration/employee", map[string]interface{}{
-		"title": "Daftar Karyawan",
-		"users": users,
-		"msg":   r.URL.Query().Get("msg"),
-		"error": r.URL.Query().Get("error"),
+		"title":       "Daftar Karyawan",
+		"users":       users,
+		"pending":     pending,
+		"pendingOnly": pendingOnly,
+		"msg":         r.URL.Query().Get("msg"),
+		"error":       r.URL.Query().Get("error"),
 	})
 }
 
@@ -28,12 +50,14 @@ func (server *Server) CreateEmployeeForm(w http.ResponseWriter, r *http.Request)
 	var positions []models.MasterPosition
 
 	server.DB.Preload("Departments.SubDepartments").Find(&branches)
+	branches = server.dataScope(r).scopedBranches(branches)
 	server.DB.Find(&positions)
 
 	server.RenderHTML(w, r, http.StatusOK, "administration/employee_form", map[string]interface{}{
 		"title":     "Tambah Karyawan",
 		"branches":  branches,
 		"positions": positions,
+		"error":     r.URL.Query().Get("error"),
 	})
 }
 
@@ -45,18 +69,26 @@ func (server *Server) StoreEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	placement, err := server.orgPlacementFromForm(r)
+	if err != nil {
+		http.Redirect(w, r, "/administration/employee/create?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+
 	user := models.User{
 		ID:             uuid.New().String(),
 		NIK:            r.FormValue("nik"),
 		Name:           r.FormValue("name"),
 		Email:          r.FormValue("email"),
-		Branch:         r.FormValue("branch"),
-		Department:     r.FormValue("department"),
-		SubDepartment:  r.FormValue("sub_department"),
-		Position:       r.FormValue("position"),
 		StatusKaryawan: r.FormValue("status_karyawan"),
 		Password:       "password123", // Default password
 	}
+	placement.assign(&user)
+
+	if !server.dataScope(r).allowsUser(user) {
+		forbidScope(w)
+		return
+	}
 
 	if err := server.DB.Create(&user).Error; err != nil {
 		http.Redirect(w, r, "/administration/employee?error=Gagal menambah karyawan: "+err.Error(), http.StatusSeeOther)
@@ -71,15 +103,20 @@ func (server *Server) EditEmployeeForm(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	var user models.User
-	if err := server.DB.Where("id = ?", id).First(&user).Error; err != nil {
+	if err := preloadUserOrg(server.DB, "").Where("id = ?", id).First(&user).Error; err != nil {
 		http.Redirect(w, r, "/administration/employee", http.StatusSeeOther)
 		return
 	}
+	if !server.dataScope(r).allowsUser(user) {
+		forbidScope(w)
+		return
+	}
 
 	var branches []models.MasterBranch
 	var positions []models.MasterPosition
 
 	server.DB.Preload("Departments.SubDepartments").Find(&branches)
+	branches = server.dataScope(r).scopedBranches(branches)
 	server.DB.Find(&positions)
 
 	server.RenderHTML(w, r, http.StatusOK, "administration/employee_form", map[string]interface{}{
@@ -87,6 +124,7 @@ func (server *Server) EditEmployeeForm(w http.ResponseWriter, r *http.Request) {
 		"user":      user,
 		"branches":  branches,
 		"positions": positions,
+		"error":     r.URL.Query().Get("error"),
 	})
 }
 
@@ -103,20 +141,28 @@ func (server *Server) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build update map
-	newData := map[string]interface{}{
-		"nik":             r.FormValue("nik"),
-		"name":            r.FormValue("name"),
-		"email":           r.FormValue("email"),
-		"branch":          r.FormValue("branch"),
-		"department":      r.FormValue("department"),
-		"sub_department":  r.FormValue("sub_department"),
-		"position":        r.FormValue("position"),
-		"status_karyawan": r.FormValue("status_karyawan"),
+	placement, err := server.orgPlacementFromForm(r)
+	if err != nil {
+		http.Redirect(w, r, "/administration/employee/edit/"+id+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
 	}
 
+	// Build update map
+	newData := placement.columns()
+	newData["nik"] = r.FormValue("nik")
+	newData["name"] = r.FormValue("name")
+	newData["email"] = r.FormValue("email")
+	newData["status_karyawan"] = r.FormValue("status_karyawan")
+
 	fmt.Printf("[UpdateEmployee] New Data: %+v\n", newData)
 
+	// Karyawan lama maupun unit barunya harus berada di dalam cakupan admin
+	scope := server.dataScope(r)
+	if !server.userAllowed(scope, id) || !scope.allows(placement.Branch.ID, placement.Department.ID, placement.SubDepartment.ID) {
+		forbidScope(w)
+		return
+	}
+
 	// Perform update
 	result := server.DB.Model(&models.User{}).Where("id = ?", id).Updates(newData)
 	if result.Error != nil {
@@ -139,6 +185,319 @@ func (server *Server) DeleteEmployee(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	var user models.User
+	server.DB.Where("id = ?", id).Limit(1).Find(&user)
+	if user.ID != "" && !server.dataScope(r).allowsUser(user) {
+		forbidScope(w)
+		return
+	}
+	// Karyawan yang masih memegang aset tidak boleh dihapus agar aset tidak menunjuk ke karyawan yang sudah dihapus
+	if user.ID != "" {
+		if status := server.employeeOffboardingStatus(user); !status.Clear() {
+			msg := fmt.Sprintf("%s masih memegang %d aset dan %d peminjaman aktif. Selesaikan offboarding terlebih dahulu.", user.Name, len(status.Assets), len(status.Loans))
+			http.Redirect(w, r, "/administration/employee/offboarding/"+user.ID+"?error="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
+	}
+
 	server.DB.Where("id = ?", id).Delete(&models.User{})
 	http.Redirect(w, r, "/administration/employee", http.StatusSeeOther)
 }
+
+// offboardingCheck adalah satu butir pemeriksaan offboarding karyawan
+type offboardingCheck struct {
+	Label  string
+	Result string
+	Done   bool
+}
+
+// offboardingStatus adalah aset, peminjaman dan akun yang masih terkait dengan karyawan yang akan keluar
+type offboardingStatus struct {
+	Assets    []models.AssetKSO
+	Loans     []models.AssetLoan
+	Admins    []models.Admin           // akun admin aplikasi yang terhubung dengan karyawan
+	Clearance models.EmployeeClearance // offboarding terakhir yang sudah selesai
+}
+
+// Clear menandakan karyawan tidak lagi memegang aset maupun peminjaman aktif
+func (s offboardingStatus) Clear() bool {
+	return len(s.Assets) == 0 && len(s.Loans) == 0
+}
+
+// Checklist menyusun butir pemeriksaan offboarding untuk halaman dan surat keterangan bebas aset
+func (s offboardingStatus) Checklist() []offboardingCheck {
+	assets := offboardingCheck{Label: "Aset inventaris", Result: "Tidak ada aset yang masih dipegang", Done: true}
+	if len(s.Assets) > 0 {
+		assets = offboardingCheck{Label: "Aset inventaris", Result: fmt.Sprintf("%d aset masih dipegang", len(s.Assets))}
+	}
+	loans := offboardingCheck{Label: "Peminjaman aset", Result: "Tidak ada peminjaman aktif", Done: true}
+	if len(s.Loans) > 0 {
+		loans = offboardingCheck{Label: "Peminjaman aset", Result: fmt.Sprintf("%d peminjaman masih aktif", len(s.Loans))}
+	}
+
+	account := offboardingCheck{Label: "Akun admin aplikasi", Result: "Tidak memiliki akun admin", Done: true}
+	if len(s.Admins) > 0 {
+		var names []string
+		for _, admin := range s.Admins {
+			names = append(names, admin.Username)
+			if !admin.Disabled {
+				account.Done = false
+			}
+		}
+		account.Result = "Akun " + strings.Join(names, ", ") + " dinonaktifkan"
+		if !account.Done {
+			account.Result = "Akun " + strings.Join(names, ", ") + " akan dinonaktifkan saat offboarding selesai"
+		}
+	}
+	return []offboardingCheck{assets, loans, account}
+}
+
+// Completed menandakan offboarding sudah selesai dan karyawan masih berstatus resign
+func (s offboardingStatus) Completed(user models.User) bool {
+	return s.Clearance.ID != "" && user.StatusKaryawan == models.EmployeeStatusResign
+}
+
+// employeeOffboardingStatus memuat aset, peminjaman aktif, akun admin dan offboarding terakhir milik karyawan
+func (server *Server) employeeOffboardingStatus(user models.User) offboardingStatus {
+	var status offboardingStatus
+	server.DB.Where("user_id = ?", user.ID).Order("inventory_number asc").Find(&status.Assets)
+	server.DB.Preload("Items.Asset").
+		Where("borrower_id = ? AND status IN ?", user.ID, []string{models.LoanStatusPending, models.LoanStatusApproved, models.LoanStatusOnLoan}).
+		Order("start_date asc").Find(&status.Loans)
+	server.DB.Where("user_id = ?", user.ID).Find(&status.Admins)
+	server.DB.Where("user_id = ?", user.ID).Order("created_at desc").Limit(1).Find(&status.Clearance)
+	return status
+}
+
+// errOffboardingChanged membatalkan transaksi offboarding bila data karyawan berubah sejak diperiksa
+var errOffboardingChanged = errors.New("data offboarding berubah")
+
+// recheckOffboarding mengunci karyawan lalu memastikan status, aset, peminjaman aktif dan akun admin yang
+// terhubung masih sama dengan hasil pemeriksaan sebelum transaksi
+func (server *Server) recheckOffboarding(tx *gorm.DB, user models.User, status offboardingStatus) error {
+	var current models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", user.ID).Limit(1).Find(&current).Error; err != nil {
+		return err
+	}
+	var clearance models.EmployeeClearance
+	tx.Where("user_id = ?", user.ID).Order("created_at desc").Limit(1).Find(&clearance)
+	if current.ID == "" || current.StatusKaryawan != user.StatusKaryawan || clearance.ID != status.Clearance.ID {
+		return errOffboardingChanged
+	}
+
+	var assets, loans int64
+	tx.Model(&models.AssetKSO{}).Where("user_id = ?", user.ID).Count(&assets)
+	tx.Model(&models.AssetLoan{}).
+		Where("borrower_id = ? AND status IN ?", user.ID, []string{models.LoanStatusPending, models.LoanStatusApproved, models.LoanStatusOnLoan}).
+		Count(&loans)
+	if assets > 0 || loans > 0 {
+		return errOffboardingChanged
+	}
+
+	var adminIDs []string
+	tx.Model(&models.Admin{}).Where("user_id = ?", user.ID).Pluck("id", &adminIDs)
+	if len(adminIDs) != len(status.Admins) {
+		return errOffboardingChanged
+	}
+	for _, admin := range status.Admins {
+		if !slices.Contains(adminIDs, admin.ID) {
+			return errOffboardingChanged
+		}
+	}
+	return nil
+}
+
+// EmployeeOffboarding menampilkan aset dan peminjaman yang masih dipegang karyawan sebelum keluar/tukar perangkat
+func (server *Server) EmployeeOffboarding(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var user models.User
+	preloadUserOrg(server.DB, "").Where("id = ?", id).Limit(1).Find(&user)
+	if user.ID == "" {
+		http.Redirect(w, r, "/administration/employee?error=Karyawan tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(user) {
+		forbidScope(w)
+		return
+	}
+
+	status := server.employeeOffboardingStatus(user)
+
+	var clearanceFile models.DMSFile
+	if status.Clearance.FileID != nil {
+		server.DB.Where("id = ?", *status.Clearance.FileID).Limit(1).Find(&clearanceFile)
+	}
+
+	// Calon pemegang baru untuk aset yang dialihkan: karyawan aktif lain di dalam cakupan admin
+	var employees []models.User
+	if len(status.Assets) > 0 {
+		server.dataScope(r).applyUsers(server.DB).
+			Where("id <> ? AND (status_karyawan IS NULL OR status_karyawan <> ?)", user.ID, models.EmployeeStatusResign).
+			Order("name asc").Find(&employees)
+	}
+
+	server.RenderHTML(w, r, http.StatusOK, "administration/employee_offboarding", map[string]interface{}{
+		"title":         "Offboarding Karyawan",
+		"user":          user,
+		"assets":        status.Assets,
+		"loans":         status.Loans,
+		"checklist":     status.Checklist(),
+		"clear":         status.Clear(),
+		"completed":     status.Completed(user),
+		"clearance":     status.Clearance,
+		"clearanceFile": clearanceFile,
+		"employees":     employees,
+		"today":         time.Now().Format("2006-01-02"),
+		"msg":           r.URL.Query().Get("msg"),
+		"error":         r.URL.Query().Get("error"),
+	})
+}
+
+// ReassignOffboardingAsset mengalihkan aset yang masih dipegang karyawan yang keluar kepada karyawan lain
+func (server *Server) ReassignOffboardingAsset(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	back := "/administration/employee/offboarding/" + id
+
+	var asset models.AssetKSO
+	server.DB.Where("id = ? AND user_id = ?", r.FormValue("asset_id"), id).Limit(1).Find(&asset)
+	if asset.ID == "" {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Aset tidak ditemukan atau sudah tidak dipegang karyawan ini"), http.StatusSeeOther)
+		return
+	}
+	var target models.User
+	server.DB.Where("id = ?", r.FormValue("user_id")).Limit(1).Find(&target)
+	if target.ID == "" || target.ID == id || target.StatusKaryawan == models.EmployeeStatusResign {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Pilih karyawan aktif lain sebagai pemegang baru"), http.StatusSeeOther)
+		return
+	}
+	scope := server.dataScope(r)
+	if !server.assetAllowed(scope, asset) || !scope.allowsUser(target) {
+		forbidScope(w)
+		return
+	}
+
+	if err := server.DB.Model(&models.AssetKSO{}).Where("id = ? AND user_id = ?", asset.ID, id).Update("user_id", target.ID).Error; err != nil {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Gagal mengalihkan aset: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, back+"?msg="+url.QueryEscape("Aset "+asset.InventoryNumber+" dialihkan ke "+target.Name), http.StatusSeeOther)
+}
+
+// CompleteEmployeeOffboarding menyelesaikan offboarding: mencatat tanggal keluar, mengubah status menjadi Resign,
+// menonaktifkan akun admin yang terhubung dan membuat surat keterangan bebas aset untuk HR
+func (server *Server) CompleteEmployeeOffboarding(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	back := "/administration/employee/offboarding/" + id
+
+	var user models.User
+	preloadUserOrg(server.DB, "").Where("id = ?", id).Limit(1).Find(&user)
+	if user.ID == "" {
+		http.Redirect(w, r, "/administration/employee?error=Karyawan tidak ditemukan", http.StatusSeeOther)
+		return
+	}
+	if !server.dataScope(r).allowsUser(user) {
+		forbidScope(w)
+		return
+	}
+
+	status := server.employeeOffboardingStatus(user)
+	if status.Completed(user) {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Offboarding karyawan ini sudah selesai"), http.StatusSeeOther)
+		return
+	}
+	if !status.Clear() {
+		msg := fmt.Sprintf("Masih ada %d aset dan %d peminjaman aktif yang harus diselesaikan", len(status.Assets), len(status.Loans))
+		http.Redirect(w, r, back+"?error="+url.QueryEscape(msg), http.StatusSeeOther)
+		return
+	}
+	adminID, _, _, _ := GetCurrentAdmin(r)
+	var roles []string
+	for _, admin := range status.Admins {
+		if admin.ID == adminID {
+			http.Redirect(w, r, back+"?error="+url.QueryEscape("Karyawan ini terhubung dengan akun admin yang sedang Anda pakai"), http.StatusSeeOther)
+			return
+		}
+		roles = append(roles, admin.Role)
+	}
+	// Offboarding menonaktifkan akun admin yang terhubung, termasuk akun super_admin
+	if !server.canManageRoles(r, roles...) {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape(errSuperAdminOnly), http.StatusSeeOther)
+		return
+	}
+	resignDate, err := time.Parse("2006-01-02", r.FormValue("resign_date"))
+	if err != nil {
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Tanggal keluar wajib diisi"), http.StatusSeeOther)
+		return
+	}
+
+	now := time.Now()
+	clearance := models.EmployeeClearance{
+		ID:             uuid.New().String(),
+		UserID:         user.ID,
+		ResignDate:     resignDate,
+		Reason:         truncate(strings.TrimSpace(r.FormValue("reason")), 255),
+		PreviousStatus: user.StatusKaryawan,
+		AdminID:        adminID,
+		CreatedAt:      now,
+	}
+	// Akun admin dinonaktifkan bersamaan dengan offboarding sehingga surat mencatat kondisi akhirnya
+	for i := range status.Admins {
+		status.Admins[i].Disabled = true
+	}
+
+	signer := server.adminUser(adminID)
+	root := server.ensureFolder("Laporan Digital", nil, "#3b82f6")
+	folder := server.ensureFolder("Offboarding Karyawan", &root.ID, "#64748b")
+	fileID := uuid.New().String()
+	uploadDir := filepath.Join("public", "uploads", "edoc")
+	os.MkdirAll(uploadDir, 0755)
+	physicalPath := filepath.Join(uploadDir, fileID+".pdf")
+
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := server.recheckOffboarding(tx, user, status); err != nil {
+			return err
+		}
+		number, err := nextDocNumber(tx, "clearance", user.Branch.Name, now)
+		if err != nil {
+			return err
+		}
+		clearance.Number = number
+		stamp := server.newPDFStamp(number, "SURAT KETERANGAN BEBAS ASET", user.Name, now)
+		if err := server.GenerateClearancePDF(user, clearance, status.Checklist(), signer, stamp, physicalPath); err != nil {
+			return err
+		}
+		fileName := fmt.Sprintf("Surat_Bebas_Aset_%s_%s.pdf", user.Name, now.Format("20060102_150405"))
+		file, err := server.storeGeneratedPDF(tx, fileID, folder, fileName, "Offboarding Karyawan", stamp)
+		if err != nil {
+			return err
+		}
+		clearance.FileID = &file.ID
+		if err := tx.Create(&clearance).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"status_karyawan":  models.EmployeeStatusResign,
+			"resign_date":      resignDate,
+			"offboarding_note": "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Admin{}).Where("user_id = ?", user.ID).Update("disabled", true).Error
+	})
+	if errors.Is(err, errOffboardingChanged) {
+		os.Remove(physicalPath)
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Data offboarding berubah saat diproses (aset, peminjaman atau akun admin). Periksa kembali lalu ulangi"), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		os.Remove(physicalPath)
+		http.Redirect(w, r, back+"?error="+url.QueryEscape("Gagal menyelesaikan offboarding: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+	for _, admin := range status.Admins {
+		revokeAdminSessions(server.DB, admin.ID)
+	}
+
+	http.Redirect(w, r, back+"?msg="+url.QueryEscape("Offboarding selesai. Surat keterangan bebas aset disimpan ke eDoc."), http.StatusSeeOther)
+}
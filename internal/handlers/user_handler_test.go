@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestCompleteEmployeeOffboardingProtectsSuperAdmin(t *testing.T) {
+	server := newTestServer(t)
+	createRole(t, server, "hr", "dashboard.view", "employee.offboarding")
+	client := loginAs(t, server, scopedAdmin(t, server, "h1", "hr"))
+	server.DB.Model(&models.Admin{}).Where("id = ?", "a1").Update("user_id", "u1")
+
+	w := client.do(http.MethodPost, "/administration/employee/offboarding/u1/complete", url.Values{"resign_date": {"2026-01-31"}})
+	if msg := redirectError(t, w.Header().Get("Location")); msg != errSuperAdminOnly {
+		t.Fatalf("error = %q, want %q", msg, errSuperAdminOnly)
+	}
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", "a1")
+	if admin.Disabled {
+		t.Error("super_admin account was disabled")
+	}
+	var user models.User
+	server.DB.First(&user, "id = ?", "u1")
+	if user.StatusKaryawan == models.EmployeeStatusResign {
+		t.Error("employee was marked resigned")
+	}
+}
+
+func TestRecheckOffboardingDetectsChanges(t *testing.T) {
+	server := newTestServer(t)
+	var user models.User
+	server.DB.First(&user, "id = ?", "u1")
+	status := server.employeeOffboardingStatus(user)
+
+	recheck := func() error {
+		return server.DB.Transaction(func(tx *gorm.DB) error {
+			return server.recheckOffboarding(tx, user, status)
+		})
+	}
+	if err := recheck(); err != nil {
+		t.Fatalf("unchanged offboarding: %v", err)
+	}
+
+	// Aset diserahkan ke karyawan setelah halaman offboarding diperiksa
+	server.DB.Create(&models.AssetKSO{ID: "as-late", InventoryNumber: "INV-LATE", AssetName: "Laptop", Category: "Laptop", UserID: &user.ID, Status: "Ready"})
+	if err := recheck(); !errors.Is(err, errOffboardingChanged) {
+		t.Errorf("new asset: err = %v, want %v", err, errOffboardingChanged)
+	}
+	server.DB.Delete(&models.AssetKSO{}, "id = ?", "as-late")
+
+	// Akun admin baru dihubungkan ke karyawan setelah pemeriksaan
+	server.DB.Create(&models.Admin{ID: "late", Username: "late", Password: "x", Role: models.RoleSuperAdmin, UserID: user.ID})
+	if err := recheck(); !errors.Is(err, errOffboardingChanged) {
+		t.Errorf("new linked admin: err = %v, want %v", err, errOffboardingChanged)
+	}
+}
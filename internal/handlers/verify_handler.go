@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// pdfStamp adalah identitas verifikasi yang dicetak (QR code + hash) di setiap PDF hasil generate
+type pdfStamp struct {
+	ID          string
+	DocNumber   string
+	Title       string
+	IssuedTo    string
+	IssuedAt    time.Time
+	Fingerprint string
+	URL         string
+	Draft       bool // pratinjau: tanpa QR verifikasi dan tanda tangan digital, diberi watermark DRAFT
+}
+
+// newPDFStamp menyiapkan identitas verifikasi baru untuk dokumen yang akan digenerate
+func (server *Server) newPDFStamp(docNumber, title, issuedTo string, issuedAt time.Time) pdfStamp {
+	stamp := pdfStamp{
+		ID:        uuid.New().String(),
+		DocNumber: docNumber,
+		Title:     strings.Join(strings.Fields(title), " "),
+		IssuedTo:  issuedTo,
+		IssuedAt:  issuedAt,
+	}
+	stamp.Fingerprint = docFingerprint(stamp.ID, stamp.DocNumber, stamp.Title, stamp.IssuedTo, stamp.IssuedAt)
+	stamp.URL = server.AppURL + "/verify/" + stamp.ID
+	return stamp
+}
+
+// stampOf membangun ulang identitas verifikasi yang sudah tersimpan, dipakai saat PDF dicetak ulang
+func (server *Server) stampOf(v models.DocumentVerification) pdfStamp {
+	return pdfStamp{
+		ID:          v.ID,
+		DocNumber:   v.DocNumber,
+		Title:       v.Title,
+		IssuedTo:    v.IssuedTo,
+		IssuedAt:    v.IssuedAt,
+		Fingerprint: v.Fingerprint,
+		URL:         server.AppURL + "/verify/" + v.ID,
+	}
+}
+
+// docFingerprint menghitung hash metadata dokumen yang dicetak di PDF
+func docFingerprint(id, docNumber, title, issuedTo string, issuedAt time.Time) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{id, docNumber, title, issuedTo, issuedAt.UTC().Format(time.RFC3339)}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// fileSHA256 menghitung SHA-256 isi file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recordVerification menyimpan identitas verifikasi beserta hash file PDF yang sudah ditulis
+func recordVerification(tx *gorm.DB, stamp pdfStamp, fileID string) error {
+	fileHash, err := fileSHA256(filepath.Join("public", "uploads", "edoc", fileID+".pdf"))
+	if err != nil {
+		return err
+	}
+
+	return tx.Save(&models.DocumentVerification{
+		ID:          stamp.ID,
+		FileID:      fileID,
+		DocNumber:   stamp.DocNumber,
+		Title:       stamp.Title,
+		IssuedTo:    stamp.IssuedTo,
+		IssuedAt:    stamp.IssuedAt,
+		Fingerprint: stamp.Fingerprint,
+		FileHash:    fileHash,
+	}).Error
+}
+
+// VerifyDocument menampilkan halaman publik verifikasi keaslian dokumen tanpa menampilkan isi dokumen
+func (server *Server) VerifyDocument(w http.ResponseWriter, r *http.Request) {
+	server.renderVerification(w, r, "")
+}
+
+// verifyUploadLimit membatasi ukuran request unggah di halaman verifikasi publik
+const verifyUploadLimit = 50 << 20 // 50MB
+
+// VerifyDocumentUpload mencocokkan file PDF yang diunggah dengan hash dokumen asli
+func (server *Server) VerifyDocumentUpload(w http.ResponseWriter, r *http.Request) {
+	// Halaman ini publik: batasi body sebelum multipart dibaca agar file besar tidak memenuhi disk sementara
+	r.Body = http.MaxBytesReader(w, r.Body, verifyUploadLimit)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		server.renderVerification(w, r, "")
+		return
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		server.renderVerification(w, r, "")
+		return
+	}
+	server.renderVerification(w, r, hex.EncodeToString(h.Sum(nil)))
+}
+
+// renderVerification memeriksa hash file tersimpan dan menampilkan hasil verifikasi
+func (server *Server) renderVerification(w http.ResponseWriter, r *http.Request, uploadedHash string) {
+	var v models.DocumentVerification
+	server.DB.Where("id = ?", mux.Vars(r)["id"]).Limit(1).Find(&v)
+
+	data := map[string]interface{}{
+		"title":        "Verifikasi Dokumen",
+		"found":        v.ID != "",
+		"doc":          v,
+		"uploadedHash": uploadedHash,
+	}
+
+	if v.ID != "" {
+		// Dokumen yang sudah dihapus/di-trash tetap bisa diverifikasi selama file fisiknya masih ada
+		currentHash, err := fileSHA256(filepath.Join("public", "uploads", "edoc", v.FileID+".pdf"))
+		data["fileAvailable"] = err == nil
+		data["intact"] = err == nil && currentHash == v.FileHash
+		data["uploadMatches"] = uploadedHash != "" && uploadedHash == v.FileHash
+
+		var file models.DMSFile
+		server.DB.Unscoped().Where("id = ?", v.FileID).Limit(1).Find(&file)
+		data["revoked"] = file.ID == "" || file.DeletedAt.Valid || file.TrashedAt != nil
+	}
+
+	status := http.StatusOK
+	if v.ID == "" {
+		status = http.StatusNotFound
+	}
+	server.RenderHTML(w, r, status, "verify/show", data)
+}
@@ -0,0 +1,619 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/webdav"
+)
+
+type davAdminKey struct{}
+
+type davUploadKey struct{}
+
+// davUpload mencatat kesalahan saat body PUT dibaca, sehingga file yang terputus tidak disimpan
+type davUpload struct {
+	err error
+}
+
+// davUploadBody membungkus body PUT dan mencatat kesalahan baca selain akhir body
+type davUploadBody struct {
+	io.ReadCloser
+	upload *davUpload
+}
+
+func (b *davUploadBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		b.upload.err = err
+	}
+	return n, err
+}
+
+// WebDAVHandler membuat handler WebDAV di bawah /dav/ yang memetakan pohon DMSFolder/DMSFile
+func (server *Server) WebDAVHandler() http.Handler {
+	fs := &dmsFileSystem{server: server}
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: fs,
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				log.Printf("[WebDAV] %s %s: %v", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+	return server.davAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			// Dokumen terverifikasi tidak boleh ditimpa karena hash-nya dicocokkan di halaman verifikasi
+			if node, err := fs.resolve(strings.TrimPrefix(r.URL.Path, "/dav")); err == nil && node.file != nil && fs.verifiedDocument(node.file.ID) {
+				http.Error(w, "Forbidden: dokumen terverifikasi tidak dapat ditimpa", http.StatusForbidden)
+				return
+			}
+			upload := &davUpload{}
+			r.Body = &davUploadBody{ReadCloser: r.Body, upload: upload}
+			r = r.WithContext(context.WithValue(r.Context(), davUploadKey{}, upload))
+		}
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+// davAuth memverifikasi kredensial HTTP Basic (token WebDAV, atau password untuk akun tanpa 2FA) milik admin.
+// Jeda percobaan, penguncian akun dan log login sama dengan form login sehingga WebDAV tidak dapat
+// dipakai untuk menebak password.
+func (server *Server) davAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			davUnauthorized(w)
+			return
+		}
+
+		var admin models.Admin
+		server.DB.Where("username = ?", username).Limit(1).Find(&admin)
+
+		if wait := server.loginRetryAfter(username, clientIP(r), &admin); wait > 0 {
+			server.recordLoginAttempt(r, username, admin.ID, false, loginReasonRateLimited)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		if admin.ID == "" {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			server.recordLoginAttempt(r, username, "", false, loginReasonUnknownUser)
+			davUnauthorized(w)
+			return
+		}
+		if server.accountLockedFor(&admin) > 0 {
+			server.recordLoginAttempt(r, username, admin.ID, false, loginReasonLocked)
+			davUnauthorized(w)
+			return
+		}
+
+		valid := admin.WebDAVToken != "" && bcrypt.CompareHashAndPassword([]byte(admin.WebDAVToken), []byte(password)) == nil
+		// Password akun hanya berlaku untuk akun tanpa 2FA; akun dengan 2FA wajib memakai token WebDAV
+		// karena Basic auth tidak dapat meminta kode langkah kedua
+		if !valid && server.davAcceptsPassword(admin) {
+			valid = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)) == nil
+		}
+		if !valid {
+			server.registerLoginFailure(&admin)
+			server.recordLoginAttempt(r, username, admin.ID, false, loginReasonPassword)
+			davUnauthorized(w)
+			return
+		}
+		if admin.Disabled {
+			server.recordLoginAttempt(r, username, admin.ID, false, loginReasonNoAccess)
+			davUnauthorized(w)
+			return
+		}
+
+		// Klien WebDAV mengirim kredensial di setiap request; keberhasilan hanya dicatat bila sebelumnya ada
+		// kegagalan atau belum ada login WebDAV dalam jendela percobaan agar log tidak dibanjiri
+		if admin.FailedLogins > 0 || admin.LastFailedLoginAt != nil {
+			server.resetLoginFailures(&admin)
+			server.recordLoginAttempt(r, username, admin.ID, true, loginReasonDAVSuccess)
+		} else if !server.recentDAVLogin(admin.ID) {
+			server.recordLoginAttempt(r, username, admin.ID, true, loginReasonDAVSuccess)
+		}
+
+		if !server.hasPermission(admin.Role, davAction(r.Method)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		ctx := context.WithValue(r.Context(), davAdminKey{}, admin.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// davUnauthorized meminta klien WebDAV mengirim kredensial Basic
+func davUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="gokso DMS"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// recentDAVLogin memeriksa apakah login WebDAV admin sudah tercatat dalam jendela percobaan
+func (server *Server) recentDAVLogin(adminID string) bool {
+	var count int64
+	server.DB.Model(&models.LoginAttempt{}).
+		Where("admin_id = ? AND success = ? AND reason = ? AND created_at > ?", adminID, true, loginReasonDAVSuccess, time.Now().Add(-loginAttemptWindow)).
+		Count(&count)
+	return count > 0
+}
+
+// davAcceptsPassword menandakan akun boleh masuk WebDAV dengan password akunnya. Akun dengan 2FA aktif
+// atau yang role-nya mewajibkan 2FA hanya dapat memakai token WebDAV dari halaman profil.
+func (server *Server) davAcceptsPassword(admin models.Admin) bool {
+	return !admin.TOTPEnabled && !server.requires2FA(admin.Role)
+}
+
+// davAction memetakan metode WebDAV ke izin GoDMS; DELETE hanya memindahkan ke sampah
+func davAction(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND":
+		return "dms.view"
+	case http.MethodDelete:
+		return "dms.delete"
+	default:
+		return "dms.upload"
+	}
+}
+
+// GenerateWebDAVToken membuat token WebDAV baru untuk admin yang sedang login dan menampilkannya sekali
+func (server *Server) GenerateWebDAVToken(w http.ResponseWriter, r *http.Request) {
+	adminID, _, _, _ := GetCurrentAdmin(r)
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		http.Redirect(w, r, "/profile?error=Gagal membuat token WebDAV", http.StatusSeeOther)
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
+	server.DB.Model(&models.Admin{}).Where("id = ?", adminID).Update("webdav_token", string(hashed))
+
+	var admin models.Admin
+	server.DB.First(&admin, "id = ?", adminID)
+
+	server.RenderHTML(w, r, http.StatusOK, "auth/profile", map[string]interface{}{
+		"title":    "My Profile",
+		"admin":    admin,
+		"davToken": token,
+		"sessions": server.adminSessions(r, adminID),
+		"msg":      "Token WebDAV berhasil dibuat. Simpan token ini, token tidak akan ditampilkan lagi.",
+	})
+}
+
+// dmsFileSystem mengimplementasikan webdav.FileSystem di atas tabel dms_folders dan dms_files
+type dmsFileSystem struct {
+	server *Server
+}
+
+// dmsNode adalah hasil resolusi path WebDAV; folder dan file nil berarti root
+type dmsNode struct {
+	folder *models.DMSFolder
+	file   *models.DMSFile
+}
+
+func (n dmsNode) isRoot() bool {
+	return n.folder == nil && n.file == nil
+}
+
+// folderID mengembalikan ID folder node (nil untuk root)
+func (n dmsNode) folderID() *string {
+	if n.folder == nil {
+		return nil
+	}
+	return &n.folder.ID
+}
+
+func (n dmsNode) info() os.FileInfo {
+	switch {
+	case n.file != nil:
+		return dmsFileInfo{name: n.file.Name, size: n.file.Size, modTime: n.file.UpdatedAt}
+	case n.folder != nil:
+		return dmsFileInfo{name: n.folder.Name, modTime: n.folder.UpdatedAt, isDir: true}
+	default:
+		return dmsFileInfo{name: "/", modTime: time.Now(), isDir: true}
+	}
+}
+
+// findFolder mencari subfolder aktif (bukan sampah/sistem) berdasarkan nama di bawah parentID
+func (fs *dmsFileSystem) findFolder(name string, parentID *string) (*models.DMSFolder, error) {
+	var folder models.DMSFolder
+	query := fs.server.DB.Where("name = ? AND trashed_at IS NULL AND is_system = ?", name, false)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	query.Limit(1).Find(&folder)
+	if folder.ID == "" {
+		return nil, os.ErrNotExist
+	}
+	return &folder, nil
+}
+
+// findFile mencari file aktif berdasarkan nama di dalam folderID
+func (fs *dmsFileSystem) findFile(name string, folderID *string) (*models.DMSFile, error) {
+	var file models.DMSFile
+	query := fs.server.DB.Where("name = ? AND trashed_at IS NULL", name)
+	if folderID == nil {
+		query = query.Where("folder_id IS NULL")
+	} else {
+		query = query.Where("folder_id = ?", *folderID)
+	}
+	query.Limit(1).Find(&file)
+	if file.ID == "" {
+		return nil, os.ErrNotExist
+	}
+	return &file, nil
+}
+
+// resolve menerjemahkan path WebDAV (mis. /Dokumen Legal/SOP.pdf) menjadi folder atau file DMS
+func (fs *dmsFileSystem) resolve(name string) (dmsNode, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return dmsNode{}, nil
+	}
+
+	segments := strings.Split(name, "/")
+	var parentID *string
+	for i, seg := range segments {
+		folder, err := fs.findFolder(seg, parentID)
+		if err == nil {
+			if i == len(segments)-1 {
+				return dmsNode{folder: folder}, nil
+			}
+			parentID = &folder.ID
+			continue
+		}
+		if i == len(segments)-1 {
+			file, err := fs.findFile(seg, parentID)
+			if err != nil {
+				return dmsNode{}, err
+			}
+			return dmsNode{file: file}, nil
+		}
+		return dmsNode{}, os.ErrNotExist
+	}
+	return dmsNode{}, os.ErrNotExist
+}
+
+// resolveParent mengembalikan folder induk dari sebuah path beserta nama elemen terakhirnya
+func (fs *dmsFileSystem) resolveParent(name string) (dmsNode, string, error) {
+	name = path.Clean("/" + name)
+	parent, err := fs.resolve(path.Dir(name))
+	if err != nil {
+		return dmsNode{}, "", err
+	}
+	if parent.file != nil {
+		return dmsNode{}, "", os.ErrInvalid
+	}
+	return parent, path.Base(name), nil
+}
+
+// physicalPath mengubah FilePath (format URL) menjadi path penyimpanan fisik
+func (fs *dmsFileSystem) physicalPath(file *models.DMSFile) string {
+	return filepath.Join("public", "uploads", "edoc", filepath.Base(file.FilePath))
+}
+
+// verifiedDocument memeriksa apakah file memiliki DocumentVerification. Isi file seperti itu tidak boleh
+// diganti agar hash-nya tetap cocok dengan yang tercatat.
+func (fs *dmsFileSystem) verifiedDocument(fileID string) bool {
+	var count int64
+	fs.server.DB.Model(&models.DocumentVerification{}).Where("file_id = ?", fileID).Count(&count)
+	return count > 0
+}
+
+// createUploadTemp membuat file sementara di folder upload. Isinya baru menggantikan file tujuan saat
+// dmsWriteFile ditutup tanpa kesalahan.
+func createUploadTemp(uploadDir string) (*os.File, error) {
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(uploadDir, ".webdav-*.tmp")
+}
+
+func (fs *dmsFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.resolve(name); err == nil {
+		return os.ErrExist
+	}
+	_, err = fs.server.createFolder(base, parent.folderID())
+	return err
+}
+
+func (fs *dmsFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	node, err := fs.resolve(name)
+	if err == nil {
+		if node.file == nil {
+			if writable {
+				return nil, os.ErrPermission
+			}
+			return &dmsDir{fs: fs, node: node}, nil
+		}
+		if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+			return nil, os.ErrExist
+		}
+		if !writable {
+			f, err := os.Open(fs.physicalPath(node.file))
+			if err != nil {
+				return nil, err
+			}
+			return &dmsReadFile{File: f, info: node.info()}, nil
+		}
+		if fs.verifiedDocument(node.file.ID) {
+			return nil, os.ErrPermission
+		}
+		f, err := createUploadTemp(filepath.Join("public", "uploads", "edoc"))
+		if err != nil {
+			return nil, err
+		}
+		upload, _ := ctx.Value(davUploadKey{}).(*davUpload)
+		return &dmsWriteFile{File: f, fs: fs, record: *node.file, target: fs.physicalPath(node.file), upload: upload}, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) || flag&os.O_CREATE == 0 {
+		return nil, err
+	}
+
+	// File baru: pastikan folder induk ada, lalu simpan di lokasi upload DMS yang sama
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadDir := filepath.Join("public", "uploads", "edoc")
+	f, err := createUploadTemp(uploadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID := uuid.New().String()
+	ext := filepath.Ext(base)
+	physicalName := fileID + ext
+
+	uploadedBy, _ := ctx.Value(davAdminKey{}).(string)
+	record := models.DMSFile{
+		ID:         fileID,
+		FolderID:   parent.folderID(),
+		Name:       base,
+		Extension:  strings.TrimPrefix(ext, "."),
+		FilePath:   "/public/uploads/edoc/" + physicalName,
+		UploadedBy: uploadedBy,
+		Category:   "WebDAV",
+	}
+	upload, _ := ctx.Value(davUploadKey{}).(*davUpload)
+	return &dmsWriteFile{File: f, fs: fs, record: record, isNew: true, target: filepath.Join(uploadDir, physicalName), upload: upload}, nil
+}
+
+// RemoveAll memindahkan folder atau file ke tempat sampah (bukan hapus permanen)
+func (fs *dmsFileSystem) RemoveAll(ctx context.Context, name string) error {
+	node, err := fs.resolve(name)
+	if err != nil {
+		return err
+	}
+	if node.isRoot() {
+		return os.ErrPermission
+	}
+
+	now := time.Now()
+	if node.file != nil {
+		return fs.server.DB.Model(&models.DMSFile{}).Where("id = ?", node.file.ID).Update("trashed_at", &now).Error
+	}
+	return fs.server.DB.Model(&models.DMSFolder{}).Where("id = ?", node.folder.ID).Update("trashed_at", &now).Error
+}
+
+// Rename mengganti nama dan/atau memindahkan folder atau file ke folder lain
+func (fs *dmsFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	node, err := fs.resolve(oldName)
+	if err != nil {
+		return err
+	}
+	if node.isRoot() {
+		return os.ErrPermission
+	}
+
+	parent, base, err := fs.resolveParent(newName)
+	if err != nil {
+		return err
+	}
+	targetID := parent.folderID()
+
+	if node.file != nil {
+		return fs.server.DB.Model(&models.DMSFile{}).Where("id = ?", node.file.ID).Updates(map[string]interface{}{
+			"name":      base,
+			"folder_id": targetID,
+			"extension": strings.TrimPrefix(filepath.Ext(base), "."),
+		}).Error
+	}
+
+	// Cegah memindahkan folder ke dalam dirinya sendiri atau subfoldernya
+	for _, crumb := range fs.server.getEDocBreadcrumb(derefString(targetID)) {
+		if crumb.ID == node.folder.ID {
+			return os.ErrInvalid
+		}
+	}
+
+	return fs.server.DB.Model(&models.DMSFolder{}).Where("id = ?", node.folder.ID).Updates(map[string]interface{}{
+		"name":      base,
+		"parent_id": targetID,
+	}).Error
+}
+
+func (fs *dmsFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	node, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return node.info(), nil
+}
+
+// dmsFileInfo adalah os.FileInfo untuk folder/file DMS dengan nama tampilan dari database
+type dmsFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (fi dmsFileInfo) Name() string       { return fi.name }
+func (fi dmsFileInfo) Size() int64        { return fi.size }
+func (fi dmsFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi dmsFileInfo) IsDir() bool        { return fi.isDir }
+func (fi dmsFileInfo) Sys() interface{}   { return nil }
+
+func (fi dmsFileInfo) Mode() os.FileMode {
+	if fi.isDir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// dmsDir adalah webdav.File untuk root dan folder DMS
+type dmsDir struct {
+	fs     *dmsFileSystem
+	node   dmsNode
+	offset int
+}
+
+func (d *dmsDir) Close() error                                 { return nil }
+func (d *dmsDir) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (d *dmsDir) Write(p []byte) (int, error)                  { return 0, os.ErrInvalid }
+func (d *dmsDir) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (d *dmsDir) Stat() (os.FileInfo, error)                   { return d.node.info(), nil }
+
+func (d *dmsDir) Readdir(count int) ([]os.FileInfo, error) {
+	parentID := d.node.folderID()
+
+	var folders []models.DMSFolder
+	var files []models.DMSFile
+	folderQuery := d.fs.server.DB.Where("trashed_at IS NULL AND is_system = ?", false)
+	fileQuery := d.fs.server.DB.Where("trashed_at IS NULL")
+	if parentID == nil {
+		folderQuery = folderQuery.Where("parent_id IS NULL")
+		fileQuery = fileQuery.Where("folder_id IS NULL")
+	} else {
+		folderQuery = folderQuery.Where("parent_id = ?", *parentID)
+		fileQuery = fileQuery.Where("folder_id = ?", *parentID)
+	}
+	folderQuery.Order("name ASC").Find(&folders)
+	fileQuery.Order("name ASC").Find(&files)
+
+	var infos []os.FileInfo
+	for i := range folders {
+		infos = append(infos, dmsNode{folder: &folders[i]}.info())
+	}
+	for i := range files {
+		infos = append(infos, dmsNode{file: &files[i]}.info())
+	}
+
+	if d.offset >= len(infos) {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	infos = infos[d.offset:]
+	if count > 0 && count < len(infos) {
+		infos = infos[:count]
+	}
+	d.offset += len(infos)
+	return infos, nil
+}
+
+// dmsReadFile membungkus file fisik agar Stat mengembalikan nama file DMS
+type dmsReadFile struct {
+	*os.File
+	info os.FileInfo
+}
+
+func (f *dmsReadFile) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f *dmsReadFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+
+func (f *dmsReadFile) Write(p []byte) (int, error) { return 0, os.ErrPermission }
+
+// dmsWriteFile menulis ke file sementara; saat ditutup file itu menggantikan file fisik tujuan dan
+// metadata DMSFile disimpan. Upload yang gagal atau terputus dibuang tanpa mengubah file lama.
+type dmsWriteFile struct {
+	*os.File
+	fs     *dmsFileSystem
+	record models.DMSFile
+	isNew  bool
+	target string     // path fisik file tujuan
+	upload *davUpload // nil bila tidak dibuka oleh PUT
+}
+
+func (f *dmsWriteFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return dmsFileInfo{name: f.record.Name, size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+func (f *dmsWriteFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+
+func (f *dmsWriteFile) Close() error {
+	temp := f.File.Name()
+	info, err := f.File.Stat()
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && f.upload != nil && f.upload.err != nil {
+		err = f.upload.err
+	}
+	if err == nil {
+		err = os.Chmod(temp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(temp, f.target)
+	}
+	if err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	f.record.Size = info.Size()
+	if f.isNew {
+		if err := f.fs.server.DB.Create(&f.record).Error; err != nil {
+			os.Remove(f.target)
+			return err
+		}
+		return nil
+	}
+	return f.fs.server.DB.Model(&models.DMSFile{}).Where("id = ?", f.record.ID).Updates(map[string]interface{}{
+		"size":       f.record.Size,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AbsoluteZero24/gokso/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// davClient membuat admin dengan password bcrypt untuk Basic auth WebDAV
+func davClient(tb testing.TB, server *Server) models.Admin {
+	tb.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("dav-secret"), bcrypt.MinCost)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	admin := models.Admin{ID: "d1", Username: "dav", Password: string(hashed), Role: models.RoleSuperAdmin}
+	server.DB.Create(&admin)
+	return admin
+}
+
+// davPut mengirim PUT WebDAV dengan body yang diberikan
+func davPut(server *Server, path string, body io.Reader) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPut, path, body)
+	r.SetBasicAuth("dav", "dav-secret")
+	w := httptest.NewRecorder()
+	server.Router.ServeHTTP(w, r)
+	return w
+}
+
+// failingReader mengirim sebagian isi lalu terputus
+type failingReader struct{ sent bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.sent {
+		return 0, errors.New("connection reset")
+	}
+	r.sent = true
+	return copy(p, "partial"), nil
+}
+
+// existingDAVFile menyiapkan file DMS di root beserta isi fisiknya
+func existingDAVFile(tb testing.TB, server *Server, content string) models.DMSFile {
+	tb.Helper()
+	file := models.DMSFile{ID: "f1", Name: "laporan.txt", Extension: "txt", FilePath: "/public/uploads/edoc/f1.txt", Size: int64(len(content))}
+	server.DB.Create(&file)
+	if err := os.WriteFile(filepath.Join("public", "uploads", "edoc", "f1.txt"), []byte(content), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { os.Remove(filepath.Join("public", "uploads", "edoc", "f1.txt")) })
+	return file
+}
+
+// readUpload membaca isi file fisik di folder upload DMS
+func readUpload(tb testing.TB, name string) string {
+	tb.Helper()
+	data, err := os.ReadFile(filepath.Join("public", "uploads", "edoc", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(data)
+}
+
+// uploadTemps menghitung file sementara WebDAV yang tertinggal
+func uploadTemps(tb testing.TB) int {
+	tb.Helper()
+	matches, err := filepath.Glob(filepath.Join("public", "uploads", "edoc", ".webdav-*.tmp"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return len(matches)
+}
+
+func TestWebDAVPutCreatesFile(t *testing.T) {
+	server := newTestServer(t)
+	davClient(t, server)
+
+	if w := davPut(server, "/dav/baru.txt", strings.NewReader("isi baru")); w.Code != http.StatusCreated {
+		t.Fatalf("PUT = %d, want %d", w.Code, http.StatusCreated)
+	}
+	var file models.DMSFile
+	if err := server.DB.Where("name = ?", "baru.txt").First(&file).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(filepath.Join("public", "uploads", "edoc", filepath.Base(file.FilePath))) })
+	if got := readUpload(t, filepath.Base(file.FilePath)); got != "isi baru" {
+		t.Errorf("content = %q, want %q", got, "isi baru")
+	}
+	if file.Size != int64(len("isi baru")) || file.UploadedBy != "d1" {
+		t.Errorf("size = %d, uploaded_by = %q", file.Size, file.UploadedBy)
+	}
+	if n := uploadTemps(t); n != 0 {
+		t.Errorf("%d temp files left", n)
+	}
+}
+
+func TestWebDAVPutOverwritesFile(t *testing.T) {
+	server := newTestServer(t)
+	davClient(t, server)
+	existingDAVFile(t, server, "lama")
+
+	if w := davPut(server, "/dav/laporan.txt", strings.NewReader("isi yang lebih baru")); w.Code != http.StatusCreated {
+		t.Fatalf("PUT = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if got := readUpload(t, "f1.txt"); got != "isi yang lebih baru" {
+		t.Errorf("content = %q", got)
+	}
+	var file models.DMSFile
+	server.DB.First(&file, "id = ?", "f1")
+	if file.Size != int64(len("isi yang lebih baru")) {
+		t.Errorf("size = %d", file.Size)
+	}
+}
+
+func TestWebDAVInterruptedPutKeepsOriginal(t *testing.T) {
+	server := newTestServer(t)
+	davClient(t, server)
+	existingDAVFile(t, server, "lama")
+
+	if w := davPut(server, "/dav/laporan.txt", &failingReader{}); w.Code < 400 {
+		t.Fatalf("interrupted PUT = %d, want an error", w.Code)
+	}
+	if got := readUpload(t, "f1.txt"); got != "lama" {
+		t.Errorf("content = %q, want original", got)
+	}
+
+	if w := davPut(server, "/dav/putus.txt", &failingReader{}); w.Code < 400 {
+		t.Fatalf("interrupted PUT of new file = %d, want an error", w.Code)
+	}
+	var count int64
+	server.DB.Model(&models.DMSFile{}).Where("name = ?", "putus.txt").Count(&count)
+	if count != 0 {
+		t.Error("interrupted upload was recorded")
+	}
+	if n := uploadTemps(t); n != 0 {
+		t.Errorf("%d temp files left", n)
+	}
+}
+
+func TestWebDAVPutRejectsVerifiedDocument(t *testing.T) {
+	server := newTestServer(t)
+	davClient(t, server)
+	file := existingDAVFile(t, server, "asli")
+	server.DB.Create(&models.DocumentVerification{ID: "v1", FileID: file.ID, Fingerprint: "fp"})
+
+	if w := davPut(server, "/dav/laporan.txt", strings.NewReader("palsu")); w.Code != http.StatusForbidden {
+		t.Fatalf("PUT = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got := readUpload(t, "f1.txt"); got != "asli" {
+		t.Errorf("verified content changed to %q", got)
+	}
+}
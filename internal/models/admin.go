@@ -5,13 +5,29 @@ import (
 )
 
 type Admin struct {
-	ID        string `gorm:"size:36;not null;uniqueIndex;primaryKey"`
-	UserID    string `gorm:"size:36;index"` // link to User model
-	Username  string `gorm:"size:50;not null;uniqueIndex"`
-	Password  string `gorm:"size:255;not null"` // hashed password with bcrypt
-	Role      string `gorm:"size:50;not null"`  // super_admin, asset_manager
-	Avatar    string `gorm:"size:255"`          // profile picture filename
-	Signature string `gorm:"size:255"`          // signature picture filename
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID          string `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	UserID      string `gorm:"size:36;index"` // link to User model
+	Username    string `gorm:"size:50;not null;uniqueIndex"`
+	Password    string `gorm:"size:255;not null"`            // hashed password with bcrypt
+	Role        string `gorm:"size:50;not null"`             // super_admin, asset_manager
+	Avatar      string `gorm:"size:255"`                     // profile picture filename
+	Signature   string `gorm:"size:255"`                     // signature picture filename
+	WebDAVToken string `gorm:"column:webdav_token;size:255"` // hashed token for WebDAV clients
+
+	TOTPSecret     string `gorm:"column:totp_secret;size:64"`        // secret TOTP (base32); berlaku bila TOTPEnabled
+	TOTPEnabled    bool   `gorm:"column:totp_enabled;default:false"` // verifikasi dua langkah aktif
+	TOTPLastStep   int64  `gorm:"column:totp_last_step"`             // langkah waktu kode terakhir, mencegah kode dipakai ulang
+	RecoveryCodes  string `gorm:"type:text"`                         // hash SHA-256 kode pemulihan yang belum terpakai, satu per baris
+	LoginChallenge string `gorm:"size:64"`                           // hash SHA-256 nonce langkah kedua login yang sedang berjalan
+
+	FailedLogins      int        `gorm:"default:0"` // login gagal berturut-turut dalam jendela percobaan
+	LastFailedLoginAt *time.Time // waktu login gagal terakhir
+	LockedUntil       *time.Time `gorm:"index"` // akun dikunci sementara hingga waktu ini
+
+	AuthSource  string `gorm:"size:20;default:'local'"`            // local (password bcrypt) atau ldap (bind ke direktori)
+	DirectoryDN string `gorm:"size:255"`                           // DN entri direktori untuk akun ldap
+	OIDCSubject string `gorm:"column:oidc_subject;size:255;index"` // claim sub dari identity provider SSO yang tertaut
+	Disabled    bool   `gorm:"default:false"`                      // akun dinonaktifkan, mis. karena dinonaktifkan di direktori
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
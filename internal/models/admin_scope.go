@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Tingkat node organisasi untuk AdminScope
+const (
+	ScopeLevelBranch        = "branch"
+	ScopeLevelDepartment    = "department"
+	ScopeLevelSubDepartment = "sub_department"
+)
+
+// AdminScope membatasi data yang dapat diakses admin ke satu node organisasi (MasterBranch, MasterDepartment atau
+// MasterSubDepartment). Admin tanpa AdminScope dapat mengakses seluruh data; super_admin tidak pernah dibatasi.
+type AdminScope struct {
+	ID        uint   `gorm:"primaryKey"`
+	AdminID   string `gorm:"size:36;not null;index"`
+	Level     string `gorm:"size:20;not null"` // branch, department atau sub_department
+	NodeID    uint   `gorm:"not null"`         // ID pada tabel master sesuai Level
+	CreatedAt time.Time
+}
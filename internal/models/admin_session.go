@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminSession adalah session login admin yang disimpan di server; cookie browser hanya membawa token acak.
+// ID adalah HMAC token tersebut sehingga isi tabel tidak bisa dipakai untuk login.
+type AdminSession struct {
+	ID         string `gorm:"size:64;not null;primaryKey"`
+	AdminID    string `gorm:"size:36;index"`
+	Data       []byte // nilai session (gob)
+	UserAgent  string `gorm:"size:255"`
+	IP         string `gorm:"size:45"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index"`
+	CreatedAt  time.Time
+}
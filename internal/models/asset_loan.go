@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status peminjaman aset
+const (
+	LoanStatusPending  = "Menunggu"
+	LoanStatusApproved = "Disetujui"
+	LoanStatusRejected = "Ditolak"
+	LoanStatusOnLoan   = "Dipinjam"
+	LoanStatusReturned = "Dikembalikan"
+)
+
+// AssetStatusOnLoan adalah status AssetKSO selama aset berada di tangan peminjam
+const AssetStatusOnLoan = "Dipinjam"
+
+// AssetLoan adalah permohonan peminjaman aset oleh karyawan untuk rentang tanggal tertentu
+type AssetLoan struct {
+	ID             string          `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	Number         string          `gorm:"size:100;index"` // nomor surat peminjaman, diisi saat disetujui
+	BorrowerID     string          `gorm:"size:36;not null;index"`
+	Borrower       User            `gorm:"foreignKey:BorrowerID"`
+	Purpose        string          `gorm:"type:text"`
+	StartDate      time.Time       `gorm:"not null;index"`
+	EndDate        time.Time       `gorm:"not null;index"`
+	Status         string          `gorm:"size:20;not null;index"`
+	Items          []AssetLoanItem `gorm:"foreignKey:LoanID"`
+	RequestedByID  string          `gorm:"size:36"` // Admin ID pembuat permohonan
+	ApprovedByID   string          `gorm:"size:36"` // Admin ID yang menyetujui/menolak
+	ApprovedAt     *time.Time
+	RejectReason   string `gorm:"type:text"`
+	CheckedOutByID string `gorm:"size:36"`
+	CheckedOutAt   *time.Time
+	ReturnedByID   string `gorm:"size:36"`
+	ReturnedAt     *time.Time
+	FileID         *string `gorm:"size:36"` // DMSFile surat peminjaman
+	SubmissionID   *string `gorm:"size:36"` // GoFormSubmission asal permohonan
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+// IsOverdue bernilai true jika aset masih dipinjam melewati tanggal selesai
+func (l AssetLoan) IsOverdue() bool {
+	if l.Status != LoanStatusOnLoan {
+		return false
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return l.EndDate.Before(today)
+}
+
+// AssetLoanItem adalah satu aset dalam peminjaman beserta catatan serah dan kembali
+type AssetLoanItem struct {
+	ID           uint     `gorm:"primaryKey"`
+	LoanID       string   `gorm:"size:36;not null;index"`
+	AssetID      string   `gorm:"size:36;not null;index"`
+	Asset        AssetKSO `gorm:"foreignKey:AssetID"`
+	ConditionOut string   `gorm:"size:50"` // kondisi saat diserahkan
+	ConditionIn  string   `gorm:"size:50"` // kondisi saat dikembalikan
+	Notes        string   `gorm:"type:text"`
+	CheckedOutAt *time.Time
+	ReturnedAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status surat jalan perpindahan aset
+const (
+	TransferStatusInTransit   = "Dalam Perjalanan"
+	TransferStatusReceived    = "Diterima"
+	TransferStatusDiscrepancy = "Selisih"
+)
+
+// AssetStatusInTransit adalah status AssetKSO sejak surat jalan dibuat sampai barang dikonfirmasi diterima
+const AssetStatusInTransit = "Dalam Perjalanan"
+
+// AssetTransfer adalah surat jalan perpindahan aset antar cabang (MasterBranch)
+type AssetTransfer struct {
+	ID                string              `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	Number            string              `gorm:"size:100;index"` // nomor surat jalan
+	OriginBranch      string              `gorm:"size:100;not null"`
+	DestinationBranch string              `gorm:"size:100;not null"`
+	Courier           string              `gorm:"size:100;not null"`
+	ShipDate          time.Time           `gorm:"not null"`
+	Status            string              `gorm:"size:30;not null;index"`
+	Notes             string              `gorm:"type:text"`
+	Items             []AssetTransferItem `gorm:"foreignKey:TransferID"`
+	SenderID          string              `gorm:"size:36"`
+	Sender            User                `gorm:"foreignKey:SenderID"`
+	SenderSignature   string              `gorm:"type:text"` // base64 PNG
+	ReceiverID        *string             `gorm:"size:36"`
+	Receiver          User                `gorm:"foreignKey:ReceiverID"`
+	ReceiverSignature string              `gorm:"type:text"` // base64 PNG
+	ReceivedAt        *time.Time
+	ReceivedByID      string  `gorm:"size:36"` // Admin ID yang mengonfirmasi penerimaan
+	CreatedByID       string  `gorm:"size:36"`
+	FileID            *string `gorm:"size:36"` // DMSFile surat jalan saat dikirim
+	ReceiptFileID     *string `gorm:"size:36"` // DMSFile surat jalan bertanda tangan penerima
+	SubmissionID      *string `gorm:"size:36"` // GoFormSubmission asal surat jalan
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+}
+
+// AssetTransferItem adalah satu aset dalam surat jalan beserta hasil pemeriksaan saat diterima
+type AssetTransferItem struct {
+	ID             uint     `gorm:"primaryKey"`
+	TransferID     string   `gorm:"size:36;not null;index"`
+	AssetID        string   `gorm:"size:36;not null;index"`
+	Asset          AssetKSO `gorm:"foreignKey:AssetID"`
+	OriginLocation string   `gorm:"size:100"` // lokasi aset sebelum dikirim
+	OriginStatus   string   `gorm:"size:50"`  // status aset sebelum dikirim, dipulihkan saat diterima
+	Received       bool     `gorm:"default:false"`
+	Condition      string   `gorm:"size:50"` // kondisi saat diterima: Baik, Lecet, Rusak, Hilang
+	Notes          string   `gorm:"type:text"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
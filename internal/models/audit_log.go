@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog mencatat tindakan admin yang perlu bisa ditelusuri kembali, misalnya saat super_admin
+// memakai mode "lihat sebagai" untuk menelusuri masalah akun admin lain.
+type AuditLog struct {
+	ID             uint      `gorm:"primaryKey"`
+	AdminID        string    `gorm:"size:36;index"` // admin yang sebenarnya melakukan tindakan
+	Username       string    `gorm:"size:50;index"`
+	Action         string    `gorm:"size:50;index"` // mis. impersonate.start atau impersonate.blocked
+	TargetAdminID  string    `gorm:"size:36;index"` // akun yang sedang dilihat, bila ada
+	TargetUsername string    `gorm:"size:50"`
+	Detail         string    `gorm:"size:255"` // keterangan tambahan, mis. metode dan path request
+	IP             string    `gorm:"size:45"`
+	CreatedAt      time.Time `gorm:"index"`
+}
@@ -28,7 +28,8 @@ type DMSFile struct {
 	Extension  string     `gorm:"type:varchar(10)"`
 	FilePath   string     `gorm:"type:text"`
 	UploadedBy string     `gorm:"type:varchar(36)"`
-	TrashedAt  *time.Time `gorm:"index"` // When it was moved to trash
+	DocNumber  string     `gorm:"type:varchar(100);index"` // Nomor surat resmi untuk dokumen yang digenerate sistem
+	TrashedAt  *time.Time `gorm:"index"`                   // When it was moved to trash
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	DeletedAt  gorm.DeletedAt `gorm:"index"`
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// DocumentNumberFormat menyimpan pola penomoran surat per jenis dokumen, mis. "{seq}/BAST-IT/{branch}/{roman_month}/{year}"
+type DocumentNumberFormat struct {
+	DocType   string `gorm:"size:50;primaryKey"`
+	Pattern   string `gorm:"size:255;not null"`
+	Padding   int    `gorm:"not null;default:3"` // jumlah digit {seq}
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DocumentSequence adalah nomor urut terakhir per jenis dokumen per tahun
+type DocumentSequence struct {
+	DocType    string `gorm:"size:50;primaryKey"`
+	Year       int    `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int    `gorm:"not null;default:0"`
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// DocumentTemplate adalah satu versi tata letak PDF sebuah jenis dokumen (kop, paragraf, tabel dan blok tanda tangan).
+// Setiap perubahan disimpan sebagai versi baru; hanya satu versi per jenis dokumen yang aktif.
+type DocumentTemplate struct {
+	ID             uint   `gorm:"primaryKey"`
+	DocType        string `gorm:"size:50;not null;uniqueIndex:idx_document_template_version"` // bast, bast-laptop, bast-return
+	Version        int    `gorm:"not null;uniqueIndex:idx_document_template_version"`
+	IsActive       bool   `gorm:"default:false;index"`
+	Title          string `gorm:"size:255"`
+	City           string `gorm:"size:100"`  // kota penerbitan, dipakai placeholder {kota}
+	Logos          string `gorm:"type:text"` // satu logo per baris: path|x|y|lebar (mm)
+	Opening        string `gorm:"type:text"` // paragraf pembuka sebelum identitas para pihak
+	FirstParty     string `gorm:"type:text"` // keterangan di bawah identitas PIHAK PERTAMA
+	SecondParty    string `gorm:"type:text"` // keterangan di bawah identitas PIHAK KEDUA
+	Body           string `gorm:"type:text"` // paragraf sebelum tabel, dipisah baris kosong
+	Columns        string `gorm:"type:text"` // satu kolom per baris: judul|lebar|isian|perataan
+	Closing        string `gorm:"type:text"` // paragraf setelah tabel, dipisah baris kosong
+	SignaturePlace string `gorm:"size:255"`  // baris tempat dan tanggal di atas tanda tangan
+	SignatureLeft  string `gorm:"type:text"` // keterangan blok tanda tangan kiri
+	SignatureRight string `gorm:"type:text"` // keterangan blok tanda tangan kanan
+	LeftParty      string `gorm:"size:10"`   // p1 atau p2 yang menandatangani di kiri
+	RightParty     string `gorm:"size:10"`   // p1 atau p2 yang menandatangani di kanan
+	Note           string `gorm:"size:255"`  // catatan perubahan versi
+	CreatedByID    string `gorm:"size:36"`   // Admin ID pembuat versi
+	CreatedAt      time.Time
+}
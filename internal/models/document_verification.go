@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DocumentVerification mencatat sidik dokumen PDF hasil generate agar keasliannya bisa diperiksa lewat /verify/{id}
+type DocumentVerification struct {
+	ID          string `gorm:"size:36;not null;uniqueIndex;primaryKey"` // dipakai pada QR code
+	FileID      string `gorm:"size:36;index"`                           // DMSFile yang diverifikasi
+	DocNumber   string `gorm:"size:100;index"`
+	Title       string `gorm:"size:255"`
+	IssuedTo    string `gorm:"size:255"`
+	IssuedAt    time.Time
+	Fingerprint string `gorm:"size:64;not null"` // hash metadata yang dicetak di PDF
+	FileHash    string `gorm:"size:64"`          // SHA-256 isi file PDF yang disimpan
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
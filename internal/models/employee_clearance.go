@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// EmployeeClearance adalah catatan offboarding karyawan yang sudah selesai beserta surat keterangan
+// bebas aset untuk HR. Dibuat setelah semua aset dikembalikan atau dialihkan.
+type EmployeeClearance struct {
+	ID             string `gorm:"size:36;primaryKey"`
+	UserID         string `gorm:"size:36;index"`
+	Number         string `gorm:"size:100"` // nomor surat keterangan
+	ResignDate     time.Time
+	Reason         string  `gorm:"size:255"`
+	PreviousStatus string  `gorm:"size:50"` // status karyawan sebelum diubah menjadi Resign
+	FileID         *string `gorm:"size:36"` // DMSFile surat keterangan
+	AdminID        string  `gorm:"size:36"`
+	CreatedAt      time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// EmployeeImport menyimpan satu file master karyawan dari HR yang diunggah untuk disinkronkan.
+// Baris file disimpan sebagai JSON agar pratinjau dan penerapan memakai data yang sama.
+type EmployeeImport struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	FileName  string `gorm:"size:255"`
+	Rows      string `gorm:"type:text"` // baris file dalam JSON, lihat handlers.employeeImportRow
+	AdminID   string `gorm:"size:36;index"`
+	Username  string `gorm:"size:50"`
+	AppliedAt *time.Time
+	// Ringkasan hasil penerapan
+	Created   int
+	Updated   int
+	Resigned  int
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GoForm adalah definisi formulir digital yang tampil di katalog GoForm
+type GoForm struct {
+	ID          string `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	Slug        string `gorm:"size:100;not null;uniqueIndex"` // dipakai di URL /goform/fill/{slug}
+	Name        string `gorm:"size:150;not null"`
+	Description string `gorm:"type:text"`
+	Icon        string `gorm:"size:50"`  // Bootstrap icon class, e.g. bi-tools
+	Color       string `gorm:"size:20"`  // hex color
+	Category    string `gorm:"size:100"` // IT Support, Logistik, Asset Control
+	Handler     string `gorm:"size:50"`  // alur bawaan (bast, bast-laptop); kosong = form dinamis
+	PDFTitle    string `gorm:"size:255"`
+	PDFTemplate string `gorm:"type:text"` // isi PDF dengan placeholder {nama_field}
+	IsActive    bool   `gorm:"default:true"`
+	SortOrder   int
+	Fields      []GoFormField `gorm:"foreignKey:FormID"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// GoFormField adalah satu isian pada definisi GoForm
+type GoFormField struct {
+	ID           uint   `gorm:"primaryKey"`
+	FormID       string `gorm:"size:36;not null;index"`
+	Name         string `gorm:"size:100;not null"` // key isian, e.g. tanggal_pinjam
+	Label        string `gorm:"size:150;not null"`
+	Type         string `gorm:"size:30;not null"` // text, textarea, number, date, email, select, checkbox
+	Placeholder  string `gorm:"size:255"`
+	Required     bool   `gorm:"default:false"`
+	Pattern      string `gorm:"size:255"`  // regex validasi opsional
+	Options      string `gorm:"type:text"` // opsi statis, satu per baris
+	OptionSource string `gorm:"size:50"`   // sumber master data: branch, department, position, employee, asset, ...
+	SortOrder    int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// GoFormSubmission menyimpan isian formulir secara terstruktur beserta dokumen PDF hasilnya
+type GoFormSubmission struct {
+	ID            string  `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	FormID        string  `gorm:"size:36;not null;index"`
+	Form          GoForm  `gorm:"foreignKey:FormID"`
+	Data          string  `gorm:"type:jsonb"` // []GoFormAnswer dalam format JSON
+	FileID        *string `gorm:"size:36"`    // DMSFile hasil generate
+	SubmittedByID string  `gorm:"size:36;index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+// GoFormAnswer adalah satu jawaban isian yang disimpan di GoFormSubmission.Data
+type GoFormAnswer struct {
+	Field   string `json:"field"`
+	Label   string `json:"label"`
+	Value   string `json:"value"`
+	Display string `json:"display"`
+}
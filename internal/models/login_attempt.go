@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt mencatat setiap percobaan login (berhasil maupun gagal) untuk peninjauan keamanan
+// dan sebagai dasar pembatasan percobaan per username dan per alamat IP.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey"`
+	Username  string    `gorm:"size:100;index"` // username yang diketik, walau tidak terdaftar
+	AdminID   string    `gorm:"size:36;index"`  // kosong bila username tidak dikenal
+	IP        string    `gorm:"size:45;index"`
+	UserAgent string    `gorm:"size:255"`
+	Success   bool      `gorm:"default:false"`
+	Reason    string    `gorm:"size:100"` // keterangan hasil, mis. "Password salah" atau "Akun terkunci"
+	CreatedAt time.Time `gorm:"index"`
+}
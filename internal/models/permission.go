@@ -2,6 +2,20 @@ package models
 
 import "time"
 
+// RoleSuperAdmin adalah role sistem yang selalu memiliki semua izin dan tidak dapat diubah atau dihapus
+const RoleSuperAdmin = "super_admin"
+
+// Role adalah peran admin. Name dipakai sebagai kunci di Admin.Role, RolePermission dan RolePolicy.
+type Role struct {
+	Name        string `gorm:"size:50;primaryKey"`
+	Label       string `gorm:"size:100;not null"`
+	Description string `gorm:"size:255"`
+	IsSystem    bool   `gorm:"default:false"` // role bawaan yang tidak boleh dihapus
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// RolePermission menyimpan izin satu aksi (Resource, misalnya "asset.create") untuk satu role
 type RolePermission struct {
 	ID        uint   `gorm:"primaryKey"`
 	Role      string `gorm:"size:50;not null;index"`
@@ -10,3 +24,86 @@ type RolePermission struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// RolePolicy adalah kebijakan keamanan login per role
+type RolePolicy struct {
+	Role       string `gorm:"size:50;primaryKey"`
+	Require2FA bool   `gorm:"column:require_2fa;default:false"` // admin role ini wajib memakai verifikasi dua langkah
+	UpdatedAt  time.Time
+}
+
+// Permission adalah satu aksi yang dapat diizinkan untuk role
+type Permission struct {
+	Key   string
+	Label string
+}
+
+// PermissionGroup mengelompokkan aksi per modul untuk halaman pengaturan role
+type PermissionGroup struct {
+	Module      string
+	Label       string
+	Permissions []Permission
+}
+
+// PermissionCatalog adalah daftar seluruh aksi yang diperiksa oleh PermissionRequired
+var PermissionCatalog = []PermissionGroup{
+	{Module: "dashboard", Label: "Dashboard", Permissions: []Permission{
+		{Key: "dashboard.view", Label: "Lihat dashboard"},
+	}},
+	{Module: "inventori", Label: "Inventori", Permissions: []Permission{
+		{Key: "asset.view", Label: "Lihat data aset"},
+		{Key: "asset.create", Label: "Tambah aset"},
+		{Key: "asset.update", Label: "Ubah aset"},
+		{Key: "asset.delete", Label: "Hapus aset"},
+		{Key: "asset_category.manage", Label: "Kelola kategori dan master spesifikasi aset"},
+	}},
+	{Module: "asset_management", Label: "Asset Management", Permissions: []Permission{
+		{Key: "assignment.view", Label: "Lihat penempatan aset"},
+		{Key: "assignment.manage", Label: "Serahkan / tarik aset dan ubah label"},
+		{Key: "loan.approve", Label: "Setujui / tolak peminjaman"},
+		{Key: "loan.handover", Label: "Serah terima peminjaman (check-out / check-in)"},
+		{Key: "transfer.create", Label: "Buat surat jalan pengiriman aset"},
+		{Key: "transfer.receive", Label: "Konfirmasi penerimaan surat jalan"},
+	}},
+	{Module: "maintenance", Label: "Maintenance", Permissions: []Permission{
+		{Key: "maintenance.view", Label: "Lihat laporan dan riwayat"},
+		{Key: "maintenance.create", Label: "Isi dan ajukan laporan"},
+		{Key: "maintenance.approve", Label: "Setujui laporan"},
+	}},
+	{Module: "godms", Label: "GoDMS", Permissions: []Permission{
+		{Key: "dms.view", Label: "Lihat dan unduh dokumen"},
+		{Key: "dms.upload", Label: "Unggah, buat folder, ubah nama dan pindahkan"},
+		{Key: "dms.delete", Label: "Pindahkan ke sampah dan pulihkan"},
+		{Key: "dms.delete_permanent", Label: "Hapus permanen"},
+	}},
+	{Module: "goform", Label: "GoForm", Permissions: []Permission{
+		{Key: "goform.view", Label: "Lihat dan isi formulir"},
+		{Key: "goform.manage", Label: "Form builder dan data isian"},
+		{Key: "signature.manage", Label: "Perpanjang tautan, terbitkan ulang dan batalkan permintaan tanda tangan"},
+	}},
+	{Module: "administration", Label: "Administration", Permissions: []Permission{
+		{Key: "employee.view", Label: "Lihat karyawan"},
+		{Key: "employee.create", Label: "Tambah karyawan"},
+		{Key: "employee.update", Label: "Ubah karyawan"},
+		{Key: "employee.delete", Label: "Hapus karyawan"},
+		{Key: "employee.offboarding", Label: "Offboarding karyawan"},
+		{Key: "employee.import", Label: "Impor dan sinkronisasi master karyawan dari HR"},
+		{Key: "master_data.manage", Label: "Kelola master data karyawan"},
+	}},
+	{Module: "setting", Label: "Setting", Permissions: []Permission{
+		{Key: "setting.user", Label: "Kelola user admin dan log login"},
+		{Key: "setting.role", Label: "Kelola role dan izin"},
+		{Key: "setting.document", Label: "Penomoran dan template dokumen"},
+	}},
+}
+
+// PermissionKeys mengembalikan semua kunci aksi pada PermissionCatalog
+func PermissionKeys() []string {
+	var keys []string
+	for _, group := range PermissionCatalog {
+		for _, p := range group.Permissions {
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
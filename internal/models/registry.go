@@ -17,10 +17,33 @@ func RegisterModels() []Model {
 		{Model: MasterAssetCategory{}},
 		{Model: MasterRamType{}},
 		{Model: MasterStorageType{}},
+		{Model: MasterProcessorFamily{}},
+		{Model: MasterOperatingSystem{}},
+		{Model: Role{}},
 		{Model: RolePermission{}},
+		{Model: RolePolicy{}},
 		{Model: MaintenanceDocument{}},
 		{Model: MaintenanceReport{}},
 		{Model: DMSFolder{}},
 		{Model: DMSFile{}},
+		{Model: GoForm{}},
+		{Model: GoFormField{}},
+		{Model: GoFormSubmission{}},
+		{Model: AssetLoan{}},
+		{Model: AssetLoanItem{}},
+		{Model: AssetTransfer{}},
+		{Model: AssetTransferItem{}},
+		{Model: DocumentNumberFormat{}},
+		{Model: DocumentSequence{}},
+		{Model: DocumentVerification{}},
+		{Model: SignatureRequest{}},
+		{Model: SignatureRequestSigner{}},
+		{Model: DocumentTemplate{}},
+		{Model: AdminSession{}},
+		{Model: LoginAttempt{}},
+		{Model: AdminScope{}},
+		{Model: AuditLog{}},
+		{Model: EmployeeImport{}},
+		{Model: EmployeeClearance{}},
 	}
 }
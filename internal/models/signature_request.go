@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Status permintaan tanda tangan elektronik
+const (
+	SignatureStatusPending   = "Menunggu Tanda Tangan"
+	SignatureStatusCompleted = "Selesai"
+	SignatureStatusCancelled = "Dibatalkan"
+)
+
+// SignatureRequest adalah draf dokumen GoForm (BAST) yang ditandatangani para pihak dari perangkat masing-masing;
+// PDF baru diterbitkan setelah semua tanda tangan terkumpul
+type SignatureRequest struct {
+	ID          string                   `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	FormID      string                   `gorm:"size:36;not null;index"`
+	Form        GoForm                   `gorm:"foreignKey:FormID"`
+	Title       string                   `gorm:"size:255"`
+	Summary     string                   `gorm:"type:text"` // GoFormAnswer JSON untuk ditampilkan ke penandatangan
+	FormData    string                   `gorm:"type:text"` // isian formulir asli (url-encoded) untuk membangun ulang dokumen
+	Status      string                   `gorm:"size:30;not null;index"`
+	Signers     []SignatureRequestSigner `gorm:"foreignKey:RequestID"`
+	CreatedByID string                   `gorm:"size:36"` // Admin ID pembuat draf
+	FileID      *string                  `gorm:"size:36"` // DMSFile hasil akhir
+	DocNumber   string                   `gorm:"size:100"`
+	LastError   string                   `gorm:"type:text"` // alasan terakhir dokumen gagal diterbitkan
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SignatureRequestSigner adalah satu pihak yang harus menandatangani melalui tautan pribadinya
+type SignatureRequestSigner struct {
+	ID         uint   `gorm:"primaryKey"`
+	RequestID  string `gorm:"size:36;not null;index"`
+	Role       string `gorm:"size:10;not null"` // p1, p2
+	Label      string `gorm:"size:50"`          // PIHAK PERTAMA, PIHAK KEDUA
+	UserID     string `gorm:"size:36;not null;index"`
+	User       User   `gorm:"foreignKey:UserID"`
+	Token      string `gorm:"size:64;uniqueIndex"` // bagian dari tautan /sign/{token}; NULL setelah permintaan ditutup
+	Signature  string `gorm:"type:text"`           // base64 PNG
+	Method     string `gorm:"size:20"`             // digambar, tersimpan
+	SignedAt   *time.Time
+	SignedIP   string `gorm:"size:45"`
+	NotifiedAt *time.Time
+	ExpiresAt  *time.Time // tautan tidak berlaku lagi setelah waktu ini; diperpanjang saat pengingat dikirim
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
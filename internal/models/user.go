@@ -6,18 +6,27 @@ import (
 	"gorm.io/gorm"
 )
 
+// EmployeeStatusResign menandai karyawan yang sudah keluar, misalnya karena tidak lagi ada di master karyawan HR
+const EmployeeStatusResign = "Resign"
+
 type User struct {
-	ID             string `gorm:"size:36;not null;uniqueIndex;primaryKey"`
-	NIK            string `gorm:"size:20;uniqueIndex"`
-	Name           string `gorm:"size:100;not null"`
-	Email          string `gorm:"size:100;not null;uniqueIndex"`
-	Branch         string `gorm:"size:100"`         // Cabang
-	Department     string `gorm:"size:100"`         // Bagian
-	SubDepartment  string `gorm:"size:100"`         // Sub Bagian
-	Position       string `gorm:"size:50;not null"` // Jabatan
-	StatusKaryawan string `gorm:"size:50"`
-	Password       string `gorm:"size:100;not null"`
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	DeletedAt      gorm.DeletedAt
+	ID              string              `gorm:"size:36;not null;uniqueIndex;primaryKey"`
+	NIK             string              `gorm:"size:20;uniqueIndex"`
+	Name            string              `gorm:"size:100;not null"`
+	Email           string              `gorm:"size:100;not null;uniqueIndex"`
+	BranchID        *uint               `gorm:"index"` // Cabang
+	Branch          MasterBranch        `gorm:"foreignKey:BranchID"`
+	DepartmentID    *uint               `gorm:"index"` // Bagian
+	Department      MasterDepartment    `gorm:"foreignKey:DepartmentID"`
+	SubDepartmentID *uint               `gorm:"index"` // Sub Bagian
+	SubDepartment   MasterSubDepartment `gorm:"foreignKey:SubDepartmentID"`
+	PositionID      *uint               `gorm:"index"` // Jabatan
+	Position        MasterPosition      `gorm:"foreignKey:PositionID"`
+	StatusKaryawan  string              `gorm:"size:50"`
+	ResignDate      *time.Time          // tanggal keluar, diisi saat offboarding selesai
+	OffboardingNote string              `gorm:"size:100"` // alasan impor HR menandai karyawan perlu offboarding; kosong bila tidak ada
+	Password        string              `gorm:"size:100;not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt
 }